@@ -3,14 +3,83 @@ package api
 import (
 	"net/http"
 
+	"github.com/crikke/cms/cmd/contentdelivery/api/handlers"
+	assetapi "github.com/crikke/cms/cmd/contentdelivery/api/v1/asset"
+	"github.com/crikke/cms/cmd/contentdelivery/api/v1/content"
+	"github.com/crikke/cms/cmd/contentdelivery/api/v1/routing"
+	searchapi "github.com/crikke/cms/cmd/contentdelivery/api/v1/search"
 	"github.com/crikke/cms/cmd/contentdelivery/app"
+	"github.com/crikke/cms/cmd/contentdelivery/app/query"
+	"github.com/crikke/cms/pkg/asset"
+	contentrepo "github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/crikke/cms/pkg/locale"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
 	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func NewContentDeliveryAPI(app app.App) http.Handler {
 
 	r := chi.NewRouter()
+	wsHandler := handlers.WorkspaceHandler{App: app}
+
+	r.Route("/workspaces/{workspace}", func(r chi.Router) {
+		r.Use(wsHandler.WorkspaceParamContext)
+		r.Use(locale.WorkspaceHandler(handlers.WithWorkspace))
+
+		r.Mount("/content", content.NewContentRoute(app))
+		r.Mount("/routes", routing.NewRoutingRoute(app))
+		r.Mount("/search", searchapi.NewSearchRoute(app))
+		r.Mount("/assets", assetapi.NewAssetRoute(app))
+	})
 
-	// r.Mount("/v1/content", content.NewContentRoute(app))
 	return r
 }
+
+// NewApp initializes the query handlers of the content delivery application.
+// The files of assets are read from storage, transformed images are kept in cache.
+func NewApp(c *mongo.Client, storage asset.Storage, cache imaging.Cache, signer imaging.Signer) app.App {
+
+	contentRepo := contentrepo.NewContentRepository(c)
+	workspaceRepo := workspace.NewWorkspaceRepository(c)
+	contentDefinitionRepo := contentdefinition.NewContentDefinitionRepository(c)
+
+	return app.App{
+		Queries: app.Queries{
+			GetContentByID: query.GetContentByIDHandler{
+				Repo:                        contentRepo,
+				ContentDefinitionRepository: contentDefinitionRepo,
+			},
+			GetContentByTags: query.GetContentByTagsHandler{
+				Repo:                        contentRepo,
+				ContentDefinitionRepository: contentDefinitionRepo,
+			},
+			GetContentNear: query.GetContentNearHandler{
+				Repo:                        contentRepo,
+				ContentDefinitionRepository: contentDefinitionRepo,
+			},
+			GetContentURL: query.GetContentURLHandler{
+				Repo: contentRepo,
+			},
+			ResolveRoute: query.ResolveRouteHandler{
+				Repo: contentRepo,
+			},
+			GetWorkspace: query.GetWorkspaceHandler{
+				Repo: workspaceRepo,
+			},
+			Search: query.SearchHandler{
+				Repo: search.NewSearchRepository(c),
+			},
+			GetAssetImage: query.GetAssetImageHandler{
+				Repo:              asset.NewAssetRepository(c),
+				ContentRepository: contentRepo,
+				Storage:           storage,
+				Cache:             cache,
+				Signer:            signer,
+			},
+		},
+	}
+}
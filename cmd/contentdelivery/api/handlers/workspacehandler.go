@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/crikke/cms/cmd/contentdelivery/app"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type key string
+
+const workspacekey = key("workspace")
+const WorkspaceQueryParam = "workspace"
+
+type WorkspaceHandler struct {
+	App app.App
+}
+
+func (h WorkspaceHandler) WorkspaceParamContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		uid, err := uuid.Parse(chi.URLParam(r, WorkspaceQueryParam))
+
+		if err != nil {
+			http.Error(w, "workspace param: bad format", http.StatusBadRequest)
+			return
+		}
+
+		ws, err := h.App.Queries.GetWorkspace.Handle(r.Context(), uid)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "workspace not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), workspacekey, ws)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func WithWorkspace(ctx context.Context) workspace.Workspace {
+	if ws := ctx.Value(workspacekey); ws != nil {
+		return ws.(workspace.Workspace)
+	}
+	return workspace.Workspace{}
+}
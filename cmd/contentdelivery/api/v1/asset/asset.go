@@ -0,0 +1,114 @@
+package asset
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/crikke/cms/cmd/contentdelivery/api/handlers"
+	"github.com/crikke/cms/cmd/contentdelivery/app"
+	"github.com/crikke/cms/cmd/contentdelivery/app/query"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type endpoint struct {
+	app app.App
+}
+
+func NewAssetRoute(app app.App) http.Handler {
+
+	r := chi.NewRouter()
+	ep := endpoint{app: app}
+
+	r.Get("/{asset}", ep.GetAsset())
+
+	return r
+}
+
+// GetAsset 					godoc
+// @Summary 					Get asset file
+// @Description 				Returns the file of an asset. Images can be resized, cropped and converted, the options must be signed by the content management API.
+// @Description					Transformed images are cached, the first request of a transformation is slower.
+// @Tags 						asset
+// @Produces 					octet-stream
+// @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						asset		path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						width		query	int		false	"width in pixels, max 4096"
+// @Param						height		query	int		false	"height in pixels, max 4096"
+// @Param						fit			query	string	false	"how the image fits width and height, defaults to contain" Enums(contain, cover, fill)
+// @Param						format		query	string	false	"output format, defaults to the format of the image" Enums(jpeg, png, webp)
+// @Param						quality		query	int		false	"jpeg quality 1-100, defaults to 80"
+// @Param						crop		query	string	false	"x,y,width,height in pixels of the part of the image to use"
+// @Param						focal		query	string	false	"x,y between 0 and 1 that cover keeps in view, defaults to the focal point of the asset"
+// @Param						sig			query	string	false	"signature of the options, required if any option is set"
+// @Success						200			{file}		file
+// @Header						200			{string}	ETag
+// @Failure						400			{string}	string
+// @Failure						403			{string}	string	"invalid signature"
+// @Failure						404			{string}	string
+// @Router						/contentdelivery/workspaces/{workspace}/assets/{asset} [get]
+func (ep endpoint) GetAsset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uuid.Parse(chi.URLParam(r, "asset"))
+		if err != nil {
+			http.Error(w, "asset: bad format", http.StatusBadRequest)
+			return
+		}
+
+		opts, err := imaging.ParseOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := ep.app.Queries.GetAssetImage.Handle(r.Context(), query.GetAssetImage{
+			ID:          id,
+			WorkspaceId: handlers.WithWorkspace(r.Context()).ID,
+			Options:     opts,
+			Signature:   r.URL.Query().Get(imaging.SignatureParam),
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil && strings.HasPrefix(err.Error(), imaging.ErrInvalidSignature) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if err != nil && (strings.HasPrefix(err.Error(), imaging.ErrUnsupportedImage) ||
+			strings.HasPrefix(err.Error(), imaging.ErrSourceImageTooLarge) ||
+			strings.HasPrefix(err.Error(), imaging.ErrInvalidCrop)) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer res.File.Close()
+
+		w.Header().Set("Content-Type", res.ContentType)
+		w.Header().Set("ETag", strconv.Quote(res.ETag))
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+
+		// files that are not images, such as uploaded html, are never rendered on the origin of the API
+		if !imaging.IsInline(res.ContentType) {
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": res.Asset.Filename}))
+		}
+
+		http.ServeContent(w, r, res.Asset.Filename, res.Asset.Updated, res.File)
+	}
+}
@@ -1,193 +1,337 @@
 package content
 
-// type key string
-
-// const langKey = key("language")
-// const contentKey = key("language")
-
-// type endpoint struct {
-// 	app app.App
-// }
-
-// func NewContentRoute(app app.App) http.Handler {
-
-// 	r := chi.NewRouter()
-// 	ep := endpoint{app: app}
-
-// 	r.Use(ep.localeContext)
-
-// 	r.Get("/", ep.ListContentByTags())
-// 	r.Route("/{id}", func(r chi.Router) {
-// 		r.Use(idContext)
-// 		r.Get("/", ep.GetContentById())
-// 	})
-
-// 	return r
-// }
-
-// func idContext(next http.Handler) http.Handler {
-// 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-// 		contentID := chi.URLParam(r, "id")
-// 		if contentID == "" {
-
-// 			models.WithError(r.Context(), models.GenericError{
-// 				StatusCode: http.StatusBadRequest,
-// 				Body: models.ErrorBody{
-// 					FieldName: "contentid",
-// 					Message:   "parameter contentid is required",
-// 				},
-// 			})
-// 			return
-// 		}
-
-// 		cid, err := uuid.Parse(contentID)
-
-// 		if err != nil {
-// 			models.WithError(r.Context(), models.GenericError{
-// 				StatusCode: http.StatusBadRequest,
-// 				Body: models.ErrorBody{
-// 					FieldName: "contentid",
-// 					Message:   "bad format",
-// 				},
-// 			})
-// 			return
-// 		}
-
-// 		ctx := context.WithValue(r.Context(), contentKey, cid)
-// 		next.ServeHTTP(w, r.WithContext(ctx))
-// 	})
-// }
-
-// func withID(ctx context.Context) uuid.UUID {
-
-// 	var id uuid.UUID
-
-// 	if r := ctx.Value(contentKey); r != nil {
-// 		id = r.(uuid.UUID)
-// 	}
-
-// 	return id
-// }
-
-// func withLocale(ctx context.Context) string {
-
-// 	if tag := ctx.Value(langKey); tag != nil {
-
-// 		t := tag.(language.Tag)
-
-// 		return t.String()
-// 	}
-
-// 	return ""
-// }
-
-// func (ep endpoint) localeContext(next http.Handler) http.Handler {
-
-// 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-// 		ctx := r.Context()
-// 		accept := r.Header.Get("Accept-Language")
-
-// 		if accept == "" {
-
-// 			l := ep.app.SiteConfiguration.Languages[0]
-// 			ctx = context.WithValue(ctx, langKey, l)
-
-// 			next.ServeHTTP(w, r.WithContext(ctx))
-// 			return
-// 		}
-
-// 		t, _, err := language.ParseAcceptLanguage(accept)
-
-// 		if err != nil {
-
-// 			models.WithError(r.Context(), models.GenericError{
-// 				StatusCode: http.StatusBadRequest,
-// 				Body: models.ErrorBody{
-// 					FieldName: "",
-// 					Message:   "Accept-language",
-// 				},
-// 			})
-// 			return
-// 		}
-// 		matcher := language.NewMatcher(ep.app.SiteConfiguration.Languages)
-
-// 		tag, _, _ := matcher.Match(t...)
-
-// 		base, _ := tag.Base()
-// 		region, _ := tag.Region()
-// 		tag, err = language.Compose(base, region)
-
-// 		if err != nil {
-// 			models.WithError(r.Context(), models.GenericError{
-// 				StatusCode: http.StatusBadRequest,
-// 				Body: models.ErrorBody{
-// 					FieldName: "",
-// 					Message:   "Accept-language",
-// 				},
-// 			})
-// 			return
-// 		}
-// 		ctx = context.WithValue(ctx, langKey, tag)
-
-// 		next.ServeHTTP(w, r.WithContext(ctx))
-// 	})
-// }
-
-// // GetContentById 		godoc
-// // @Summary 					Get content by ID
-// // @Description 				Gets content by ID and language. If Accept-Language header is not set,
-// // @Description					the default language will be used.
-// //
-// // @Tags 						content
-// // @Accept 						json
-// // @Produces 					json
-// // @Param						id					path	string	true 	"uuid formatted ID." format(uuid)
-// // @Param 						Accept-Language 	header 	string 	false 	"content language"
-// // @Success						200			{object}	query.ContentResponse
-// // @Failure						default		{object}	models.GenericError
-// // @Router						/contentdelivery/content/{id} [get]
-// func (ep endpoint) GetContentById() http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-
-// 		lang := withLocale(r.Context())
-// 		id := withID(r.Context())
-
-// 		content, err := ep.app.Queries.GetContentByID.Handle(r.Context(), query.GetContentByID{
-// 			ID:       id,
-// 			Language: lang,
-// 		})
-
-// 		if err != nil {
-// 			models.WithError(r.Context(), err)
-// 			return
-// 		}
-
-// 		data, err := json.Marshal(&content)
-// 		if err != nil {
-// 			w.WriteHeader(http.StatusBadRequest)
-// 			w.Write([]byte(err.Error()))
-// 			return
-// 		}
-
-// 		w.Write(data)
-// 	}
-// }
-
-// // ListContentByTags 			godoc
-// // @Summary 					List content by tags
-// // @Description 				Returns a list of content which has specified tags
-// //
-// // @Tags 						content
-// // @Accept 						json
-// // @Produces 					json
-// // @Param						id					path	string	true 	"uuid formatted ID." format(uuid)
-// // @Param 						Accept-Language 	header 	string 	false 	"content language"
-// // @Success						200			{object}	query.ContentResponse
-// // @Failure						default		{object}	models.GenericError
-// // @Router						/contentdelivery/content/ [get]
-// func (ep endpoint) ListContentByTags() http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-
-// 	}
-// }
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/crikke/cms/cmd/contentdelivery/api/handlers"
+	"github.com/crikke/cms/cmd/contentdelivery/app"
+	"github.com/crikke/cms/cmd/contentdelivery/app/query"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/locale"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type key string
+
+const contentKey = key("content")
+
+type endpoint struct {
+	app app.App
+}
+
+func NewContentRoute(app app.App) http.Handler {
+
+	r := chi.NewRouter()
+	ep := endpoint{app: app}
+
+	r.With(db.PageHandler).Get("/", ep.ListContentByTags())
+	r.With(db.PageHandler).Get("/near", ep.ListContentNear())
+	r.Route("/{id}", func(r chi.Router) {
+		r.Use(idContext)
+		r.Get("/", ep.GetContentById())
+		r.Get("/url", ep.GetContentURL())
+	})
+
+	return r
+}
+
+func idContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		cid, err := uuid.Parse(chi.URLParam(r, "id"))
+
+		if err != nil {
+			http.Error(w, "id: bad format", http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contentKey, cid)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// fields returns the fields query parameter, both fields=a,b and fields=a&fields=b are supported.
+func fields(r *http.Request) []string {
+	return list(r, "fields")
+}
+
+// expand returns the expand and depth query parameters, the range of depth is validated by query.Expand
+func expand(r *http.Request) (query.Expand, error) {
+
+	e := query.Expand{Fields: list(r, "expand")}
+
+	if depth := r.URL.Query().Get("depth"); depth != "" {
+		d, err := strconv.Atoi(depth)
+		if err != nil {
+			return query.Expand{}, fmt.Errorf("%s: depth must be a number", query.ErrInvalidExpand)
+		}
+		e.Depth = d
+	}
+
+	return e, nil
+}
+
+func list(r *http.Request, name string) []string {
+
+	result := make([]string, 0)
+	for _, value := range r.URL.Query()[name] {
+		for _, f := range strings.Split(value, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				result = append(result, f)
+			}
+		}
+	}
+
+	return result
+}
+
+func withID(ctx context.Context) uuid.UUID {
+
+	var id uuid.UUID
+
+	if r := ctx.Value(contentKey); r != nil {
+		id = r.(uuid.UUID)
+	}
+
+	return id
+}
+
+// GetContentById 			godoc
+// @Summary 					Get content by ID
+// @Description 				Gets the published version of content in the requested language. Localized fields that are missing
+// @Description					in the requested language fall back through the languages of the workspace, the language of each field is returned.
+//
+// @Tags 						content
+// @Produces 					json
+// @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param 						locale 		query 	string 	false 	"content language, overrides Accept-Language"
+// @Param 						Accept-Language 	header 	string 	false 	"content language"
+// @Param						fields		query	[]string	false	"fields to return, all fields are returned if empty"
+// @Param						expand		query	[]string	false	"reference fields to replace with the referenced content, * expands every reference field"
+// @Param						depth		query	int			false	"levels of referenced content to expand, 1-3, 0 or no value for the default of 1"
+// @Param						format		query	string		false	"format of richtext fields, ast or html, defaults to ast"	Enums(ast, html)
+// @Param						timezone	query	string		false	"IANA time zone datetime fields are returned in, ie Europe/Stockholm, defaults to UTC"
+// @Success						200			{object}	query.ContentResponse
+// @Header						200			{string}	Content-Language
+// @Failure						404			{string}	string
+// @Router						/contentdelivery/workspaces/{workspace}/content/{id} [get]
+func (ep endpoint) GetContentById() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		exp, err := expand(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := ep.app.Queries.GetContentByID.Handle(r.Context(), query.GetContentByID{
+			ID:        withID(r.Context()),
+			Language:  locale.FromContext(r.Context()),
+			Workspace: handlers.WithWorkspace(r.Context()),
+			Fields:    fields(r),
+			Expand:    exp,
+			Format:    query.RichTextFormat(r.URL.Query().Get("format")),
+			Timezone:  r.URL.Query().Get("timezone"),
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
+
+// ListContentByTags 			godoc
+// @Summary 					List content by tags
+// @Description 				Returns published content which has any of the specified tags
+//
+// @Tags 						content
+// @Produces 					json
+// @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						tag			query	[]string	false	"tag id"
+// @Param						filter		query	string		false	"filter expression, ie: price gt 10 and title contains \"x\", dates are compared with strings, ie: start ge \"2022-01-01T00:00:00Z\""
+// @Param						sort		query	string		false	"created, updated or a property, prefix with - for descending order"
+// @Param						limit		query	int			false	"page size, 1-100, defaults to 20"
+// @Param						token		query	string		false	"continuation token of the previous page"
+// @Param						fields		query	[]string	false	"fields to return, all fields are returned if empty"
+// @Param						expand		query	[]string	false	"reference fields to replace with the referenced content, * expands every reference field"
+// @Param						depth		query	int			false	"levels of referenced content to expand, 1-3, 0 or no value for the default of 1"
+// @Param						format		query	string		false	"format of richtext fields, ast or html, defaults to ast"	Enums(ast, html)
+// @Param						timezone	query	string		false	"IANA time zone datetime fields are returned in, ie Europe/Stockholm, defaults to UTC"
+// @Param 						locale 		query 	string 	false 	"content language, overrides Accept-Language"
+// @Param 						Accept-Language 	header 	string 	false 	"content language"
+// @Success						200			{object}	query.ContentListResponse
+// @Header						200			{string}	Content-Language
+// @Failure						default		{string}	string
+// @Router						/contentdelivery/workspaces/{workspace}/content [get]
+func (ep endpoint) ListContentByTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		exp, err := expand(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := ep.app.Queries.GetContentByTags.Handle(r.Context(), query.GetContentByTags{
+			Tags:      r.URL.Query()["tag"],
+			Filter:    r.URL.Query().Get("filter"),
+			Page:      db.PageFromContext(r.Context()),
+			Fields:    fields(r),
+			Expand:    exp,
+			Format:    query.RichTextFormat(r.URL.Query().Get("format")),
+			Timezone:  r.URL.Query().Get("timezone"),
+			Language:  locale.FromContext(r.Context()),
+			Workspace: handlers.WithWorkspace(r.Context()),
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
+
+// ListContentNear 			godoc
+// @Summary 					List content near a point
+// @Description 				Returns published content with a geopoint within distance km of lat, lng, nearest first.
+// @Description					Geopoints in lists and objects are included.
+// @Description					The distance of each item is the distance in km to its nearest geopoint.
+//
+// @Tags 						content
+// @Produces 					json
+// @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						lat			query	number		true	"latitude, -90 to 90"
+// @Param						lng			query	number		true	"longitude, -180 to 180"
+// @Param						distance	query	number		true	"max distance in km"
+// @Param						tag			query	[]string	false	"tag id"
+// @Param						limit		query	int			false	"max number of items, 1-100, defaults to 20"
+// @Param						fields		query	[]string	false	"fields to return, all fields are returned if empty"
+// @Param						expand		query	[]string	false	"reference fields to replace with the referenced content, * expands every reference field"
+// @Param						depth		query	int			false	"levels of referenced content to expand, 1-3, 0 or no value for the default of 1"
+// @Param						format		query	string		false	"format of richtext fields, ast or html, defaults to ast"	Enums(ast, html)
+// @Param						timezone	query	string		false	"IANA time zone datetime fields are returned in, ie Europe/Stockholm, defaults to UTC"
+// @Param 						locale 		query 	string 	false 	"content language, overrides Accept-Language"
+// @Param 						Accept-Language 	header 	string 	false 	"content language"
+// @Success						200			{object}	query.NearbyContentListResponse
+// @Header						200			{string}	Content-Language
+// @Failure						default		{string}	string
+// @Router						/contentdelivery/workspaces/{workspace}/content/near [get]
+func (ep endpoint) ListContentNear() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		params := make(map[string]float64)
+		for _, name := range []string{"lat", "lng", "distance"} {
+			v, err := strconv.ParseFloat(r.URL.Query().Get(name), 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("%s: bad format", name), http.StatusBadRequest)
+				return
+			}
+			params[name] = v
+		}
+
+		exp, err := expand(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := ep.app.Queries.GetContentNear.Handle(r.Context(), query.GetContentNear{
+			Lat:       params["lat"],
+			Lng:       params["lng"],
+			Distance:  params["distance"],
+			Tags:      r.URL.Query()["tag"],
+			Page:      db.PageFromContext(r.Context()),
+			Fields:    fields(r),
+			Expand:    exp,
+			Format:    query.RichTextFormat(r.URL.Query().Get("format")),
+			Timezone:  r.URL.Query().Get("timezone"),
+			Language:  locale.FromContext(r.Context()),
+			Workspace: handlers.WithWorkspace(r.Context()),
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
+
+// GetContentURL 				godoc
+// @Summary 					Get URL of content
+// @Description 				Returns the canonical path of published content in the requested language.
+// @Tags 						content
+// @Produces 					json
+// @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param 						locale 		query 	string 	false 	"content language, overrides Accept-Language"
+// @Param 						Accept-Language 	header 	string 	false 	"content language"
+// @Success						200			{object}	query.URLResponse
+// @Failure						404			{string}	string
+// @Router						/contentdelivery/workspaces/{workspace}/content/{id}/url [get]
+func (ep endpoint) GetContentURL() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		res, err := ep.app.Queries.GetContentURL.Handle(r.Context(), query.GetContentURL{
+			ID:        withID(r.Context()),
+			Language:  locale.FromContext(r.Context()),
+			Workspace: handlers.WithWorkspace(r.Context()),
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
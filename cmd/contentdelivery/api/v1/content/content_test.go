@@ -0,0 +1,41 @@
+//go:build unit
+
+package content
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crikke/cms/cmd/contentdelivery/app/query"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Expand(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		query string
+		depth int
+		valid bool
+	}{
+		{name: "no depth", query: "expand=*", valid: true},
+		{name: "default depth", query: "expand=*&depth=0", valid: true},
+		{name: "max depth", query: "expand=*&depth=3", depth: query.MaxExpandDepth, valid: true},
+		{name: "negative depth", query: "expand=*&depth=-1", depth: -1},
+		{name: "depth above max", query: "expand=*&depth=4", depth: query.MaxExpandDepth + 1},
+		{name: "not a number", query: "expand=*&depth=a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, err := expand(httptest.NewRequest("GET", "/?"+test.query, nil))
+			if err == nil {
+				assert.Equal(t, test.depth, e.Depth)
+				err = e.Validate()
+			}
+
+			// the HTTP parameter and the query agree on which depths are valid
+			assert.Equal(t, test.valid, err == nil, err)
+		})
+	}
+}
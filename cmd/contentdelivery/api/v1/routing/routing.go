@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/crikke/cms/cmd/contentdelivery/api/handlers"
+	"github.com/crikke/cms/cmd/contentdelivery/app"
+	"github.com/crikke/cms/cmd/contentdelivery/app/query"
+	"github.com/crikke/cms/pkg/locale"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type endpoint struct {
+	app app.App
+}
+
+func NewRoutingRoute(app app.App) http.Handler {
+
+	r := chi.NewRouter()
+	ep := endpoint{app: app}
+
+	r.Get("/*", ep.ResolveRoute())
+
+	return r
+}
+
+// ResolveRoute 				godoc
+// @Summary 					Get content by URL
+// @Description 				Resolves a localized path, e.g. /about/team, to published content.
+// @Description					Previous paths and paths in other languages are redirected to the canonical path of the content.
+// @Tags 						routing
+// @Produces 					json
+// @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						path		path	string	true 	"path of the content"
+// @Param 						locale 		query 	string 	false 	"content language, overrides Accept-Language"
+// @Param 						Accept-Language 	header 	string 	false 	"content language"
+// @Success						200			{object}	query.ContentResponse
+// @Header						200			{string}	Content-Language
+// @Header						301			{string}	Location	"canonical URL"
+// @Failure						404			{string}	string
+// @Router						/contentdelivery/workspaces/{workspace}/routes/{path} [get]
+func (ep endpoint) ResolveRoute() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		path := "/" + chi.URLParam(r, "*")
+		ws := handlers.WithWorkspace(r.Context())
+
+		res, err := ep.app.Queries.ResolveRoute.Handle(r.Context(), query.ResolveRoute{
+			Path:      path,
+			Language:  locale.FromContext(r.Context()),
+			Workspace: ws,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if res.Canonical != path {
+			location := routePrefix(r, ws.ID.String()) + escapePath(res.Canonical)
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+
+			http.Redirect(w, r, location, http.StatusMovedPermanently)
+			return
+		}
+
+		data, err := json.Marshal(&res.Content)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
+
+// routePrefix returns the path the routing route is mounted at, e.g. /contentdelivery/workspaces/{workspace}/routes.
+// It is built from the matched route pattern instead of the request path, which may be encoded differently than the path param.
+func routePrefix(r *http.Request, workspace string) string {
+	pattern := strings.TrimSuffix(chi.RouteContext(r.Context()).RoutePattern(), "/*")
+	return strings.Replace(pattern, "{"+handlers.WorkspaceQueryParam+"}", url.PathEscape(workspace), 1)
+}
+
+// escapePath escapes each segment of the path, so segments with reserved characters are kept in the Location header.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
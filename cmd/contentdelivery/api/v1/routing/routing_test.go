@@ -0,0 +1,35 @@
+//go:build unit
+
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedirectLocation(t *testing.T) {
+
+	ws := "0b6c5a49-7a8e-4a8f-9a1e-2f7d8c6b5e44"
+
+	var actual string
+	r := chi.NewRouter()
+	r.Route("/contentdelivery/workspaces/{workspace}", func(r chi.Router) {
+		r.Mount("/routes", func() http.Handler {
+			sub := chi.NewRouter()
+			sub.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+				actual = routePrefix(r, ws) + escapePath("/om oss/100%/?")
+			})
+			return sub
+		}())
+	})
+
+	// the request path is percent-encoded, the location must not depend on how the client encoded it
+	req := httptest.NewRequest(http.MethodGet, "/contentdelivery/workspaces/"+ws+"/routes/%6Fm%20oss", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "/contentdelivery/workspaces/"+ws+"/routes/om%20oss/100%25/%3F", actual)
+}
@@ -0,0 +1,68 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/crikke/cms/cmd/contentdelivery/api/handlers"
+	"github.com/crikke/cms/cmd/contentdelivery/app"
+	"github.com/crikke/cms/cmd/contentdelivery/app/query"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/locale"
+	"github.com/go-chi/chi/v5"
+)
+
+type endpoint struct {
+	app app.App
+}
+
+func NewSearchRoute(app app.App) http.Handler {
+
+	r := chi.NewRouter()
+	ep := endpoint{app: app}
+
+	r.With(db.PageHandler).Get("/", ep.Search())
+
+	return r
+}
+
+// Search 						godoc
+// @Summary 					Search published content
+// @Description 				Full-text search of the text fields of published content in the requested language.
+// @Description					Results are ordered by relevance and contain highlighted snippets of the matching fields.
+// @Tags 						search
+// @Produces 					json
+// @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						q			query	string	true	"search query, words in quotes are searched as a phrase and words prefixed with - are excluded"
+// @Param						limit		query	int		false	"page size, 1-100, defaults to 20"
+// @Param						token		query	string	false	"continuation token of the previous page"
+// @Param 						locale 		query 	string 	false 	"content language, overrides Accept-Language"
+// @Param 						Accept-Language 	header 	string 	false 	"content language"
+// @Success						200			{object}	query.SearchResponse
+// @Header						200			{string}	Content-Language
+// @Failure						default		{string}	string
+// @Router						/contentdelivery/workspaces/{workspace}/search [get]
+func (ep endpoint) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		res, err := ep.app.Queries.Search.Handle(r.Context(), query.Search{
+			Query:     r.URL.Query().Get("q"),
+			Language:  locale.FromContext(r.Context()),
+			Page:      db.PageFromContext(r.Context()),
+			Workspace: handlers.WithWorkspace(r.Context()),
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
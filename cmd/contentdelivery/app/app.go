@@ -5,7 +5,14 @@ import (
 )
 
 type Queries struct {
-	GetContentByID query.GetContentByIDHandler
+	GetContentByID   query.GetContentByIDHandler
+	GetContentByTags query.GetContentByTagsHandler
+	GetContentNear   query.GetContentNearHandler
+	GetContentURL    query.GetContentURLHandler
+	ResolveRoute     query.ResolveRouteHandler
+	GetWorkspace     query.GetWorkspaceHandler
+	Search           query.SearchHandler
+	GetAssetImage    query.GetAssetImageHandler
 }
 type App struct {
 	Queries Queries
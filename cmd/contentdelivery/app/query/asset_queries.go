@@ -0,0 +1,121 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/crikke/cms/pkg/asset"
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type GetAssetImage struct {
+	ID          uuid.UUID
+	WorkspaceId uuid.UUID
+	// Options of the transformation, the file of the asset is returned unchanged if they are zero
+	Options imaging.Options
+	// Signature of the options, created by the content management API
+	Signature string
+}
+
+type AssetImageResponse struct {
+	Asset       asset.Asset
+	ContentType string
+	// ETag identifies the returned file, the checksum of the asset or the cache key of the transformed image
+	ETag string
+	// File is closed by the caller
+	File io.ReadSeekCloser
+}
+
+type GetAssetImageHandler struct {
+	Repo              asset.AssetRepository
+	ContentRepository content.ContentManagementRepository
+	Storage           asset.Storage
+	Cache             imaging.Cache
+	Signer            imaging.Signer
+}
+
+// Handle returns the file of the asset, transformed if options are set.
+// Transformed images are rendered once and then served from the cache.
+// Returns mongo.ErrNoDocuments if the asset does not exist or is not used by published content.
+func (h GetAssetImageHandler) Handle(ctx context.Context, query GetAssetImage) (AssetImageResponse, error) {
+
+	if !query.Options.IsZero() && !h.Signer.Verify(query.WorkspaceId, query.ID, query.Options, query.Signature) {
+		return AssetImageResponse{}, errors.New(imaging.ErrInvalidSignature)
+	}
+
+	// the asset is always read so images of deleted assets are not served from the cache
+	a, err := h.Repo.GetAsset(ctx, query.ID, query.WorkspaceId)
+	if err != nil {
+		return AssetImageResponse{}, err
+	}
+
+	// assets are uploaded before they are published, only assets of published content are public
+	published, err := h.ContentRepository.IsAssetPublished(ctx, query.ID, query.WorkspaceId)
+	if err != nil {
+		return AssetImageResponse{}, err
+	}
+
+	if !published {
+		return AssetImageResponse{}, mongo.ErrNoDocuments
+	}
+
+	if query.Options.IsZero() {
+		file, err := h.Storage.Open(ctx, query.WorkspaceId, a.Key())
+		if err != nil {
+			return AssetImageResponse{}, err
+		}
+
+		return AssetImageResponse{Asset: a, ContentType: a.MimeType, ETag: a.Checksum, File: file}, nil
+	}
+
+	format, ok := imaging.FormatOf(a.MimeType)
+	if !ok {
+		return AssetImageResponse{}, fmt.Errorf("%s: %s", imaging.ErrUnsupportedImage, a.MimeType)
+	}
+
+	opts := query.Options
+	if opts.Format == "" {
+		opts.Format = format
+	}
+	if opts.Focal == nil {
+		opts.Focal = a.Focal
+	}
+
+	key := imaging.Key(a.Checksum, opts)
+	res := AssetImageResponse{Asset: a, ContentType: opts.Format.ContentType(), ETag: key}
+
+	res.File, err = h.Cache.Open(query.WorkspaceId, query.ID, key)
+	if err == nil {
+		return res, nil
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return AssetImageResponse{}, err
+	}
+
+	src, err := h.Storage.Open(ctx, query.WorkspaceId, a.Key())
+	if err != nil {
+		return AssetImageResponse{}, err
+	}
+	defer src.Close()
+
+	err = h.Cache.Put(query.WorkspaceId, query.ID, key, func(w io.Writer) error {
+		return imaging.Render(w, src, opts)
+	})
+	if err != nil {
+		return AssetImageResponse{}, err
+	}
+
+	res.File, err = h.Cache.Open(query.WorkspaceId, query.ID, key)
+	if err != nil {
+		return AssetImageResponse{}, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,117 @@
+//go:build integration
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/crikke/cms/pkg/asset"
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_GetAssetImage(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 40, 20))))
+
+	a, err := asset.AssetFactory{}.NewAsset("bike.png", bytes.NewReader(buf.Bytes()), nil, []string{"sv-SE"}, time.Now().UTC())
+	assert.NoError(t, err)
+
+	storage := asset.NewFileSystemStorage(t.TempDir())
+	repo := asset.NewAssetRepository(c)
+	assert.NoError(t, storage.Put(context.Background(), wsId, a.Key(), bytes.NewReader(buf.Bytes())))
+	assert.NoError(t, repo.CreateAsset(context.Background(), a, wsId))
+
+	contentRepo := content.NewContentRepository(c)
+	signer := imaging.NewSigner("secret")
+	handler := GetAssetImageHandler{
+		Repo:              repo,
+		ContentRepository: contentRepo,
+		Storage:           storage,
+		Cache:             imaging.NewCache(t.TempDir()),
+		Signer:            signer,
+	}
+
+	t.Run("asset that is not published is not served", func(t *testing.T) {
+		_, err := handler.Handle(context.Background(), GetAssetImage{ID: a.ID, WorkspaceId: wsId})
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+
+	_, err = contentRepo.CreateContent(context.Background(), content.Content{
+		Data: content.ContentData{Status: content.Published, Assets: []uuid.UUID{a.ID}},
+	}, wsId)
+	assert.NoError(t, err)
+
+	t.Run("original file needs no signature", func(t *testing.T) {
+		res, err := handler.Handle(context.Background(), GetAssetImage{ID: a.ID, WorkspaceId: wsId})
+		assert.NoError(t, err)
+		defer res.File.Close()
+
+		data, err := io.ReadAll(res.File)
+		assert.NoError(t, err)
+		assert.Equal(t, buf.Bytes(), data)
+		assert.Equal(t, "image/png", res.ContentType)
+		assert.Equal(t, a.Checksum, res.ETag)
+	})
+
+	t.Run("unsigned options are rejected", func(t *testing.T) {
+		_, err := handler.Handle(context.Background(), GetAssetImage{ID: a.ID, WorkspaceId: wsId, Options: imaging.Options{Width: 10}})
+		assert.EqualError(t, err, imaging.ErrInvalidSignature)
+	})
+
+	t.Run("image is transformed and cached", func(t *testing.T) {
+		opts := imaging.Options{Width: 10, Format: imaging.FormatJPEG}
+		query := GetAssetImage{ID: a.ID, WorkspaceId: wsId, Options: opts, Signature: signer.Sign(wsId, a.ID, opts)}
+
+		res, err := handler.Handle(context.Background(), query)
+		assert.NoError(t, err)
+		defer res.File.Close()
+		assert.Equal(t, "image/jpeg", res.ContentType)
+
+		cfg, _, err := image.DecodeConfig(res.File)
+		assert.NoError(t, err)
+		assert.Equal(t, 10, cfg.Width)
+		assert.Equal(t, 5, cfg.Height)
+
+		// the source is not read again
+		assert.NoError(t, storage.Delete(context.Background(), wsId, a.Key()))
+		cached, err := handler.Handle(context.Background(), query)
+		assert.NoError(t, err)
+		defer cached.File.Close()
+		assert.Equal(t, res.ETag, cached.ETag)
+	})
+
+	t.Run("deleted asset is not served from cache", func(t *testing.T) {
+		assert.NoError(t, repo.DeleteAsset(context.Background(), a.ID, wsId))
+
+		opts := imaging.Options{Width: 10, Format: imaging.FormatJPEG}
+		_, err := handler.Handle(context.Background(), GetAssetImage{ID: a.ID, WorkspaceId: wsId, Options: opts, Signature: signer.Sign(wsId, a.ID, opts)})
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+		_, err = storage.Open(context.Background(), wsId, a.Key())
+		assert.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+}
@@ -2,58 +2,156 @@ package query
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/workspace"
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type GetContentByID struct {
-	ID       uuid.UUID
+	ID        uuid.UUID
+	Language  string
+	Workspace workspace.Workspace
+	// What fields to return, all fields are returned if empty
+	Fields []string
+	// Reference fields to replace with the referenced content
+	Expand Expand
+	// Format of richtext fields, defaults to RichTextAST
+	Format RichTextFormat
+	// Timezone datetime fields are returned in, an IANA name like Europe/Stockholm. Defaults to UTC
+	Timezone string
+}
+
+// FieldResponse is a field of published content in the requested language
+type FieldResponse struct {
+	ID        uuid.UUID
+	Type      string
+	Localized bool
+	Value     interface{}
+	// Label is the display name of the selected option of an enum field in the requested language,
+	// or the display names of the selected options if the field allows more than one.
+	Label interface{} `json:",omitempty"`
+	// Language the value comes from, differs from the requested language when the value is missing
+	// in the requested language and a fallback language is used.
 	Language string
 }
 
 type ContentResponse struct {
 	ID                 uuid.UUID
+	Language           string
 	AvailableLanguages []string
-	Fields             content.ContentFields
+	Fields             map[string]FieldResponse
 	Created            time.Time `bson:"created"`
+	// URL is the canonical path of the content in Language, empty if the content is not routable
+	URL string `json:",omitempty"`
+
+	// contentDefinitionID is used to read the labels of enum fields
+	contentDefinitionID uuid.UUID
+}
+
+// newContentResponse returns the published content in language.
+// Unlocalized fields only exist in the default language and are added from there. Localized fields that
+// are missing in language fall back through the languages of the workspace in order.
+func newContentResponse(c content.Content, language string, languages []string) ContentResponse {
+
+	defaultLanguage := languages[0]
+	fallback := append([]string{language}, languages...)
+
+	fields := make(map[string]FieldResponse)
+	for name, f := range c.Data.Properties[defaultLanguage] {
+		field := FieldResponse{
+			ID:        f.ID,
+			Type:      f.Type,
+			Localized: f.Localized,
+			Value:     f.Value,
+			Language:  defaultLanguage,
+		}
+
+		if f.Localized {
+			field.Value = nil
+			field.Language = ""
+
+			for _, lang := range fallback {
+				if lf, ok := c.Data.Properties[lang][name]; ok && lf.Value != nil {
+					field.Value = lf.Value
+					field.Language = lang
+					break
+				}
+			}
+		}
+
+		fields[name] = field
+	}
+
+	url, _ := c.Route(language)
+
+	return ContentResponse{
+		ID:                 c.ID,
+		Language:           language,
+		AvailableLanguages: c.Data.AvailableLanguages(),
+		Fields:             fields,
+		Created:            c.Data.Created,
+		URL:                url,
+
+		contentDefinitionID: c.ContentDefinitionID,
+	}
 }
+
 type GetContentByIDHandler struct {
-	Repo content.ContentManagementRepository
+	Repo                        content.ContentManagementRepository
+	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
 }
 
+// Handle returns mongo.ErrNoDocuments if the content does not exist or is not published.
 func (h GetContentByIDHandler) Handle(ctx context.Context, query GetContentByID) (ContentResponse, error) {
 
-	// if query.ID == (uuid.UUID{}) {
-	// 	return ContentResponse{}, errors.New("missing id")
-	// }
+	if query.ID == (uuid.UUID{}) {
+		return ContentResponse{}, errors.New("missing id")
+	}
+
+	projection := content.FieldProjection{
+		Fields:    query.Fields,
+		Languages: query.Workspace.Languages,
+	}
 
-	// contentresult, err := h.Repo.GetContent(ctx, query.ID, nil)
+	if err := projection.Validate(); err != nil {
+		return ContentResponse{}, err
+	}
 
-	// if err != nil {
-	// 	return ContentResponse{}, err
-	// }
+	if err := query.Expand.Validate(); err != nil {
+		return ContentResponse{}, err
+	}
 
-	// cv := contentresult.Data
-	// if cv.Status != content.Published {
-	// 	return ContentResponse{}, errors.New("not published")
-	// }
+	if err := query.Format.Validate(); err != nil {
+		return ContentResponse{}, err
+	}
 
-	// fields, ok := cv.Properties[query.Language]
+	loc, err := loadTimezone(query.Timezone)
+	if err != nil {
+		return ContentResponse{}, err
+	}
 
-	// if !ok {
-	// 	return ContentResponse{}, errors.New(content.ErrMissingLanguage)
-	// }
+	c, err := h.Repo.GetPublishedContent(ctx, query.ID, projection, query.Workspace.ID)
+	if err != nil {
+		return ContentResponse{}, err
+	}
 
-	// response := ContentResponse{
-	// 	ID:                 contentresult.ID,
-	// 	AvailableLanguages: cv.AvailableLanguages(),
-	// 	Fields:             fields,
-	// 	Created:            cv.Created,
-	// }
+	res := newContentResponse(c, query.Language, query.Workspace.Languages)
+	if err := newExpander(h.Repo, query.Expand, query.Language, query.Workspace).expandResponse(ctx, &res); err != nil {
+		return ContentResponse{}, err
+	}
 
-	return ContentResponse{}, nil
+	if err := newLabeler(h.ContentDefinitionRepository, query.Language, query.Workspace).labelResponse(ctx, &res); err != nil {
+		return ContentResponse{}, err
+	}
+
+	formatResponse(&res, query.Format, loc)
+	return res, nil
 }
 
 type ContentListResponse struct {
@@ -63,45 +161,125 @@ type ContentListResponse struct {
 	Count int
 	// how many items exists
 	Total int
+	// continuation token of the next page, empty if this is the last page
+	Next string `json:",omitempty"`
 }
 
 type GetContentByTags struct {
 	Tags []string
-	// What fields to return
+	// Filter expression, see content.Filter. Localized fields are compared in Language without fallback
+	Filter string
+	// What fields to return, all fields are returned if empty
 	Fields []string
+	// Reference fields to replace with the referenced content
+	Expand Expand
+	// Format of richtext fields, defaults to RichTextAST
+	Format RichTextFormat
+	// Timezone datetime fields are returned in, an IANA name like Europe/Stockholm. Defaults to UTC
+	Timezone string
+	// Page.Sort is created, updated or a property, see content.SortField
+	Page      db.Page
+	Language  string
+	Workspace workspace.Workspace
 }
 
 type GetContentByTagsHandler struct {
-	Repo content.ContentManagementRepository
+	Repo                        content.ContentManagementRepository
+	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
 }
 
 //! TODO: Query builder, Find a way how it can be done loosly coupled.
 //! Since in the future, there will probably exist filtering on more than just tags
 
-//! TODO: Find a way to use projection to only return specified fields from repository instead
-//! of fetching the whole content object and projecting fields on the server
-//! this could otherwise become an performance issue.
+// Fields are projected in the database, since properties are stored per language every requested field
+// is read in every language of the workspace so missing values can fall back to another language.
+func (h GetContentByTagsHandler) Handle(ctx context.Context, query GetContentByTags) (ContentListResponse, error) {
+
+	projection := content.FieldProjection{
+		Fields:    query.Fields,
+		Languages: query.Workspace.Languages,
+	}
+
+	if err := projection.Validate(); err != nil {
+		return ContentListResponse{}, err
+	}
+
+	if err := query.Expand.Validate(); err != nil {
+		return ContentListResponse{}, err
+	}
+
+	if err := query.Format.Validate(); err != nil {
+		return ContentListResponse{}, err
+	}
+
+	loc, err := loadTimezone(query.Timezone)
+	if err != nil {
+		return ContentListResponse{}, err
+	}
+
+	filter, page, err := h.compileQuery(ctx, query)
+	if err != nil {
+		return ContentListResponse{}, err
+	}
+
+	items, res, err := h.Repo.ListPublishedContent(ctx, query.Tags, filter, projection, page, query.Workspace.ID)
+	if err != nil {
+		return ContentListResponse{}, err
+	}
+
+	result := ContentListResponse{
+		Items: make([]ContentResponse, 0, len(items)),
+		Count: len(items),
+		Total: res.Total,
+		Next:  res.Next,
+	}
+
+	expander := newExpander(h.Repo, query.Expand, query.Language, query.Workspace)
+	labeler := newLabeler(h.ContentDefinitionRepository, query.Language, query.Workspace)
+	for _, item := range items {
+		res := newContentResponse(item, query.Language, query.Workspace.Languages)
+		if err := expander.expandResponse(ctx, &res); err != nil {
+			return ContentListResponse{}, err
+		}
+
+		if err := labeler.labelResponse(ctx, &res); err != nil {
+			return ContentListResponse{}, err
+		}
+		formatResponse(&res, query.Format, loc)
+
+		result.Items = append(result.Items, res)
+	}
+
+	return result, nil
+}
+
+// compileQuery validates the filter and sort against every content definition in the workspace
+func (h GetContentByTagsHandler) compileQuery(ctx context.Context, query GetContentByTags) (bson.M, db.Page, error) {
+
+	f, err := content.ParseFilter(query.Filter)
+	if err != nil {
+		return nil, db.Page{}, err
+	}
 
-//! This should work by getting all fields from default locale where field.localized: false
-//! and fields from specified locale where field.localized:true
-func (h GetContentByTagsHandler) Handle(ctx context.Context, query GetContentByTags) ([]ContentListResponse, error) {
+	page := query.Page
+	if f.IsEmpty() && page.Sort == "" {
+		return nil, page, nil
+	}
 
-	// items, err := h.Repo.ListContentByTags(ctx, query.Tags)
+	definitions, err := h.ContentDefinitionRepository.ListContentDefinitions(ctx, query.Workspace.ID)
+	if err != nil {
+		return nil, db.Page{}, err
+	}
 
-	// if err != nil {
-	// 	return nil, err
-	// }
+	page.Sort, err = content.SortField(page.Sort, definitions, query.Language, query.Workspace.Languages[0])
+	if err != nil {
+		return nil, db.Page{}, err
+	}
 
-	// result := make([]ContentListResponse, 0)
-	// for _, item := range items {
-	// 	if item.Status != content.Published {
-	// 		continue
-	// 	}
-	// 	// result = append(result, ContentListResponse{
-	// 	// 	ID: item.ID,
-	// 	// 	Name: item,
-	// 	// })
-	// }
+	filter, err := f.Compile(definitions, query.Language, query.Workspace.Languages[0])
+	if err != nil {
+		return nil, db.Page{}, err
+	}
 
-	return nil, nil
+	return filter, page, nil
 }
@@ -0,0 +1,461 @@
+//go:build integration
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_FieldProjection(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE", "en-US"},
+	})
+	assert.NoError(t, err)
+
+	ws, err := wsRepo.Get(context.Background(), wsId)
+	assert.NoError(t, err)
+
+	repo := content.NewContentRepository(c)
+	id, err := repo.CreateContent(context.Background(), content.Content{
+		Data: content.ContentData{
+			Status: content.Published,
+			Tags:   []string{"news"},
+			Properties: content.ContentLanguage{
+				"sv-SE": content.ContentFields{
+					"name":  {ID: uuid.New(), Type: "text", Localized: true, Value: "namn"},
+					"title": {ID: uuid.New(), Type: "text", Localized: true, Value: "titel"},
+					"body":  {ID: uuid.New(), Type: "text", Localized: true, Value: "text"},
+				},
+				"en-US": content.ContentFields{
+					"title": {Type: "text", Localized: true, Value: "title"},
+					"body":  {Type: "text", Localized: true, Value: "body"},
+				},
+			},
+		},
+	}, wsId)
+	assert.NoError(t, err)
+
+	projection := content.FieldProjection{
+		Fields:    []string{"title"},
+		Languages: ws.Languages,
+	}
+
+	// the projection is applied by the database, not when the response is created
+	actual, err := repo.GetPublishedContent(context.Background(), id, projection, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, id, actual.ID)
+	assert.Equal(t, []string{"news"}, actual.Data.Tags)
+	assert.Equal(t, content.ContentFields{"title": {ID: actual.Data.Properties["sv-SE"]["title"].ID, Type: "text", Localized: true, Value: "titel"}}, actual.Data.Properties["sv-SE"])
+	assert.Equal(t, content.ContentFields{"title": {Type: "text", Localized: true, Value: "title"}}, actual.Data.Properties["en-US"])
+
+	items, _, err := repo.ListPublishedContent(context.Background(), []string{"news"}, nil, projection, db.Page{}, wsId)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Len(t, items[0].Data.Properties["sv-SE"], 1)
+	assert.Len(t, items[0].Data.Properties["en-US"], 1)
+
+	// the sort field is below a projected field
+	items, _, err = repo.ListPublishedContent(context.Background(), []string{"news"}, nil, projection, db.Page{Sort: "data.properties.sv-SE.title.value", Size: 1}, wsId)
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "titel", items[0].Data.Properties["sv-SE"]["title"].Value)
+	}
+
+	handler := GetContentByTagsHandler{Repo: repo}
+	res, err := handler.Handle(context.Background(), GetContentByTags{
+		Tags:      []string{"news"},
+		Fields:    []string{"title"},
+		Language:  "en-US",
+		Workspace: ws,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Count)
+	assert.Len(t, res.Items[0].Fields, 1)
+	assert.Equal(t, "title", res.Items[0].Fields["title"].Value)
+
+	_, err = GetContentByIDHandler{Repo: repo}.Handle(context.Background(), GetContentByID{
+		ID:        id,
+		Fields:    []string{"data.status"},
+		Language:  "en-US",
+		Workspace: ws,
+	})
+	assert.EqualError(t, err, content.ErrInvalidFieldName)
+
+	t.Cleanup(func() {
+		wsRepo.Delete(context.Background(), wsId)
+	})
+}
+
+func Test_FilterContent(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE", "en-US"},
+	})
+	assert.NoError(t, err)
+
+	ws, err := wsRepo.Get(context.Background(), wsId)
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	cdId, err := cdRepo.CreateContentDefinition(context.Background(), &contentdefinition.ContentDefinition{
+		Name: "product",
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"title": {ID: uuid.New(), Type: contentdefinition.PropertyTypeText, Localized: true},
+			"price": {ID: uuid.New(), Type: contentdefinition.PropertyTypeNumber},
+		},
+	}, wsId)
+	assert.NoError(t, err)
+
+	repo := content.NewContentRepository(c)
+	newProduct := func(title string, price float64) uuid.UUID {
+		id, err := repo.CreateContent(context.Background(), content.Content{
+			ContentDefinitionID: cdId,
+			Data: content.ContentData{
+				Status: content.Published,
+				Properties: content.ContentLanguage{
+					"sv-SE": content.ContentFields{
+						"price": {Type: contentdefinition.PropertyTypeNumber, Value: price},
+					},
+					"en-US": content.ContentFields{
+						"title": {Type: contentdefinition.PropertyTypeText, Localized: true, Value: title},
+					},
+				},
+			},
+		}, wsId)
+		assert.NoError(t, err)
+		return id
+	}
+
+	cheap := newProduct("Cheap shoe", 5)
+	expensive := newProduct("Expensive shoe", 50)
+	newProduct("Expensive hat", 100)
+
+	handler := GetContentByTagsHandler{Repo: repo, ContentDefinitionRepository: cdRepo}
+	list := func(filter string) []uuid.UUID {
+		res, err := handler.Handle(context.Background(), GetContentByTags{
+			Filter:    filter,
+			Language:  "en-US",
+			Workspace: ws,
+		})
+		assert.NoError(t, err)
+
+		ids := make([]uuid.UUID, 0)
+		for _, item := range res.Items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []uuid.UUID{cheap, expensive}, list(`title contains "SHOE"`))
+	assert.ElementsMatch(t, []uuid.UUID{expensive}, list(`title contains "shoe" and price gt 10`))
+	assert.ElementsMatch(t, []uuid.UUID{cheap}, list(`not price ge 50`))
+
+	_, err = handler.Handle(context.Background(), GetContentByTags{
+		Filter:    `price gt "10"`,
+		Language:  "en-US",
+		Workspace: ws,
+	})
+	assert.Error(t, err)
+
+	t.Cleanup(func() {
+		wsRepo.Delete(context.Background(), wsId)
+	})
+}
+
+func Test_PageContent(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	ws, err := wsRepo.Get(context.Background(), wsId)
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	cdId, err := cdRepo.CreateContentDefinition(context.Background(), &contentdefinition.ContentDefinition{
+		Name: "product",
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"price": {ID: uuid.New(), Type: contentdefinition.PropertyTypeNumber},
+		},
+	}, wsId)
+	assert.NoError(t, err)
+
+	repo := content.NewContentRepository(c)
+	expect := make([]uuid.UUID, 0)
+
+	// two items without price are sorted first, and two items have the same price
+	for _, price := range []interface{}{nil, nil, 1.0, 2.0, 2.0, 3.0, 4.0} {
+		fields := content.ContentFields{}
+		if price != nil {
+			fields["price"] = content.ContentField{Type: contentdefinition.PropertyTypeNumber, Value: price}
+		}
+
+		id, err := repo.CreateContent(context.Background(), content.Content{
+			ContentDefinitionID: cdId,
+			Data: content.ContentData{
+				Status:     content.Published,
+				Properties: content.ContentLanguage{"sv-SE": fields},
+			},
+		}, wsId)
+		assert.NoError(t, err)
+		expect = append(expect, id)
+	}
+
+	handler := GetContentByTagsHandler{Repo: repo, ContentDefinitionRepository: cdRepo}
+	list := func(sort string) []uuid.UUID {
+		ids := make([]uuid.UUID, 0)
+		page := db.Page{Sort: sort, Size: 2}
+
+		for {
+			res, err := handler.Handle(context.Background(), GetContentByTags{
+				Page:      page,
+				Language:  "sv-SE",
+				Workspace: ws,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, len(expect), res.Total)
+			assert.LessOrEqual(t, res.Count, 2)
+
+			for _, item := range res.Items {
+				ids = append(ids, item.ID)
+			}
+
+			if res.Next == "" {
+				return ids
+			}
+			page.Token = res.Next
+		}
+	}
+
+	ascending := list("price")
+	assert.ElementsMatch(t, expect[:2], ascending[:2])
+	assert.ElementsMatch(t, expect[3:5], ascending[3:5])
+	assert.Equal(t, expect[2], ascending[2])
+	assert.Equal(t, expect[5:], ascending[5:])
+
+	descending := list("-price")
+	for i := range ascending {
+		if i < 2 || (i >= 3 && i < 5) {
+			continue
+		}
+		assert.Equal(t, ascending[i], descending[len(descending)-1-i])
+	}
+	assert.ElementsMatch(t, expect, descending)
+
+	_, err = handler.Handle(context.Background(), GetContentByTags{
+		Page:      db.Page{Sort: "created", Size: 2, Token: "invalid"},
+		Language:  "sv-SE",
+		Workspace: ws,
+	})
+	assert.EqualError(t, err, db.ErrInvalidPageToken)
+
+	t.Cleanup(func() {
+		wsRepo.Delete(context.Background(), wsId)
+	})
+}
+
+func Test_ExpandReferences(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	ws, err := wsRepo.Get(context.Background(), wsId)
+	assert.NoError(t, err)
+
+	repo := content.NewContentRepository(c)
+	create := func(name string, status content.PublishStatus, refs ...string) uuid.UUID {
+		fields := content.ContentFields{
+			"name":   {Type: "text", Localized: true, Value: name},
+			"author": {Type: contentdefinition.PropertyTypeReference},
+			"tags":   {Type: contentdefinition.PropertyTypeReference},
+		}
+
+		if len(refs) > 0 {
+			fields["author"] = content.ContentField{Type: contentdefinition.PropertyTypeReference, Value: refs[0]}
+
+			tags := make([]interface{}, 0)
+			for _, r := range refs {
+				tags = append(tags, r)
+			}
+			fields["tags"] = content.ContentField{Type: contentdefinition.PropertyTypeReference, Value: tags}
+		}
+
+		id, err := repo.CreateContent(context.Background(), content.Content{
+			Data: content.ContentData{
+				Status:     status,
+				Properties: content.ContentLanguage{"sv-SE": fields},
+			},
+		}, wsId)
+		assert.NoError(t, err)
+		return id
+	}
+
+	draft := create("draft", content.Draft)
+	leaf := create("leaf", content.Published)
+	middle := create("middle", content.Published, leaf.String(), draft.String())
+	root := create("root", content.Published, middle.String())
+
+	handler := GetContentByIDHandler{Repo: repo}
+
+	t.Run("not expanded", func(t *testing.T) {
+		res, err := handler.Handle(context.Background(), GetContentByID{ID: root, Language: "sv-SE", Workspace: ws})
+		assert.NoError(t, err)
+		assert.Equal(t, middle.String(), res.Fields["author"].Value)
+	})
+
+	t.Run("single level", func(t *testing.T) {
+		res, err := handler.Handle(context.Background(), GetContentByID{
+			ID:        root,
+			Language:  "sv-SE",
+			Workspace: ws,
+			Expand:    Expand{Fields: []string{"author"}},
+		})
+		assert.NoError(t, err)
+
+		author := res.Fields["author"].Value.(ContentResponse)
+		assert.Equal(t, middle, author.ID)
+		assert.Equal(t, leaf.String(), author.Fields["author"].Value)
+
+		// only listed fields are expanded
+		assert.IsType(t, primitive.A{}, res.Fields["tags"].Value)
+	})
+
+	t.Run("nested levels skip unpublished content", func(t *testing.T) {
+		res, err := handler.Handle(context.Background(), GetContentByID{
+			ID:        root,
+			Language:  "sv-SE",
+			Workspace: ws,
+			Expand:    Expand{Fields: []string{ExpandAll}, Depth: 2},
+		})
+		assert.NoError(t, err)
+
+		tags := res.Fields["tags"].Value.([]ContentResponse)
+		if assert.Len(t, tags, 1) {
+			nested := tags[0].Fields["tags"].Value.([]ContentResponse)
+			if assert.Len(t, nested, 1) {
+				assert.Equal(t, leaf, nested[0].ID)
+			}
+		}
+	})
+
+	t.Run("depth is limited", func(t *testing.T) {
+		_, err := handler.Handle(context.Background(), GetContentByID{
+			ID:        root,
+			Language:  "sv-SE",
+			Workspace: ws,
+			Expand:    Expand{Fields: []string{ExpandAll}, Depth: MaxExpandDepth + 1},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func Test_ContentNear(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	ws, err := wsRepo.Get(context.Background(), wsId)
+	assert.NoError(t, err)
+
+	repo := content.NewContentRepository(c)
+	assert.NoError(t, repo.EnsureIndexes(context.Background(), wsId))
+
+	create := func(name string, lat, lng float64) uuid.UUID {
+		p, err := validator.NewGeoPoint(lat, lng)
+		assert.NoError(t, err)
+
+		id, err := repo.CreateContent(context.Background(), content.Content{
+			Data: content.ContentData{
+				Status: content.Published,
+				Properties: content.ContentLanguage{
+					"sv-SE": content.ContentFields{
+						"name":     {ID: uuid.New(), Type: contentdefinition.PropertyTypeText, Value: name},
+						"location": {ID: uuid.New(), Type: contentdefinition.PropertyTypeGeoPoint, Value: p},
+					},
+				},
+				Locations: []validator.GeoPoint{p},
+			},
+		}, wsId)
+		assert.NoError(t, err)
+		return id
+	}
+
+	odenplan := create("odenplan", 59.3429, 18.0497)
+	slussen := create("slussen", 59.3199, 18.0719)
+	create("göteborg", 57.7089, 11.9746)
+
+	handler := GetContentNearHandler{Repo: repo}
+
+	// from the central station
+	res, err := handler.Handle(context.Background(), GetContentNear{
+		Lat:       59.3307,
+		Lng:       18.0586,
+		Distance:  5,
+		Language:  "sv-SE",
+		Workspace: ws,
+	})
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, res.Count) {
+		assert.Equal(t, slussen, res.Items[0].ID)
+		assert.Equal(t, odenplan, res.Items[1].ID)
+		assert.True(t, res.Items[0].Distance < res.Items[1].Distance)
+		assert.Equal(t, validator.GeoPoint{Type: "Point", Coordinates: []float64{18.0719, 59.3199}}, res.Items[0].Fields["location"].Value)
+	}
+
+	res, err = handler.Handle(context.Background(), GetContentNear{
+		Lat:       59.3307,
+		Lng:       18.0586,
+		Distance:  500,
+		Page:      db.Page{Size: 1},
+		Language:  "sv-SE",
+		Workspace: ws,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Count)
+
+	_, err = handler.Handle(context.Background(), GetContentNear{Lat: 91, Lng: 18, Distance: 5, Language: "sv-SE", Workspace: ws})
+	assert.Error(t, err)
+
+	_, err = handler.Handle(context.Background(), GetContentNear{Lat: 59, Lng: 18, Language: "sv-SE", Workspace: ws})
+	assert.Error(t, err)
+
+	t.Cleanup(func() {
+		wsRepo.Delete(context.Background(), wsId)
+	})
+}
@@ -0,0 +1,209 @@
+//go:build unit
+
+package query
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/richtext"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_NewContentResponse(t *testing.T) {
+
+	languages := []string{"sv-SE", "en-US", "nb-NO"}
+
+	c := content.Content{
+		ID: uuid.New(),
+		Data: content.ContentData{
+			Status: content.Published,
+			Properties: content.ContentLanguage{
+				"sv-SE": content.ContentFields{
+					"name":     {Localized: true, Value: "namn"},
+					"title":    {Localized: true, Value: "titel"},
+					"price":    {Localized: false, Value: 10},
+					"subtitle": {Localized: true},
+				},
+				"en-US": content.ContentFields{
+					"name":  {Localized: true, Value: "name"},
+					"title": {Localized: true},
+				},
+				"nb-NO": content.ContentFields{
+					"name":  {Localized: true},
+					"title": {Localized: true, Value: "tittel"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		expected map[string]FieldResponse
+	}{
+		{
+			name:     "default language",
+			language: "sv-SE",
+			expected: map[string]FieldResponse{
+				"name":     {Localized: true, Value: "namn", Language: "sv-SE"},
+				"title":    {Localized: true, Value: "titel", Language: "sv-SE"},
+				"price":    {Localized: false, Value: 10, Language: "sv-SE"},
+				"subtitle": {Localized: true},
+			},
+		},
+		{
+			name:     "missing value falls back to default language",
+			language: "en-US",
+			expected: map[string]FieldResponse{
+				"name":     {Localized: true, Value: "name", Language: "en-US"},
+				"title":    {Localized: true, Value: "titel", Language: "sv-SE"},
+				"price":    {Localized: false, Value: 10, Language: "sv-SE"},
+				"subtitle": {Localized: true},
+			},
+		},
+		{
+			name:     "language without version",
+			language: "nb-NO",
+			expected: map[string]FieldResponse{
+				"name":     {Localized: true, Value: "namn", Language: "sv-SE"},
+				"title":    {Localized: true, Value: "tittel", Language: "nb-NO"},
+				"price":    {Localized: false, Value: 10, Language: "sv-SE"},
+				"subtitle": {Localized: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := newContentResponse(c, test.language, languages)
+
+			assert.Equal(t, test.language, actual.Language)
+			assert.Equal(t, test.expected, actual.Fields)
+		})
+	}
+}
+
+func Test_FormatResponse(t *testing.T) {
+
+	stored := bson.M{"blocks": bson.A{
+		bson.M{"type": "paragraph", "spans": bson.A{bson.M{"text": "x", "marks": bson.A{bson.M{"type": "bold"}}}}},
+	}}
+	doc := richtext.Document{Blocks: []richtext.Block{
+		{Type: richtext.Paragraph, Spans: []richtext.Span{{Text: "x", Marks: []richtext.Mark{{Type: richtext.Bold}}}}},
+	}}
+
+	newResponse := func() ContentResponse {
+		return ContentResponse{Fields: map[string]FieldResponse{
+			"body":  {Type: contentdefinition.PropertyTypeRichText, Value: stored},
+			"empty": {Type: contentdefinition.PropertyTypeRichText},
+			"name":  {Type: contentdefinition.PropertyTypeText, Value: "name"},
+			"related": {Type: contentdefinition.PropertyTypeReference, Value: []ContentResponse{
+				{Fields: map[string]FieldResponse{"body": {Type: contentdefinition.PropertyTypeRichText, Value: stored}}},
+			}},
+		}}
+	}
+
+	tests := []struct {
+		format RichTextFormat
+		expect interface{}
+	}{
+		{format: "", expect: doc},
+		{format: RichTextAST, expect: doc},
+		{format: RichTextHTML, expect: "<p><strong>x</strong></p>"},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			res := newResponse()
+			formatResponse(&res, test.format, time.UTC)
+
+			assert.Equal(t, test.expect, res.Fields["body"].Value)
+			assert.Nil(t, res.Fields["empty"].Value)
+			assert.Equal(t, "name", res.Fields["name"].Value)
+			assert.Equal(t, test.expect, res.Fields["related"].Value.([]ContentResponse)[0].Fields["body"].Value)
+		})
+	}
+
+	assert.Error(t, RichTextFormat("markdown").Validate())
+}
+
+func Test_FormatResponse_DateTime(t *testing.T) {
+
+	start := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	res := ContentResponse{Fields: map[string]FieldResponse{
+		"start": {Type: contentdefinition.PropertyTypeDateTime, Value: primitive.NewDateTimeFromTime(start)},
+		"end":   {Type: contentdefinition.PropertyTypeDateTime},
+		"day":   {Type: contentdefinition.PropertyTypeDate, Value: "2022-05-01"},
+	}}
+
+	loc, err := loadTimezone("Europe/Stockholm")
+	assert.NoError(t, err)
+
+	formatResponse(&res, RichTextAST, loc)
+
+	value := res.Fields["start"].Value.(time.Time)
+	assert.True(t, start.Equal(value))
+	assert.Equal(t, "2022-05-01T10:00:00+02:00", value.Format(time.RFC3339))
+	assert.Nil(t, res.Fields["end"].Value)
+	assert.Equal(t, "2022-05-01", res.Fields["day"].Value)
+
+	loc, err = loadTimezone("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	for _, invalid := range []string{"Local", "Mars/Olympus_Mons", "../etc"} {
+		_, err = loadTimezone(invalid)
+		assert.True(t, strings.HasPrefix(err.Error(), ErrInvalidTimezone), invalid)
+	}
+}
+
+func Test_FormatResponse_Nested(t *testing.T) {
+
+	arrival := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	stop := content.ContentFields{
+		"station":  {ID: uuid.New(), Type: contentdefinition.PropertyTypeText, Value: "Centralen"},
+		"arrival":  {ID: uuid.New(), Type: contentdefinition.PropertyTypeDateTime, Value: arrival},
+		"lines":    {ID: uuid.New(), Type: contentdefinition.PropertyTypeList, Value: []interface{}{1, 2}},
+		"position": {ID: uuid.New(), Type: contentdefinition.PropertyTypeGeoPoint, Value: validator.GeoPoint{Type: "Point", Coordinates: []float64{18.0586, 59.3307}}},
+	}
+
+	// objects are documents when they are read from the database
+	data, err := bson.Marshal(bson.M{"value": primitive.A{stop}})
+	assert.NoError(t, err)
+	var stored struct{ Value interface{} }
+	assert.NoError(t, bson.Unmarshal(data, &stored))
+
+	res := ContentResponse{Fields: map[string]FieldResponse{
+		"stops": {Type: contentdefinition.PropertyTypeList, Value: stored.Value},
+		"tags":  {Type: contentdefinition.PropertyTypeList, Value: primitive.A{"a", "b"}},
+		"empty": {Type: contentdefinition.PropertyTypeObject},
+		"location": {Type: contentdefinition.PropertyTypeGeoPoint, Value: primitive.D{
+			{Key: "type", Value: "Point"}, {Key: "coordinates", Value: primitive.A{18.0686, 59.3293}},
+		}},
+	}}
+
+	loc, err := loadTimezone("Europe/Stockholm")
+	assert.NoError(t, err)
+
+	formatResponse(&res, RichTextAST, loc)
+
+	stops := res.Fields["stops"].Value.([]interface{})
+	if assert.Len(t, stops, 1) {
+		values := stops[0].(map[string]interface{})
+		assert.Equal(t, "Centralen", values["station"])
+		assert.Equal(t, validator.GeoPoint{Type: "Point", Coordinates: []float64{18.0586, 59.3307}}, values["position"])
+		assert.Equal(t, "2022-05-01T10:00:00+02:00", values["arrival"].(time.Time).Format(time.RFC3339))
+		assert.Equal(t, []interface{}{int32(1), int32(2)}, values["lines"])
+	}
+	assert.Equal(t, []interface{}{"a", "b"}, res.Fields["tags"].Value)
+	assert.Nil(t, res.Fields["empty"].Value)
+	assert.Equal(t, validator.GeoPoint{Type: "Point", Coordinates: []float64{18.0686, 59.3293}}, res.Fields["location"].Value)
+}
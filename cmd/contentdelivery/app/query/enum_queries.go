@@ -0,0 +1,117 @@
+package query
+
+import (
+	"context"
+	"errors"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// labeler sets the label of enum fields from the options of their contentdefinition.
+// Contentdefinitions are read once per request, even if several content items are of the same contentdefinition.
+type labeler struct {
+	repo      contentdefinition.ContentDefinitionRepository
+	workspace uuid.UUID
+	// languages labels are read from in order, the requested language followed by the languages of the workspace
+	languages []string
+	// cache is nil for contentdefinitions that have been deleted
+	cache map[uuid.UUID]*contentdefinition.ContentDefinition
+}
+
+func newLabeler(repo contentdefinition.ContentDefinitionRepository, language string, ws workspace.Workspace) *labeler {
+
+	return &labeler{
+		repo:      repo,
+		workspace: ws.ID,
+		languages: append([]string{language}, ws.Languages...),
+		cache:     make(map[uuid.UUID]*contentdefinition.ContentDefinition),
+	}
+}
+
+// labelResponse sets the label of every enum field of res, including the fields of expanded content.
+// The label of a single value is a string and the labels of a list of values is a list, values without a label are their own label.
+func (l *labeler) labelResponse(ctx context.Context, res *ContentResponse) error {
+
+	for name, f := range res.Fields {
+		// fields are a map, so expanded content is labeled in place
+		switch v := f.Value.(type) {
+		case ContentResponse:
+			if err := l.labelResponse(ctx, &v); err != nil {
+				return err
+			}
+		case []ContentResponse:
+			for i := range v {
+				if err := l.labelResponse(ctx, &v[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		if f.Type != contentdefinition.PropertyTypeEnum || f.Value == nil {
+			continue
+		}
+
+		rule, err := l.rule(ctx, res.contentDefinitionID, name)
+		if err != nil {
+			return err
+		}
+
+		values, err := validator.Enum{Multiple: true}.Values(f.Value)
+		if err != nil {
+			// values are validated when content is published, so this only happens if the property type has changed
+			continue
+		}
+
+		labels := make([]string, 0, len(values))
+		for _, v := range values {
+			labels = append(labels, rule.Label(v, l.languages...))
+		}
+
+		if _, single := f.Value.(string); single {
+			f.Label = labels[0]
+		} else {
+			f.Label = labels
+		}
+
+		res.Fields[name] = f
+	}
+
+	return nil
+}
+
+// rule returns the options of the enum property, a property that does not exist anymore has no options
+func (l *labeler) rule(ctx context.Context, contentDefinitionID uuid.UUID, property string) (validator.Enum, error) {
+
+	cd, ok := l.cache[contentDefinitionID]
+	if !ok {
+		found, err := l.repo.GetContentDefinition(ctx, contentDefinitionID, l.workspace)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return validator.Enum{}, err
+		}
+
+		if err == nil {
+			cd = &found
+		}
+		l.cache[contentDefinitionID] = cd
+	}
+
+	if cd == nil {
+		return validator.Enum{}, nil
+	}
+
+	v, ok := cd.Propertydefinitions[property].Validators[validator.RuleEnum]
+	if !ok {
+		return validator.Enum{}, nil
+	}
+
+	rule, err := validator.Parse(validator.RuleEnum, v)
+	if err != nil {
+		return validator.Enum{}, err
+	}
+
+	return rule.(validator.Enum), nil
+}
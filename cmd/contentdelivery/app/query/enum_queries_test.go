@@ -0,0 +1,81 @@
+//go:build unit
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_LabelResponse(t *testing.T) {
+
+	productId := uuid.New()
+	product := &contentdefinition.ContentDefinition{
+		ID: productId,
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"color": {
+				Type: contentdefinition.PropertyTypeEnum,
+				Validators: map[string]interface{}{
+					validator.RuleEnum: validator.Enum{Options: []validator.EnumOption{
+						{Value: "red", Labels: map[string]string{"sv-SE": "Röd", "en-US": "Red"}},
+						{Value: "green", Labels: map[string]string{"sv-SE": "Grön"}},
+						{Value: "blue"},
+					}},
+				},
+			},
+			"sizes": {
+				Type: contentdefinition.PropertyTypeEnum,
+				Validators: map[string]interface{}{
+					validator.RuleEnum: primitive.D{
+						{Key: "options", Value: primitive.A{primitive.D{{Key: "value", Value: "s"}, {Key: "labels", Value: primitive.D{{Key: "en-US", Value: "Small"}}}}}},
+						{Key: "multiple", Value: true},
+					},
+				},
+			},
+		},
+	}
+	deletedId := uuid.New()
+
+	l := &labeler{
+		languages: []string{"en-US", "sv-SE"},
+		cache: map[uuid.UUID]*contentdefinition.ContentDefinition{
+			productId: product,
+			deletedId: nil,
+		},
+	}
+
+	related := ContentResponse{
+		contentDefinitionID: deletedId,
+		Fields: map[string]FieldResponse{
+			"color": {Type: contentdefinition.PropertyTypeEnum, Value: "red"},
+		},
+	}
+
+	res := ContentResponse{
+		contentDefinitionID: productId,
+		Fields: map[string]FieldResponse{
+			"color":   {Type: contentdefinition.PropertyTypeEnum, Value: "green"},
+			"sizes":   {Type: contentdefinition.PropertyTypeEnum, Value: primitive.A{"s", "removed"}},
+			"empty":   {Type: contentdefinition.PropertyTypeEnum},
+			"name":    {Type: contentdefinition.PropertyTypeText, Value: "red"},
+			"related": {Type: contentdefinition.PropertyTypeReference, Value: []ContentResponse{related}},
+		},
+	}
+
+	assert.NoError(t, l.labelResponse(context.Background(), &res))
+
+	// falls back to the next language with a label
+	assert.Equal(t, "Grön", res.Fields["color"].Label)
+	assert.Equal(t, []string{"Small", "removed"}, res.Fields["sizes"].Label)
+	assert.Nil(t, res.Fields["empty"].Label)
+	assert.Nil(t, res.Fields["name"].Label)
+
+	// the contentdefinition of expanded content has been deleted
+	assert.Equal(t, "red", res.Fields["related"].Value.([]ContentResponse)[0].Fields["color"].Label)
+}
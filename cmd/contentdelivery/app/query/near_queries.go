@@ -0,0 +1,127 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/workspace"
+)
+
+const ErrInvalidDistance = "invalid distance"
+
+// maxDistance is the largest distance in km content can be found within, about half the circumference of the earth
+const maxDistance = 20000
+
+// NearbyContentResponse is published content and the distance from the requested point to its nearest location
+type NearbyContentResponse struct {
+	ContentResponse
+	// Distance in km
+	Distance float64
+}
+
+type NearbyContentListResponse struct {
+	// Items are ordered by distance, nearest first
+	Items []NearbyContentResponse
+	// how many items was returned
+	Count int
+}
+
+type GetContentNear struct {
+	Lat float64
+	Lng float64
+	// Distance in km content must be within
+	Distance float64
+	Tags     []string
+	// What fields to return, all fields are returned if empty
+	Fields []string
+	// Reference fields to replace with the referenced content
+	Expand Expand
+	// Format of richtext fields, defaults to RichTextAST
+	Format RichTextFormat
+	// Timezone datetime fields are returned in, an IANA name like Europe/Stockholm. Defaults to UTC
+	Timezone string
+	// Page.Size is the max number of items, the nearest content is returned so there are no further pages
+	Page      db.Page
+	Language  string
+	Workspace workspace.Workspace
+}
+
+type GetContentNearHandler struct {
+	Repo                        content.ContentManagementRepository
+	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
+}
+
+// Content is found by the values of its geopoint properties when it was published, see content.ContentData.Locations
+func (h GetContentNearHandler) Handle(ctx context.Context, query GetContentNear) (NearbyContentListResponse, error) {
+
+	point, err := validator.NewGeoPoint(query.Lat, query.Lng)
+	if err != nil {
+		return NearbyContentListResponse{}, err
+	}
+
+	if query.Distance <= 0 || query.Distance > maxDistance {
+		return NearbyContentListResponse{}, fmt.Errorf("%s: must be greater than 0 and at most %d km", ErrInvalidDistance, maxDistance)
+	}
+
+	projection := content.FieldProjection{
+		Fields:    query.Fields,
+		Languages: query.Workspace.Languages,
+	}
+
+	if err := projection.Validate(); err != nil {
+		return NearbyContentListResponse{}, err
+	}
+
+	if err := query.Expand.Validate(); err != nil {
+		return NearbyContentListResponse{}, err
+	}
+
+	if err := query.Format.Validate(); err != nil {
+		return NearbyContentListResponse{}, err
+	}
+
+	loc, err := loadTimezone(query.Timezone)
+	if err != nil {
+		return NearbyContentListResponse{}, err
+	}
+
+	size := query.Page.Size
+	if size == 0 {
+		size = db.DefaultPageSize
+	}
+
+	items, err := h.Repo.ListPublishedContentNear(ctx, point, query.Distance*1000, query.Tags, projection, size, query.Workspace.ID)
+	if err != nil {
+		return NearbyContentListResponse{}, err
+	}
+
+	result := NearbyContentListResponse{
+		Items: make([]NearbyContentResponse, 0, len(items)),
+		Count: len(items),
+	}
+
+	expander := newExpander(h.Repo, query.Expand, query.Language, query.Workspace)
+	labeler := newLabeler(h.ContentDefinitionRepository, query.Language, query.Workspace)
+	for _, item := range items {
+		res := newContentResponse(item.Content, query.Language, query.Workspace.Languages)
+		if err := expander.expandResponse(ctx, &res); err != nil {
+			return NearbyContentListResponse{}, err
+		}
+
+		if err := labeler.labelResponse(ctx, &res); err != nil {
+			return NearbyContentListResponse{}, err
+		}
+		formatResponse(&res, query.Format, loc)
+
+		result.Items = append(result.Items, NearbyContentResponse{
+			ContentResponse: res,
+			Distance:        item.Distance / 1000,
+		})
+	}
+
+	return result, nil
+}
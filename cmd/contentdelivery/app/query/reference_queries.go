@@ -0,0 +1,167 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MaxExpandDepth is the number of levels of referenced content that can be expanded
+const MaxExpandDepth = 3
+
+const ErrInvalidExpand = "invalid expand"
+
+// ExpandAll expands every reference field
+const ExpandAll = "*"
+
+// Expand describes which reference fields are replaced with the referenced content
+type Expand struct {
+	// Fields are the names of the reference fields to expand, ExpandAll expands every reference field.
+	// The same fields are expanded on every level of referenced content.
+	Fields []string
+	// Depth is the number of levels of referenced content to expand, 0 defaults to 1
+	Depth int
+}
+
+func (e Expand) IsEmpty() bool {
+	return len(e.Fields) == 0
+}
+
+func (e Expand) Validate() error {
+
+	if e.IsEmpty() {
+		return nil
+	}
+
+	if e.Depth < 0 || e.Depth > MaxExpandDepth {
+		return fmt.Errorf("%s: depth must be between 1 and %d, or 0 for the default depth", ErrInvalidExpand, MaxExpandDepth)
+	}
+
+	return nil
+}
+
+func (e Expand) expands(field string) bool {
+
+	for _, f := range e.Fields {
+		if f == field || f == ExpandAll {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expander replaces reference fields with the published content they reference.
+// Referenced content is read once per request, even if it is referenced by several fields.
+type expander struct {
+	repo      content.ContentManagementRepository
+	expand    Expand
+	language  string
+	workspace workspace.Workspace
+	// cache is nil for content that is not published
+	cache map[uuid.UUID]*content.Content
+}
+
+func newExpander(repo content.ContentManagementRepository, expand Expand, language string, ws workspace.Workspace) *expander {
+
+	if expand.Depth == 0 {
+		expand.Depth = 1
+	}
+
+	return &expander{
+		repo:      repo,
+		expand:    expand,
+		language:  language,
+		workspace: ws,
+		cache:     make(map[uuid.UUID]*content.Content),
+	}
+}
+
+// expandResponse replaces the value of every expanded reference field of res. A single reference is replaced with a ContentResponse,
+// or nil if the referenced content is not published. A list of references is replaced with the ContentResponse of every published content.
+func (e *expander) expandResponse(ctx context.Context, res *ContentResponse) error {
+
+	if e.expand.IsEmpty() {
+		return nil
+	}
+
+	return e.expandFields(ctx, res, e.expand.Depth)
+}
+
+func (e *expander) expandFields(ctx context.Context, res *ContentResponse, depth int) error {
+
+	if depth == 0 {
+		return nil
+	}
+
+	for name, f := range res.Fields {
+		if f.Type != contentdefinition.PropertyTypeReference || !e.expand.expands(name) {
+			continue
+		}
+
+		ids, err := validator.Reference{Multiple: true}.IDs(f.Value)
+		if err != nil {
+			// references are validated when content is published, so this only happens if the property type has changed
+			continue
+		}
+
+		items := make([]ContentResponse, 0, len(ids))
+		for _, id := range ids {
+			c, err := e.get(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if c == nil {
+				continue
+			}
+
+			item := newContentResponse(*c, e.language, e.workspace.Languages)
+			if err := e.expandFields(ctx, &item, depth-1); err != nil {
+				return err
+			}
+
+			items = append(items, item)
+		}
+
+		if _, single := f.Value.(string); single {
+			f.Value = nil
+			if len(items) > 0 {
+				f.Value = items[0]
+			}
+		} else {
+			f.Value = items
+		}
+
+		res.Fields[name] = f
+	}
+
+	return nil
+}
+
+func (e *expander) get(ctx context.Context, id uuid.UUID) (*content.Content, error) {
+
+	if c, ok := e.cache[id]; ok {
+		return c, nil
+	}
+
+	c, err := e.repo.GetPublishedContent(ctx, id, content.FieldProjection{}, e.workspace.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		e.cache[id] = nil
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	e.cache[id] = &c
+	return &c, nil
+}
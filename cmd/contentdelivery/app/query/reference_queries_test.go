@@ -0,0 +1,36 @@
+//go:build unit
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExpandValidate(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		expand Expand
+		valid  bool
+	}{
+		{name: "no fields", expand: Expand{Depth: -1}, valid: true},
+		{name: "default depth", expand: Expand{Fields: []string{ExpandAll}}, valid: true},
+		{name: "max depth", expand: Expand{Fields: []string{ExpandAll}, Depth: MaxExpandDepth}, valid: true},
+		{name: "negative depth", expand: Expand{Fields: []string{ExpandAll}, Depth: -1}},
+		{name: "depth above max", expand: Expand{Fields: []string{ExpandAll}, Depth: MaxExpandDepth + 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.expand.Validate()
+
+			if test.valid {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/richtext"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const ErrInvalidRichTextFormat = "invalid richtext format"
+
+// RichTextFormat is how richtext fields are returned
+// swagger:enum RichTextFormat
+type RichTextFormat string
+
+const (
+	// RichTextAST returns richtext as a richtext.Document, this is the default
+	RichTextAST RichTextFormat = "ast"
+	// RichTextHTML returns richtext rendered as HTML
+	RichTextHTML RichTextFormat = "html"
+)
+
+func (f RichTextFormat) Validate() error {
+
+	switch f {
+	case "", RichTextAST, RichTextHTML:
+		return nil
+	}
+
+	return fmt.Errorf("%s: %s, must be %s or %s", ErrInvalidRichTextFormat, f, RichTextAST, RichTextHTML)
+}
+
+const ErrInvalidTimezone = "invalid timezone"
+
+// loadTimezone returns the time zone datetimes are returned in, an IANA name like Europe/Stockholm. Defaults to UTC.
+func loadTimezone(name string) (*time.Location, error) {
+
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	// Local is the time zone of the server, which clients know nothing about
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("%s: %s", ErrInvalidTimezone, name)
+	}
+
+	return loc, nil
+}
+
+// formatResponse converts every richtext field of res to the format and every datetime field to loc,
+// including the fields of expanded content. Objects in list and object fields are returned as their values by name
+// and geopoints as GeoJSON.
+func formatResponse(res *ContentResponse, format RichTextFormat, loc *time.Location) {
+
+	for name, f := range res.Fields {
+		// fields are a map, so expanded content is converted in place
+		switch v := f.Value.(type) {
+		case ContentResponse:
+			formatResponse(&v, format, loc)
+		case []ContentResponse:
+			for i := range v {
+				formatResponse(&v[i], format, loc)
+			}
+		}
+
+		if (f.Type == contentdefinition.PropertyTypeList || f.Type == contentdefinition.PropertyTypeObject) && f.Value != nil {
+			f.Value = nestedValue(f.Value, loc)
+			res.Fields[name] = f
+			continue
+		}
+
+		if f.Type == contentdefinition.PropertyTypeGeoPoint && f.Value != nil {
+			if p, err := validator.ParseGeoPoint(f.Value); err == nil {
+				f.Value = p
+				res.Fields[name] = f
+			}
+			continue
+		}
+
+		if f.Type == contentdefinition.PropertyTypeDateTime && f.Value != nil {
+			if t, err := validator.ParseDateTime(f.Value); err == nil {
+				f.Value = t.In(loc)
+				res.Fields[name] = f
+			}
+			continue
+		}
+
+		if f.Type != contentdefinition.PropertyTypeRichText || f.Value == nil {
+			continue
+		}
+
+		doc, err := richtext.Parse(f.Value)
+		if err != nil {
+			// richtext is validated when it is saved, so this only happens if the property type has changed
+			continue
+		}
+
+		if format == RichTextHTML {
+			f.Value = richtext.ToHTML(doc)
+		} else {
+			f.Value = doc
+		}
+
+		res.Fields[name] = f
+	}
+}
+
+// nestedValue returns the value of a list or object field as it is returned.
+// Objects and geopoints are the only documents a list or object can contain, since richtext cannot be nested.
+func nestedValue(value interface{}, loc *time.Location) interface{} {
+
+	switch v := value.(type) {
+	case primitive.DateTime:
+		return v.Time().In(loc)
+	case time.Time:
+		return v.In(loc)
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, nestedValue(item, loc))
+		}
+		return values
+	case primitive.A:
+		return nestedValue([]interface{}(v), loc)
+	case content.ContentFields, primitive.D, primitive.M:
+		fields, err := content.ObjectFields(v)
+		if err != nil {
+			if p, err := validator.ParseGeoPoint(v); err == nil {
+				return p
+			}
+			return value
+		}
+
+		values := make(map[string]interface{}, len(fields))
+		for name, field := range fields {
+			values[name] = nestedValue(field.Value, loc)
+		}
+		return values
+	}
+
+	return value
+}
@@ -0,0 +1,82 @@
+package query
+
+import (
+	"context"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ResolveRoute struct {
+	Path      string
+	Language  string
+	Workspace workspace.Workspace
+}
+
+type RouteResponse struct {
+	Content ContentResponse
+	// Canonical is the path of the content in the requested language.
+	// When it differs from the requested path the client should be redirected.
+	Canonical string
+}
+
+type ResolveRouteHandler struct {
+	Repo content.ContentManagementRepository
+}
+
+// Handle returns mongo.ErrNoDocuments if no published content has path in the requested language.
+func (h ResolveRouteHandler) Handle(ctx context.Context, query ResolveRoute) (RouteResponse, error) {
+
+	candidates, err := h.Repo.FindByPath(ctx, content.NormalizePath(query.Path), query.Workspace.ID)
+	if err != nil {
+		return RouteResponse{}, err
+	}
+
+	c, canonical, ok := content.ResolveRoute(candidates, query.Path, query.Language)
+	if !ok {
+		return RouteResponse{}, mongo.ErrNoDocuments
+	}
+
+	return RouteResponse{
+		Content:   newContentResponse(c, query.Language, query.Workspace.Languages),
+		Canonical: canonical,
+	}, nil
+}
+
+type GetContentURL struct {
+	ID        uuid.UUID
+	Language  string
+	Workspace workspace.Workspace
+}
+
+type URLResponse struct {
+	ID       uuid.UUID
+	Language string
+	URL      string
+}
+
+type GetContentURLHandler struct {
+	Repo content.ContentManagementRepository
+}
+
+// Handle returns mongo.ErrNoDocuments if the content is not published or not routable in the requested language.
+func (h GetContentURLHandler) Handle(ctx context.Context, query GetContentURL) (URLResponse, error) {
+
+	c, err := h.Repo.GetPublishedContent(ctx, query.ID, content.FieldProjection{}, query.Workspace.ID)
+	if err != nil {
+		return URLResponse{}, err
+	}
+
+	url, ok := c.Route(query.Language)
+	if !ok {
+		return URLResponse{}, mongo.ErrNoDocuments
+	}
+
+	return URLResponse{
+		ID:       c.ID,
+		Language: query.Language,
+		URL:      url,
+	}, nil
+}
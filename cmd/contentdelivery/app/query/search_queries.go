@@ -0,0 +1,67 @@
+package query
+
+import (
+	"context"
+
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+// SearchHit is published content that matches a search
+type SearchHit struct {
+	ID   uuid.UUID
+	Name string
+	// Score is the relevance of the match, hits are ordered by score
+	Score float64
+	// Highlights are HTML escaped snippets of the matching fields with the matches wrapped in <em>
+	Highlights map[string]string
+}
+
+type SearchResponse struct {
+	Items []SearchHit
+	// how many items was returned
+	Count int
+	// how many items exists
+	Total int
+	// continuation token of the next page, empty if this is the last page
+	Next string `json:",omitempty"`
+}
+
+type Search struct {
+	Query     string
+	Language  string
+	Page      db.Page
+	Workspace workspace.Workspace
+}
+
+type SearchHandler struct {
+	Repo search.SearchRepository
+}
+
+func (h SearchHandler) Handle(ctx context.Context, query Search) (SearchResponse, error) {
+
+	hits, res, err := h.Repo.Search(ctx, query.Query, query.Language, search.Published, query.Page, query.Workspace.ID)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+
+	result := SearchResponse{
+		Items: make([]SearchHit, 0, len(hits)),
+		Count: len(hits),
+		Total: res.Total,
+		Next:  res.Next,
+	}
+
+	for _, hit := range hits {
+		result.Items = append(result.Items, SearchHit{
+			ID:         hit.ContentID,
+			Name:       hit.Name,
+			Score:      hit.Score,
+			Highlights: hit.Highlights(query.Query, search.SnippetLength),
+		})
+	}
+
+	return result, nil
+}
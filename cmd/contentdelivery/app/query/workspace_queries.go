@@ -0,0 +1,22 @@
+package query
+
+import (
+	"context"
+	"errors"
+
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+type GetWorkspaceHandler struct {
+	Repo workspace.WorkspaceRepository
+}
+
+func (h GetWorkspaceHandler) Handle(ctx context.Context, id uuid.UUID) (workspace.Workspace, error) {
+
+	if id == (uuid.UUID{}) {
+		return workspace.Workspace{}, errors.New("empty id")
+	}
+
+	return h.Repo.Get(ctx, id)
+}
@@ -1,67 +1,58 @@
 package main
 
-// type Server struct {
-// 	// Configuration config.SiteConfiguration
-// 	database   *mongo.Client
-// 	SiteConfig *siteconfiguration.SiteConfiguration
-// }
+import (
+	"context"
+	"net/http"
 
-// func main() {
+	// time zones of the timezone parameter are available without tzdata installed on the host
+	_ "time/tzdata"
 
-// 	serverConfig := config.LoadServerConfiguration()
+	"github.com/crikke/cms/cmd/contentdelivery/api"
+	"github.com/crikke/cms/pkg/asset"
+	"github.com/crikke/cms/pkg/config"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
-// 	c, err := db.Connect(context.Background(), serverConfig.ConnectionString.Mongodb)
+type Server struct {
+	Database *mongo.Client
+	Storage  asset.Storage
+	Cache    imaging.Cache
+	Signer   imaging.Signer
+}
 
-// 	if err != nil {
-// 		panic(err)
-// 	}
+func main() {
 
-// 	configRepo := siteconfiguration.NewConfigurationRepository(c)
-// 	siteConfig, err := configRepo.LoadConfiguration(context.Background())
-// 	if err != nil {
-// 		panic(err)
-// 	}
+	serverConfig := config.LoadServerConfiguration()
 
-// 	if serverConfig.ConnectionString.Mongodb != "" {
-// 		cfgQueue, err := siteconfiguration.NewConfigurationEventHandler(serverConfig.ConnectionString.RabbitMQ)
+	c, err := db.Connect(context.Background(), serverConfig.ConnectionString.Mongodb)
 
-// 		if err != nil {
-// 			panic(err)
-// 		}
+	if err != nil {
+		panic(err)
+	}
 
-// 		defer func() {
-// 			err = cfgQueue.Close()
-// 			if err != nil {
-// 				panic(err)
-// 			}
-// 		}()
-// 		cfgQueue.Watch(siteConfig)
-// 	}
+	server := Server{
+		Database: c,
+		Storage:  asset.NewFileSystemStorage(serverConfig.AssetPath),
+		Cache:    imaging.NewCache(serverConfig.ImageCachePath),
+		Signer:   imaging.NewSigner(serverConfig.ImageSigningKey),
+	}
 
-// 	server := Server{
-// 		database:   c,
-// 		SiteConfig: siteConfig,
-// 	}
+	panic(server.Start())
+}
 
-// 	panic(server.Start())
-// }
+func (s Server) Start() error {
 
-// func (s Server) Start() error {
+	r := chi.NewRouter()
 
-// 	r := gin.Default()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
 
-// 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
-// 	r.Use(
-// 		telemetry.Handle(),
-// 		gin.Recovery(),
-// 		// locale.Handler(s.SiteConfig),
-// 	)
+	r.Mount("/contentdelivery", api.NewContentDeliveryAPI(api.NewApp(s.Database, s.Storage, s.Cache, s.Signer)))
 
-// 	// v1 := r.Group("/v1")
-// 	{
-// 		// contentRepo := content.NewContentRepository(s.database, s.SiteConfig)
-// 		// contentapi.NewContentRoute(v1, s.SiteConfig, contentRepo)
-// 	}
-
-// 	return r.Run()
-// }
+	return http.ListenAndServe(":8081", r)
+}
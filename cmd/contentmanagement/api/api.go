@@ -5,8 +5,10 @@ import (
 
 	// docs is generated by Swag CLI, you have to import it.
 	"github.com/crikke/cms/cmd/contentmanagement/api/handlers"
+	assetapi "github.com/crikke/cms/cmd/contentmanagement/api/v1/asset"
 	contentapi "github.com/crikke/cms/cmd/contentmanagement/api/v1/content"
 	contentdefapi "github.com/crikke/cms/cmd/contentmanagement/api/v1/contentdefinition"
+	searchapi "github.com/crikke/cms/cmd/contentmanagement/api/v1/search"
 	workspaceapi "github.com/crikke/cms/cmd/contentmanagement/api/v1/workspace"
 	"github.com/crikke/cms/pkg/workspace"
 	"go.uber.org/zap"
@@ -14,13 +16,18 @@ import (
 	"github.com/crikke/cms/cmd/contentmanagement/app"
 	"github.com/crikke/cms/cmd/contentmanagement/app/command"
 	"github.com/crikke/cms/cmd/contentmanagement/app/query"
+	"github.com/crikke/cms/pkg/asset"
 	"github.com/crikke/cms/pkg/content"
 	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/crikke/cms/pkg/schedule"
+	"github.com/crikke/cms/pkg/search"
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func NewContentManagementAPI(c *mongo.Client, log *zap.SugaredLogger) http.Handler {
+func NewContentManagementAPI(c *mongo.Client, storage asset.Storage, cache imaging.Cache, signer imaging.Signer, log *zap.SugaredLogger) http.Handler {
 
 	// docs.SwaggerInfo.Title = "Content management API"
 	// docs.SwaggerInfo.Version = "0.1.0"
@@ -28,7 +35,7 @@ func NewContentManagementAPI(c *mongo.Client, log *zap.SugaredLogger) http.Handl
 	// docs.SwaggerInfo.Host = "localhost:8080"
 	// docs.SwaggerInfo.BasePath = "/contentmanagement/"
 
-	app := initializeHandlers(c)
+	app := NewApp(c, storage, cache, signer)
 	wsHandler := handlers.WorkspaceHandler{App: app}
 
 	r := chi.NewRouter()
@@ -41,17 +48,40 @@ func NewContentManagementAPI(c *mongo.Client, log *zap.SugaredLogger) http.Handl
 
 			r.Mount("/content", contentapi.NewContentRoute(app))
 			r.Mount("/contentdefinitions", contentdefapi.NewContentDefinitionRoute(app))
+			r.Mount("/search", searchapi.NewSearchRoute(app))
+			r.Mount("/assets", assetapi.NewAssetRoute(app))
 		})
 	})
 
 	return r
 }
 
-func initializeHandlers(c *mongo.Client) app.App {
+// NewApp initializes the command & query handlers of the content management application, the files of assets are kept in storage.
+// Signer signs the image URLs of the content delivery API, and images rendered by it are kept in cache.
+func NewApp(c *mongo.Client, storage asset.Storage, cache imaging.Cache, signer imaging.Signer) app.App {
 
 	contentRepo := content.NewContentRepository(c)
 	contentDefinitionRepo := contentdefinition.NewContentDefinitionRepository(c)
 	workspaceRepo := workspace.NewWorkspaceRepository(c)
+	scheduleRepo := schedule.NewScheduleRepository(c)
+	searchRepo := search.NewSearchRepository(c)
+	assetRepo := asset.NewAssetRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	publishContent := command.PublishContentHandler{
+		ContentDefinitionRepository: contentDefinitionRepo,
+		ContentRepository:           contentRepo,
+		AssetRepository:             assetRepo,
+		WorkspaceRepository:         workspaceRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	archiveContent := command.ArchiveContentHandler{
+		ContentRepository:   contentRepo,
+		WorkspaceRepository: workspaceRepo,
+		SearchRepository:    searchRepo,
+		UnitOfWork:          uow,
+	}
 
 	app := app.App{
 		Queries: app.Queries{
@@ -59,7 +89,32 @@ func initializeHandlers(c *mongo.Client) app.App {
 				Repo:          contentRepo,
 				WorkspaceRepo: workspaceRepo,
 			},
+			GetContentDiff: query.GetContentDiffHandler{
+				Repo: contentRepo,
+			},
 			ListContent: query.ListContentHandler{
+				Repo:                        contentRepo,
+				WorkspaceRepository:         workspaceRepo,
+				ContentDefinitionRepository: contentDefinitionRepo,
+			},
+			ValidateContent: query.ValidateContentHandler{
+				Repo:                        contentRepo,
+				ContentDefinitionRepository: contentDefinitionRepo,
+				WorkspaceRepository:         workspaceRepo,
+			},
+			ListReferencingContent: query.ListReferencingContentHandler{
+				Repo:                contentRepo,
+				WorkspaceRepository: workspaceRepo,
+			},
+			ListChildren: query.ListChildrenHandler{
+				Repo:                contentRepo,
+				WorkspaceRepository: workspaceRepo,
+			},
+			ListAncestors: query.ListAncestorsHandler{
+				Repo:                contentRepo,
+				WorkspaceRepository: workspaceRepo,
+			},
+			ListDescendants: query.ListDescendantsHandler{
 				Repo:                contentRepo,
 				WorkspaceRepository: workspaceRepo,
 			},
@@ -72,6 +127,28 @@ func initializeHandlers(c *mongo.Client) app.App {
 			ListContentDefinitions: query.ListContentDefinitionHandler{
 				Repo: contentDefinitionRepo,
 			},
+			ListValidators: query.ListValidatorsHandler{},
+			ListSchedules: query.ListSchedulesHandler{
+				Repo: scheduleRepo,
+			},
+			Search: query.SearchHandler{
+				Repo:                searchRepo,
+				WorkspaceRepository: workspaceRepo,
+			},
+			GetAsset: query.GetAssetHandler{
+				Repo: assetRepo,
+			},
+			ListAssets: query.ListAssetsHandler{
+				Repo: assetRepo,
+			},
+			GetAssetFile: query.GetAssetFileHandler{
+				Repo:    assetRepo,
+				Storage: storage,
+			},
+			GetAssetURL: query.GetAssetURLHandler{
+				Repo:   assetRepo,
+				Signer: signer,
+			},
 			WorkspaceQueries: app.WorkspaceQueries{
 				GetWorkspace: query.GetWorkspaceHandler{
 					Repo: workspaceRepo,
@@ -93,29 +170,56 @@ func initializeHandlers(c *mongo.Client) app.App {
 				ContentRepository:           contentRepo,
 				Factory:                     content.ContentFactory{},
 				WorkspaceRepository:         workspaceRepo,
+				SearchRepository:            searchRepo,
+				UnitOfWork:                  uow,
 			},
 			UpdateContentFields: command.UpdateContentFieldsHandler{
 				ContentRepository:           contentRepo,
 				ContentDefinitionRepository: contentDefinitionRepo,
+				WorkspaceRepository:         workspaceRepo,
+				SearchRepository:            searchRepo,
+				Factory:                     content.ContentFactory{},
+				UnitOfWork:                  uow,
+			},
+			ArchiveContent: archiveContent,
+			PublishContent: publishContent,
+			RestoreContent: command.RestoreContentVersionHandler{
+				ContentRepository:           contentRepo,
+				ContentDefinitionRepository: contentDefinitionRepo,
+				WorkspaceRepository:         workspaceRepo,
+				SearchRepository:            searchRepo,
 				Factory:                     content.ContentFactory{},
+				UnitOfWork:                  uow,
+				PublishContent:              publishContent,
+			},
+			MoveContent: command.MoveContentHandler{
+				ContentRepository:   contentRepo,
+				WorkspaceRepository: workspaceRepo,
+				UnitOfWork:          uow,
 			},
-			ArchiveContent: command.ArchiveContentHandler{
+			ReorderChildren: command.ReorderChildrenHandler{
 				ContentRepository: contentRepo,
+				UnitOfWork:        uow,
 			},
-			PublishContent: command.PublishContentHandler{
-				ContentDefinitionRepository: contentDefinitionRepo,
-				ContentRepository:           contentRepo,
-				WorkspaceRepository:         workspaceRepo,
+			SetStartPage: command.SetStartPageHandler{
+				ContentRepository:   contentRepo,
+				WorkspaceRepository: workspaceRepo,
+				UnitOfWork:          uow,
 			},
 			CreateContentDefinition: command.CreateContentDefinitionHandler{
 				Repo:          contentDefinitionRepo,
 				WorkspaceRepo: workspaceRepo,
 			},
 			UpdateContentDefinition: command.UpdateContentDefinitionHandler{
-				Repo:          contentDefinitionRepo,
-				WorkspaceRepo: workspaceRepo,
+				Repo:              contentDefinitionRepo,
+				WorkspaceRepo:     workspaceRepo,
+				ContentRepository: contentRepo,
+			},
+			DeleteContentDefinition: command.DeleteContentDefinitionHandler{
+				Repo:              contentDefinitionRepo,
+				ContentRepository: contentRepo,
+				UnitOfWork:        uow,
 			},
-			DeleteContentDefinition: command.DeleteContentDefinitionHandler{},
 			CreatePropertyDefinition: command.CreatePropertyDefinitionHandler{
 				Repo:    contentDefinitionRepo,
 				Factory: contentdefinition.ContentDefinitionFactory{},
@@ -124,10 +228,43 @@ func initializeHandlers(c *mongo.Client) app.App {
 				Repo: contentDefinitionRepo,
 			},
 			DeletePropertyDefinition: command.DeletePropertyDefinitionHandler{},
+			CreateSchedule: command.CreateScheduleHandler{
+				ContentRepository:  contentRepo,
+				ScheduleRepository: scheduleRepo,
+				Factory:            schedule.ScheduleFactory{},
+			},
+			CancelSchedule: command.CancelScheduleHandler{
+				ScheduleRepository: scheduleRepo,
+			},
+			ExecuteSchedules: command.ExecuteSchedulesHandler{
+				ScheduleRepository:  scheduleRepo,
+				ContentRepository:   contentRepo,
+				WorkspaceRepository: workspaceRepo,
+				PublishContent:      publishContent,
+				ArchiveContent:      archiveContent,
+			},
+			UploadAsset: command.UploadAssetHandler{
+				Repo:                assetRepo,
+				Storage:             storage,
+				WorkspaceRepository: workspaceRepo,
+				Factory:             asset.AssetFactory{},
+			},
+			UpdateAsset: command.UpdateAssetHandler{
+				Repo:                assetRepo,
+				WorkspaceRepository: workspaceRepo,
+			},
+			DeleteAsset: command.DeleteAssetHandler{
+				Repo:              assetRepo,
+				Storage:           storage,
+				Cache:             cache,
+				ContentRepository: contentRepo,
+			},
 
 			WorkspaceCommands: app.WorkspaceCommands{
 				CreateWorkspace: command.CreateWorkspaceHandler{
-					Repo: workspaceRepo,
+					Repo:              workspaceRepo,
+					ContentRepository: contentRepo,
+					SearchRepository:  searchRepo,
 				},
 				UpdateWorkspace: command.UpdateWorkspaceHandler{
 					Repo: workspaceRepo,
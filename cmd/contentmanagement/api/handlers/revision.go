@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/crikke/cms/pkg/db"
+)
+
+const revisionkey = key("revision")
+
+// IfMatchContext parses the If-Match header into the expected revision of the resource.
+// The header is required, a request without it is answered with 428 Precondition Required.
+// "*" matches any revision, it explicitly opts out of the check and overwrites concurrent changes.
+func IfMatchContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		header := r.Header.Get("If-Match")
+
+		if header == "" {
+			http.Error(w, "If-Match: header is required, use the ETag of the resource or *", http.StatusPreconditionRequired)
+			return
+		}
+
+		if header == "*" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		etag := strings.TrimPrefix(header, "W/")
+		rev, err := strconv.Atoi(strings.Trim(etag, `"`))
+
+		if err != nil {
+			http.Error(w, "If-Match: bad format", http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), revisionkey, rev)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// WithRevision returns the revision set by IfMatchContext or nil if If-Match is "*".
+func WithRevision(ctx context.Context) *int {
+	if rev := ctx.Value(revisionkey); rev != nil {
+		i := rev.(int)
+		return &i
+	}
+	return nil
+}
+
+func SetETag(w http.ResponseWriter, revision int) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, revision))
+}
+
+// RevisionError writes 412 Precondition Failed with the current revision as ETag if err is a revision mismatch.
+// Returns false if err is of another type and nothing has been written.
+func RevisionError(w http.ResponseWriter, err error) bool {
+
+	var mismatch db.RevisionMismatchError
+	if !errors.As(err, &mismatch) {
+		return false
+	}
+
+	SetETag(w, mismatch.Current)
+	http.Error(w, mismatch.Error(), http.StatusPreconditionFailed)
+	return true
+}
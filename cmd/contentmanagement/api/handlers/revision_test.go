@@ -0,0 +1,87 @@
+//go:build unit
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crikke/cms/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IfMatchContext(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		header     string
+		expect     *int
+		statusCode int
+	}{
+		{
+			name:       "no header",
+			statusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:       "wildcard",
+			header:     "*",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "strong etag",
+			header:     `"3"`,
+			expect:     makeInt(3),
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "weak etag",
+			header:     `W/"4"`,
+			expect:     makeInt(4),
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "bad format",
+			header:     `"foo"`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			var actual *int
+			h := IfMatchContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actual = WithRevision(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodPut, "/", nil)
+			if test.header != "" {
+				r.Header.Set("If-Match", test.header)
+			}
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, test.statusCode, w.Code)
+			assert.Equal(t, test.expect, actual)
+		})
+	}
+}
+
+func Test_RevisionError(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	assert.False(t, RevisionError(w, errors.New("other")))
+	assert.False(t, RevisionError(w, nil))
+
+	w = httptest.NewRecorder()
+	assert.True(t, RevisionError(w, db.MatchRevision(makeInt(1), 2)))
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+}
+
+func makeInt(n int) *int {
+	return &n
+}
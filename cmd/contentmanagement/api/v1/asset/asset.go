@@ -0,0 +1,429 @@
+package asset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/crikke/cms/cmd/contentmanagement/api/handlers"
+	"github.com/crikke/cms/cmd/contentmanagement/app"
+	"github.com/crikke/cms/cmd/contentmanagement/app/command"
+	"github.com/crikke/cms/cmd/contentmanagement/app/query"
+	"github.com/crikke/cms/pkg/asset"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type key string
+
+const assetKey = key("asset")
+
+const (
+	// MaxUploadSize is the max size of an uploaded file in bytes
+	MaxUploadSize = 64 << 20
+	// uploads larger than maxMemory are buffered on disk while they are read
+	maxMemory = 8 << 20
+)
+
+type endpoint struct {
+	app app.App
+}
+
+func NewAssetRoute(app app.App) http.Handler {
+
+	r := chi.NewRouter()
+	ep := endpoint{app: app}
+
+	r.With(db.PageHandler).Get("/", ep.ListAssets())
+	r.Post("/", ep.UploadAsset())
+	r.Route("/{asset}", func(r chi.Router) {
+		r.Use(assetContext)
+		r.Get("/", ep.GetAsset())
+		r.Put("/", ep.UpdateAsset())
+		r.Delete("/", ep.DeleteAsset())
+		r.Get("/file", ep.GetAssetFile())
+		r.Get("/url", ep.GetAssetURL())
+	})
+
+	return r
+}
+
+func assetContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uuid.Parse(chi.URLParam(r, "asset"))
+		if err != nil {
+			http.Error(w, "asset: bad format", http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), assetKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func withAsset(ctx context.Context) uuid.UUID {
+
+	var id uuid.UUID
+
+	if r := ctx.Value(assetKey); r != nil {
+		id = r.(uuid.UUID)
+	}
+
+	return id
+}
+
+// UploadAsset 		godoc
+// @Summary 		Upload asset
+// @Description 	Uploads a file to the asset library. The mime type, size, checksum and dimensions of images are read from the file.
+// @Tags 			asset
+// @Accept 			mpfd
+// @Produces 		json
+// @Param			workspace	path		string	true 	"uuid formatted ID." format(uuid)
+// @Param			file		formData	file	true	"the file"
+// @Param			filename	formData	string	false	"filename, defaults to the name of the uploaded file"
+// @Param			alt			formData	string	false	"alternative text per language as a JSON object, ie {\"en-US\": \"A red bike\"}"
+// @Success			201			{object}	asset.Asset
+// @Header			201			{string}	Location
+// @Failure			413			{string}	string	"file is too large"
+// @Failure			default		{string}	string
+// @Router			/contentmanagement/workspaces/{workspace}/assets [post]
+func (ep endpoint) UploadAsset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+		if err := r.ParseMultipartForm(maxMemory); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, asset.ErrMissingFile, http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		filename := header.Filename
+		if name := r.FormValue("filename"); name != "" {
+			filename = name
+		}
+
+		alt := map[string]string{}
+		if value := r.FormValue("alt"); value != "" {
+			if err := json.Unmarshal([]byte(value), &alt); err != nil {
+				http.Error(w, fmt.Sprintf("alt: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ws := handlers.WithWorkspace(r.Context())
+		id, err := ep.app.Commands.UploadAsset.Handle(r.Context(), command.UploadAsset{
+			WorkspaceId: ws.ID,
+			Filename:    filename,
+			File:        file,
+			Alt:         alt,
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		a, err := ep.app.Queries.GetAsset.Handle(r.Context(), query.GetAsset{ID: id, WorkspaceId: ws.ID})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := json.Marshal(&a)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Location", fmt.Sprintf("%s/%s", r.URL.String(), id.String()))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+	}
+}
+
+// ListAssets 		godoc
+// @Summary 		List assets
+// @Description 	Lists the assets of the workspace
+// @Tags 			asset
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			type		query	string	false	"mime type, ie image/png, or type without subtype, ie image"
+// @Param			sort		query	string	false	"filename, size, created or updated, prefix with - for descending order"
+// @Param			limit		query	int		false	"page size, 1-100, defaults to 20"
+// @Param			token		query	string	false	"continuation token of the previous page"
+// @Success			200			{object}	[]asset.Asset
+// @Header			200			{int}		X-Total-Count
+// @Header			200			{string}	X-Continuation-Token	"token of the next page, missing on the last page"
+// @Failure			default		{string}	string
+// @Router			/contentmanagement/workspaces/{workspace}/assets [get]
+func (ep endpoint) ListAssets() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		items, page, err := ep.app.Queries.ListAssets.Handle(r.Context(), query.ListAssets{
+			WorkspaceId: handlers.WithWorkspace(r.Context()).ID,
+			MimeType:    r.URL.Query().Get("type"),
+			Page:        db.PageFromContext(r.Context()),
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&items)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		db.SetPageHeaders(w, page)
+		w.Write(data)
+	}
+}
+
+// GetAsset 		godoc
+// @Summary 		Get asset
+// @Description 	Gets the metadata and alternative text of an asset
+// @Tags 			asset
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			asset		path	string	true 	"uuid formatted ID." format(uuid)
+// @Success			200			{object}	asset.Asset
+// @Failure			404			{string}	string
+// @Router			/contentmanagement/workspaces/{workspace}/assets/{asset} [get]
+func (ep endpoint) GetAsset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		a, err := ep.app.Queries.GetAsset.Handle(r.Context(), query.GetAsset{
+			ID:          withAsset(r.Context()),
+			WorkspaceId: handlers.WithWorkspace(r.Context()).ID,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := json.Marshal(&a)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
+
+// GetAssetFile 	godoc
+// @Summary 		Download asset
+// @Description 	Returns the file of an asset, range requests are supported.
+// @Tags 			asset
+// @Produces 		octet-stream
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			asset		path	string	true 	"uuid formatted ID." format(uuid)
+// @Success			200			{file}		file
+// @Header			200			{string}	ETag	"checksum of the file"
+// @Failure			404			{string}	string
+// @Router			/contentmanagement/workspaces/{workspace}/assets/{asset}/file [get]
+func (ep endpoint) GetAssetFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		a, file, err := ep.app.Queries.GetAssetFile.Handle(r.Context(), query.GetAssetFile{
+			ID:          withAsset(r.Context()),
+			WorkspaceId: handlers.WithWorkspace(r.Context()).ID,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", a.MimeType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": a.Filename}))
+		w.Header().Set("ETag", strconv.Quote(a.Checksum))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		http.ServeContent(w, r, a.Filename, a.Updated, file)
+	}
+}
+
+type AssetURLResponse struct {
+	URL string `json:"url"`
+}
+
+// GetAssetURL 		godoc
+// @Summary 		Get asset URL
+// @Description 	Returns the content delivery URL of an asset. Image transformation options are signed, the delivery API rejects options that are not.
+// @Tags 			asset
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			asset		path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			width		query	int		false	"width in pixels, max 4096"
+// @Param			height		query	int		false	"height in pixels, max 4096"
+// @Param			fit			query	string	false	"how the image fits width and height, defaults to contain" Enums(contain, cover, fill)
+// @Param			format		query	string	false	"output format, defaults to the format of the image" Enums(jpeg, png, webp)
+// @Param			quality		query	int		false	"jpeg quality 1-100, defaults to 80"
+// @Param			crop		query	string	false	"x,y,width,height in pixels of the part of the image to use"
+// @Param			focal		query	string	false	"x,y between 0 and 1 that cover keeps in view, defaults to the focal point of the asset"
+// @Success			200			{object}	AssetURLResponse
+// @Failure			404			{string}	string
+// @Failure			default		{string}	string
+// @Router			/contentmanagement/workspaces/{workspace}/assets/{asset}/url [get]
+func (ep endpoint) GetAssetURL() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		opts, err := imaging.ParseOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		u, err := ep.app.Queries.GetAssetURL.Handle(r.Context(), query.GetAssetURL{
+			ID:          withAsset(r.Context()),
+			WorkspaceId: handlers.WithWorkspace(r.Context()).ID,
+			Options:     opts,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := json.Marshal(AssetURLResponse{URL: u})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
+
+type UpdateAssetRequestBody struct {
+	// Filename is left unchanged if empty
+	Filename string
+	// Alt is the alternative text per language, it replaces the alternative text of every language
+	Alt map[string]string
+	// Focal is the point of interest of an image, x and y are between 0 and 1 from the top left corner.
+	// It replaces the focal point, null removes it.
+	Focal *imaging.Point
+}
+
+// UpdateAsset 		godoc
+// @Summary 		Update asset
+// @Description 	Updates the filename, alternative text and focal point of an asset, the file cannot be replaced.
+// @Tags 			asset
+// @Accept 			json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			asset		path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			requestbody	body	UpdateAssetRequestBody	true	"body"
+// @Success			200
+// @Failure			404			{string}	string
+// @Failure			default		{string}	string
+// @Router			/contentmanagement/workspaces/{workspace}/assets/{asset} [put]
+func (ep endpoint) UpdateAsset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		body := UpdateAssetRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err := ep.app.Commands.UpdateAsset.Handle(r.Context(), command.UpdateAsset{
+			ID:          withAsset(r.Context()),
+			WorkspaceId: handlers.WithWorkspace(r.Context()).ID,
+			Filename:    body.Filename,
+			Alt:         body.Alt,
+			Focal:       body.Focal,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+}
+
+// DeleteAsset 		godoc
+// @Summary 		Delete asset
+// @Description 	Deletes an asset and its file. Assets that are used by published content are not deleted unless force is set.
+// @Tags 			asset
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			asset		path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			force		query	bool	false	"delete the asset even if it is used by published content"
+// @Success			200
+// @Failure			404			{string}	string
+// @Failure			409			{string}	string	"asset is used by published content"
+// @Router			/contentmanagement/workspaces/{workspace}/assets/{asset} [delete]
+func (ep endpoint) DeleteAsset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+		err := ep.app.Commands.DeleteAsset.Handle(r.Context(), command.DeleteAsset{
+			ID:          withAsset(r.Context()),
+			WorkspaceId: handlers.WithWorkspace(r.Context()).ID,
+			Force:       force,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil && strings.HasPrefix(err.Error(), asset.ErrAssetReferenced) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -3,18 +3,24 @@ package content
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/crikke/cms/cmd/contentmanagement/api/handlers"
 	"github.com/crikke/cms/cmd/contentmanagement/api/models"
 	"github.com/crikke/cms/cmd/contentmanagement/app"
 	"github.com/crikke/cms/cmd/contentmanagement/app/command"
 	"github.com/crikke/cms/cmd/contentmanagement/app/query"
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/locale"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type key string
@@ -29,20 +35,39 @@ type contentEndpoint struct {
 func NewContentRoute(app app.App) http.Handler {
 	r := chi.NewRouter()
 	c := contentEndpoint{app}
+	localeContext := locale.WorkspaceHandler(handlers.WithWorkspace)
 
-	r.Get("/", c.ListContent())
+	r.With(localeContext, db.PageHandler).Get("/", c.ListContent())
 	r.Post("/", c.CreateContent())
+	r.With(localeContext).Get("/children", c.ListChildren())
+	r.Put("/children", c.ReorderChildren())
 	r.Route("/{id}", func(r chi.Router) {
 		r.Use(contentIdContext)
-		r.Put("/", c.UpdateContent())
-		r.Delete("/", c.ArchiveContent())
+		r.With(handlers.IfMatchContext).Put("/", c.UpdateContent())
 
 		r.Route("/", func(r chi.Router) {
 			r.Use(contentVersionContext)
 			r.Get("/", c.GetContent())
+			r.With(handlers.IfMatchContext).Delete("/", c.ArchiveContent())
+			r.With(handlers.IfMatchContext).Post("/publish", c.PublishContent())
+			r.Post("/validate", c.ValidateContent())
+			r.Post("/restore", c.RestoreContent())
 		})
 
-		r.Post("/publish", c.PublishContent())
+		r.Get("/diff", c.GetContentDiff())
+		r.With(localeContext).Get("/references", c.ListReferencingContent())
+
+		r.With(localeContext).Get("/children", c.ListChildren())
+		r.Put("/children", c.ReorderChildren())
+		r.With(localeContext).Get("/ancestors", c.ListAncestors())
+		r.With(localeContext).Get("/descendants", c.ListDescendants())
+		r.Post("/move", c.MoveContent())
+
+		r.Route("/schedules", func(r chi.Router) {
+			r.Get("/", c.ListSchedules())
+			r.Post("/", c.CreateSchedule())
+			r.With(scheduleIdContext).Delete("/{schedule}", c.CancelSchedule())
+		})
 	})
 	return r
 }
@@ -145,7 +170,16 @@ func contentVersionContext(next http.Handler) http.Handler {
 // @Produces 		json
 // @Param			cid			query	[]string	true 	"uuid formatted ID." format(uuid)
 // @Param			tag			query	[]string	true 	"tag id"
+// @Param			filter		query	string		false 	"filter expression, ie: price gt 10 and title contains \"x\""
+// @Param			sort		query	string		false 	"created, updated or a property, prefix with - for descending order"
+// @Param			limit		query	int			false 	"page size, 1-100, defaults to 20"
+// @Param			token		query	string		false 	"continuation token of the previous page"
+// @Param			locale		query	string		false 	"language of the name, overrides Accept-Language"
+// @Param			Accept-Language	header	string	false 	"language of the name"
 // @Success			200			{object}	[]query.ContentListReadModel
+// @Header			200			{string}	Content-Language
+// @Header			200			{int}		X-Total-Count
+// @Header			200			{string}	X-Continuation-Token	"token of the next page, missing on the last page"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/content [get]
 func (c contentEndpoint) ListContent() http.HandlerFunc {
@@ -162,6 +196,7 @@ func (c contentEndpoint) ListContent() http.HandlerFunc {
 		q := query.ListContent{
 			ContentDefinitionIDs: make([]uuid.UUID, 0),
 			WorkspaceId:          ws.ID,
+			Language:             locale.FromContext(r.Context()),
 		}
 
 		if ids, ok := val["cid"]; ok {
@@ -176,7 +211,15 @@ func (c contentEndpoint) ListContent() http.HandlerFunc {
 			q.Tags = append(q.Tags, tags...)
 		}
 
-		res, err := c.app.Queries.ListContent.Handle(r.Context(), q)
+		q.Filter = val.Get("filter")
+		q.Page = db.PageFromContext(r.Context())
+
+		res, page, err := c.app.Queries.ListContent.Handle(r.Context(), q)
+
+		if err != nil && invalidListQuery(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -189,10 +232,21 @@ func (c contentEndpoint) ListContent() http.HandlerFunc {
 			return
 		}
 
+		db.SetPageHeaders(w, page)
 		w.Write(data)
 	}
 }
 
+// invalidListQuery returns true if the filter, sort or page token of a list request is invalid
+func invalidListQuery(err error) bool {
+	for _, prefix := range []string{content.ErrInvalidFilter, db.ErrInvalidSort, db.ErrInvalidPageToken} {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetContent 		godoc
 // @Summary 		Get content by id
 // @Description 	Get content by id and optionally version
@@ -203,6 +257,7 @@ func (c contentEndpoint) ListContent() http.HandlerFunc {
 // @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			version		query	int		false 	"content version"
 // @Success			200			{object}	query.ContentReadModel
+// @Header			200			{string}	ETag	"revision of the content version"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/content/{id} [get]
 func (c contentEndpoint) GetContent() http.HandlerFunc {
@@ -231,6 +286,64 @@ func (c contentEndpoint) GetContent() http.HandlerFunc {
 			return
 		}
 
+		handlers.SetETag(w, res.Revision)
+		w.Write(data)
+	}
+}
+
+// GetContentDiff 	godoc
+// @Summary 		Get changes between versions
+// @Description 	Compares two versions of content and returns the added, removed, changed and renamed fields per language
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			from		query	int		true 	"version to compare from"
+// @Param			to			query	int		true 	"version to compare to"
+// @Success			200			{object}	content.ContentDiff
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/diff [get]
+func (c contentEndpoint) GetContentDiff() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id := withID(r.Context())
+		ws := handlers.WithWorkspace(r.Context())
+
+		from, err := strconv.Atoi(r.URL.Query().Get("from"))
+		if err != nil {
+			http.Error(w, "from: bad formatted version", http.StatusBadRequest)
+			return
+		}
+
+		to, err := strconv.Atoi(r.URL.Query().Get("to"))
+		if err != nil {
+			http.Error(w, "to: bad formatted version", http.StatusBadRequest)
+			return
+		}
+
+		res, err := c.app.Queries.GetContentDiff.Handle(r.Context(), query.GetContentDiff{
+			Id:          id,
+			WorkspaceId: ws.ID,
+			From:        from,
+			To:          to,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, fmt.Sprintf("content %s version %d or %d not found", id, from, to), http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		w.Write(data)
 	}
 }
@@ -264,10 +377,20 @@ func (c contentEndpoint) CreateContent() http.HandlerFunc {
 			return
 		}
 
+		var parent uuid.UUID
+		if body.ParentID != "" {
+			parent, err = uuid.Parse(body.ParentID.String())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
 		id, err := c.app.Commands.CreateContent.Handle(r.Context(),
 			command.CreateContent{
 				ContentDefinitionId: cid,
 				WorkspaceId:         ws.ID,
+				ParentID:            parent,
 			},
 		)
 		if err != nil {
@@ -302,14 +425,22 @@ func (c contentEndpoint) CreateContent() http.HandlerFunc {
 
 // UpdateContent 	godoc
 // @Summary 		Update content
-// @Description 	Update content
+// @Description 	Update content. Richtext fields are set with an object containing one of blocks, html or markdown,
+// @Description 	the value is converted to a document and sanitized before it is saved.
+// @Description 	Values are converted to the type of the property where it is safe, like "42" for a number. If any field
+// @Description 	cannot be set nothing is saved, and every field that failed is returned in the report.
 // @Tags 			content
 // @Accept 			json
 // @Produces 		json
 // @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			requestbody	body UpdateContentRequestBody true "body"
+// @Param			If-Match	header	string	true	"expected revision of the content version, or * to skip the check"
 // @Success			200		{object}		models.OKResult
+// @Failure			422		{object}		content.ValidationReport	"a field does not exist or a value is not of the type of the property"
+// @Failure			412		{string}		string	"revision mismatch, ETag contains the current revision"
+// @Failure			428		{string}		string	"If-Match header is missing"
+// @Failure			409		{string}		string	"the new draft was created by another request"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/content/{id} [put]
 func (c contentEndpoint) UpdateContent() http.HandlerFunc {
@@ -317,6 +448,7 @@ func (c contentEndpoint) UpdateContent() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		id := withID(r.Context())
+		ws := handlers.WithWorkspace(r.Context())
 		body := &UpdateContentRequestBody{}
 
 		err := json.NewDecoder(r.Body).Decode(body)
@@ -326,12 +458,29 @@ func (c contentEndpoint) UpdateContent() http.HandlerFunc {
 		}
 
 		err = c.app.Commands.UpdateContentFields.Handle(r.Context(), command.UpdateContentFields{
-			ContentID: id,
-			Version:   body.Version,
-			Language:  body.Language,
-			Fields:    body.Fields,
+			ContentID:   id,
+			Version:     body.Version,
+			Language:    body.Language,
+			Fields:      body.Fields,
+			WorkspaceId: ws.ID,
+			Revision:    handlers.WithRevision(r.Context()),
 		})
 
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
+		if err != nil && strings.HasPrefix(err.Error(), content.ErrVersionExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		var verr content.ValidationError
+		if errors.As(err, &verr) {
+			writeValidationReport(w, verr.Report, http.StatusUnprocessableEntity)
+			return
+		}
+
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -341,13 +490,22 @@ func (c contentEndpoint) UpdateContent() http.HandlerFunc {
 
 // ArchivesContent 	godoc
 // @Summary 		Archives content
-// @Description 	Archives content with ID
+// @Description 	Archives content with ID. Content that is referenced by other published content is not archived unless force is set,
+// @Description 	archiving it anyway returns the referencing content in a Warning header.
+// @Description 	If-Match is the ETag of the published version, which is given by version.
 // @Tags 			content
 // @Accept 			json
 // @Produces 		json
 // @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			version		query	int		true 	"published content version"
+// @Param			force		query	bool	false	"archive even if the content is referenced"
+// @Param			If-Match	header	string	true	"expected revision of the published content version, or * to skip the check"
 // @Success			200		{object}		OKResult
+// @Header			200		{string}		Warning	"the content is referenced by published content"
+// @Failure			409		{string}		string	"the content is referenced by published content or version is not the published version"
+// @Failure			412		{string}		string	"revision mismatch, ETag contains the current revision"
+// @Failure			428		{string}		string	"If-Match header is missing"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/content/{id} [delete]
 func (c contentEndpoint) ArchiveContent() http.HandlerFunc {
@@ -355,20 +513,96 @@ func (c contentEndpoint) ArchiveContent() http.HandlerFunc {
 
 		id := withID(r.Context())
 		ws := handlers.WithWorkspace(r.Context())
+		force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+		var referencing []query.ContentListReadModel
+		if force {
+			var err error
+			referencing, err = c.app.Queries.ListReferencingContent.Handle(r.Context(), query.ListReferencingContent{
+				ID:          id,
+				WorkspaceId: ws.ID,
+			})
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
 
 		err := c.app.Commands.ArchiveContent.Handle(
 			r.Context(),
 			command.ArchiveContent{
 				ID:          id,
 				WorkspaceId: ws.ID,
+				Version:     withVersion(r.Context()),
+				Revision:    handlers.WithRevision(r.Context()),
+				Force:       force,
 			})
 
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
+		if err != nil && (strings.HasPrefix(err.Error(), content.ErrContentReferenced) || strings.HasPrefix(err.Error(), content.ErrNotPublished)) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
 		if err != nil {
 			models.WithError(r.Context(), err)
+			return
+		}
+
+		if len(referencing) > 0 {
+			ids := make([]string, 0, len(referencing))
+			for _, ref := range referencing {
+				ids = append(ids, ref.ID.String())
+			}
+
+			w.Header().Set("Warning", fmt.Sprintf("299 - %q", fmt.Sprintf("%s: %s", content.ErrContentReferenced, strings.Join(ids, ", "))))
 		}
 	}
 }
 
+// ListReferencingContent 	godoc
+// @Summary 		List referencing content
+// @Description 	List the published content that references the content
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			locale		query	string	false 	"language of the name, overrides Accept-Language"
+// @Param			Accept-Language	header	string	false 	"language of the name"
+// @Success			200			{object}	[]query.ContentListReadModel
+// @Header			200			{string}	Content-Language
+// @Failure			default		{string}	string
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/references [get]
+func (c contentEndpoint) ListReferencingContent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ws := handlers.WithWorkspace(r.Context())
+
+		res, err := c.app.Queries.ListReferencingContent.Handle(r.Context(), query.ListReferencingContent{
+			ID:          withID(r.Context()),
+			WorkspaceId: ws.ID,
+			Language:    locale.FromContext(r.Context()),
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
+
 // PublishContent 	godoc
 // @Summary 		Publishes content
 // @Description 	Publishes content with ID
@@ -378,7 +612,12 @@ func (c contentEndpoint) ArchiveContent() http.HandlerFunc {
 // @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			version		query	int		true 	"content version"
+// @Param			If-Match	header	string	true	"expected revision of the content version, or * to skip the check"
 // @Success			200			{object}		OKResult
+// @Failure			422			{object}		content.ValidationReport	"the content version is not valid"
+// @Failure			409			{string}		string	"a unique value was claimed by content published at the same time"
+// @Failure			412			{string}		string	"revision mismatch, ETag contains the current revision"
+// @Failure			428			{string}		string	"If-Match header is missing"
 // @Failure			default		{object}		models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/content/{id}/publish [post]
 func (c contentEndpoint) PublishContent() http.HandlerFunc {
@@ -395,10 +634,143 @@ func (c contentEndpoint) PublishContent() http.HandlerFunc {
 				ContentID:   id,
 				Version:     version,
 				WorkspaceId: ws.ID,
+				Revision:    handlers.WithRevision(r.Context()),
 			})
 
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
+		var verr content.ValidationError
+		if errors.As(err, &verr) {
+			writeValidationReport(w, verr.Report, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// content published at the same time claimed a unique value
+		if err != nil && strings.HasPrefix(err.Error(), content.ErrNotUnique) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
 		if err != nil {
 			models.WithError(r.Context(), err)
 		}
 	}
 }
+
+// ValidateContent 	godoc
+// @Summary 		Validates content
+// @Description 	Validates a content version like it is validated when it is published, without publishing it.
+// @Description 	Every property, language and validator that fails is returned.
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			version		query	int		true 	"content version"
+// @Success			200			{object}	content.ValidationReport
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/validate [post]
+func (c contentEndpoint) ValidateContent() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ws := handlers.WithWorkspace(r.Context())
+		report, err := c.app.Queries.ValidateContent.Handle(r.Context(), query.ValidateContent{
+			ID:          withID(r.Context()),
+			Version:     withVersion(r.Context()),
+			WorkspaceId: ws.ID,
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeValidationReport(w, report, http.StatusOK)
+	}
+}
+
+func writeValidationReport(w http.ResponseWriter, report content.ValidationReport, status int) {
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// RestoreContent 	godoc
+// @Summary 		Restores a previous version
+// @Description 	Creates a new draft from a previous version. The draft is reconciled against the current contentdefinition.
+// @Description 	With publish=true the new version is published directly.
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			version		query	int		true 	"content version to restore"
+// @Param			publish		query	bool	false 	"publish the restored version"
+// @Success			201			{object}	query.ContentReadModel
+// @Header			201			{string}	Location
+// @Failure			409			{string}	string	"the new version was created by another request"
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/restore [post]
+func (c contentEndpoint) RestoreContent() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id := withID(r.Context())
+		version := withVersion(r.Context())
+		ws := handlers.WithWorkspace(r.Context())
+
+		publish := false
+		if p := r.URL.Query().Get("publish"); p != "" {
+			var err error
+			publish, err = strconv.ParseBool(p)
+			if err != nil {
+				http.Error(w, "publish: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		restored, err := c.app.Commands.RestoreContent.Handle(r.Context(), command.RestoreContentVersion{
+			ContentID:   id,
+			Version:     version,
+			WorkspaceId: ws.ID,
+			Publish:     publish,
+		})
+		if err != nil && strings.HasPrefix(err.Error(), content.ErrVersionExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := c.app.Queries.GetContent.Handle(r.Context(), query.GetContent{
+			Id:          id,
+			Version:     restored,
+			WorkspaceId: ws.ID,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Location", fmt.Sprintf("%s?version=%d", strings.TrimSuffix(r.URL.Path, "/restore"), restored))
+		handlers.SetETag(w, res.Revision)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+	}
+}
@@ -4,6 +4,8 @@ import "github.com/go-openapi/strfmt"
 
 type CreateContentRequest struct {
 	ContentDefinitionId strfmt.UUID
+	// ParentID is empty for content at the root
+	ParentID strfmt.UUID
 }
 
 type UpdateContentRequestBody struct {
@@ -17,3 +19,26 @@ type UpdateContentRequestBody struct {
 
 type OKResult struct {
 }
+
+type CreateScheduleRequestBody struct {
+	// Version to publish or unpublish
+	Version int
+	// RFC 3339 timestamp, if no offset is given the timestamp is in TimeZone
+	PublishAt string
+	// RFC 3339 timestamp, if no offset is given the timestamp is in TimeZone
+	UnpublishAt string
+	// IANA time zone, defaults to UTC
+	TimeZone string
+}
+
+type MoveContentRequestBody struct {
+	// New parent, empty moves the content to the root
+	ParentID strfmt.UUID
+	// Position among the new siblings, if not set the content is put last
+	Position *int
+}
+
+type ReorderChildrenRequestBody struct {
+	// IDs of every child in the new order
+	Order []strfmt.UUID
+}
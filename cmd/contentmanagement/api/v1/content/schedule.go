@@ -0,0 +1,175 @@
+package content
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/crikke/cms/cmd/contentmanagement/api/handlers"
+	"github.com/crikke/cms/cmd/contentmanagement/app/command"
+	"github.com/crikke/cms/cmd/contentmanagement/app/query"
+	"github.com/crikke/cms/pkg/schedule"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var scheduleKey = key("schedule")
+
+func scheduleIdContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := uuid.Parse(chi.URLParam(r, "schedule"))
+
+		if err != nil {
+			http.Error(w, "schedule: bad format", http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), scheduleKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func withScheduleID(ctx context.Context) uuid.UUID {
+
+	var id uuid.UUID
+
+	if r := ctx.Value(scheduleKey); r != nil {
+		id = r.(uuid.UUID)
+	}
+
+	return id
+}
+
+// ListSchedules 	godoc
+// @Summary 		List schedules
+// @Description 	List publish and unpublish schedules of content
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Success			200			{object}	[]query.ScheduleReadModel
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/schedules [get]
+func (c contentEndpoint) ListSchedules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id := withID(r.Context())
+		ws := handlers.WithWorkspace(r.Context())
+
+		res, err := c.app.Queries.ListSchedules.Handle(r.Context(), query.ListSchedules{
+			ContentID:   id,
+			WorkspaceId: ws.ID,
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(data)
+	}
+}
+
+// CreateSchedule 	godoc
+// @Summary 		Schedule publish and unpublish
+// @Description 	Schedules publish and/or unpublish of a content version.
+// @Description 	Timestamps are RFC 3339, timestamps without offset are in the given time zone.
+// @Tags 			content
+// @Accept 			json
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			requestbody	body 	CreateScheduleRequestBody true "body"
+// @Success			201			{object}	[]string
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/schedules [post]
+func (c contentEndpoint) CreateSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id := withID(r.Context())
+		ws := handlers.WithWorkspace(r.Context())
+		body := &CreateScheduleRequestBody{}
+
+		err := json.NewDecoder(r.Body).Decode(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ids, err := c.app.Commands.CreateSchedule.Handle(r.Context(), command.CreateSchedule{
+			ContentID:   id,
+			Version:     body.Version,
+			WorkspaceId: ws.ID,
+			PublishAt:   body.PublishAt,
+			UnpublishAt: body.UnpublishAt,
+			TimeZone:    body.TimeZone,
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&ids)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Location", r.URL.String())
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+	}
+}
+
+// CancelSchedule 	godoc
+// @Summary 		Cancel schedule
+// @Description 	Cancels a pending schedule
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			schedule	path	string	true 	"uuid formatted ID." format(uuid)
+// @Success			200			{object}	models.OKResult
+// @Failure			404			{string}	string	"the content has no schedule with the id"
+// @Failure			409			{string}	string	"the schedule is not pending"
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/schedules/{schedule} [delete]
+func (c contentEndpoint) CancelSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id := withID(r.Context())
+		ws := handlers.WithWorkspace(r.Context())
+
+		err := c.app.Commands.CancelSchedule.Handle(r.Context(), command.CancelSchedule{
+			ID:          withScheduleID(r.Context()),
+			ContentID:   id,
+			WorkspaceId: ws.ID,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, fmt.Sprintf("schedule %s not found", withScheduleID(r.Context())), http.StatusNotFound)
+			return
+		}
+
+		if err != nil && err.Error() == schedule.ErrNotPending {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+}
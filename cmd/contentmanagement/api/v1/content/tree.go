@@ -0,0 +1,228 @@
+package content
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/crikke/cms/cmd/contentmanagement/api/handlers"
+	"github.com/crikke/cms/cmd/contentmanagement/app/command"
+	"github.com/crikke/cms/cmd/contentmanagement/app/query"
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/locale"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ListChildren 	godoc
+// @Summary 		List children
+// @Description 	List children of content ordered by sort order. Without id the content at the root is listed.
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			locale		query	string	false 	"language of the name, overrides Accept-Language"
+// @Param			Accept-Language	header	string	false 	"language of the name"
+// @Success			200			{object}	[]query.ContentTreeReadModel
+// @Header			200			{string}	Content-Language
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/children [get]
+// @Router			/contentmanagement/workspaces/{workspace}/content/children [get]
+func (c contentEndpoint) ListChildren() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ws := handlers.WithWorkspace(r.Context())
+
+		res, err := c.app.Queries.ListChildren.Handle(r.Context(), query.ListChildren{
+			Id:          withID(r.Context()),
+			WorkspaceId: ws.ID,
+			Language:    locale.FromContext(r.Context()),
+		})
+
+		writeTree(w, res, err)
+	}
+}
+
+// ListAncestors 	godoc
+// @Summary 		List ancestors
+// @Description 	List ancestors of content starting at the root
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			locale		query	string	false 	"language of the name, overrides Accept-Language"
+// @Param			Accept-Language	header	string	false 	"language of the name"
+// @Success			200			{object}	[]query.ContentTreeReadModel
+// @Header			200			{string}	Content-Language
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/ancestors [get]
+func (c contentEndpoint) ListAncestors() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ws := handlers.WithWorkspace(r.Context())
+
+		res, err := c.app.Queries.ListAncestors.Handle(r.Context(), query.ListAncestors{
+			Id:          withID(r.Context()),
+			WorkspaceId: ws.ID,
+			Language:    locale.FromContext(r.Context()),
+		})
+
+		writeTree(w, res, err)
+	}
+}
+
+// ListDescendants 	godoc
+// @Summary 		List descendants
+// @Description 	List every descendant of content ordered by depth
+// @Tags 			content
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			locale		query	string	false 	"language of the name, overrides Accept-Language"
+// @Param			Accept-Language	header	string	false 	"language of the name"
+// @Success			200			{object}	[]query.ContentTreeReadModel
+// @Header			200			{string}	Content-Language
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/descendants [get]
+func (c contentEndpoint) ListDescendants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ws := handlers.WithWorkspace(r.Context())
+
+		res, err := c.app.Queries.ListDescendants.Handle(r.Context(), query.ListDescendants{
+			Id:          withID(r.Context()),
+			WorkspaceId: ws.ID,
+			Language:    locale.FromContext(r.Context()),
+		})
+
+		writeTree(w, res, err)
+	}
+}
+
+func writeTree(w http.ResponseWriter, res []query.ContentTreeReadModel, err error) {
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(&res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
+}
+
+// MoveContent 		godoc
+// @Summary 		Move content
+// @Description 	Moves content to a new parent and position among its siblings. Content cannot be moved below itself.
+// @Tags 			content
+// @Accept 			json
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			requestbody	body 	MoveContentRequestBody true "body"
+// @Success			200			{object}	models.OKResult
+// @Failure			409			{string}	string	"a value that is unique per parent is already used below the new parent"
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/move [post]
+func (c contentEndpoint) MoveContent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ws := handlers.WithWorkspace(r.Context())
+		body := &MoveContentRequestBody{}
+
+		err := json.NewDecoder(r.Body).Decode(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var parent uuid.UUID
+		if body.ParentID != "" {
+			parent, err = uuid.Parse(body.ParentID.String())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = c.app.Commands.MoveContent.Handle(r.Context(), command.MoveContent{
+			ContentID:   withID(r.Context()),
+			ParentID:    parent,
+			Position:    body.Position,
+			WorkspaceId: ws.ID,
+		})
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if err != nil && strings.HasPrefix(err.Error(), content.ErrNotUnique) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+}
+
+// ReorderChildren 	godoc
+// @Summary 		Reorder children
+// @Description 	Sets the order of the children of content, the order must contain every child. Without id the content at the root is ordered.
+// @Tags 			content
+// @Accept 			json
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			id			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			requestbody	body 	ReorderChildrenRequestBody true "body"
+// @Success			200			{object}	models.OKResult
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/content/{id}/children [put]
+// @Router			/contentmanagement/workspaces/{workspace}/content/children [put]
+func (c contentEndpoint) ReorderChildren() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ws := handlers.WithWorkspace(r.Context())
+		body := &ReorderChildrenRequestBody{}
+
+		err := json.NewDecoder(r.Body).Decode(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		order := make([]uuid.UUID, 0, len(body.Order))
+		for _, o := range body.Order {
+			id, err := uuid.Parse(o.String())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			order = append(order, id)
+		}
+
+		err = c.app.Commands.ReorderChildren.Handle(r.Context(), command.ReorderChildren{
+			ParentID:    withID(r.Context()),
+			Order:       order,
+			WorkspaceId: ws.ID,
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+}
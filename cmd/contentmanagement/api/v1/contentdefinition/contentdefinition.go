@@ -3,16 +3,22 @@ package contentdefinition
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/crikke/cms/cmd/contentmanagement/api/handlers"
 	"github.com/crikke/cms/cmd/contentmanagement/api/models"
 	"github.com/crikke/cms/cmd/contentmanagement/app"
 	"github.com/crikke/cms/cmd/contentmanagement/app/command"
 	"github.com/crikke/cms/cmd/contentmanagement/app/query"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type key string
@@ -29,16 +35,17 @@ func NewContentDefinitionRoute(app app.App) http.Handler {
 	c := endpoint{app: app}
 	r := chi.NewRouter()
 
-	r.Get("/", c.ListContentDefinitions())
+	r.With(db.PageHandler).Get("/", c.ListContentDefinitions())
 	r.Post("/", c.CreateContentDefinition())
+	r.Get("/validators", c.ListValidators())
 
 	r.Route("/{id}", func(r chi.Router) {
 		r.Use(func(h http.Handler) http.Handler {
 			return contentDefinitionIdContext(h, "id", contentKey)
 		})
 		r.Get("/", c.GetContentDefinition())
-		r.Delete("/", c.DeleteContentDefinition())
-		r.Put("/", c.UpdateContentDefinition())
+		r.With(handlers.IfMatchContext).Delete("/", c.DeleteContentDefinition())
+		r.With(handlers.IfMatchContext).Put("/", c.UpdateContentDefinition())
 	})
 	return r
 }
@@ -104,6 +111,7 @@ func withPID(ctx context.Context) uuid.UUID {
 // @Description 				Creates a new contentdefinition. The contentdefinition
 // @Description 				acts as a template for creating new content,
 // @Description 				containing what properties to create & their validation.
+// @Description 				Content of routable contentdefinitions has a url segment and is found by path in the delivery API.
 //
 // @Tags 						contentdefinition
 // @Accept 						json
@@ -131,6 +139,7 @@ func (c endpoint) CreateContentDefinition() http.HandlerFunc {
 			Name:        req.Name,
 			Description: req.Description,
 			WorkspaceId: ws.ID,
+			Routable:    req.Routable,
 		})
 
 		if err != nil {
@@ -153,6 +162,7 @@ func (c endpoint) CreateContentDefinition() http.HandlerFunc {
 // @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
 // @Param						id			path	string	true 	"uuid formatted ID." format(uuid)
 // @Success						200			{object}	contentdefinition.ContentDefinition
+// @Header						200			{string}	ETag	"revision of the content definition"
 // @Failure						default		{object}	models.GenericError
 // @Router						/contentmanagement/workspaces/{workspace}/contentdefinitions/{id} [get]
 func (c endpoint) GetContentDefinition() http.HandlerFunc {
@@ -176,6 +186,7 @@ func (c endpoint) GetContentDefinition() http.HandlerFunc {
 			return
 		}
 
+		handlers.SetETag(w, cd.Revision)
 		w.Write(bytes)
 	}
 }
@@ -188,14 +199,22 @@ func (c endpoint) GetContentDefinition() http.HandlerFunc {
 // @Accept 						json
 // @Produces 					json
 // @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						sort		query	string	false	"name or created, prefix with - for descending order"
+// @Param						limit		query	int		false	"page size, 1-100, defaults to 20"
+// @Param						token		query	string	false	"continuation token of the previous page"
 // @Success						200			{object}	[]query.ListContentDefinitionModel
+// @Header						200			{int}		X-Total-Count
+// @Header						200			{string}	X-Continuation-Token	"token of the next page, missing on the last page"
 // @Failure						default		{object}	models.GenericError
 // @Router						/contentmanagement/workspaces/{workspace}/contentdefinitions [get]
 func (c endpoint) ListContentDefinitions() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		ws := handlers.WithWorkspace(r.Context())
-		cd, err := c.app.Queries.ListContentDefinitions.Handle(r.Context(), query.ListContentDefinition{WorkspaceID: ws.ID})
+		cd, page, err := c.app.Queries.ListContentDefinitions.Handle(r.Context(), query.ListContentDefinition{
+			WorkspaceID: ws.ID,
+			Page:        db.PageFromContext(r.Context()),
+		})
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -208,20 +227,52 @@ func (c endpoint) ListContentDefinitions() http.HandlerFunc {
 			return
 		}
 
+		db.SetPageHeaders(w, page)
+		w.Write(data)
+	}
+}
+
+// ListValidators 				godoc
+// @Summary 					Get all validators
+// @Description 				Gets the validators that can be configured on property definitions
+// @Description 				and the schema of their configuration
+//
+// @Tags 						contentdefinition
+// @Accept 						json
+// @Produces 					json
+// @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Success						200			{object}	[]query.ValidatorReadModel
+// @Failure						default		{object}	models.GenericError
+// @Router						/contentmanagement/workspaces/{workspace}/contentdefinitions/validators [get]
+func (c endpoint) ListValidators() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		data, err := json.Marshal(c.app.Queries.ListValidators.Handle(r.Context()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		w.Write(data)
 	}
 }
 
 // DeleteContentDefinition 		godoc
 // @Summary 					Delete a content definition
-// @Description 				Delete a content definition
+// @Description 				Delete a content definition, a content definition that is used by content is not deleted
 //
 // @Tags 						contentdefinition
 // @Accept 						json
 // @Produces 					json
 // @Param						id			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param						If-Match	header	string	true	"expected revision of the content definition, or * to skip the check"
 // @Success						200			{object}	models.OKResult
+// @Failure						404			{string}	string	"the content definition does not exist"
+// @Failure						409			{string}	string	"the content definition is used by content"
+// @Failure						412			{string}	string	"revision mismatch, ETag contains the current revision"
+// @Failure						428			{string}	string	"If-Match header is missing"
 // @Failure						default		{object}	models.GenericError
 // @Router						/contentmanagement/workspaces/{workspace}/contentdefinitions/{id} [delete]
 func (c endpoint) DeleteContentDefinition() http.HandlerFunc {
@@ -231,7 +282,26 @@ func (c endpoint) DeleteContentDefinition() http.HandlerFunc {
 		id := withID(r.Context())
 		ws := handlers.WithWorkspace(r.Context())
 
-		err := c.app.Commands.DeleteContentDefinition.Handle(r.Context(), command.DeleteContentDefinition{ID: id, WorkspaceId: ws.ID})
+		err := c.app.Commands.DeleteContentDefinition.Handle(r.Context(), command.DeleteContentDefinition{
+			ID:          id,
+			WorkspaceId: ws.ID,
+			Revision:    handlers.WithRevision(r.Context()),
+		})
+
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, fmt.Sprintf("content definition %s not found", id), http.StatusNotFound)
+			return
+		}
+
+		if err != nil && strings.HasPrefix(err.Error(), contentdefinition.ErrContentDefinitionUsed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -241,7 +311,11 @@ func (c endpoint) DeleteContentDefinition() http.HandlerFunc {
 
 // UpdateContentDefinition 		godoc
 // @Summary 					Updates a contentdefinition
-// @Description 				Updates a contentdefinition
+// @Description 				Updates a contentdefinition. Options are not removed from enum properties while published content
+// @Description 				uses them unless force is set, content keeps a removed option until it is published again.
+// @Description 				Rules are CEL expressions over the properties that are checked when the contentdefinition is saved
+// @Description 				and evaluated when content is published.
+// @Description 				Reference, asset and richtext properties can only be top level properties, they cannot be list items or sub-properties of objects.
 //
 // @Tags 						contentdefinition
 // @Accept 						json
@@ -249,7 +323,12 @@ func (c endpoint) DeleteContentDefinition() http.HandlerFunc {
 // @Param						id			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param						workspace	path	string	true 	"uuid formatted ID." format(uuid)
 // @Param						body		body	ContentDefinitionBody	true 	"request body"
+// @Param						If-Match	header	string	true	"expected revision of the content definition, or * to skip the check"
+// @Param						force		query	bool	false	"remove enum options even if they are used by published content"
 // @Success						200			{object}	models.OKResult
+// @Failure						409			{string}	string	"a removed enum option is used by published content"
+// @Failure						412			{string}	string	"revision mismatch, ETag contains the current revision"
+// @Failure						428			{string}	string	"If-Match header is missing"
 // @Failure						default		{object}	models.GenericError
 // @Router						/contentmanagement/workspaces/{workspace}/contentdefinitions/{id} [put]
 func (c endpoint) UpdateContentDefinition() http.HandlerFunc {
@@ -266,12 +345,31 @@ func (c endpoint) UpdateContentDefinition() http.HandlerFunc {
 			return
 		}
 
-		c.app.Commands.UpdateContentDefinition.Handle(r.Context(), command.UpdateContentDefinition{
+		force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+		err = c.app.Commands.UpdateContentDefinition.Handle(r.Context(), command.UpdateContentDefinition{
 			ContentDefinitionID: id,
 			Name:                body.Name,
 			Description:         body.Description,
 			WorkspaceId:         ws.ID,
 			PropertyDefinitions: body.PropertyDefinitions,
+			Rules:               body.Rules,
+			Revision:            handlers.WithRevision(r.Context()),
+			Force:               force,
 		})
+
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
+		if err != nil && strings.HasPrefix(err.Error(), contentdefinition.ErrEnumOptionInUse) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 }
@@ -7,9 +7,13 @@ type ContentDefinitionBody struct {
 	Name string
 	// Content definition description
 	Description string
+	// Routable adds a url segment to the content definition, only used when it is created
+	Routable bool
 	// PropertyDefinitions
 
 	PropertyDefinitions map[string]contentdefinition.PropertyDefinition
+	// Rules that span properties, the rules are kept if left out
+	Rules *[]contentdefinition.Rule
 }
 
 //! TODO Remove this
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/crikke/cms/cmd/contentmanagement/api/handlers"
+	"github.com/crikke/cms/cmd/contentmanagement/app"
+	"github.com/crikke/cms/cmd/contentmanagement/app/query"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/locale"
+	"github.com/go-chi/chi/v5"
+)
+
+type endpoint struct {
+	app app.App
+}
+
+func NewSearchRoute(app app.App) http.Handler {
+
+	r := chi.NewRouter()
+	ep := endpoint{app: app}
+
+	r.With(locale.WorkspaceHandler(handlers.WithWorkspace), db.PageHandler).Get("/", ep.Search())
+
+	return r
+}
+
+// Search 			godoc
+// @Summary 		Search content
+// @Description 	Full-text search of the text fields of the latest version of content.
+// @Description		Results are ordered by relevance and contain highlighted snippets of the matching fields.
+// @Tags 			search
+// @Produces 		json
+// @Param			workspace	path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			q			query	string	true	"search query, words in quotes are searched as a phrase and words prefixed with - are excluded"
+// @Param			limit		query	int		false	"page size, 1-100, defaults to 20"
+// @Param			token		query	string	false	"continuation token of the previous page"
+// @Param			locale		query	string	false 	"language to search in, overrides Accept-Language"
+// @Param			Accept-Language	header	string	false 	"language to search in"
+// @Success			200			{object}	[]query.SearchResultReadModel
+// @Header			200			{string}	Content-Language
+// @Header			200			{int}		X-Total-Count
+// @Header			200			{string}	X-Continuation-Token	"token of the next page, missing on the last page"
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/search [get]
+func (ep endpoint) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ws := handlers.WithWorkspace(r.Context())
+
+		res, page, err := ep.app.Queries.Search.Handle(r.Context(), query.Search{
+			Query:       r.URL.Query().Get("q"),
+			Language:    locale.FromContext(r.Context()),
+			Page:        db.PageFromContext(r.Context()),
+			WorkspaceId: ws.ID,
+		})
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.Marshal(&res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		db.SetPageHeaders(w, page)
+		w.Write(data)
+	}
+}
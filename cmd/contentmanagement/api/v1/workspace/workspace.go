@@ -10,8 +10,10 @@ import (
 	"github.com/crikke/cms/cmd/contentmanagement/app"
 	"github.com/crikke/cms/cmd/contentmanagement/app/command"
 	"github.com/crikke/cms/cmd/contentmanagement/app/query"
+	"github.com/crikke/cms/pkg/db"
 	"github.com/crikke/cms/pkg/workspace"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -25,21 +27,22 @@ func NewWorkspaceRoute(app app.App, log *zap.SugaredLogger) http.Handler {
 	wsHandler := handlers.WorkspaceHandler{App: app}
 
 	r.Post("/", createWorkspace(app))
-	r.Get("/", listWorkspaces(app))
+	r.With(db.PageHandler).Get("/", listWorkspaces(app))
 	r.Route("/{workspace}", func(r chi.Router) {
 		r.Use(wsHandler.WorkspaceParamContext)
 
-		r.Put("/", updateWorkspace(app))
+		r.With(handlers.IfMatchContext).Put("/", updateWorkspace(app))
 		r.Get("/", getWorkspace(app))
+		r.With(handlers.IfMatchContext).Put("/startpage", setStartPage(app))
 
 		r.Route("/tags", func(r chi.Router) {
-			r.Get("/", listTags(app))
-			r.Post("/", createTag(app))
+			r.With(db.PageHandler).Get("/", listTags(app))
+			r.With(handlers.IfMatchContext).Post("/", createTag(app))
 			r.Route("/{tag}", func(r chi.Router) {
 				r.Use(tagContext)
 				r.Get("/", getTag(app))
-				r.Put("/", updateTag(app))
-				r.Delete("/", deleteTag(app))
+				r.With(handlers.IfMatchContext).Put("/", updateTag(app))
+				r.With(handlers.IfMatchContext).Delete("/", deleteTag(app))
 			})
 		})
 	})
@@ -74,6 +77,7 @@ func withTag(ctx context.Context) string {
 // @Produces 		json
 // @Param			workspace			path	string	true 	"uuid formatted ID." format(uuid)
 // @Success			200			{object}	workspace.Workspace
+// @Header			200			{string}	ETag	"revision of the workspace"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace} [get]
 func getWorkspace(app app.App) http.HandlerFunc {
@@ -87,6 +91,7 @@ func getWorkspace(app app.App) http.HandlerFunc {
 			return
 		}
 
+		handlers.SetETag(w, ws.Revision)
 		w.Write(data)
 	}
 }
@@ -96,16 +101,23 @@ func getWorkspace(app app.App) http.HandlerFunc {
 // @Description 	List workspaces
 // @Tags 			workspace
 // @Produces 		json
+// @Param			sort		query	string	false	"name, prefix with - for descending order"
+// @Param			limit		query	int		false	"page size, 1-100, defaults to 20"
+// @Param			token		query	string	false	"continuation token of the previous page"
 // @Success			200			{object}	[]query.ListWorkspaceResult
+// @Header			200			{int}		X-Total-Count
+// @Header			200			{string}	X-Continuation-Token	"token of the next page, missing on the last page"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces [get]
 func listWorkspaces(app app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		items, err := app.Queries.WorkspaceQueries.ListWorkspaces.Handle(r.Context())
+		items, page, err := app.Queries.WorkspaceQueries.ListWorkspaces.Handle(r.Context(), query.ListWorkspace{
+			Page: db.PageFromContext(r.Context()),
+		})
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
@@ -116,6 +128,7 @@ func listWorkspaces(app app.App) http.HandlerFunc {
 			return
 		}
 
+		db.SetPageHeaders(w, page)
 		w.Write(data)
 	}
 }
@@ -181,6 +194,9 @@ type TagBody struct {
 // @Produces 		json
 // @Param			workspace			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			body	body workspace.Workspace true "workspace body"
+// @Param			If-Match	header	string	true	"expected revision of the workspace, or * to skip the check"
+// @Failure			412			{string}	string	"revision mismatch, ETag contains the current revision"
+// @Failure			428			{string}	string	"If-Match header is missing"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace} [put]
 func updateWorkspace(app app.App) http.HandlerFunc {
@@ -199,8 +215,60 @@ func updateWorkspace(app app.App) http.HandlerFunc {
 			ID:          ws.ID,
 			Description: body.Description,
 			Name:        body.Name,
+			Revision:    handlers.WithRevision(r.Context()),
 		})
 
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+}
+
+type StartPageBody struct {
+	// ContentID is content at the root of the content tree, empty removes the start page
+	ContentID uuid.UUID
+}
+
+// setStartPage 		godoc
+// @Summary 		Set start page
+// @Description 	Sets the content that has the path / in the delivery API. The start page must be at the root of the content tree,
+// @Description 	content at the root without url segment is otherwise not routable.
+// @Tags 			workspace
+// @Consumes 		json
+// @Param			workspace			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			body	body StartPageBody true "start page"
+// @Param			If-Match	header	string	true	"expected revision of the workspace, or * to skip the check"
+// @Failure			412			{string}	string	"revision mismatch, ETag contains the current revision"
+// @Failure			428			{string}	string	"If-Match header is missing"
+// @Failure			default		{object}	models.GenericError
+// @Router			/contentmanagement/workspaces/{workspace}/startpage [put]
+func setStartPage(app app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ws := handlers.WithWorkspace(r.Context())
+		body := &StartPageBody{}
+
+		err := json.NewDecoder(r.Body).Decode(body)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = app.Commands.SetStartPage.Handle(r.Context(), command.SetStartPage{
+			ContentID:   body.ContentID,
+			WorkspaceId: ws.ID,
+			Revision:    handlers.WithRevision(r.Context()),
+		})
+
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -216,7 +284,10 @@ func updateWorkspace(app app.App) http.HandlerFunc {
 // @Produces 		json
 // @Param			workspace			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			body			body 	TagBody true "Tag"
+// @Param			If-Match	header	string	true	"expected revision of the workspace, or * to skip the check"
 // @Success			201			{object}	workspace.Workspace
+// @Failure			412			{string}	string	"revision mismatch, ETag contains the current revision"
+// @Failure			428			{string}	string	"If-Match header is missing"
 // @Header						201			{string}	Location
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/tag [post]
@@ -237,8 +308,13 @@ func createTag(app app.App) http.HandlerFunc {
 			WorkspaceId: ws.ID,
 			Name:        tag.Name,
 			Id:          tag.ID,
+			Revision:    handlers.WithRevision(r.Context()),
 		})
 
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -256,18 +332,26 @@ func createTag(app app.App) http.HandlerFunc {
 // @Description 	List all tags in workspace
 // @Tags 			workspace
 // @Param			workspace			path	string	true 	"uuid formatted ID." format(uuid)
+// @Param			sort		query	string	false	"name or id, prefix with - for descending order"
+// @Param			limit		query	int		false	"page size, 1-100, defaults to 20"
+// @Param			token		query	string	false	"continuation token of the previous page"
 // @Produces 		json
 // @Success			200			{object}	[]query.Tag
+// @Header			200			{int}		X-Total-Count
+// @Header			200			{string}	X-Continuation-Token	"token of the next page, missing on the last page"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/tag [get]
 func listTags(app app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		workspace := handlers.WithWorkspace(r.Context())
 
-		tags, err := app.Queries.WorkspaceQueries.ListTags.Handle(r.Context(), workspace.ID)
+		tags, page, err := app.Queries.WorkspaceQueries.ListTags.Handle(r.Context(), query.ListTags{
+			WorkspaceID: workspace.ID,
+			Page:        db.PageFromContext(r.Context()),
+		})
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
@@ -277,6 +361,7 @@ func listTags(app app.App) http.HandlerFunc {
 			return
 		}
 
+		db.SetPageHeaders(w, page)
 		w.Write(data)
 	}
 }
@@ -319,7 +404,10 @@ func getTag(app app.App) http.HandlerFunc {
 // @Param			workspace			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			tag					path	string	true 	"name"
 // @Param			body	body string true "Tag"
+// @Param			If-Match	header	string	true	"expected revision of the workspace, or * to skip the check"
 // @Success			200			{object}	query.Tag
+// @Failure			412			{string}	string	"revision mismatch, ETag contains the current revision"
+// @Failure			428			{string}	string	"If-Match header is missing"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/tag/{tag} [put]
 func updateTag(app app.App) http.HandlerFunc {
@@ -338,8 +426,13 @@ func updateTag(app app.App) http.HandlerFunc {
 			WorkspaceId: ws.ID,
 			Name:        tag.Name,
 			Id:          tag.ID,
+			Revision:    handlers.WithRevision(r.Context()),
 		})
 
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -367,6 +460,9 @@ func updateTag(app app.App) http.HandlerFunc {
 // @Produces 		json
 // @Param			workspace			path	string	true 	"uuid formatted ID." format(uuid)
 // @Param			tag					path	string	true 	"tag id"
+// @Param			If-Match	header	string	true	"expected revision of the workspace, or * to skip the check"
+// @Failure			412			{string}	string	"revision mismatch, ETag contains the current revision"
+// @Failure			428			{string}	string	"If-Match header is missing"
 // @Failure			default		{object}	models.GenericError
 // @Router			/contentmanagement/workspaces/{workspace}/tag/{tag} [delete]
 func deleteTag(app app.App) http.HandlerFunc {
@@ -377,8 +473,13 @@ func deleteTag(app app.App) http.HandlerFunc {
 		err := app.Commands.WorkspaceCommands.DeleteTag.Handle(r.Context(), command.DeleteTag{
 			WorkspaceId: ws.ID,
 			Id:          tagId,
+			Revision:    handlers.WithRevision(r.Context()),
 		})
 
+		if handlers.RevisionError(w, err) {
+			return
+		}
+
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
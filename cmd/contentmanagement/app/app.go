@@ -11,12 +11,30 @@ type App struct {
 }
 
 type Queries struct {
-	GetContent  query.GetContentHandler
-	ListContent query.ListContentHandler
+	GetContent      query.GetContentHandler
+	GetContentDiff  query.GetContentDiffHandler
+	ListContent     query.ListContentHandler
+	ValidateContent query.ValidateContentHandler
+
+	ListReferencingContent query.ListReferencingContentHandler
+
+	ListChildren    query.ListChildrenHandler
+	ListAncestors   query.ListAncestorsHandler
+	ListDescendants query.ListDescendantsHandler
 
 	GetContentDefinition   query.GetContentDefinitionHandler
 	GetPropertyDefinition  query.GetPropertyDefinitionHandler
 	ListContentDefinitions query.ListContentDefinitionHandler
+	ListValidators         query.ListValidatorsHandler
+
+	ListSchedules query.ListSchedulesHandler
+
+	Search query.SearchHandler
+
+	GetAsset     query.GetAssetHandler
+	ListAssets   query.ListAssetsHandler
+	GetAssetFile query.GetAssetFileHandler
+	GetAssetURL  query.GetAssetURLHandler
 
 	WorkspaceQueries WorkspaceQueries
 }
@@ -25,6 +43,11 @@ type Commands struct {
 	UpdateContentFields contentcmd.UpdateContentFieldsHandler
 	ArchiveContent      contentcmd.ArchiveContentHandler
 	PublishContent      contentcmd.PublishContentHandler
+	RestoreContent      contentcmd.RestoreContentVersionHandler
+
+	MoveContent     contentcmd.MoveContentHandler
+	ReorderChildren contentcmd.ReorderChildrenHandler
+	SetStartPage    contentcmd.SetStartPageHandler
 
 	CreateContentDefinition contentcmd.CreateContentDefinitionHandler
 	UpdateContentDefinition contentcmd.UpdateContentDefinitionHandler
@@ -34,6 +57,14 @@ type Commands struct {
 	UpdatePropertyDefinition contentcmd.UpdatePropertyDefinitionHandler
 	DeletePropertyDefinition contentcmd.DeletePropertyDefinitionHandler
 
+	CreateSchedule   contentcmd.CreateScheduleHandler
+	CancelSchedule   contentcmd.CancelScheduleHandler
+	ExecuteSchedules contentcmd.ExecuteSchedulesHandler
+
+	UploadAsset contentcmd.UploadAssetHandler
+	UpdateAsset contentcmd.UpdateAssetHandler
+	DeleteAsset contentcmd.DeleteAssetHandler
+
 	WorkspaceCommands WorkspaceCommands
 }
 
@@ -0,0 +1,240 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/crikke/cms/pkg/asset"
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/crikke/cms/pkg/richtext"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+type UploadAsset struct {
+	WorkspaceId uuid.UUID
+	Filename    string
+	// File is read twice, once for the metadata and once when it is stored
+	File io.ReadSeeker
+	// Alt is the alternative text per language
+	Alt map[string]string
+}
+
+type UploadAssetHandler struct {
+	Repo                asset.AssetRepository
+	Storage             asset.Storage
+	WorkspaceRepository workspace.WorkspaceRepository
+	Factory             asset.AssetFactory
+}
+
+// The file is stored before the asset is created, so an asset never exists without its file.
+func (h UploadAssetHandler) Handle(ctx context.Context, cmd UploadAsset) (uuid.UUID, error) {
+
+	if cmd.File == nil {
+		return uuid.UUID{}, errors.New(asset.ErrMissingFile)
+	}
+
+	ws, err := h.WorkspaceRepository.Get(ctx, cmd.WorkspaceId)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	a, err := h.Factory.NewAsset(cmd.Filename, cmd.File, cmd.Alt, ws.Languages, time.Now().UTC())
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if _, err := cmd.File.Seek(0, io.SeekStart); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := h.Storage.Put(ctx, cmd.WorkspaceId, a.Key(), cmd.File); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := h.Repo.CreateAsset(ctx, a, cmd.WorkspaceId); err != nil {
+		h.Storage.Delete(ctx, cmd.WorkspaceId, a.Key())
+		return uuid.UUID{}, err
+	}
+
+	return a.ID, nil
+}
+
+type UpdateAsset struct {
+	ID          uuid.UUID
+	WorkspaceId uuid.UUID
+	// Filename is left unchanged if empty
+	Filename string
+	// Alt replaces the alternative text of every language
+	Alt map[string]string
+	// Focal replaces the focal point, nil removes it
+	Focal *imaging.Point
+}
+
+type UpdateAssetHandler struct {
+	Repo                asset.AssetRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+}
+
+func (h UpdateAssetHandler) Handle(ctx context.Context, cmd UpdateAsset) error {
+
+	ws, err := h.WorkspaceRepository.Get(ctx, cmd.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err := asset.ValidateAlt(cmd.Alt, ws.Languages); err != nil {
+		return err
+	}
+
+	if cmd.Focal != nil {
+		if err := cmd.Focal.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return h.Repo.UpdateAsset(ctx, cmd.ID, cmd.WorkspaceId, func(ctx context.Context, a *asset.Asset) (*asset.Asset, error) {
+
+		if cmd.Filename != "" {
+			name, err := asset.CleanFilename(cmd.Filename)
+			if err != nil {
+				return nil, err
+			}
+			a.Filename = name
+		}
+
+		a.Alt = cmd.Alt
+		a.Focal = cmd.Focal
+		a.Updated = time.Now().UTC()
+		return a, nil
+	})
+}
+
+type DeleteAsset struct {
+	ID          uuid.UUID
+	WorkspaceId uuid.UUID
+	// Force deletes the asset even if it is used by published content
+	Force bool
+}
+
+type DeleteAssetHandler struct {
+	Repo    asset.AssetRepository
+	Storage asset.Storage
+	// Cache is the cache of images rendered by the content delivery API
+	Cache             imaging.Cache
+	ContentRepository content.ContentManagementRepository
+}
+
+// The asset is deleted before its file, a file without an asset is unreachable but an asset without a file is broken.
+// Images rendered from the file are removed last.
+func (h DeleteAssetHandler) Handle(ctx context.Context, cmd DeleteAsset) error {
+
+	if !cmd.Force {
+		using, err := h.ContentRepository.ListContentUsingAsset(ctx, cmd.ID, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		if len(using) > 0 {
+			return fmt.Errorf("%s: %s", asset.ErrAssetReferenced, strings.Join(referencingIDs(using, uuid.UUID{}), ", "))
+		}
+	}
+
+	if err := h.Repo.DeleteAsset(ctx, cmd.ID, cmd.WorkspaceId); err != nil {
+		return err
+	}
+
+	err := h.Storage.Delete(ctx, cmd.WorkspaceId, asset.Asset{ID: cmd.ID}.Key())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return h.Cache.Remove(cmd.WorkspaceId, cmd.ID)
+}
+
+// usedAssets returns the IDs of the assets used by the asset properties and richtext of the content version.
+// Every asset must exist in the workspace.
+func usedAssets(ctx context.Context, repo asset.AssetRepository, cd contentdefinition.ContentDefinition, data content.ContentData, ws workspace.Workspace) ([]uuid.UUID, error) {
+
+	ids := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool)
+
+	add := func(value string) error {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return fmt.Errorf("%s: %s", content.ErrMissingAsset, value)
+		}
+
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		return nil
+	}
+
+	for propName, pd := range cd.Propertydefinitions {
+		if pd.Type != contentdefinition.PropertyTypeAsset && pd.Type != contentdefinition.PropertyTypeRichText {
+			continue
+		}
+
+		languages := ws.Languages[:1]
+		if pd.Localized {
+			languages = ws.Languages
+		}
+
+		for _, l := range languages {
+			value := getPropertyValue(data, propName, l)
+			if value == nil || value == "" {
+				continue
+			}
+
+			var values []string
+			if pd.Type == contentdefinition.PropertyTypeRichText {
+				doc, err := richtext.Parse(value)
+				if err != nil {
+					return nil, err
+				}
+				values = doc.Embeds(richtext.EmbedAsset)
+			} else if s, ok := value.(string); ok {
+				values = append(values, s)
+			} else {
+				return nil, fmt.Errorf("%s: %v", content.ErrMissingAsset, value)
+			}
+
+			for _, v := range values {
+				if err := add(v); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	items, err := repo.ListAssetsByIDs(ctx, ids, ws.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[uuid.UUID]bool, len(items))
+	for _, item := range items {
+		found[item.ID] = true
+	}
+
+	for _, id := range ids {
+		if !found[id] {
+			return nil, fmt.Errorf("%s: %s", content.ErrMissingAsset, id)
+		}
+	}
+
+	return ids, nil
+}
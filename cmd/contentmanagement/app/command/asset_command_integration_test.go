@@ -0,0 +1,199 @@
+//go:build integration
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/crikke/cms/pkg/asset"
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_Assets(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE", "en-US"},
+	})
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	factory := contentdefinition.ContentDefinitionFactory{}
+
+	article, err := factory.NewContentDefinition("article", "")
+	assert.NoError(t, err)
+	assert.NoError(t, factory.NewPropertyDefinition(&article, "image", contentdefinition.PropertyTypeAsset, "", false))
+	articleId, err := cdRepo.CreateContentDefinition(context.Background(), &article, wsId)
+	assert.NoError(t, err)
+
+	storage := asset.NewFileSystemStorage(t.TempDir())
+	assetRepo := asset.NewAssetRepository(c)
+	contentRepo := content.NewContentRepository(c)
+
+	upload := UploadAssetHandler{
+		Repo:                assetRepo,
+		Storage:             storage,
+		WorkspaceRepository: wsRepo,
+		Factory:             asset.AssetFactory{},
+	}
+	update := UpdateAssetHandler{
+		Repo:                assetRepo,
+		WorkspaceRepository: wsRepo,
+	}
+	cache := imaging.NewCache(t.TempDir())
+	remove := DeleteAssetHandler{
+		Repo:              assetRepo,
+		Storage:           storage,
+		Cache:             cache,
+		ContentRepository: contentRepo,
+	}
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            search.NewSearchRepository(c),
+		UnitOfWork:                  db.NewUnitOfWork(c),
+	}
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		AssetRepository:             assetRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            search.NewSearchRepository(c),
+		UnitOfWork:                  db.NewUnitOfWork(c),
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 4, 3))))
+	file := buf.Bytes()
+
+	id, err := upload.Handle(context.Background(), UploadAsset{
+		WorkspaceId: wsId,
+		Filename:    "bike.png",
+		File:        bytes.NewReader(file),
+		Alt:         map[string]string{"sv-SE": "En cykel"},
+	})
+	assert.NoError(t, err)
+
+	t.Run("upload stores file and metadata", func(t *testing.T) {
+		a, err := assetRepo.GetAsset(context.Background(), id, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, "image/png", a.MimeType)
+		assert.Equal(t, int64(len(file)), a.Size)
+		assert.Equal(t, 4, a.Width)
+		assert.Equal(t, 3, a.Height)
+
+		f, err := storage.Open(context.Background(), wsId, a.Key())
+		assert.NoError(t, err)
+		defer f.Close()
+
+		stored, err := io.ReadAll(f)
+		assert.NoError(t, err)
+		assert.Equal(t, file, stored)
+
+		items, _, err := assetRepo.ListAssets(context.Background(), "image", db.Page{}, wsId)
+		assert.NoError(t, err)
+		assert.Len(t, items, 1)
+
+		items, _, err = assetRepo.ListAssets(context.Background(), "application/pdf", db.Page{}, wsId)
+		assert.NoError(t, err)
+		assert.Len(t, items, 0)
+	})
+
+	t.Run("alt text must be in a language of the workspace", func(t *testing.T) {
+		err := update.Handle(context.Background(), UpdateAsset{ID: id, WorkspaceId: wsId, Alt: map[string]string{"de-DE": "Ein Fahrrad"}})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), asset.ErrInvalidLanguage))
+		}
+
+		alt := map[string]string{"sv-SE": "En cykel", "en-US": "A bike"}
+		assert.NoError(t, update.Handle(context.Background(), UpdateAsset{ID: id, WorkspaceId: wsId, Alt: alt}))
+
+		a, err := assetRepo.GetAsset(context.Background(), id, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, alt, a.Alt)
+		assert.Equal(t, "bike.png", a.Filename)
+	})
+
+	t.Run("focal point must be within the image", func(t *testing.T) {
+		err := update.Handle(context.Background(), UpdateAsset{ID: id, WorkspaceId: wsId, Focal: &imaging.Point{X: 1.5, Y: 0.5}})
+		assert.EqualError(t, err, imaging.ErrInvalidFocal)
+
+		focal := &imaging.Point{X: 0.25, Y: 0.75}
+		assert.NoError(t, update.Handle(context.Background(), UpdateAsset{ID: id, WorkspaceId: wsId, Focal: focal}))
+
+		a, err := assetRepo.GetAsset(context.Background(), id, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, focal, a.Focal)
+	})
+
+	newArticle := func(image string) uuid.UUID {
+		contentId, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: articleId, WorkspaceId: wsId})
+		assert.NoError(t, err)
+
+		err = contentRepo.UpdateContentData(context.Background(), contentId, 0, wsId, func(ctx context.Context, data *content.ContentData) (*content.ContentData, error) {
+			f := data.Properties["sv-SE"]["image"]
+			f.Value = image
+			data.Properties["sv-SE"]["image"] = f
+			return data, nil
+		})
+		assert.NoError(t, err)
+		return contentId
+	}
+
+	t.Run("missing asset is not published", func(t *testing.T) {
+		contentId := newArticle(uuid.NewString())
+
+		err := publish.Handle(context.Background(), PublishContent{ContentID: contentId, WorkspaceId: wsId})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), content.ErrMissingAsset))
+		}
+	})
+
+	t.Run("used asset is not deleted unless forced", func(t *testing.T) {
+		contentId := newArticle(id.String())
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: contentId, WorkspaceId: wsId}))
+
+		err := remove.Handle(context.Background(), DeleteAsset{ID: id, WorkspaceId: wsId})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), asset.ErrAssetReferenced))
+		}
+
+		// an image rendered by the content delivery API
+		assert.NoError(t, cache.Put(wsId, id, "rendered.png", func(w io.Writer) error {
+			_, err := w.Write(file)
+			return err
+		}))
+
+		assert.NoError(t, remove.Handle(context.Background(), DeleteAsset{ID: id, WorkspaceId: wsId, Force: true}))
+
+		_, err = assetRepo.GetAsset(context.Background(), id, wsId)
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+		_, err = storage.Open(context.Background(), wsId, id.String())
+		assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+		_, err = cache.Open(wsId, id, "rendered.png")
+		assert.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+}
@@ -2,10 +2,18 @@ package command
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/crikke/cms/pkg/asset"
 	"github.com/crikke/cms/pkg/content"
 	"github.com/crikke/cms/pkg/contentdefinition"
 	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/richtext"
+	"github.com/crikke/cms/pkg/search"
 	"github.com/crikke/cms/pkg/workspace"
 	"github.com/google/uuid"
 )
@@ -13,15 +21,21 @@ import (
 type CreateContent struct {
 	ContentDefinitionId uuid.UUID
 	WorkspaceId         uuid.UUID
+	// ParentID is empty for content at the root
+	ParentID uuid.UUID
 }
 
 type CreateContentHandler struct {
 	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
 	ContentRepository           content.ContentManagementRepository
 	WorkspaceRepository         workspace.WorkspaceRepository
+	SearchRepository            search.SearchRepository
 	Factory                     content.ContentFactory
+	UnitOfWork                  db.UnitOfWork
 }
 
+// The sort order of new content is the number of its siblings, so reading the siblings and creating the content
+// is done in the same unit of work.
 func (h CreateContentHandler) Handle(ctx context.Context, cmd CreateContent) (uuid.UUID, error) {
 
 	cd, err := h.ContentDefinitionRepository.GetContentDefinition(ctx, cmd.ContentDefinitionId, cmd.WorkspaceId)
@@ -35,7 +49,38 @@ func (h CreateContentHandler) Handle(ctx context.Context, cmd CreateContent) (uu
 
 	c := h.Factory.NewContent(cd, ws.Languages[0])
 
-	return h.ContentRepository.CreateContent(ctx, c, cmd.WorkspaceId)
+	var id uuid.UUID
+	err = h.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+
+		// make sure the parent exists
+		if cmd.ParentID != (uuid.UUID{}) {
+			if _, err := h.ContentRepository.GetAncestors(ctx, cmd.ParentID, cmd.WorkspaceId); err != nil {
+				return err
+			}
+		}
+
+		if err := h.ContentRepository.LockChildren(ctx, cmd.ParentID, cmd.WorkspaceId); err != nil {
+			return err
+		}
+
+		siblings, err := h.ContentRepository.GetChildren(ctx, cmd.ParentID, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		// new content is put last among its siblings
+		c.ParentID = cmd.ParentID
+		c.SortOrder = len(siblings)
+
+		id, err = h.ContentRepository.CreateContent(ctx, c, cmd.WorkspaceId)
+		return err
+	})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	c.Data.ContentID = id
+	return id, h.SearchRepository.Index(ctx, c.Data, search.Latest, ws.Languages, cmd.WorkspaceId)
 }
 
 type UpdateContentFields struct {
@@ -45,22 +90,57 @@ type UpdateContentFields struct {
 
 	Fields      map[string]interface{}
 	WorkspaceId uuid.UUID
+	// Revision is the expected revision of the content version, nil skips the check.
+	Revision *int
 }
 
 type UpdateContentFieldsHandler struct {
 	ContentRepository           content.ContentManagementRepository
 	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
+	WorkspaceRepository         workspace.WorkspaceRepository
+	SearchRepository            search.SearchRepository
 	Factory                     content.ContentFactory
+	UnitOfWork                  db.UnitOfWork
 }
 
+// the version number of a new draft is based on existing versions, so reading the versions and
+// writing the draft is done in the same unit of work.
 func (h UpdateContentFieldsHandler) Handle(ctx context.Context, cmd UpdateContentFields) error {
 
-	return h.ContentRepository.UpdateContentData(ctx, cmd.ContentID, cmd.Version, cmd.WorkspaceId, func(ctx context.Context, c *content.ContentData) (*content.ContentData, error) {
+	return h.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		return h.update(ctx, cmd)
+	})
+}
+
+func (h UpdateContentFieldsHandler) update(ctx context.Context, cmd UpdateContentFields) error {
+
+	existing, err := h.ContentRepository.GetContent(ctx, cmd.ContentID, cmd.Version, cmd.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	// values are converted by the definitions of their properties
+	cd, err := h.ContentDefinitionRepository.GetContentDefinition(ctx, existing.ContentDefinitionID, cmd.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	ws, err := h.WorkspaceRepository.Get(ctx, cmd.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	var updated content.ContentData
+	err = h.ContentRepository.UpdateContentData(ctx, cmd.ContentID, cmd.Version, cmd.WorkspaceId, func(ctx context.Context, c *content.ContentData) (*content.ContentData, error) {
 
 		// if this version is a draft, update it directly.
-		// Otherwise create a new version based on this version.
+		// Otherwise create a new version based on this version, which does not share its properties.
+
+		if err := db.MatchRevision(cmd.Revision, c.Revision); err != nil {
+			return nil, err
+		}
 
-		contentData := *c
+		contentData := c
 
 		if c.Status != content.Draft {
 			versions, err := h.ContentRepository.ListContentVersions(ctx, cmd.ContentID, cmd.WorkspaceId)
@@ -68,36 +148,157 @@ func (h UpdateContentFieldsHandler) Handle(ctx context.Context, cmd UpdateConten
 				return nil, err
 			}
 
-			contentData.Version = len(versions)
-			contentData.Status = content.Draft
+			existing.Data = *c
+			contentData, err = h.Factory.NewContentVersion(existing, cd, len(versions), ws.Languages[0])
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		for f, v := range cmd.Fields {
-			err := h.Factory.SetField(c, cmd.Language, f, v)
+		// every field is set before failing, so all values that cannot be set are reported at once
+		names := make([]string, 0, len(cmd.Fields))
+		for f := range cmd.Fields {
+			names = append(names, f)
+		}
+		sort.Strings(names)
+
+		report := content.NewValidationReport()
+		for _, f := range names {
+			err := h.Factory.SetField(contentData, cmd.Language, f, cmd.Fields[f], cd)
+
+			var fieldErr content.FieldError
+			if errors.As(err, &fieldErr) {
+				report.Add(fieldErr.Failure())
+				continue
+			}
+
 			if err != nil {
 				return nil, err
 			}
 		}
 
-		return &contentData, nil
+		if err := report.Err(); err != nil {
+			return nil, err
+		}
+
+		updated = *contentData
+		return contentData, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.SearchRepository.Index(ctx, updated, search.Latest, ws.Languages, cmd.WorkspaceId)
+}
+
+type RestoreContentVersion struct {
+	ContentID   uuid.UUID
+	Version     int
+	WorkspaceId uuid.UUID
+	// Publish publishes the restored version in the same unit of work
+	Publish bool
+}
+
+type RestoreContentVersionHandler struct {
+	ContentRepository           content.ContentManagementRepository
+	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
+	WorkspaceRepository         workspace.WorkspaceRepository
+	SearchRepository            search.SearchRepository
+	Factory                     content.ContentFactory
+	UnitOfWork                  db.UnitOfWork
+	PublishContent              PublishContentHandler
+}
+
+// Handle creates a new draft from a previous version and returns the new version number.
+// The draft is reconciled against the current contentdefinition, so properties that have been
+// renamed keep their values and properties that have been deleted are dropped.
+func (h RestoreContentVersionHandler) Handle(ctx context.Context, cmd RestoreContentVersion) (int, error) {
+
+	version := 0
+	err := h.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+
+		c, err := h.ContentRepository.GetContent(ctx, cmd.ContentID, cmd.Version, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		cd, err := h.ContentDefinitionRepository.GetContentDefinition(ctx, c.ContentDefinitionID, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		ws, err := h.WorkspaceRepository.Get(ctx, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		versions, err := h.ContentRepository.ListContentVersions(ctx, cmd.ContentID, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+		version = len(versions)
+
+		var restored *content.ContentData
+		err = h.ContentRepository.UpdateContentData(ctx, cmd.ContentID, cmd.Version, cmd.WorkspaceId, func(ctx context.Context, _ *content.ContentData) (*content.ContentData, error) {
+			restored, err = h.Factory.NewContentVersion(c, cd, version, ws.Languages[0])
+			return restored, err
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := h.SearchRepository.Index(ctx, *restored, search.Latest, ws.Languages, cmd.WorkspaceId); err != nil {
+			return err
+		}
+
+		if !cmd.Publish {
+			return nil
+		}
+
+		return h.PublishContent.Handle(ctx, PublishContent{
+			ContentID:   cmd.ContentID,
+			Version:     version,
+			WorkspaceId: cmd.WorkspaceId,
+		})
 	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
 }
 
 type PublishContent struct {
 	ContentID   uuid.UUID
 	Version     int
 	WorkspaceId uuid.UUID
+	// Revision is the expected revision of the content version, nil skips the check.
+	Revision *int
 }
 
 type PublishContentHandler struct {
 	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
 	ContentRepository           content.ContentManagementRepository
+	AssetRepository             asset.AssetRepository
 	WorkspaceRepository         workspace.WorkspaceRepository
+	SearchRepository            search.SearchRepository
+	UnitOfWork                  db.UnitOfWork
 }
 
+// Publishing touches the content and two content versions, it is done in a single unit of work
+// so a failure never leaves two published versions behind.
 func (h PublishContentHandler) Handle(ctx context.Context, cmd PublishContent) error {
 
-	return h.ContentRepository.UpdateContent(ctx, cmd.ContentID, cmd.WorkspaceId, func(ctx context.Context, c *content.Content) (*content.Content, error) {
+	return h.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		return h.publish(ctx, cmd)
+	})
+}
+
+func (h PublishContentHandler) publish(ctx context.Context, cmd PublishContent) error {
+
+	var published content.ContentData
+	err := h.ContentRepository.UpdateContent(ctx, cmd.ContentID, cmd.WorkspaceId, func(ctx context.Context, c *content.Content) (*content.Content, error) {
 		previousVersion := c.Data.Version
 
 		ws, err := h.WorkspaceRepository.Get(ctx, cmd.WorkspaceId)
@@ -113,47 +314,60 @@ func (h PublishContentHandler) Handle(ctx context.Context, cmd PublishContent) e
 		// set new version to status published
 		err = h.ContentRepository.UpdateContentData(ctx, cmd.ContentID, cmd.Version, cmd.WorkspaceId, func(ctx context.Context, cd *content.ContentData) (*content.ContentData, error) {
 
-			for propName, pd := range contentDefinition.Propertydefinitions {
-
-				var propvalues []interface{}
-				var validators []validator.Validator
+			if err := db.MatchRevision(cmd.Revision, cd.Revision); err != nil {
+				return nil, err
+			}
 
-				for typ, v := range pd.Validators {
-					val, err := validator.Parse(typ, v)
+			report, err := content.Validate(ctx, contentDefinition, *cd, ws.Languages)
+			if err != nil {
+				return nil, err
+			}
 
-					if err != nil {
-						return nil, err
-					}
+			failures, err := content.ValidateUnique(ctx, h.ContentRepository, contentDefinition, *c, *cd, ws)
+			if err != nil {
+				return nil, err
+			}
 
-					validators = append(validators, val)
-				}
+			report.Add(failures...)
+			if err := report.Err(); err != nil {
+				return nil, err
+			}
 
-				if pd.Localized {
+			// the values are claimed in the unit of work, so content published at the same time cannot claim the same value
+			if err := h.ContentRepository.ReleaseUniqueValues(ctx, c.ID, cmd.WorkspaceId); err != nil {
+				return nil, err
+			}
 
-					for _, l := range ws.Languages {
+			values, err := content.UniqueValues(contentDefinition, *c, *cd, ws)
+			if err != nil {
+				return nil, err
+			}
 
-						p := getPropertyValue(*cd, propName, l)
-						propvalues = append(propvalues, p)
-					}
-				} else {
-					p := getPropertyValue(*cd, propName, ws.Languages[0])
-					propvalues = append(propvalues, p)
+			if err := h.ContentRepository.ClaimUniqueValues(ctx, values, cmd.WorkspaceId); err != nil {
+				return nil, err
+			}
 
-				}
+			refs, err := references(ctx, h.ContentRepository, contentDefinition, *cd, ws)
+			if err != nil {
+				return nil, err
+			}
 
-				for _, value := range propvalues {
-					for _, v := range validators {
-						err := v.Validate(ctx, value)
+			assets, err := usedAssets(ctx, h.AssetRepository, contentDefinition, *cd, ws)
+			if err != nil {
+				return nil, err
+			}
 
-						if err != nil {
-							return nil, err
-						}
-					}
-				}
+			locs, err := locations(contentDefinition, *cd, ws)
+			if err != nil {
+				return nil, err
 			}
 
+			cd.References = refs
+			cd.Assets = assets
+			cd.Locations = locs
 			cd.Status = content.Published
 			c.Data = *cd
+			published = *cd
 			return cd, nil
 		})
 		if err != nil {
@@ -172,6 +386,20 @@ func (h PublishContentHandler) Handle(ctx context.Context, cmd PublishContent) e
 		}
 		return c, nil
 	})
+	if err != nil {
+		return err
+	}
+
+	ws, err := h.WorkspaceRepository.Get(ctx, cmd.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err := h.SearchRepository.Index(ctx, published, search.Published, ws.Languages, cmd.WorkspaceId); err != nil {
+		return err
+	}
+
+	return updateRoutes(ctx, h.ContentRepository, cmd.ContentID, ws)
 }
 
 func getPropertyValue(c content.ContentData, name, locale string) interface{} {
@@ -185,26 +413,234 @@ func getPropertyValue(c content.ContentData, name, locale string) interface{} {
 	return properties[name].Value
 }
 
+// locations returns the values of the geopoint properties of the content version, a point used in several languages is returned once.
+// Geopoints in lists and objects are included, so content is found by any of its points.
+func locations(cd contentdefinition.ContentDefinition, data content.ContentData, ws workspace.Workspace) ([]validator.GeoPoint, error) {
+
+	var points []validator.GeoPoint
+	seen := make(map[[2]float64]bool)
+
+	for propName, pd := range cd.Propertydefinitions {
+		languages := ws.Languages[:1]
+		if pd.Localized {
+			languages = ws.Languages
+		}
+
+		for _, l := range languages {
+			values, err := content.ValuesOfType(pd, getPropertyValue(data, propName, l), contentdefinition.PropertyTypeGeoPoint)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, value := range values {
+				p, err := validator.ParseGeoPoint(value)
+				if err != nil {
+					return nil, err
+				}
+
+				if key := [2]float64{p.Lng(), p.Lat()}; !seen[key] {
+					seen[key] = true
+					points = append(points, p)
+				}
+			}
+		}
+	}
+
+	return points, nil
+}
+
+// references returns the IDs of the content referenced by the reference properties of the content version.
+// Referenced content must exist in the workspace and be of a contentdefinition allowed by the property.
+func references(ctx context.Context, repo content.ContentManagementRepository, cd contentdefinition.ContentDefinition, data content.ContentData, ws workspace.Workspace) ([]uuid.UUID, error) {
+
+	// every reference is checked against the contentdefinitions allowed by its property
+	type reference struct {
+		id      uuid.UUID
+		allowed []string
+	}
+
+	refs := make([]reference, 0)
+	ids := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool)
+
+	for propName, pd := range cd.Propertydefinitions {
+		if pd.Type != contentdefinition.PropertyTypeReference && pd.Type != contentdefinition.PropertyTypeRichText {
+			continue
+		}
+
+		rule := validator.Reference{}
+		if v, ok := pd.Validators[validator.RuleReference]; ok {
+			parsed, err := validator.Parse(validator.RuleReference, v)
+			if err != nil {
+				return nil, err
+			}
+			rule = parsed.(validator.Reference)
+		}
+
+		languages := ws.Languages[:1]
+		if pd.Localized {
+			languages = ws.Languages
+		}
+
+		for _, l := range languages {
+			value := getPropertyValue(data, propName, l)
+
+			// content embedded in richtext is referenced like a reference property allowing any contentdefinition
+			if pd.Type == contentdefinition.PropertyTypeRichText {
+				doc, err := richtext.Parse(value)
+				if err != nil {
+					return nil, err
+				}
+				value = doc.Embeds(richtext.EmbedContent)
+			}
+
+			values, err := rule.IDs(value)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, id := range values {
+				refs = append(refs, reference{id: id, allowed: rule.ContentDefinitions})
+
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	items, err := repo.ListContentByIDs(ctx, ids, ws.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[uuid.UUID]content.Content, len(items))
+	for _, item := range items {
+		found[item.ID] = item
+	}
+
+	for _, ref := range refs {
+		c, ok := found[ref.id]
+		if !ok {
+			return nil, fmt.Errorf("%s: %s", content.ErrMissingReference, ref.id)
+		}
+
+		if !allowedContentDefinition(ref.allowed, c.ContentDefinitionID) {
+			return nil, fmt.Errorf("%s: %s", content.ErrReferenceNotAllowed, ref.id)
+		}
+	}
+
+	return ids, nil
+}
+
+// allowedContentDefinition returns true if allowed is empty or contains id
+func allowedContentDefinition(allowed []string, id uuid.UUID) bool {
+
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, a := range allowed {
+		if a == id.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
 type ArchiveContent struct {
 	ID          uuid.UUID
 	WorkspaceId uuid.UUID
+	// Version is the content version Revision was read from, it must be the published version.
+	Version int
+	// Revision is the expected revision of the published content version, nil skips the check.
+	Revision *int
+	// Force archives the content even if it is referenced by other published content
+	Force bool
 }
 type ArchiveContentHandler struct {
-	ContentRepository content.ContentManagementRepository
+	ContentRepository   content.ContentManagementRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+	SearchRepository    search.SearchRepository
+	UnitOfWork          db.UnitOfWork
 }
 
 func (h ArchiveContentHandler) Handle(ctx context.Context, cmd ArchiveContent) error {
 
-	return h.ContentRepository.UpdateContent(ctx, cmd.ID, cmd.WorkspaceId, func(ctx context.Context, c *content.Content) (*content.Content, error) {
+	return h.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if !cmd.Force {
+			referencing, err := h.ContentRepository.ListReferencingContent(ctx, cmd.ID, cmd.WorkspaceId)
+			if err != nil {
+				return err
+			}
 
-		err := h.ContentRepository.UpdateContentData(ctx, cmd.ID, c.Data.Version, cmd.WorkspaceId, func(ctx context.Context, cd *content.ContentData) (*content.ContentData, error) {
-			cd.Status = content.PreviouslyPublished
-			return cd, nil
+			if ids := referencingIDs(referencing, cmd.ID); len(ids) > 0 {
+				return fmt.Errorf("%s: %s", content.ErrContentReferenced, strings.Join(ids, ", "))
+			}
+		}
+
+		err := h.ContentRepository.UpdateContent(ctx, cmd.ID, cmd.WorkspaceId, func(ctx context.Context, c *content.Content) (*content.Content, error) {
+
+			// the revision of another version says nothing about the published version
+			if cmd.Revision != nil && cmd.Version != c.Data.Version {
+				return nil, fmt.Errorf("%s: version %d", content.ErrNotPublished, cmd.Version)
+			}
+
+			err := h.ContentRepository.UpdateContentData(ctx, cmd.ID, c.Data.Version, cmd.WorkspaceId, func(ctx context.Context, cd *content.ContentData) (*content.ContentData, error) {
+				if err := db.MatchRevision(cmd.Revision, cd.Revision); err != nil {
+					return nil, err
+				}
+
+				cd.Status = content.PreviouslyPublished
+				return cd, nil
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			// archived content is no longer routable
+			c.Data.Status = content.PreviouslyPublished
+			return c, nil
 		})
+		if err != nil {
+			return err
+		}
 
+		// archived content is no longer found in the content delivery API
+		if err := h.SearchRepository.Remove(ctx, cmd.ID, search.Published, cmd.WorkspaceId); err != nil {
+			return err
+		}
+
+		// and its unique values can be used by other content
+		if err := h.ContentRepository.ReleaseUniqueValues(ctx, cmd.ID, cmd.WorkspaceId); err != nil {
+			return err
+		}
+
+		ws, err := h.WorkspaceRepository.Get(ctx, cmd.WorkspaceId)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		return c, nil
+
+		return updateRoutes(ctx, h.ContentRepository, cmd.ID, ws)
 	})
 }
+
+// referencingIDs returns the IDs of the referencing content, content referencing itself is left out
+func referencingIDs(referencing []content.Content, id uuid.UUID) []string {
+
+	ids := make([]string, 0, len(referencing))
+	for _, c := range referencing {
+		if c.ID != id {
+			ids = append(ids, c.ID.String())
+		}
+	}
+
+	return ids
+}
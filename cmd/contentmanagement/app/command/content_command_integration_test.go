@@ -4,6 +4,8 @@ package command
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,9 +13,11 @@ import (
 	"github.com/crikke/cms/pkg/contentdefinition"
 	"github.com/crikke/cms/pkg/contentdefinition/validator"
 	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
 	"github.com/crikke/cms/pkg/workspace"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 var (
@@ -86,10 +90,12 @@ func Test_CreateContent(t *testing.T) {
 		WorkspaceId:         ws,
 	}
 	handler := CreateContentHandler{
+		SearchRepository:            search.NewSearchRepository(c),
 		ContentDefinitionRepository: cdRepo,
 		ContentRepository:           content.NewContentRepository(c),
 		Factory:                     content.ContentFactory{},
 		WorkspaceRepository:         wsRepo,
+		UnitOfWork:                  db.NewUnitOfWork(c),
 	}
 
 	contentId, err := handler.Handle(context.Background(), cmd)
@@ -455,9 +461,11 @@ func Test_PublishContent(t *testing.T) {
 			}
 
 			handler := PublishContentHandler{
+				SearchRepository:            search.NewSearchRepository(c),
 				ContentDefinitionRepository: cdRepo,
 				ContentRepository:           contentRepo,
 				WorkspaceRepository:         wsRepo,
+				UnitOfWork:                  db.NewUnitOfWork(c),
 			}
 
 			err = handler.Handle(context.Background(), cmd)
@@ -500,3 +508,449 @@ func Test_PublishContent(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnitOfWork_Rollback(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	ws := workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	}
+	wsId, err := wsRepo.Create(context.Background(), ws)
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	_, err = cdRepo.CreateContentDefinition(context.Background(), &emptyContentDef, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	factory := content.ContentFactory{}
+
+	existing, err := contentRepo.CreateContent(context.Background(), factory.NewContent(emptyContentDef, ws.Languages[0]), wsId)
+	assert.NoError(t, err)
+
+	var created uuid.UUID
+	err = db.NewUnitOfWork(c).Do(context.Background(), func(ctx context.Context) error {
+
+		created, err = contentRepo.CreateContent(ctx, factory.NewContent(emptyContentDef, ws.Languages[0]), wsId)
+		if err != nil {
+			return err
+		}
+
+		err = contentRepo.UpdateContentData(ctx, existing, 0, wsId, func(ctx context.Context, cd *content.ContentData) (*content.ContentData, error) {
+			cd.Status = content.Published
+			return cd, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return errors.New("fail")
+	})
+	assert.EqualError(t, err, "fail")
+
+	_, err = contentRepo.GetContent(context.Background(), created, 0, wsId)
+	assert.Error(t, err)
+
+	actual, err := contentRepo.GetContent(context.Background(), existing, 0, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, content.Draft, actual.Data.Status)
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
+
+func Test_PublishContent_PartialFailureRollsBack(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	ws := workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	}
+	wsId, err := wsRepo.Create(context.Background(), ws)
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	_, err = cdRepo.CreateContentDefinition(context.Background(), &emptyContentDef, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	factory := content.ContentFactory{}
+
+	id, err := contentRepo.CreateContent(context.Background(), factory.NewContent(emptyContentDef, ws.Languages[0]), wsId)
+	assert.NoError(t, err)
+
+	err = contentRepo.UpdateContentData(context.Background(), id, 0, wsId, func(ctx context.Context, cd *content.ContentData) (*content.ContentData, error) {
+		cd.Version = 1
+		return cd, nil
+	})
+	assert.NoError(t, err)
+
+	// remove the currently published version so publishing fails after the new version has been set to published.
+	_, err = c.Database(wsId.String()).Collection("contentversion").DeleteOne(context.Background(), bson.M{"contentId": id, "version": 0})
+	assert.NoError(t, err)
+
+	handler := PublishContentHandler{
+		SearchRepository:            search.NewSearchRepository(c),
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		UnitOfWork:                  db.NewUnitOfWork(c),
+	}
+
+	err = handler.Handle(context.Background(), PublishContent{
+		ContentID:   id,
+		Version:     1,
+		WorkspaceId: wsId,
+	})
+	assert.Error(t, err)
+
+	actual, err := contentRepo.GetContent(context.Background(), id, 1, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, content.Draft, actual.Data.Status)
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
+
+func Test_RestoreContentVersion(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	ws := workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	}
+	wsId, err := wsRepo.Create(context.Background(), ws)
+	assert.NoError(t, err)
+
+	propertyID := uuid.New()
+	cd := contentdefinition.ContentDefinition{
+		Name: "test",
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"title": {
+				ID:   propertyID,
+				Type: "text",
+			},
+		},
+	}
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	cdId, err := cdRepo.CreateContentDefinition(context.Background(), &cd, wsId)
+	assert.NoError(t, err)
+	cd.ID = cdId
+
+	contentRepo := content.NewContentRepository(c)
+	factory := content.ContentFactory{}
+
+	id, err := contentRepo.CreateContent(context.Background(), factory.NewContent(cd, ws.Languages[0]), wsId)
+	assert.NoError(t, err)
+
+	// version 0 was published with "first", version 1 is published with "second"
+	err = contentRepo.UpdateContentData(context.Background(), id, 0, wsId, func(ctx context.Context, data *content.ContentData) (*content.ContentData, error) {
+		data.Properties["sv-SE"]["title"] = content.ContentField{ID: propertyID, Type: "text", Value: "first"}
+		data.Status = content.PreviouslyPublished
+		return data, nil
+	})
+	assert.NoError(t, err)
+
+	err = contentRepo.UpdateContentData(context.Background(), id, 0, wsId, func(ctx context.Context, data *content.ContentData) (*content.ContentData, error) {
+		data.Version = 1
+		data.Properties["sv-SE"]["title"] = content.ContentField{ID: propertyID, Type: "text", Value: "second"}
+		data.Status = content.Published
+		return data, nil
+	})
+	assert.NoError(t, err)
+
+	err = contentRepo.UpdateContent(context.Background(), id, wsId, func(ctx context.Context, c *content.Content) (*content.Content, error) {
+		c.Data.Version = 1
+		return c, nil
+	})
+	assert.NoError(t, err)
+
+	// the property is renamed after version 0 was created
+	err = cdRepo.UpdateContentDefinition(context.Background(), cdId, wsId, func(ctx context.Context, cd *contentdefinition.ContentDefinition) (*contentdefinition.ContentDefinition, error) {
+		cd.Propertydefinitions["heading"] = cd.Propertydefinitions["title"]
+		delete(cd.Propertydefinitions, "title")
+		return cd, nil
+	})
+	assert.NoError(t, err)
+
+	uow := db.NewUnitOfWork(c)
+	handler := RestoreContentVersionHandler{
+		SearchRepository:            search.NewSearchRepository(c),
+		ContentRepository:           contentRepo,
+		ContentDefinitionRepository: cdRepo,
+		WorkspaceRepository:         wsRepo,
+		Factory:                     factory,
+		UnitOfWork:                  uow,
+		PublishContent: PublishContentHandler{
+			SearchRepository:            search.NewSearchRepository(c),
+			ContentDefinitionRepository: cdRepo,
+			ContentRepository:           contentRepo,
+			WorkspaceRepository:         wsRepo,
+			UnitOfWork:                  uow,
+		},
+	}
+
+	version, err := handler.Handle(context.Background(), RestoreContentVersion{
+		ContentID:   id,
+		Version:     0,
+		WorkspaceId: wsId,
+		Publish:     true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	restored, err := contentRepo.GetContent(context.Background(), id, 2, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, content.Published, restored.Data.Status)
+	assert.Equal(t, "first", restored.Data.Properties["sv-SE"]["heading"].Value)
+	assert.NotContains(t, restored.Data.Properties["sv-SE"], "title")
+
+	previous, err := contentRepo.GetContent(context.Background(), id, 1, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, content.PreviouslyPublished, previous.Data.Status)
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
+
+func Test_UpdateContentFieldTypes(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	factory := contentdefinition.ContentDefinitionFactory{}
+
+	product, err := factory.NewContentDefinition("product", "")
+	assert.NoError(t, err)
+	assert.NoError(t, factory.NewPropertyDefinition(&product, "price", contentdefinition.PropertyTypeNumber, "", false))
+	assert.NoError(t, factory.NewPropertyDefinition(&product, "instock", contentdefinition.PropertyTypeBool, "", false))
+	productId, err := cdRepo.CreateContentDefinition(context.Background(), &product, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	searchRepo := search.NewSearchRepository(c)
+
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  db.NewUnitOfWork(c),
+	}
+	update := UpdateContentFieldsHandler{
+		ContentRepository:           contentRepo,
+		ContentDefinitionRepository: cdRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		Factory:                     content.ContentFactory{},
+		UnitOfWork:                  db.NewUnitOfWork(c),
+	}
+
+	id, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: productId, WorkspaceId: wsId})
+	assert.NoError(t, err)
+
+	t.Run("values are converted to the type of the property", func(t *testing.T) {
+		assert.NoError(t, update.Handle(context.Background(), UpdateContentFields{
+			ContentID:   id,
+			Language:    "sv-SE",
+			WorkspaceId: wsId,
+			Fields:      map[string]interface{}{"price": "42", "instock": "true"},
+		}))
+
+		actual, err := contentRepo.GetContent(context.Background(), id, 0, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, 42.0, actual.Data.Properties["sv-SE"]["price"].Value)
+		assert.Equal(t, true, actual.Data.Properties["sv-SE"]["instock"].Value)
+	})
+
+	t.Run("every invalid field is reported and nothing is saved", func(t *testing.T) {
+		err := update.Handle(context.Background(), UpdateContentFields{
+			ContentID:   id,
+			Language:    "sv-SE",
+			WorkspaceId: wsId,
+			Fields:      map[string]interface{}{"price": "cheap", "instock": "yes", "color": "red", contentdefinition.PROPFIELD_NAME: "chair"},
+		})
+		assertFailure(t, err, "price", validator.CodeInvalidType)
+		assertFailure(t, err, "instock", validator.CodeInvalidType)
+		assertFailure(t, err, "color", validator.CodeUnknownProperty)
+
+		actual, err := contentRepo.GetContent(context.Background(), id, 0, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, 42.0, actual.Data.Properties["sv-SE"]["price"].Value)
+		assert.Nil(t, actual.Data.Properties["sv-SE"][contentdefinition.PROPFIELD_NAME].Value)
+	})
+
+	t.Run("editing a published version creates a draft", func(t *testing.T) {
+		err := contentRepo.UpdateContentData(context.Background(), id, 0, wsId, func(ctx context.Context, data *content.ContentData) (*content.ContentData, error) {
+			data.Status = content.Published
+			return data, nil
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, update.Handle(context.Background(), UpdateContentFields{
+			ContentID:   id,
+			Version:     0,
+			Language:    "sv-SE",
+			WorkspaceId: wsId,
+			Fields:      map[string]interface{}{"price": 10},
+		}))
+
+		draft, err := contentRepo.GetContent(context.Background(), id, 1, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, content.Draft, draft.Data.Status)
+		assert.Equal(t, 10.0, draft.Data.Properties["sv-SE"]["price"].Value)
+		assert.Equal(t, true, draft.Data.Properties["sv-SE"]["instock"].Value)
+
+		// the published version is not changed
+		published, err := contentRepo.GetContent(context.Background(), id, 0, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, content.Published, published.Data.Status)
+		assert.Equal(t, 42.0, published.Data.Properties["sv-SE"]["price"].Value)
+	})
+}
+
+func Test_NewVersionExists(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{Name: "test", Languages: []string{"sv-SE"}})
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	assert.NoError(t, contentRepo.EnsureIndexes(context.Background(), wsId))
+
+	id, err := contentRepo.CreateContent(context.Background(), content.ContentFactory{}.NewContent(emptyContentDef, "sv-SE"), wsId)
+	assert.NoError(t, err)
+
+	// two writers drafting from version 0 both create version 1
+	newVersion := func(ctx context.Context, data *content.ContentData) (*content.ContentData, error) {
+		data.Version = 1
+		return data, nil
+	}
+
+	assert.NoError(t, contentRepo.UpdateContentData(context.Background(), id, 0, wsId, newVersion))
+
+	err = contentRepo.UpdateContentData(context.Background(), id, 0, wsId, newVersion)
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), content.ErrVersionExists))
+
+	versions, err := contentRepo.ListContentVersions(context.Background(), id, wsId)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
+
+func Test_ArchiveContentRevision(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{Name: "test", Languages: []string{"sv-SE"}})
+	assert.NoError(t, err)
+
+	cd, err := contentdefinition.NewContentDefinition("page", "")
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	cdId, err := cdRepo.CreateContentDefinition(context.Background(), &cd, wsId)
+	assert.NoError(t, err)
+	cd.ID = cdId
+
+	contentRepo := content.NewContentRepository(c)
+	searchRepo := search.NewSearchRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	id, err := contentRepo.CreateContent(context.Background(), content.ContentFactory{}.NewContent(cd, "sv-SE"), wsId)
+	assert.NoError(t, err)
+
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, Version: 0, WorkspaceId: wsId}))
+
+	// version 1 is a draft of the published version 0
+	err = contentRepo.UpdateContentData(context.Background(), id, 0, wsId, func(ctx context.Context, data *content.ContentData) (*content.ContentData, error) {
+		data.Version = 1
+		data.Status = content.Draft
+		return data, nil
+	})
+	assert.NoError(t, err)
+
+	published, err := contentRepo.GetContent(context.Background(), id, 0, wsId)
+	assert.NoError(t, err)
+	draft, err := contentRepo.GetContent(context.Background(), id, 1, wsId)
+	assert.NoError(t, err)
+
+	archive := ArchiveContentHandler{
+		ContentRepository:   contentRepo,
+		WorkspaceRepository: wsRepo,
+		SearchRepository:    searchRepo,
+		UnitOfWork:          uow,
+	}
+
+	// the ETag of the draft does not guard the published version
+	err = archive.Handle(context.Background(), ArchiveContent{ID: id, WorkspaceId: wsId, Version: 1, Revision: &draft.Data.Revision})
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), content.ErrNotPublished))
+
+	stale := published.Data.Revision - 1
+	err = archive.Handle(context.Background(), ArchiveContent{ID: id, WorkspaceId: wsId, Version: 0, Revision: &stale})
+	assert.ErrorAs(t, err, &db.RevisionMismatchError{})
+
+	assert.NoError(t, archive.Handle(context.Background(), ArchiveContent{ID: id, WorkspaceId: wsId, Version: 0, Revision: &published.Data.Revision}))
+
+	archived, err := contentRepo.GetContent(context.Background(), id, 0, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, content.PreviouslyPublished, archived.Data.Status)
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
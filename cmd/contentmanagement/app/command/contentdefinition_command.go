@@ -3,8 +3,12 @@ package command
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/crikke/cms/pkg/content"
 	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
 	"github.com/crikke/cms/pkg/workspace"
 	"github.com/google/uuid"
 )
@@ -13,11 +17,14 @@ type CreateContentDefinition struct {
 	Name        string
 	Description string
 	WorkspaceId uuid.UUID
+	// Routable adds a url segment to the contentdefinition, so its content has a path in the delivery API
+	Routable bool
 }
 
 type CreateContentDefinitionHandler struct {
-	WorkspaceRepo workspace.WorkspaceRepository
-	Repo          contentdefinition.ContentDefinitionRepository
+	WorkspaceRepo            workspace.WorkspaceRepository
+	Repo                     contentdefinition.ContentDefinitionRepository
+	ContentDefinitionFactory contentdefinition.ContentDefinitionFactory
 }
 
 func (c CreateContentDefinitionHandler) Handle(ctx context.Context, cmd CreateContentDefinition) (id uuid.UUID, err error) {
@@ -27,7 +34,12 @@ func (c CreateContentDefinitionHandler) Handle(ctx context.Context, cmd CreateCo
 		fmt.Println("CreateContentDefinitionHandler", cmd, err)
 	}()
 
-	cd, err := contentdefinition.NewContentDefinition(cmd.Name, cmd.Description)
+	var cd contentdefinition.ContentDefinition
+	if cmd.Routable {
+		cd, err = c.ContentDefinitionFactory.NewRoutableContentDefinition(cmd.Name, cmd.Description)
+	} else {
+		cd, err = c.ContentDefinitionFactory.NewContentDefinition(cmd.Name, cmd.Description)
+	}
 	if err != nil {
 		return
 	}
@@ -43,11 +55,18 @@ type UpdateContentDefinition struct {
 	Description         string    `bson:"omitempty"`
 	WorkspaceId         uuid.UUID
 	PropertyDefinitions map[string]contentdefinition.PropertyDefinition
+	// Rules replace the rules of the contentdefinition, nil keeps the rules
+	Rules *[]contentdefinition.Rule
+	// Revision is the expected revision of the contentdefinition, nil skips the check.
+	Revision *int
+	// Force removes enum options even if they are used by published content
+	Force bool
 }
 
 type UpdateContentDefinitionHandler struct {
 	WorkspaceRepo            workspace.WorkspaceRepository
 	Repo                     contentdefinition.ContentDefinitionRepository
+	ContentRepository        content.ContentManagementRepository
 	ContentDefinitionFactory contentdefinition.ContentDefinitionFactory
 
 	// Properties are stored by their name, since name is unique
@@ -63,6 +82,10 @@ func (c UpdateContentDefinitionHandler) Handle(ctx context.Context, cmd UpdateCo
 
 	err = c.Repo.UpdateContentDefinition(ctx, cmd.ContentDefinitionID, cmd.WorkspaceId, func(ctx context.Context, cd *contentdefinition.ContentDefinition) (*contentdefinition.ContentDefinition, error) {
 
+		if err := db.MatchRevision(cmd.Revision, cd.Revision); err != nil {
+			return nil, err
+		}
+
 		if cmd.Name != "" {
 			cd.Name = cmd.Name
 		}
@@ -71,33 +94,188 @@ func (c UpdateContentDefinitionHandler) Handle(ctx context.Context, cmd UpdateCo
 			cd.Description = cmd.Description
 		}
 
+		// validators are updated in place, so the options are read before the update
+		previous, err := enumProperties(*cd)
+		if err != nil {
+			return nil, err
+		}
+
 		if err = c.ContentDefinitionFactory.UpdatePropertyDefinitions(cd, cmd.PropertyDefinitions); err != nil {
 			return nil, err
 		}
 
+		if cmd.Rules != nil {
+			if err := c.ContentDefinitionFactory.UpdateRules(cd, *cmd.Rules); err != nil {
+				return nil, err
+			}
+		}
+
+		// the rules are checked against the updated properties, so a property used by a rule cannot be removed or change type
+		if _, err := cd.CompileRules(); err != nil {
+			return nil, err
+		}
+
+		if !cmd.Force {
+			if err := c.removedOptionsInUse(ctx, previous, *cd, cmd.WorkspaceId); err != nil {
+				return nil, err
+			}
+		}
+
 		return cd, nil
 	})
+	if err != nil {
+		return
+	}
+
+	err = c.claimUniqueValues(ctx, cmd.ContentDefinitionID, cmd.WorkspaceId)
 	return
 }
 
+// claimUniqueValues claims the values of the unique properties of the published content of the contentdefinition,
+// so a property that becomes unique is checked against content that was published before. Values that are already used
+// by several content are claimed by one of them, the others have to change the value to be published again.
+func (c UpdateContentDefinitionHandler) claimUniqueValues(ctx context.Context, contentDefinitionID, workspaceID uuid.UUID) error {
+
+	cd, err := c.Repo.GetContentDefinition(ctx, contentDefinitionID, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	ws, err := c.WorkspaceRepo.Get(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	published, err := c.ContentRepository.ListPublishedContentByDefinition(ctx, contentDefinitionID, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range published {
+		values, err := content.UniqueValues(cd, item, item.Data, ws)
+		if err != nil {
+			return err
+		}
+
+		// values claimed by the content itself or by other content are left as they are
+		err = c.ContentRepository.ClaimUniqueValues(ctx, values, workspaceID)
+		if err != nil && !strings.HasPrefix(err.Error(), content.ErrNotUnique) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enumProperty is the name and options of an enum property
+type enumProperty struct {
+	name string
+	rule validator.Enum
+}
+
+// enumProperties returns the enum properties of cd by ID
+func enumProperties(cd contentdefinition.ContentDefinition) (map[uuid.UUID]enumProperty, error) {
+
+	properties := make(map[uuid.UUID]enumProperty)
+	for name, pd := range cd.Propertydefinitions {
+		if pd.Type != contentdefinition.PropertyTypeEnum {
+			continue
+		}
+
+		rule := validator.Enum{}
+		if v, ok := pd.Validators[validator.RuleEnum]; ok {
+			parsed, err := validator.Parse(validator.RuleEnum, v)
+			if err != nil {
+				return nil, err
+			}
+			rule = parsed.(validator.Enum)
+		}
+
+		properties[pd.ID] = enumProperty{name: name, rule: rule}
+	}
+
+	return properties, nil
+}
+
+// removedOptionsInUse returns an error if an option that has been removed from an enum property is used by published content.
+// Content can keep a removed option until it is published again, the delivery API returns the value of the option as its label.
+func (c UpdateContentDefinitionHandler) removedOptionsInUse(ctx context.Context, previous map[uuid.UUID]enumProperty, cd contentdefinition.ContentDefinition, workspaceID uuid.UUID) error {
+
+	current, err := enumProperties(cd)
+	if err != nil {
+		return err
+	}
+
+	var ws *workspace.Workspace
+	for id, prev := range previous {
+		// values of deleted properties are dropped when content is published again
+		updated, ok := current[id]
+		if !ok {
+			continue
+		}
+
+		removed := prev.rule.Removed(updated.rule)
+		if len(removed) == 0 {
+			continue
+		}
+
+		if ws == nil {
+			w, err := c.WorkspaceRepo.Get(ctx, workspaceID)
+			if err != nil {
+				return err
+			}
+			ws = &w
+		}
+
+		// the values are stored under the name the property had when the content was saved
+		using, err := c.ContentRepository.ListContentWithValues(ctx, cd.ID, prev.name, ws.Languages, removed, workspaceID)
+		if err != nil {
+			return err
+		}
+
+		if len(using) > 0 {
+			return fmt.Errorf("%s: %s: %s", contentdefinition.ErrEnumOptionInUse, prev.name, strings.Join(referencingIDs(using, uuid.UUID{}), ", "))
+		}
+	}
+
+	return nil
+}
+
 type DeleteContentDefinition struct {
 	ID          uuid.UUID
 	WorkspaceId uuid.UUID
+	// Revision is the expected revision of the contentdefinition, nil skips the check.
+	Revision *int
 }
 
 type DeleteContentDefinitionHandler struct {
+	Repo              contentdefinition.ContentDefinitionRepository
+	ContentRepository content.ContentManagementRepository
+	UnitOfWork        db.UnitOfWork
 }
 
-func (c DeleteContentDefinitionHandler) Handle(ctx context.Context, cmd DeleteContentDefinition) (err error) {
+// Content is not deleted with its contentdefinition, so a contentdefinition that is used by content cannot be deleted.
+// The revision is checked in the same unit of work as the contentdefinition is deleted.
+func (c DeleteContentDefinitionHandler) Handle(ctx context.Context, cmd DeleteContentDefinition) error {
 
-	defer func() {
-		// todo better logging
-		fmt.Println("DeleteContentDefinitionHandler", cmd, err)
-	}()
+	return c.UnitOfWork.Do(ctx, func(ctx context.Context) error {
 
-	if err != nil {
-		return
-	}
+		return c.Repo.DeleteContentDefinition(ctx, cmd.ID, cmd.WorkspaceId, func(ctx context.Context, cd *contentdefinition.ContentDefinition) error {
 
-	return nil
+			if err := db.MatchRevision(cmd.Revision, cd.Revision); err != nil {
+				return err
+			}
+
+			n, err := c.ContentRepository.CountContent(ctx, cd.ID, cmd.WorkspaceId)
+			if err != nil {
+				return err
+			}
+
+			if n > 0 {
+				return fmt.Errorf("%s: %d items", contentdefinition.ErrContentDefinitionUsed, n)
+			}
+
+			return nil
+		})
+	})
 }
@@ -4,12 +4,15 @@ package command
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/crikke/cms/pkg/content"
 	"github.com/crikke/cms/pkg/contentdefinition"
 	"github.com/crikke/cms/pkg/db"
 	"github.com/crikke/cms/pkg/workspace"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func Test_CreateContentDefinition(t *testing.T) {
@@ -121,3 +124,140 @@ func Test_UpdateContentDefinition(t *testing.T) {
 		}
 	})
 }
+
+func Test_UpdateContentDefinition_Revision(t *testing.T) {
+
+	client, err := db.Connect(context.TODO(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(client)
+	ws, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name: "test",
+	})
+	assert.NoError(t, err)
+
+	repo := contentdefinition.NewContentDefinitionRepository(client)
+	id, err := repo.CreateContentDefinition(context.Background(), &contentdefinition.ContentDefinition{Name: "old"}, ws)
+	assert.NoError(t, err)
+
+	handler := UpdateContentDefinitionHandler{Repo: repo}
+	stale := 0
+
+	err = handler.Handle(context.TODO(), UpdateContentDefinition{
+		ContentDefinitionID: id,
+		WorkspaceId:         ws,
+		Name:                "first",
+		Revision:            &stale,
+	})
+	assert.NoError(t, err)
+
+	// second editor still has revision 0
+	err = handler.Handle(context.TODO(), UpdateContentDefinition{
+		ContentDefinitionID: id,
+		WorkspaceId:         ws,
+		Name:                "second",
+		Revision:            &stale,
+	})
+	assert.Equal(t, db.RevisionMismatchError{Current: 1}, err)
+
+	// concurrent write between read and write is detected by the repository
+	err = repo.UpdateContentDefinition(context.TODO(), id, ws, func(ctx context.Context, cd *contentdefinition.ContentDefinition) (*contentdefinition.ContentDefinition, error) {
+		err := repo.UpdateContentDefinition(ctx, id, ws, func(ctx context.Context, cd *contentdefinition.ContentDefinition) (*contentdefinition.ContentDefinition, error) {
+			return cd, nil
+		})
+		assert.NoError(t, err)
+
+		cd.Name = "lost update"
+		return cd, nil
+	})
+	assert.Equal(t, db.RevisionMismatchError{Current: 2}, err)
+
+	actual, err := repo.GetContentDefinition(context.TODO(), id, ws)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", actual.Name)
+	assert.Equal(t, 2, actual.Revision)
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
+
+func Test_DeleteContentDefinition(t *testing.T) {
+
+	client, err := db.Connect(context.TODO(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(client)
+	ws, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name: "test",
+	})
+	assert.NoError(t, err)
+
+	repo := contentdefinition.NewContentDefinitionRepository(client)
+	contentRepo := content.NewContentRepository(client)
+	handler := DeleteContentDefinitionHandler{
+		Repo:              repo,
+		ContentRepository: contentRepo,
+		UnitOfWork:        db.NewUnitOfWork(client),
+	}
+
+	id, err := repo.CreateContentDefinition(context.Background(), &contentdefinition.ContentDefinition{Name: "page"}, ws)
+	assert.NoError(t, err)
+
+	err = repo.UpdateContentDefinition(context.TODO(), id, ws, func(ctx context.Context, cd *contentdefinition.ContentDefinition) (*contentdefinition.ContentDefinition, error) {
+		return cd, nil
+	})
+	assert.NoError(t, err)
+
+	stale := 0
+	err = handler.Handle(context.TODO(), DeleteContentDefinition{ID: id, WorkspaceId: ws, Revision: &stale})
+	assert.Equal(t, db.RevisionMismatchError{Current: 1}, err)
+
+	// concurrent write between read and delete is detected by the repository
+	err = repo.DeleteContentDefinition(context.TODO(), id, ws, func(ctx context.Context, cd *contentdefinition.ContentDefinition) error {
+		return repo.UpdateContentDefinition(ctx, id, ws, func(ctx context.Context, cd *contentdefinition.ContentDefinition) (*contentdefinition.ContentDefinition, error) {
+			return cd, nil
+		})
+	})
+	assert.Equal(t, db.RevisionMismatchError{Current: 2}, err)
+
+	current := 2
+	assert.NoError(t, handler.Handle(context.TODO(), DeleteContentDefinition{ID: id, WorkspaceId: ws, Revision: &current}))
+
+	_, err = repo.GetContentDefinition(context.TODO(), id, ws)
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+	err = handler.Handle(context.TODO(), DeleteContentDefinition{ID: id, WorkspaceId: ws})
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+	t.Run("contentdefinition used by content is not deleted", func(t *testing.T) {
+		id, err := repo.CreateContentDefinition(context.Background(), &contentdefinition.ContentDefinition{Name: "product"}, ws)
+		assert.NoError(t, err)
+
+		_, err = contentRepo.CreateContent(context.Background(), content.Content{
+			ContentDefinitionID: id,
+			Data:                content.ContentData{Status: content.Archived},
+		}, ws)
+		assert.NoError(t, err)
+
+		err = handler.Handle(context.TODO(), DeleteContentDefinition{ID: id, WorkspaceId: ws})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), contentdefinition.ErrContentDefinitionUsed))
+		}
+
+		_, err = repo.GetContentDefinition(context.TODO(), id, ws)
+		assert.NoError(t, err)
+	})
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EnumOptions(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE", "en-US"},
+	})
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	factory := contentdefinition.ContentDefinitionFactory{}
+
+	product, err := factory.NewContentDefinition("product", "")
+	assert.NoError(t, err)
+	assert.NoError(t, factory.NewPropertyDefinition(&product, "color", contentdefinition.PropertyTypeEnum, "", false))
+	pd := product.Propertydefinitions["color"]
+	pd.Validators[validator.RuleEnum] = validator.Enum{
+		Options: []validator.EnumOption{
+			{Value: "red", Labels: map[string]string{"sv-SE": "Röd", "en-US": "Red"}},
+			{Value: "green", Labels: map[string]string{"sv-SE": "Grön"}},
+			{Value: "blue"},
+		},
+	}
+	product.Propertydefinitions["color"] = pd
+	productId, err := cdRepo.CreateContentDefinition(context.Background(), &product, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	searchRepo := search.NewSearchRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	update := UpdateContentFieldsHandler{
+		ContentRepository:           contentRepo,
+		ContentDefinitionRepository: cdRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		Factory:                     content.ContentFactory{},
+		UnitOfWork:                  uow,
+	}
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	updateDefinition := UpdateContentDefinitionHandler{
+		WorkspaceRepo:     wsRepo,
+		Repo:              cdRepo,
+		ContentRepository: contentRepo,
+	}
+
+	newContent := func(color interface{}) uuid.UUID {
+		id, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: productId, WorkspaceId: wsId})
+		assert.NoError(t, err)
+
+		err = update.Handle(context.Background(), UpdateContentFields{
+			ContentID:   id,
+			Language:    "sv-SE",
+			WorkspaceId: wsId,
+			Fields: map[string]interface{}{
+				contentdefinition.PROPFIELD_NAME: "product",
+				"color":                          color,
+			},
+		})
+		assert.NoError(t, err)
+		return id
+	}
+
+	// removeOptions updates the contentdefinition with the options of the color property that are not in values
+	removeOptions := func(force bool, values ...string) error {
+		cd, err := cdRepo.GetContentDefinition(context.Background(), productId, wsId)
+		assert.NoError(t, err)
+
+		rule, err := validator.Parse(validator.RuleEnum, cd.Propertydefinitions["color"].Validators[validator.RuleEnum])
+		assert.NoError(t, err)
+
+		remaining := validator.Enum{}
+		for _, o := range rule.(validator.Enum).Options {
+			if !containsValue(values, o.Value) {
+				remaining.Options = append(remaining.Options, o)
+			}
+		}
+
+		pd := cd.Propertydefinitions["color"]
+		pd.Validators = map[string]interface{}{validator.RuleEnum: remaining}
+		cd.Propertydefinitions["color"] = pd
+
+		return updateDefinition.Handle(context.Background(), UpdateContentDefinition{
+			ContentDefinitionID: productId,
+			WorkspaceId:         wsId,
+			PropertyDefinitions: cd.Propertydefinitions,
+			Force:               force,
+		})
+	}
+
+	t.Run("value must be an option", func(t *testing.T) {
+		id := newContent("yellow")
+
+		err := publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId})
+		assertFailure(t, err, "color", validator.CodeNotAnOption)
+	})
+
+	t.Run("property allows a single value", func(t *testing.T) {
+		id := newContent([]interface{}{"red", "blue"})
+
+		assertFailure(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}), "color", validator.CodeMultipleValues)
+	})
+
+	used := newContent("red")
+	assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: used, WorkspaceId: wsId}))
+
+	t.Run("unused option is removed", func(t *testing.T) {
+		assert.NoError(t, removeOptions(false, "green"))
+	})
+
+	t.Run("used option is not removed unless forced", func(t *testing.T) {
+		err := removeOptions(false, "red")
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), contentdefinition.ErrEnumOptionInUse))
+			assert.Contains(t, err.Error(), used.String())
+		}
+
+		assert.NoError(t, removeOptions(true, "red"))
+
+		// published content keeps the removed option until it is published again
+		c, err := contentRepo.GetContent(context.Background(), used, 0, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, "red", c.Data.Properties["sv-SE"]["color"].Value)
+
+		err = publish.Handle(context.Background(), PublishContent{ContentID: used, WorkspaceId: wsId})
+		assertFailure(t, err, "color", validator.CodeNotAnOption)
+	})
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
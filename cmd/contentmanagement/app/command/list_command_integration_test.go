@@ -0,0 +1,140 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ListOfObjects(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	factory := contentdefinition.ContentDefinitionFactory{}
+
+	route, err := factory.NewContentDefinition("route", "")
+	assert.NoError(t, err)
+	assert.NoError(t, factory.NewListPropertyDefinition(&route, "stops", contentdefinition.PropertyTypeObject, "", false))
+	_, err = factory.NewSubPropertyDefinition(&route, route.Propertydefinitions["stops"].ID, "departure", contentdefinition.PropertyTypeDate, "", "")
+	assert.NoError(t, err)
+
+	min := 1
+	stops := route.Propertydefinitions["stops"]
+	stops.Validators[validator.RuleItemCount] = validator.ItemCount{Min: &min}
+	departure := stops.Items.Properties["departure"]
+	departure.Validators[validator.RuleRequired] = true
+	stops.Items.Properties["departure"] = departure
+	route.Propertydefinitions["stops"] = stops
+
+	routeId, err := cdRepo.CreateContentDefinition(context.Background(), &route, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	searchRepo := search.NewSearchRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	update := UpdateContentFieldsHandler{
+		ContentRepository:           contentRepo,
+		ContentDefinitionRepository: cdRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		Factory:                     content.ContentFactory{},
+		UnitOfWork:                  uow,
+	}
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+
+	newContent := func(stops interface{}) uuid.UUID {
+		id, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: routeId, WorkspaceId: wsId})
+		assert.NoError(t, err)
+
+		err = update.Handle(context.Background(), UpdateContentFields{
+			ContentID:   id,
+			Language:    "sv-SE",
+			WorkspaceId: wsId,
+			Fields: map[string]interface{}{
+				contentdefinition.PROPFIELD_NAME: "route",
+				"stops":                          stops,
+			},
+		})
+		assert.NoError(t, err)
+		return id
+	}
+
+	t.Run("list must have items", func(t *testing.T) {
+		id := newContent(nil)
+		assertFailure(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}), "stops", validator.CodeLessThanMin)
+	})
+
+	t.Run("sub-properties are validated", func(t *testing.T) {
+		id := newContent([]interface{}{
+			map[string]interface{}{"departure": "2022-05-01"},
+			map[string]interface{}{},
+		})
+		assertFailure(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}), "stops[1].departure", validator.CodeRequired)
+	})
+
+	t.Run("valid list is published", func(t *testing.T) {
+		id := newContent([]interface{}{map[string]interface{}{"departure": "2022-05-01"}})
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}))
+
+		c, err := contentRepo.GetContent(context.Background(), id, 0, wsId)
+		assert.NoError(t, err)
+
+		items, err := content.ListItems(c.Data.Properties["sv-SE"]["stops"].Value)
+		assert.NoError(t, err)
+		if assert.Len(t, items, 1) {
+			fields, err := content.ObjectFields(items[0])
+			assert.NoError(t, err)
+			assert.Equal(t, "2022-05-01", fields["departure"].Value)
+		}
+	})
+}
+
+// assertFailure asserts that err is a ValidationError with a failure of property with code
+func assertFailure(t *testing.T, err error, property, code string) {
+
+	var verr content.ValidationError
+	if !assert.ErrorAs(t, err, &verr) {
+		return
+	}
+
+	for _, f := range verr.Report.Failures {
+		if f.Property == property && f.Code == code {
+			return
+		}
+	}
+
+	assert.Failf(t, "missing failure", "%s: %s not in %v", property, code, verr.Report.Failures)
+}
@@ -42,6 +42,10 @@ type CreatePropertyDefinition struct {
 	Name                string
 	Description         string
 	Type                string
+	// ItemType is the type of the items of a list property
+	ItemType string
+	// ParentID is the object property, or list of objects, the property is added to. Empty adds a top level property.
+	ParentID uuid.UUID
 }
 
 type CreatePropertyDefinitionHandler struct {
@@ -61,7 +65,22 @@ func (h CreatePropertyDefinitionHandler) Handle(ctx context.Context, cmd CreateP
 		cmd.ContentDefinitionID,
 		cmd.WorkspaceID,
 		func(ctx context.Context, cd *contentdefinition.ContentDefinition) (*contentdefinition.ContentDefinition, error) {
-			err := h.Factory.NewPropertyDefinition(cd, cmd.Name, cmd.Type, cmd.Description, false)
+			if cmd.ParentID != (uuid.UUID{}) {
+				sub, err := h.Factory.NewSubPropertyDefinition(cd, cmd.ParentID, cmd.Name, cmd.Type, cmd.ItemType, cmd.Description)
+				if err != nil {
+					return nil, err
+				}
+
+				id = sub
+				return cd, nil
+			}
+
+			var err error
+			if cmd.Type == contentdefinition.PropertyTypeList {
+				err = h.Factory.NewListPropertyDefinition(cd, cmd.Name, cmd.ItemType, cmd.Description, false)
+			} else {
+				err = h.Factory.NewPropertyDefinition(cd, cmd.Name, cmd.Type, cmd.Description, false)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -123,6 +142,11 @@ func (h UpdatePropertyDefinitionHandler) Handle(ctx context.Context, cmd UpdateP
 				return nil, err
 			}
 
+			// a renamed property is no longer a variable of the rules of the contentdefinition
+			if _, err := cd.CompileRules(); err != nil {
+				return nil, err
+			}
+
 			return cd, nil
 		})
 }
@@ -165,6 +189,10 @@ func (h DeletePropertyDefinitionHandler) Handle(ctx context.Context, cmd DeleteP
 			}
 
 			delete(cd.Propertydefinitions, name)
+
+			if _, err := cd.CompileRules(); err != nil {
+				return nil, err
+			}
 			return cd, nil
 		})
 }
@@ -0,0 +1,138 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_References(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	factory := contentdefinition.ContentDefinitionFactory{}
+
+	author, err := factory.NewContentDefinition("author", "")
+	assert.NoError(t, err)
+	authorId, err := cdRepo.CreateContentDefinition(context.Background(), &author, wsId)
+	assert.NoError(t, err)
+
+	other, err := factory.NewContentDefinition("other", "")
+	assert.NoError(t, err)
+	otherId, err := cdRepo.CreateContentDefinition(context.Background(), &other, wsId)
+	assert.NoError(t, err)
+
+	article, err := factory.NewContentDefinition("article", "")
+	assert.NoError(t, err)
+	assert.NoError(t, factory.NewPropertyDefinition(&article, "author", contentdefinition.PropertyTypeReference, "", false))
+	pd := article.Propertydefinitions["author"]
+	pd.Validators[validator.RuleReference] = validator.Reference{ContentDefinitions: []string{authorId.String()}}
+	article.Propertydefinitions["author"] = pd
+	articleId, err := cdRepo.CreateContentDefinition(context.Background(), &article, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	searchRepo := search.NewSearchRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	archive := ArchiveContentHandler{
+		ContentRepository:   contentRepo,
+		WorkspaceRepository: wsRepo,
+		SearchRepository:    searchRepo,
+		UnitOfWork:          uow,
+	}
+
+	newContent := func(cdId uuid.UUID, name string, fields map[string]interface{}) uuid.UUID {
+		id, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId})
+		assert.NoError(t, err)
+
+		err = contentRepo.UpdateContentData(context.Background(), id, 0, wsId, func(ctx context.Context, data *content.ContentData) (*content.ContentData, error) {
+			f := data.Properties["sv-SE"][contentdefinition.PROPFIELD_NAME]
+			f.Value = name
+			data.Properties["sv-SE"][contentdefinition.PROPFIELD_NAME] = f
+
+			for name, value := range fields {
+				f := data.Properties["sv-SE"][name]
+				f.Value = value
+				data.Properties["sv-SE"][name] = f
+			}
+			return data, nil
+		})
+		assert.NoError(t, err)
+		return id
+	}
+
+	authorContent := newContent(authorId, "author", nil)
+	otherContent := newContent(otherId, "other", nil)
+	assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: authorContent, WorkspaceId: wsId}))
+
+	t.Run("missing reference", func(t *testing.T) {
+		id := newContent(articleId, "article", map[string]interface{}{"author": uuid.NewString()})
+
+		err := publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), content.ErrMissingReference))
+		}
+	})
+
+	t.Run("contentdefinition not allowed", func(t *testing.T) {
+		id := newContent(articleId, "article", map[string]interface{}{"author": otherContent.String()})
+
+		err := publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), content.ErrReferenceNotAllowed))
+		}
+	})
+
+	t.Run("referenced content is not archived unless forced", func(t *testing.T) {
+		id := newContent(articleId, "article", map[string]interface{}{"author": authorContent.String()})
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}))
+
+		referencing, err := contentRepo.ListReferencingContent(context.Background(), authorContent, wsId)
+		assert.NoError(t, err)
+		if assert.Len(t, referencing, 1) {
+			assert.Equal(t, id, referencing[0].ID)
+		}
+
+		err = archive.Handle(context.Background(), ArchiveContent{ID: authorContent, WorkspaceId: wsId})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), content.ErrContentReferenced))
+		}
+
+		assert.NoError(t, archive.Handle(context.Background(), ArchiveContent{ID: authorContent, WorkspaceId: wsId, Force: true}))
+	})
+}
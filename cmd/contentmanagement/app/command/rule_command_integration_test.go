@@ -0,0 +1,142 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ContentDefinitionRules(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	factory := contentdefinition.ContentDefinitionFactory{}
+
+	event, err := factory.NewContentDefinition("event", "")
+	assert.NoError(t, err)
+	assert.NoError(t, factory.NewPropertyDefinition(&event, "start", contentdefinition.PropertyTypeDate, "", false))
+	assert.NoError(t, factory.NewPropertyDefinition(&event, "end", contentdefinition.PropertyTypeDate, "", false))
+	eventId, err := cdRepo.CreateContentDefinition(context.Background(), &event, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	searchRepo := search.NewSearchRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	updateDefinition := UpdateContentDefinitionHandler{
+		WorkspaceRepo:     wsRepo,
+		Repo:              cdRepo,
+		ContentRepository: contentRepo,
+	}
+
+	updateRules := func(rules ...contentdefinition.Rule) error {
+		return updateDefinition.Handle(context.Background(), UpdateContentDefinition{
+			ContentDefinitionID: eventId,
+			WorkspaceId:         wsId,
+			Rules:               &rules,
+		})
+	}
+
+	t.Run("invalid rule is not saved", func(t *testing.T) {
+		err := updateRules(contentdefinition.Rule{Name: "dates", Expression: `end > "2022-01-01"`})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), contentdefinition.ErrInvalidRule))
+		}
+	})
+
+	assert.NoError(t, updateRules(contentdefinition.Rule{
+		Name:       "dates",
+		Expression: "!has(fields.end) || end >= start",
+		Message:    "end must not be before start",
+	}))
+
+	t.Run("property used by a rule is not removed", func(t *testing.T) {
+		cd, err := cdRepo.GetContentDefinition(context.Background(), eventId, wsId)
+		assert.NoError(t, err)
+		delete(cd.Propertydefinitions, "start")
+
+		err = updateDefinition.Handle(context.Background(), UpdateContentDefinition{
+			ContentDefinitionID: eventId,
+			WorkspaceId:         wsId,
+			PropertyDefinitions: cd.Propertydefinitions,
+		})
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), contentdefinition.ErrInvalidRule))
+		}
+	})
+
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	update := UpdateContentFieldsHandler{
+		ContentRepository:           contentRepo,
+		ContentDefinitionRepository: cdRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		Factory:                     content.ContentFactory{},
+		UnitOfWork:                  uow,
+	}
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+
+	newContent := func(fields map[string]interface{}) uuid.UUID {
+		id, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: eventId, WorkspaceId: wsId})
+		assert.NoError(t, err)
+
+		fields[contentdefinition.PROPFIELD_NAME] = "event"
+		assert.NoError(t, update.Handle(context.Background(), UpdateContentFields{
+			ContentID:   id,
+			Language:    "sv-SE",
+			WorkspaceId: wsId,
+			Fields:      fields,
+		}))
+		return id
+	}
+
+	t.Run("rule is evaluated when content is published", func(t *testing.T) {
+		id := newContent(map[string]interface{}{"start": "2022-05-02", "end": "2022-05-01"})
+
+		err := publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId})
+		assertFailure(t, err, "", validator.CodeRuleFailed)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "end must not be before start")
+		}
+	})
+
+	t.Run("content satisfying the rule is published", func(t *testing.T) {
+		id := newContent(map[string]interface{}{"start": "2022-05-01", "end": "2022-05-02"})
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}))
+
+		id = newContent(map[string]interface{}{"start": "2022-05-01"})
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}))
+	})
+}
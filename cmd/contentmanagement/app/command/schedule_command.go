@@ -0,0 +1,163 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/schedule"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CreateSchedule struct {
+	ContentID   uuid.UUID
+	Version     int
+	WorkspaceId uuid.UUID
+	// RFC 3339 timestamps, if no offset is given the timestamp is in TimeZone
+	PublishAt   string
+	UnpublishAt string
+	// IANA time zone, defaults to UTC
+	TimeZone string
+}
+
+type CreateScheduleHandler struct {
+	ContentRepository  content.ContentManagementRepository
+	ScheduleRepository schedule.ScheduleRepository
+	Factory            schedule.ScheduleFactory
+}
+
+func (h CreateScheduleHandler) Handle(ctx context.Context, cmd CreateSchedule) ([]uuid.UUID, error) {
+
+	// make sure the version exists
+	_, err := h.ContentRepository.GetContent(ctx, cmd.ContentID, cmd.Version, cmd.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules, err := h.Factory.NewSchedules(cmd.ContentID, cmd.Version, cmd.PublishAt, cmd.UnpublishAt, cmd.TimeZone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.ScheduleRepository.CreateSchedules(ctx, schedules, cmd.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(schedules))
+	for _, s := range schedules {
+		ids = append(ids, s.ID)
+	}
+
+	return ids, nil
+}
+
+type CancelSchedule struct {
+	ID          uuid.UUID
+	ContentID   uuid.UUID
+	WorkspaceId uuid.UUID
+}
+
+type CancelScheduleHandler struct {
+	ScheduleRepository schedule.ScheduleRepository
+}
+
+func (h CancelScheduleHandler) Handle(ctx context.Context, cmd CancelSchedule) error {
+	return h.ScheduleRepository.CancelSchedule(ctx, cmd.ID, cmd.ContentID, cmd.WorkspaceId)
+}
+
+type ExecuteSchedules struct {
+	Now time.Time
+}
+
+// ExecuteSchedulesHandler runs every due schedule in every workspace.
+// Validation is done when the schedule is executed, a failing schedule is marked as failed together with the error.
+type ExecuteSchedulesHandler struct {
+	ScheduleRepository  schedule.ScheduleRepository
+	ContentRepository   content.ContentManagementRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+	PublishContent      PublishContentHandler
+	ArchiveContent      ArchiveContentHandler
+	// ClaimTimeout is how long a schedule can be running before it is executed again, schedule.DefaultClaimTimeout if 0
+	ClaimTimeout time.Duration
+}
+
+// Handle returns the number of executed schedules
+func (h ExecuteSchedulesHandler) Handle(ctx context.Context, cmd ExecuteSchedules) (int, error) {
+
+	workspaces, err := h.WorkspaceRepository.ListAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	timeout := h.ClaimTimeout
+	if timeout == 0 {
+		timeout = schedule.DefaultClaimTimeout
+	}
+
+	executed := 0
+	for _, ws := range workspaces {
+		for {
+			s, err := h.ScheduleRepository.ClaimDue(ctx, cmd.Now, timeout, ws.ID)
+
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				break
+			}
+
+			if err != nil {
+				return executed, err
+			}
+
+			execErr := h.execute(ctx, s, ws.ID)
+			executed++
+
+			err = h.ScheduleRepository.UpdateSchedule(ctx, s.ID, ws.ID, func(ctx context.Context, s *schedule.Schedule) (*schedule.Schedule, error) {
+				s.Executed = cmd.Now.UTC()
+				s.Status = schedule.Completed
+
+				if execErr != nil {
+					s.Status = schedule.Failed
+					s.Error = execErr.Error()
+				}
+				return s, nil
+			})
+
+			if err != nil {
+				return executed, err
+			}
+		}
+	}
+
+	return executed, nil
+}
+
+func (h ExecuteSchedulesHandler) execute(ctx context.Context, s schedule.Schedule, workspaceId uuid.UUID) error {
+
+	switch s.Action {
+	case schedule.Publish:
+		return h.PublishContent.Handle(ctx, PublishContent{
+			ContentID:   s.ContentID,
+			Version:     s.Version,
+			WorkspaceId: workspaceId,
+		})
+	case schedule.Unpublish:
+		c, err := h.ContentRepository.GetContent(ctx, s.ContentID, s.Version, workspaceId)
+		if err != nil {
+			return err
+		}
+
+		if c.Data.Status != content.Published {
+			return errors.New("content version is not published")
+		}
+
+		return h.ArchiveContent.Handle(ctx, ArchiveContent{
+			ID:          s.ContentID,
+			WorkspaceId: workspaceId,
+		})
+	}
+
+	return errors.New("unknown schedule action")
+}
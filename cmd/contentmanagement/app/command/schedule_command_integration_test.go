@@ -0,0 +1,224 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/schedule"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_ExecuteSchedules(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		contentdef   *contentdefinition.ContentDefinition
+		cmd          CreateSchedule
+		expectStatus []schedule.Status
+		expectData   content.PublishStatus
+	}{
+		{
+			name:       "publish",
+			contentdef: &emptyContentDef,
+			cmd: CreateSchedule{
+				PublishAt: time.Now().Add(time.Minute).Format(time.RFC3339),
+			},
+			expectStatus: []schedule.Status{schedule.Completed},
+			expectData:   content.Published,
+		},
+		{
+			name:       "publish and unpublish",
+			contentdef: &emptyContentDef,
+			cmd: CreateSchedule{
+				PublishAt:   time.Now().Add(time.Minute).Format(time.RFC3339),
+				UnpublishAt: time.Now().Add(2 * time.Minute).Format(time.RFC3339),
+			},
+			expectStatus: []schedule.Status{schedule.Completed, schedule.Completed},
+			expectData:   content.PreviouslyPublished,
+		},
+		{
+			name:       "validation fails when executed",
+			contentdef: &reqfieldContentDef,
+			cmd: CreateSchedule{
+				PublishAt: time.Now().Add(time.Minute).Format(time.RFC3339),
+			},
+			expectStatus: []schedule.Status{schedule.Failed},
+			expectData:   content.Draft,
+		},
+	}
+
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	contentRepo := content.NewContentRepository(c)
+	scheduleRepo := schedule.NewScheduleRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ws := workspace.Workspace{
+				Name:      "test",
+				Languages: []string{"sv-SE"},
+			}
+			wsId, err := wsRepo.Create(context.Background(), ws)
+			assert.NoError(t, err)
+
+			_, err = cdRepo.CreateContentDefinition(context.Background(), test.contentdef, wsId)
+			assert.NoError(t, err)
+
+			id, err := contentRepo.CreateContent(context.Background(), content.ContentFactory{}.NewContent(*test.contentdef, ws.Languages[0]), wsId)
+			assert.NoError(t, err)
+
+			create := CreateScheduleHandler{
+				ContentRepository:  contentRepo,
+				ScheduleRepository: scheduleRepo,
+			}
+
+			cmd := test.cmd
+			cmd.ContentID = id
+			cmd.WorkspaceId = wsId
+			_, err = create.Handle(context.Background(), cmd)
+			assert.NoError(t, err)
+
+			execute := ExecuteSchedulesHandler{
+				ScheduleRepository:  scheduleRepo,
+				ContentRepository:   contentRepo,
+				WorkspaceRepository: wsRepo,
+				PublishContent: PublishContentHandler{
+					SearchRepository:            search.NewSearchRepository(c),
+					ContentDefinitionRepository: cdRepo,
+					ContentRepository:           contentRepo,
+					WorkspaceRepository:         wsRepo,
+					UnitOfWork:                  uow,
+				},
+				ArchiveContent: ArchiveContentHandler{
+					SearchRepository:    search.NewSearchRepository(c),
+					ContentRepository:   contentRepo,
+					WorkspaceRepository: wsRepo,
+					UnitOfWork:          uow,
+				},
+			}
+
+			// nothing is due yet
+			n, err := execute.Handle(context.Background(), ExecuteSchedules{Now: time.Now()})
+			assert.NoError(t, err)
+			assert.Equal(t, 0, n)
+
+			n, err = execute.Handle(context.Background(), ExecuteSchedules{Now: time.Now().Add(time.Hour)})
+			assert.NoError(t, err)
+			assert.Equal(t, len(test.expectStatus), n)
+
+			schedules, err := scheduleRepo.ListSchedules(context.Background(), id, wsId)
+			assert.NoError(t, err)
+
+			for i, s := range schedules {
+				assert.Equal(t, test.expectStatus[i], s.Status)
+
+				if s.Status == schedule.Failed {
+					assert.NotEmpty(t, s.Error)
+				}
+			}
+
+			actual, err := contentRepo.GetContent(context.Background(), id, 0, wsId)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectData, actual.Data.Status)
+		})
+	}
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
+
+func Test_ClaimDue(t *testing.T) {
+
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{Name: "test"})
+	assert.NoError(t, err)
+
+	repo := schedule.NewScheduleRepository(c)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	s := schedule.Schedule{ID: uuid.New(), ContentID: uuid.New(), Action: schedule.Publish, At: now, Status: schedule.Pending}
+	assert.NoError(t, repo.CreateSchedules(context.Background(), []schedule.Schedule{s}, wsId))
+
+	claimed, err := repo.ClaimDue(context.Background(), now, time.Minute, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, schedule.Running, claimed.Status)
+	assert.Equal(t, now, claimed.Claimed)
+
+	// the worker that claimed the schedule is still running it
+	_, err = repo.ClaimDue(context.Background(), now.Add(30*time.Second), time.Minute, wsId)
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+	// the worker stopped without completing the schedule
+	claimed, err = repo.ClaimDue(context.Background(), now.Add(2*time.Minute), time.Minute, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, s.ID, claimed.ID)
+	assert.Equal(t, now.Add(2*time.Minute), claimed.Claimed)
+
+	t.Cleanup(func() {
+		wsRepo.Delete(context.Background(), wsId)
+	})
+}
+
+func Test_CancelSchedule(t *testing.T) {
+
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{Name: "test"})
+	assert.NoError(t, err)
+
+	repo := schedule.NewScheduleRepository(c)
+	handler := CancelScheduleHandler{ScheduleRepository: repo}
+
+	now := time.Now().UTC()
+	contentID := uuid.New()
+	pending := schedule.Schedule{ID: uuid.New(), ContentID: contentID, Action: schedule.Publish, At: now.Add(time.Hour), Status: schedule.Pending}
+	due := schedule.Schedule{ID: uuid.New(), ContentID: contentID, Action: schedule.Publish, At: now, Status: schedule.Pending}
+	assert.NoError(t, repo.CreateSchedules(context.Background(), []schedule.Schedule{pending, due}, wsId))
+
+	assert.NoError(t, handler.Handle(context.Background(), CancelSchedule{ID: pending.ID, ContentID: contentID, WorkspaceId: wsId}))
+
+	actual, err := repo.GetSchedule(context.Background(), pending.ID, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, schedule.Cancelled, actual.Status)
+
+	// a schedule claimed by a worker is not cancelled
+	_, err = repo.ClaimDue(context.Background(), now, time.Minute, wsId)
+	assert.NoError(t, err)
+
+	err = handler.Handle(context.Background(), CancelSchedule{ID: due.ID, ContentID: contentID, WorkspaceId: wsId})
+	assert.EqualError(t, err, schedule.ErrNotPending)
+
+	actual, err = repo.GetSchedule(context.Background(), due.ID, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, schedule.Running, actual.Status)
+
+	err = handler.Handle(context.Background(), CancelSchedule{ID: due.ID, ContentID: uuid.New(), WorkspaceId: wsId})
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+	t.Cleanup(func() {
+		wsRepo.Delete(context.Background(), wsId)
+	})
+}
@@ -0,0 +1,90 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SearchIndex(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	searchRepo := search.NewSearchRepository(c)
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"en-US"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, searchRepo.EnsureIndexes(context.Background(), wsId))
+
+	cd, err := contentdefinition.NewContentDefinition("page", "")
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	cdId, err := cdRepo.CreateContentDefinition(context.Background(), &cd, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	id, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId})
+	assert.NoError(t, err)
+
+	err = contentRepo.UpdateContentData(context.Background(), id, 0, wsId, func(ctx context.Context, data *content.ContentData) (*content.ContentData, error) {
+		data.Properties["en-US"][contentdefinition.PROPFIELD_NAME] = content.ContentField{
+			Type:      contentdefinition.PropertyTypeText,
+			Localized: true,
+			Value:     "Running shoes",
+		}
+		return data, nil
+	})
+	assert.NoError(t, err)
+
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, Version: 0, WorkspaceId: wsId}))
+
+	// stemming matches runners with running
+	hits, res, err := searchRepo.Search(context.Background(), "runners", "en-US", search.Published, db.Page{}, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Total)
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, id, hits[0].ContentID)
+		assert.Equal(t, map[string]string{"name": "<em>Running</em> shoes"}, hits[0].Highlights("runners", search.SnippetLength))
+	}
+
+	archive := ArchiveContentHandler{
+		ContentRepository:   contentRepo,
+		WorkspaceRepository: wsRepo,
+		SearchRepository:    searchRepo,
+		UnitOfWork:          uow,
+	}
+	assert.NoError(t, archive.Handle(context.Background(), ArchiveContent{ID: id, WorkspaceId: wsId}))
+
+	hits, _, err = searchRepo.Search(context.Background(), "runners", "en-US", search.Published, db.Page{}, wsId)
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+}
@@ -0,0 +1,341 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+type MoveContent struct {
+	ContentID uuid.UUID
+	// ParentID is the new parent, empty moves the content to the root
+	ParentID uuid.UUID
+	// Position among the new siblings, nil puts the content last
+	Position    *int
+	WorkspaceId uuid.UUID
+}
+
+type MoveContentHandler struct {
+	ContentRepository   content.ContentManagementRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+	UnitOfWork          db.UnitOfWork
+}
+
+// Moving content also changes the sort order of its old and new siblings, so it is done in a single unit of work.
+func (h MoveContentHandler) Handle(ctx context.Context, cmd MoveContent) error {
+
+	return h.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+
+		if cmd.ParentID != (uuid.UUID{}) {
+			ancestors, err := h.ContentRepository.GetAncestors(ctx, cmd.ParentID, cmd.WorkspaceId)
+			if err != nil {
+				return err
+			}
+
+			if err := content.CheckMove(cmd.ContentID, cmd.ParentID, ancestors); err != nil {
+				return err
+			}
+		}
+
+		moved, err := h.ContentRepository.GetContentNode(ctx, cmd.ContentID, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+		previousParent := moved.ParentID
+
+		for _, parent := range []uuid.UUID{previousParent, cmd.ParentID} {
+			if err := h.ContentRepository.LockChildren(ctx, parent, cmd.WorkspaceId); err != nil {
+				return err
+			}
+		}
+
+		siblings, err := h.ContentRepository.GetChildren(ctx, cmd.ParentID, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		position := -1
+		if cmd.Position != nil {
+			position = *cmd.Position
+		}
+
+		order := content.InsertAt(contentIDs(siblings), cmd.ContentID, position)
+
+		err = h.ContentRepository.UpdateContent(ctx, cmd.ContentID, cmd.WorkspaceId, func(ctx context.Context, c *content.Content) (*content.Content, error) {
+			c.ParentID = cmd.ParentID
+			for i, id := range order {
+				if id == c.ID {
+					c.SortOrder = i
+				}
+			}
+			return c, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// the moved content already has its sort order
+		others := make([]content.Content, 0, len(siblings))
+		for _, s := range siblings {
+			if s.ID != cmd.ContentID {
+				others = append(others, s)
+			}
+		}
+
+		err = setSortOrder(ctx, h.ContentRepository, others, order, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		// values that are unique per parent are compared with the new siblings
+		if err := h.ContentRepository.MoveUniqueValues(ctx, cmd.ContentID, cmd.ParentID, cmd.WorkspaceId); err != nil {
+			return err
+		}
+
+		// the previous siblings close the gap left by the moved content
+		if previousParent != cmd.ParentID {
+			previous, err := h.ContentRepository.GetChildren(ctx, previousParent, cmd.WorkspaceId)
+			if err != nil {
+				return err
+			}
+
+			err = setSortOrder(ctx, h.ContentRepository, previous, contentIDs(previous), cmd.WorkspaceId)
+			if err != nil {
+				return err
+			}
+		}
+
+		ws, err := h.WorkspaceRepository.Get(ctx, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		return updateRoutes(ctx, h.ContentRepository, cmd.ContentID, ws)
+	})
+}
+
+type SetStartPage struct {
+	// ContentID is content at the root, empty removes the start page
+	ContentID   uuid.UUID
+	WorkspaceId uuid.UUID
+	// Revision is the expected revision of the workspace, nil skips the check.
+	Revision *int
+}
+
+type SetStartPageHandler struct {
+	ContentRepository   content.ContentManagementRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+	UnitOfWork          db.UnitOfWork
+}
+
+// The start page has the path /, so the routes of the previous and the new start page and their descendants are
+// rebuilt in the same unit of work as the workspace is updated.
+func (h SetStartPageHandler) Handle(ctx context.Context, cmd SetStartPage) error {
+
+	return h.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+
+		if cmd.ContentID != (uuid.UUID{}) {
+			c, err := h.ContentRepository.GetContentNode(ctx, cmd.ContentID, cmd.WorkspaceId)
+			if err != nil {
+				return err
+			}
+
+			if c.ParentID != (uuid.UUID{}) {
+				return errors.New(content.ErrStartPageNotAtRoot)
+			}
+		}
+
+		var previous uuid.UUID
+		var updated workspace.Workspace
+		err := h.WorkspaceRepository.Update(ctx, cmd.WorkspaceId, func(ctx context.Context, ws *workspace.Workspace) (*workspace.Workspace, error) {
+			if err := db.MatchRevision(cmd.Revision, ws.Revision); err != nil {
+				return nil, err
+			}
+
+			previous = ws.StartPage
+			ws.StartPage = cmd.ContentID
+			updated = *ws
+			return ws, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if previous == cmd.ContentID {
+			return nil
+		}
+
+		// the previous start page releases / before the new start page takes it
+		for _, id := range []uuid.UUID{previous, cmd.ContentID} {
+			if id == (uuid.UUID{}) {
+				continue
+			}
+
+			if err := updateRoutes(ctx, h.ContentRepository, id, updated); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+type ReorderChildren struct {
+	// ParentID is empty for content at the root
+	ParentID uuid.UUID
+	// Order contains the ID of every child in the new order
+	Order       []uuid.UUID
+	WorkspaceId uuid.UUID
+}
+
+type ReorderChildrenHandler struct {
+	ContentRepository content.ContentManagementRepository
+	UnitOfWork        db.UnitOfWork
+}
+
+func (h ReorderChildrenHandler) Handle(ctx context.Context, cmd ReorderChildren) error {
+
+	return h.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+
+		if err := h.ContentRepository.LockChildren(ctx, cmd.ParentID, cmd.WorkspaceId); err != nil {
+			return err
+		}
+
+		children, err := h.ContentRepository.GetChildren(ctx, cmd.ParentID, cmd.WorkspaceId)
+		if err != nil {
+			return err
+		}
+
+		if err := content.CheckOrder(children, cmd.Order); err != nil {
+			return err
+		}
+
+		return setSortOrder(ctx, h.ContentRepository, children, cmd.Order, cmd.WorkspaceId)
+	})
+}
+
+// setSortOrder updates the sort order of the siblings that are not already at their position in order.
+func setSortOrder(ctx context.Context, repo content.ContentManagementRepository, siblings []content.Content, order []uuid.UUID, workspaceId uuid.UUID) error {
+
+	current := make(map[uuid.UUID]int)
+	for _, s := range siblings {
+		current[s.ID] = s.SortOrder
+	}
+
+	for i, id := range order {
+
+		sortOrder, ok := current[id]
+		if !ok || sortOrder == i {
+			continue
+		}
+
+		err := repo.UpdateContent(ctx, id, workspaceId, func(ctx context.Context, c *content.Content) (*content.Content, error) {
+			c.SortOrder = i
+			return c, nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func contentIDs(items []content.Content) []uuid.UUID {
+
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, c := range items {
+		ids = append(ids, c.ID)
+	}
+
+	return ids
+}
+
+// updateRoutes rebuilds the routes of content and its descendants. It is called in the same unit of work as
+// publish, archive and move, since those change the path of the content or whether it is routable.
+func updateRoutes(ctx context.Context, repo content.ContentManagementRepository, id uuid.UUID, ws workspace.Workspace) error {
+
+	ancestors, err := repo.GetAncestors(ctx, id, ws.ID)
+	if err != nil {
+		return err
+	}
+
+	var parent *content.Content
+	if len(ancestors) > 0 {
+		parent = &ancestors[len(ancestors)-1]
+	}
+
+	descendants, err := repo.GetDescendants(ctx, id, ws.ID)
+	if err != nil {
+		return err
+	}
+
+	updated := make(map[uuid.UUID]*content.Content)
+
+	err = repo.UpdateContent(ctx, id, ws.ID, func(ctx context.Context, c *content.Content) (*content.Content, error) {
+		if err := setRoutes(ctx, repo, c, parent, ws); err != nil {
+			return nil, err
+		}
+
+		updated[c.ID] = c
+		return c, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// descendants are ordered by depth, so the parent is always updated before its children
+	for _, d := range descendants {
+		d := d
+		parent := updated[d.ParentID]
+
+		if reflect.DeepEqual(d.Routes, content.BuildRoutes(d, parent, ws)) {
+			updated[d.ID] = &d
+			continue
+		}
+
+		err = repo.UpdateContent(ctx, d.ID, ws.ID, func(ctx context.Context, c *content.Content) (*content.Content, error) {
+			if err := setRoutes(ctx, repo, c, parent, ws); err != nil {
+				return nil, err
+			}
+
+			updated[c.ID] = c
+			return c, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setRoutes(ctx context.Context, repo content.ContentManagementRepository, c *content.Content, parent *content.Content, ws workspace.Workspace) error {
+
+	routes := content.BuildRoutes(*c, parent, ws)
+
+	for _, r := range routes {
+		existing, err := repo.FindByPath(ctx, r.Path, ws.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range existing {
+			if path, ok := e.Route(r.Language); e.ID != c.ID && ok && path == r.Path {
+				return fmt.Errorf("%s: %s", content.ErrPathInUse, r.Path)
+			}
+		}
+	}
+
+	c.Redirects = content.UpdateRedirects(c.Redirects, c.Routes, routes)
+	c.Routes = routes
+
+	return nil
+}
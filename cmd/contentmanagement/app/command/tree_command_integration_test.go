@@ -0,0 +1,371 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_MoveContent(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	cdId, err := cdRepo.CreateContentDefinition(context.Background(), &emptyContentDef, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	create := CreateContentHandler{
+		SearchRepository:            search.NewSearchRepository(c),
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		UnitOfWork:                  db.NewUnitOfWork(c),
+	}
+
+	// a
+	// └── b
+	//     └── c
+	// d
+	a, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId})
+	assert.NoError(t, err)
+	b, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId, ParentID: a})
+	assert.NoError(t, err)
+	cc, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId, ParentID: b})
+	assert.NoError(t, err)
+	d, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId})
+	assert.NoError(t, err)
+
+	ancestors, err := contentRepo.GetAncestors(context.Background(), cc, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{a, b}, contentIDs(ancestors))
+
+	descendants, err := contentRepo.GetDescendants(context.Background(), a, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{b, cc}, contentIDs(descendants))
+
+	move := MoveContentHandler{
+		ContentRepository:   contentRepo,
+		WorkspaceRepository: wsRepo,
+		UnitOfWork:          db.NewUnitOfWork(c),
+	}
+
+	err = move.Handle(context.Background(), MoveContent{ContentID: a, ParentID: cc, WorkspaceId: wsId})
+	assert.Equal(t, errors.New(content.ErrMoveBelowItself), err)
+
+	// move c to the root between a and d
+	position := 1
+	err = move.Handle(context.Background(), MoveContent{ContentID: cc, Position: &position, WorkspaceId: wsId})
+	assert.NoError(t, err)
+
+	root, err := contentRepo.GetChildren(context.Background(), uuid.UUID{}, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{a, cc, d}, contentIDs(root))
+
+	reorder := ReorderChildrenHandler{
+		ContentRepository: contentRepo,
+		UnitOfWork:        db.NewUnitOfWork(c),
+	}
+
+	err = reorder.Handle(context.Background(), ReorderChildren{Order: []uuid.UUID{d, a}, WorkspaceId: wsId})
+	assert.Equal(t, errors.New(content.ErrNotSiblings), err)
+
+	err = reorder.Handle(context.Background(), ReorderChildren{Order: []uuid.UUID{d, a, cc}, WorkspaceId: wsId})
+	assert.NoError(t, err)
+
+	root, err = contentRepo.GetChildren(context.Background(), uuid.UUID{}, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{d, a, cc}, contentIDs(root))
+
+	t.Run("moved content leaves no gap in the order of its previous siblings", func(t *testing.T) {
+		e, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId, ParentID: a})
+		assert.NoError(t, err)
+
+		assert.NoError(t, move.Handle(context.Background(), MoveContent{ContentID: b, ParentID: d, WorkspaceId: wsId}))
+
+		children, err := contentRepo.GetChildren(context.Background(), a, wsId)
+		assert.NoError(t, err)
+		if assert.Len(t, children, 1) {
+			assert.Equal(t, e, children[0].ID)
+			assert.Equal(t, 0, children[0].SortOrder)
+		}
+	})
+
+	t.Run("moving content does not depend on the first version", func(t *testing.T) {
+		f, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId})
+		assert.NoError(t, err)
+
+		_, err = c.Database(wsId.String()).Collection("contentversion").DeleteOne(context.Background(), bson.M{"contentId": f, "version": 0})
+		assert.NoError(t, err)
+
+		assert.NoError(t, move.Handle(context.Background(), MoveContent{ContentID: f, ParentID: d, WorkspaceId: wsId}))
+
+		moved, err := contentRepo.GetContentNode(context.Background(), f, wsId)
+		assert.NoError(t, err)
+		assert.Equal(t, d, moved.ParentID)
+	})
+
+	t.Run("content created concurrently has unique sort orders", func(t *testing.T) {
+		const n = 5
+
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId, ParentID: cc})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		children, err := contentRepo.GetChildren(context.Background(), cc, wsId)
+		assert.NoError(t, err)
+		for i, child := range children {
+			assert.Equal(t, i, child.SortOrder)
+		}
+		assert.Len(t, children, n)
+	})
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
+
+func Test_UpdateRoutes(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE", "en-US"},
+	})
+	assert.NoError(t, err)
+
+	cd := contentdefinition.ContentDefinition{
+		Name: "page",
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			contentdefinition.PROPFIELD_URLSEGMENT: {
+				ID:        uuid.New(),
+				Type:      "text",
+				Localized: true,
+			},
+		},
+	}
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	cdId, err := cdRepo.CreateContentDefinition(context.Background(), &cd, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	newPage := func(parent uuid.UUID, segments map[string]string) uuid.UUID {
+		page := content.Content{
+			ContentDefinitionID: cdId,
+			ParentID:            parent,
+			Data: content.ContentData{
+				Status:     content.Draft,
+				Properties: make(content.ContentLanguage),
+			},
+		}
+
+		for lang, segment := range segments {
+			page.Data.Properties[lang] = content.ContentFields{
+				contentdefinition.PROPFIELD_URLSEGMENT: {
+					ID:        cd.Propertydefinitions[contentdefinition.PROPFIELD_URLSEGMENT].ID,
+					Type:      "text",
+					Localized: true,
+					Value:     segment,
+				},
+			}
+		}
+
+		id, err := contentRepo.CreateContent(context.Background(), page, wsId)
+		assert.NoError(t, err)
+		return id
+	}
+
+	publish := PublishContentHandler{
+		SearchRepository:            search.NewSearchRepository(c),
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		UnitOfWork:                  uow,
+	}
+
+	// start
+	// └── about
+	start := newPage(uuid.UUID{}, map[string]string{})
+	about := newPage(start, map[string]string{"sv-SE": "om-oss", "en-US": "about"})
+
+	// not routable until the parent is published
+	assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: about, Version: 0, WorkspaceId: wsId}))
+	actual, err := contentRepo.GetPublishedContent(context.Background(), about, content.FieldProjection{}, wsId)
+	assert.NoError(t, err)
+	assert.Empty(t, actual.Routes)
+
+	// content at the root without url segment is not routable until it is the start page
+	assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: start, Version: 0, WorkspaceId: wsId}))
+	actual, err = contentRepo.GetPublishedContent(context.Background(), start, content.FieldProjection{}, wsId)
+	assert.NoError(t, err)
+	assert.Empty(t, actual.Routes)
+
+	setStartPage := SetStartPageHandler{
+		ContentRepository:   contentRepo,
+		WorkspaceRepository: wsRepo,
+		UnitOfWork:          uow,
+	}
+	assert.NoError(t, setStartPage.Handle(context.Background(), SetStartPage{ContentID: start, WorkspaceId: wsId}))
+
+	actual, err = contentRepo.GetPublishedContent(context.Background(), start, content.FieldProjection{}, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, []content.Route{{Language: "sv-SE", Path: "/"}, {Language: "en-US", Path: "/"}}, actual.Routes)
+
+	actual, err = contentRepo.GetPublishedContent(context.Background(), about, content.FieldProjection{}, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, []content.Route{{Language: "sv-SE", Path: "/om-oss"}, {Language: "en-US", Path: "/about"}}, actual.Routes)
+
+	// the start page must be at the root
+	err = setStartPage.Handle(context.Background(), SetStartPage{ContentID: about, WorkspaceId: wsId})
+	assert.EqualError(t, err, content.ErrStartPageNotAtRoot)
+
+	// a second page can not use the same path
+	other := newPage(start, map[string]string{"sv-SE": "om-oss"})
+	err = publish.Handle(context.Background(), PublishContent{ContentID: other, Version: 0, WorkspaceId: wsId})
+	assert.Error(t, err)
+
+	// the start page has the path /, so moving the page to the root keeps its path
+	move := MoveContentHandler{
+		ContentRepository:   contentRepo,
+		WorkspaceRepository: wsRepo,
+		UnitOfWork:          uow,
+	}
+	assert.NoError(t, move.Handle(context.Background(), MoveContent{ContentID: about, WorkspaceId: wsId}))
+
+	actual, err = contentRepo.GetPublishedContent(context.Background(), about, content.FieldProjection{}, wsId)
+	assert.NoError(t, err)
+	assert.Equal(t, []content.Route{{Language: "sv-SE", Path: "/om-oss"}, {Language: "en-US", Path: "/about"}}, actual.Routes)
+
+	assert.NoError(t, move.Handle(context.Background(), MoveContent{ContentID: about, ParentID: start, WorkspaceId: wsId}))
+
+	// archiving the parent removes the routes of the descendants
+	archive := ArchiveContentHandler{
+		SearchRepository:    search.NewSearchRepository(c),
+		ContentRepository:   contentRepo,
+		WorkspaceRepository: wsRepo,
+		UnitOfWork:          uow,
+	}
+	assert.NoError(t, archive.Handle(context.Background(), ArchiveContent{ID: start, WorkspaceId: wsId}))
+
+	actual, err = contentRepo.GetPublishedContent(context.Background(), about, content.FieldProjection{}, wsId)
+	assert.NoError(t, err)
+	assert.Empty(t, actual.Routes)
+	assert.Contains(t, actual.Redirects, content.Route{Language: "en-US", Path: "/about"})
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
+
+func Test_RootContentWithoutSegment(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE"},
+	})
+	assert.NoError(t, err)
+
+	// products are not routable, so they have no url segment
+	cd, err := contentdefinition.ContentDefinitionFactory{}.NewContentDefinition("product", "")
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	cdId, err := cdRepo.CreateContentDefinition(context.Background(), &cd, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	searchRepo := search.NewSearchRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	update := UpdateContentFieldsHandler{
+		ContentRepository:           contentRepo,
+		ContentDefinitionRepository: cdRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		Factory:                     content.ContentFactory{},
+		UnitOfWork:                  uow,
+	}
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+
+	for _, name := range []string{"chair", "table"} {
+		id, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: cdId, WorkspaceId: wsId})
+		assert.NoError(t, err)
+
+		assert.NoError(t, update.Handle(context.Background(), UpdateContentFields{
+			ContentID:   id,
+			Language:    "sv-SE",
+			WorkspaceId: wsId,
+			Fields:      map[string]interface{}{contentdefinition.PROPFIELD_NAME: name},
+		}))
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}))
+
+		actual, err := contentRepo.GetPublishedContent(context.Background(), id, content.FieldProjection{}, wsId)
+		assert.NoError(t, err)
+		assert.Empty(t, actual.Routes)
+	}
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+//go:build integration
+
+package command
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UniqueValues(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	wsRepo := workspace.NewWorkspaceRepository(c)
+	wsId, err := wsRepo.Create(context.Background(), workspace.Workspace{
+		Name:      "test",
+		Languages: []string{"sv-SE", "en-US"},
+	})
+	assert.NoError(t, err)
+
+	cdRepo := contentdefinition.NewContentDefinitionRepository(c)
+	factory := contentdefinition.ContentDefinitionFactory{}
+
+	product, err := factory.NewContentDefinition("product", "")
+	assert.NoError(t, err)
+	assert.NoError(t, factory.NewPropertyDefinition(&product, "sku", contentdefinition.PropertyTypeText, "", false))
+	assert.NoError(t, factory.NewPropertyDefinition(&product, "slug", contentdefinition.PropertyTypeText, "", true))
+	productId, err := cdRepo.CreateContentDefinition(context.Background(), &product, wsId)
+	assert.NoError(t, err)
+
+	contentRepo := content.NewContentRepository(c)
+	assert.NoError(t, contentRepo.EnsureIndexes(context.Background(), wsId))
+	searchRepo := search.NewSearchRepository(c)
+	uow := db.NewUnitOfWork(c)
+
+	updateDefinition := UpdateContentDefinitionHandler{
+		WorkspaceRepo:     wsRepo,
+		Repo:              cdRepo,
+		ContentRepository: contentRepo,
+	}
+
+	cd, err := cdRepo.GetContentDefinition(context.Background(), productId, wsId)
+	assert.NoError(t, err)
+
+	sku := cd.Propertydefinitions["sku"]
+	sku.Validators = map[string]interface{}{validator.RuleUnique: true}
+	cd.Propertydefinitions["sku"] = sku
+	slug := cd.Propertydefinitions["slug"]
+	slug.Validators = map[string]interface{}{validator.RuleUnique: validator.Unique{Enabled: true, PerLanguage: true}}
+	cd.Propertydefinitions["slug"] = slug
+
+	assert.NoError(t, updateDefinition.Handle(context.Background(), UpdateContentDefinition{
+		ContentDefinitionID: productId,
+		WorkspaceId:         wsId,
+		PropertyDefinitions: cd.Propertydefinitions,
+	}))
+
+	create := CreateContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		Factory:                     content.ContentFactory{},
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+	update := UpdateContentFieldsHandler{
+		ContentRepository:           contentRepo,
+		ContentDefinitionRepository: cdRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		Factory:                     content.ContentFactory{},
+		UnitOfWork:                  uow,
+	}
+	publish := PublishContentHandler{
+		ContentDefinitionRepository: cdRepo,
+		ContentRepository:           contentRepo,
+		WorkspaceRepository:         wsRepo,
+		SearchRepository:            searchRepo,
+		UnitOfWork:                  uow,
+	}
+
+	newContent := func(language string, fields map[string]interface{}) uuid.UUID {
+		id, err := create.Handle(context.Background(), CreateContent{ContentDefinitionId: productId, WorkspaceId: wsId})
+		assert.NoError(t, err)
+
+		fields[contentdefinition.PROPFIELD_NAME] = "product"
+		assert.NoError(t, update.Handle(context.Background(), UpdateContentFields{
+			ContentID:   id,
+			Language:    language,
+			WorkspaceId: wsId,
+			Fields:      fields,
+		}))
+		return id
+	}
+
+	published := newContent("sv-SE", map[string]interface{}{"sku": "A-1", "slug": "stol"})
+	assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: published, WorkspaceId: wsId}))
+
+	t.Run("value used by published content", func(t *testing.T) {
+		id := newContent("sv-SE", map[string]interface{}{"sku": "A-1"})
+
+		err := publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId})
+		assertFailure(t, err, "sku", validator.CodeNotUnique)
+		if assert.Error(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), content.ErrValidation))
+			assert.Contains(t, err.Error(), published.String())
+		}
+	})
+
+	t.Run("value used by a draft", func(t *testing.T) {
+		// drafts do not reserve values, the draft that is published first gets the value
+		draft := newContent("sv-SE", map[string]interface{}{"sku": "B-1"})
+		id := newContent("sv-SE", map[string]interface{}{"sku": "B-1"})
+
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}))
+
+		err := publish.Handle(context.Background(), PublishContent{ContentID: draft, WorkspaceId: wsId})
+		assertFailure(t, err, "sku", validator.CodeNotUnique)
+	})
+
+	t.Run("content published at the same time", func(t *testing.T) {
+		ids := []uuid.UUID{
+			newContent("sv-SE", map[string]interface{}{"sku": "C-1"}),
+			newContent("sv-SE", map[string]interface{}{"sku": "C-1"}),
+		}
+
+		errs := make(chan error, len(ids))
+		var wg sync.WaitGroup
+		for _, id := range ids {
+			wg.Add(1)
+			go func(id uuid.UUID) {
+				defer wg.Done()
+				errs <- publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId})
+			}(id)
+		}
+		wg.Wait()
+		close(errs)
+
+		failed := 0
+		for err := range errs {
+			if err != nil {
+				failed++
+			}
+		}
+		assert.Equal(t, 1, failed)
+	})
+
+	t.Run("value is unique per language", func(t *testing.T) {
+		id := newContent("en-US", map[string]interface{}{"slug": "stol"})
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}))
+	})
+
+	t.Run("content can be published again", func(t *testing.T) {
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: published, WorkspaceId: wsId}))
+	})
+
+	t.Run("archived content releases its values", func(t *testing.T) {
+		archive := ArchiveContentHandler{
+			ContentRepository:   contentRepo,
+			WorkspaceRepository: wsRepo,
+			SearchRepository:    searchRepo,
+			UnitOfWork:          uow,
+		}
+		assert.NoError(t, archive.Handle(context.Background(), ArchiveContent{ID: published, WorkspaceId: wsId}))
+
+		id := newContent("sv-SE", map[string]interface{}{"sku": "A-1"})
+		assert.NoError(t, publish.Handle(context.Background(), PublishContent{ContentID: id, WorkspaceId: wsId}))
+	})
+
+	t.Cleanup(func() {
+		workspaces, _ := wsRepo.ListAll(context.Background())
+
+		for _, ws := range workspaces {
+			wsRepo.Delete(context.Background(), ws.ID)
+		}
+	})
+}
@@ -3,6 +3,9 @@ package command
 import (
 	"context"
 
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
 	"github.com/crikke/cms/pkg/workspace"
 	"github.com/google/uuid"
 )
@@ -14,7 +17,9 @@ type CreateWorkspace struct {
 }
 
 type CreateWorkspaceHandler struct {
-	Repo workspace.WorkspaceRepository
+	Repo              workspace.WorkspaceRepository
+	ContentRepository content.ContentManagementRepository
+	SearchRepository  search.SearchRepository
 }
 
 func (h CreateWorkspaceHandler) Handle(ctx context.Context, cmd CreateWorkspace) (uuid.UUID, error) {
@@ -25,13 +30,24 @@ func (h CreateWorkspaceHandler) Handle(ctx context.Context, cmd CreateWorkspace)
 		return uuid.UUID{}, err
 	}
 
-	return h.Repo.Create(ctx, ws)
+	id, err := h.Repo.Create(ctx, ws)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := h.ContentRepository.EnsureIndexes(ctx, id); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return id, h.SearchRepository.EnsureIndexes(ctx, id)
 }
 
 type UpdateTag struct {
 	WorkspaceId uuid.UUID
 	Id          string
 	Name        string
+	// Revision is the expected revision of the workspace, nil skips the check.
+	Revision *int
 }
 
 type UpdateTagHandler struct {
@@ -42,6 +58,10 @@ func (h UpdateTagHandler) Handle(ctx context.Context, cmd UpdateTag) error {
 
 	return h.Repo.Update(ctx, cmd.WorkspaceId, func(ctx context.Context, ws *workspace.Workspace) (*workspace.Workspace, error) {
 
+		if err := db.MatchRevision(cmd.Revision, ws.Revision); err != nil {
+			return nil, err
+		}
+
 		ws.Tags[cmd.Id] = cmd.Name
 
 		return ws, nil
@@ -51,6 +71,8 @@ func (h UpdateTagHandler) Handle(ctx context.Context, cmd UpdateTag) error {
 type DeleteTag struct {
 	WorkspaceId uuid.UUID
 	Id          string
+	// Revision is the expected revision of the workspace, nil skips the check.
+	Revision *int
 }
 
 type DeleteTagHandler struct {
@@ -60,6 +82,10 @@ type DeleteTagHandler struct {
 func (h DeleteTagHandler) Handle(ctx context.Context, cmd DeleteTag) error {
 
 	return h.Repo.Update(ctx, cmd.WorkspaceId, func(ctx context.Context, ws *workspace.Workspace) (*workspace.Workspace, error) {
+		if err := db.MatchRevision(cmd.Revision, ws.Revision); err != nil {
+			return nil, err
+		}
+
 		delete(ws.Tags, cmd.Id)
 
 		return ws, nil
@@ -70,6 +96,8 @@ type UpdateWorkspace struct {
 	ID          uuid.UUID
 	Name        string
 	Description string
+	// Revision is the expected revision of the workspace, nil skips the check.
+	Revision *int
 }
 
 type UpdateWorkspaceHandler struct {
@@ -77,5 +105,20 @@ type UpdateWorkspaceHandler struct {
 }
 
 func (h UpdateWorkspaceHandler) Handle(ctx context.Context, cmd UpdateWorkspace) error {
-	return nil
+
+	return h.Repo.Update(ctx, cmd.ID, func(ctx context.Context, ws *workspace.Workspace) (*workspace.Workspace, error) {
+		if err := db.MatchRevision(cmd.Revision, ws.Revision); err != nil {
+			return nil, err
+		}
+
+		if cmd.Name != "" {
+			ws.Name = cmd.Name
+		}
+
+		if cmd.Description != "" {
+			ws.Description = cmd.Description
+		}
+
+		return ws, nil
+	})
 }
@@ -0,0 +1,111 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/crikke/cms/pkg/asset"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/google/uuid"
+)
+
+type GetAsset struct {
+	ID          uuid.UUID
+	WorkspaceId uuid.UUID
+}
+
+type GetAssetHandler struct {
+	Repo asset.AssetRepository
+}
+
+// Handle returns mongo.ErrNoDocuments if the asset does not exist.
+func (h GetAssetHandler) Handle(ctx context.Context, query GetAsset) (asset.Asset, error) {
+	return h.Repo.GetAsset(ctx, query.ID, query.WorkspaceId)
+}
+
+type ListAssets struct {
+	WorkspaceId uuid.UUID
+	// MimeType filters the assets by mime type, ie image/png. A type without subtype, ie image, matches every subtype
+	MimeType string
+	// Page.Sort is filename, size, created or updated
+	Page db.Page
+}
+
+type ListAssetsHandler struct {
+	Repo asset.AssetRepository
+}
+
+func (h ListAssetsHandler) Handle(ctx context.Context, query ListAssets) ([]asset.Asset, db.PageResult, error) {
+
+	page, err := query.Page.SortBy(map[string]string{
+		"filename": "filename",
+		"size":     "size",
+		"created":  "created",
+		"updated":  "updated",
+	})
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	return h.Repo.ListAssets(ctx, query.MimeType, page, query.WorkspaceId)
+}
+
+type GetAssetFile struct {
+	ID          uuid.UUID
+	WorkspaceId uuid.UUID
+}
+
+type GetAssetFileHandler struct {
+	Repo    asset.AssetRepository
+	Storage asset.Storage
+}
+
+// Handle returns the asset together with its file, the caller closes the file.
+func (h GetAssetFileHandler) Handle(ctx context.Context, query GetAssetFile) (asset.Asset, io.ReadSeekCloser, error) {
+
+	a, err := h.Repo.GetAsset(ctx, query.ID, query.WorkspaceId)
+	if err != nil {
+		return asset.Asset{}, nil, err
+	}
+
+	file, err := h.Storage.Open(ctx, query.WorkspaceId, a.Key())
+	if err != nil {
+		return asset.Asset{}, nil, err
+	}
+
+	return a, file, nil
+}
+
+type GetAssetURL struct {
+	ID          uuid.UUID
+	WorkspaceId uuid.UUID
+	Options     imaging.Options
+}
+
+type GetAssetURLHandler struct {
+	Repo   asset.AssetRepository
+	Signer imaging.Signer
+}
+
+// Handle returns the content delivery URL of the asset, transformation options are signed.
+func (h GetAssetURLHandler) Handle(ctx context.Context, query GetAssetURL) (string, error) {
+
+	if _, err := h.Repo.GetAsset(ctx, query.ID, query.WorkspaceId); err != nil {
+		return "", err
+	}
+
+	values, err := h.Signer.SignedValues(query.WorkspaceId, query.ID, query.Options)
+	if err != nil {
+		return "", err
+	}
+
+	u := url.URL{
+		Path:     fmt.Sprintf("/contentdelivery/workspaces/%s/assets/%s", query.WorkspaceId, query.ID),
+		RawQuery: values.Encode(),
+	}
+
+	return u.String(), nil
+}
@@ -6,8 +6,10 @@ import (
 
 	"github.com/crikke/cms/pkg/content"
 	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
 	"github.com/crikke/cms/pkg/workspace"
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // swagger:model ContentListReadModel
@@ -19,38 +21,110 @@ type ContentListReadModel struct {
 type ListContent struct {
 	ContentDefinitionIDs []uuid.UUID
 	Tags                 []string
-	WorkspaceId          uuid.UUID
+	// Filter expression, see content.Filter
+	Filter string
+	// Page.Sort is created, updated or a property, see content.SortField
+	Page        db.Page
+	WorkspaceId uuid.UUID
+	// Language of the name and of localized fields in Filter & Sort, defaults to the default language of the workspace
+	Language string
 }
 
 type ListContentHandler struct {
-	Repo                content.ContentManagementRepository
-	WorkspaceRepository workspace.WorkspaceRepository
+	Repo                        content.ContentManagementRepository
+	WorkspaceRepository         workspace.WorkspaceRepository
+	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
 }
 
 //! TODO Should name be returned for current locale?
-func (h ListContentHandler) Handle(ctx context.Context, query ListContent) ([]ContentListReadModel, error) {
+func (h ListContentHandler) Handle(ctx context.Context, query ListContent) ([]ContentListReadModel, db.PageResult, error) {
 
-	items, err := h.Repo.ListContent(ctx, query.ContentDefinitionIDs, query.Tags, query.WorkspaceId)
+	ws, err := h.WorkspaceRepository.Get(ctx, query.WorkspaceId)
 	if err != nil {
-		return nil, err
+		return nil, db.PageResult{}, err
 	}
 
-	ws, err := h.WorkspaceRepository.Get(ctx, query.WorkspaceId)
+	filter, page, err := compileQuery(ctx, h.ContentDefinitionRepository, query, ws)
 	if err != nil {
-		return nil, err
+		return nil, db.PageResult{}, err
+	}
+
+	items, res, err := h.Repo.ListContent(ctx, query.ContentDefinitionIDs, query.Tags, filter, page, query.WorkspaceId)
+	if err != nil {
+		return nil, db.PageResult{}, err
 	}
 
 	result := []ContentListReadModel{}
 
 	for _, ch := range items {
-		name := ch.Data.Properties[ws.Languages[0]][contentdefinition.PROPFIELD_NAME].Value
 		result = append(result, ContentListReadModel{
 			ID:   ch.ID,
-			Name: name.(string),
+			Name: contentName(ch, query.Language, ws.Languages[0]),
 		})
 	}
 
-	return result, nil
+	return result, res, nil
+}
+
+// compileQuery validates the filter and sort against the content definitions that are listed,
+// or against every content definition in the workspace if no content definitions are given.
+func compileQuery(ctx context.Context, repo contentdefinition.ContentDefinitionRepository, query ListContent, ws workspace.Workspace) (bson.M, db.Page, error) {
+
+	f, err := content.ParseFilter(query.Filter)
+	if err != nil {
+		return nil, db.Page{}, err
+	}
+
+	page := query.Page
+	if f.IsEmpty() && page.Sort == "" {
+		return nil, page, nil
+	}
+
+	definitions, err := repo.ListContentDefinitions(ctx, ws.ID)
+	if err != nil {
+		return nil, db.Page{}, err
+	}
+
+	if len(query.ContentDefinitionIDs) > 0 {
+		listed := make([]contentdefinition.ContentDefinition, 0, len(query.ContentDefinitionIDs))
+		for _, cd := range definitions {
+			for _, id := range query.ContentDefinitionIDs {
+				if cd.ID == id {
+					listed = append(listed, cd)
+				}
+			}
+		}
+		definitions = listed
+	}
+
+	language := query.Language
+	if language == "" {
+		language = ws.Languages[0]
+	}
+
+	page.Sort, err = content.SortField(page.Sort, definitions, language, ws.Languages[0])
+	if err != nil {
+		return nil, db.Page{}, err
+	}
+
+	filter, err := f.Compile(definitions, language, ws.Languages[0])
+	if err != nil {
+		return nil, db.Page{}, err
+	}
+
+	return filter, page, nil
+}
+
+// contentName returns the name of content in language, or in the default language if the name is not translated.
+func contentName(c content.Content, language, defaultLanguage string) string {
+
+	for _, lang := range []string{language, defaultLanguage} {
+		if name, ok := c.Data.Properties[lang][contentdefinition.PROPFIELD_NAME].Value.(string); ok && name != "" {
+			return name
+		}
+	}
+
+	return ""
 }
 
 // ContentReadModel is the representation of the content for the Content management API
@@ -68,6 +142,9 @@ type ContentReadModel struct {
 	Updated time.Time `bson:"updated"`
 
 	Tags map[string]string `bson:"tags,omitempty"`
+
+	// Revision of the content version, returned as ETag
+	Revision int `bson:"revision"`
 }
 
 // In contentmanagement, all languages should be retrived for content of given version
@@ -108,6 +185,7 @@ func (q GetContentHandler) Handle(ctx context.Context, query GetContent) (Conten
 		Updated:             c.Updated,
 		Properties:          c.Data.Properties,
 		Tags:                make(map[string]string),
+		Revision:            c.Data.Revision,
 	}
 
 	for _, tagId := range c.Data.Tags {
@@ -125,3 +203,29 @@ func (q GetContentHandler) Handle(ctx context.Context, query GetContent) (Conten
 
 	return crm, nil
 }
+
+type GetContentDiff struct {
+	Id          uuid.UUID
+	WorkspaceId uuid.UUID
+	From        int
+	To          int
+}
+
+type GetContentDiffHandler struct {
+	Repo content.ContentManagementRepository
+}
+
+func (q GetContentDiffHandler) Handle(ctx context.Context, query GetContentDiff) (content.ContentDiff, error) {
+
+	from, err := q.Repo.GetContent(ctx, query.Id, query.From, query.WorkspaceId)
+	if err != nil {
+		return content.ContentDiff{}, err
+	}
+
+	to, err := q.Repo.GetContent(ctx, query.Id, query.To, query.WorkspaceId)
+	if err != nil {
+		return content.ContentDiff{}, err
+	}
+
+	return content.Diff(from.Data, to.Data), nil
+}
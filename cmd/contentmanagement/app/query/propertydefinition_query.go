@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
 	"github.com/google/uuid"
 )
 
@@ -38,6 +39,8 @@ func (h GetPropertyDefinitionHandler) Handle(ctx context.Context, query GetPrope
 
 type ListContentDefinition struct {
 	WorkspaceID uuid.UUID
+	// Page.Sort is name or created
+	Page db.Page
 }
 
 type ListContentDefinitionModel struct {
@@ -50,12 +53,20 @@ type ListContentDefinitionHandler struct {
 	Repo contentdefinition.ContentDefinitionRepository
 }
 
-func (h ListContentDefinitionHandler) Handle(ctx context.Context, query ListContentDefinition) ([]ListContentDefinitionModel, error) {
+func (h ListContentDefinitionHandler) Handle(ctx context.Context, query ListContentDefinition) ([]ListContentDefinitionModel, db.PageResult, error) {
 
-	items, err := h.Repo.ListContentDefinitions(ctx, query.WorkspaceID)
+	page, err := query.Page.SortBy(map[string]string{
+		"name":    "name",
+		"created": "created",
+	})
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	items, res, err := h.Repo.ListContentDefinitionsPage(ctx, page, query.WorkspaceID)
 
 	if err != nil {
-		return nil, err
+		return nil, db.PageResult{}, err
 	}
 
 	result := make([]ListContentDefinitionModel, 0)
@@ -68,5 +79,5 @@ func (h ListContentDefinitionHandler) Handle(ctx context.Context, query ListCont
 		})
 	}
 
-	return result, nil
+	return result, res, nil
 }
@@ -0,0 +1,49 @@
+package query
+
+import (
+	"context"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+type ListReferencingContent struct {
+	ID          uuid.UUID
+	WorkspaceId uuid.UUID
+	// Language of the name, defaults to the default language of the workspace
+	Language string
+}
+
+type ListReferencingContentHandler struct {
+	Repo                content.ContentManagementRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+}
+
+// Handle returns the published content that references the content, content referencing itself is left out.
+func (h ListReferencingContentHandler) Handle(ctx context.Context, query ListReferencingContent) ([]ContentListReadModel, error) {
+
+	ws, err := h.WorkspaceRepository.Get(ctx, query.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := h.Repo.ListReferencingContent(ctx, query.ID, query.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ContentListReadModel{}
+	for _, c := range items {
+		if c.ID == query.ID {
+			continue
+		}
+
+		result = append(result, ContentListReadModel{
+			ID:   c.ID,
+			Name: contentName(c, query.Language, ws.Languages[0]),
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/crikke/cms/pkg/schedule"
+	"github.com/google/uuid"
+)
+
+// swagger:model ScheduleReadModel
+type ScheduleReadModel struct {
+	ID        uuid.UUID
+	ContentID uuid.UUID
+	Version   int
+	Action    schedule.Action
+	// At is the scheduled time in the time zone the schedule was created in
+	At       time.Time
+	TimeZone string
+	Status   schedule.Status
+	Executed time.Time
+	Error    string
+}
+
+type ListSchedules struct {
+	ContentID   uuid.UUID
+	WorkspaceId uuid.UUID
+}
+
+type ListSchedulesHandler struct {
+	Repo schedule.ScheduleRepository
+}
+
+func (h ListSchedulesHandler) Handle(ctx context.Context, query ListSchedules) ([]ScheduleReadModel, error) {
+
+	items, err := h.Repo.ListSchedules(ctx, query.ContentID, query.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ScheduleReadModel, 0, len(items))
+	for _, s := range items {
+		result = append(result, ScheduleReadModel{
+			ID:        s.ID,
+			ContentID: s.ContentID,
+			Version:   s.Version,
+			Action:    s.Action,
+			At:        s.Local(),
+			TimeZone:  s.TimeZone,
+			Status:    s.Status,
+			Executed:  s.Executed,
+			Error:     s.Error,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package query
+
+import (
+	"context"
+
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+// SearchResultReadModel is content that matches a search, the latest version of content is searched.
+// swagger:model SearchResultReadModel
+type SearchResultReadModel struct {
+	ID       uuid.UUID
+	Version  int
+	Language string
+	Name     string
+	// Score is the relevance of the match, results are ordered by score
+	Score float64
+	// Highlights are HTML escaped snippets of the matching fields with the matches wrapped in <em>
+	Highlights map[string]string
+}
+
+type Search struct {
+	Query string
+	// Language to search in, defaults to the default language of the workspace
+	Language    string
+	Page        db.Page
+	WorkspaceId uuid.UUID
+}
+
+type SearchHandler struct {
+	Repo                search.SearchRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+}
+
+func (h SearchHandler) Handle(ctx context.Context, query Search) ([]SearchResultReadModel, db.PageResult, error) {
+
+	language := query.Language
+	if language == "" {
+		ws, err := h.WorkspaceRepository.Get(ctx, query.WorkspaceId)
+		if err != nil {
+			return nil, db.PageResult{}, err
+		}
+		language = ws.Languages[0]
+	}
+
+	hits, res, err := h.Repo.Search(ctx, query.Query, language, search.Latest, query.Page, query.WorkspaceId)
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	result := make([]SearchResultReadModel, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, SearchResultReadModel{
+			ID:         hit.ContentID,
+			Version:    hit.Version,
+			Language:   hit.Language,
+			Name:       hit.Name,
+			Score:      hit.Score,
+			Highlights: hit.Highlights(query.Query, search.SnippetLength),
+		})
+	}
+
+	return result, res, nil
+}
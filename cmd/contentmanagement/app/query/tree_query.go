@@ -0,0 +1,112 @@
+package query
+
+import (
+	"context"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+// ContentTreeReadModel is a node in the content tree
+// swagger:model ContentTreeReadModel
+type ContentTreeReadModel struct {
+	ID                  uuid.UUID
+	ParentID            uuid.UUID
+	ContentDefinitionID uuid.UUID
+	SortOrder           int
+	// Name in the requested language
+	Name   string
+	Status content.PublishStatus
+}
+
+type ListChildren struct {
+	// Id is empty for content at the root
+	Id          uuid.UUID
+	WorkspaceId uuid.UUID
+	// Language of the name, defaults to the default language of the workspace
+	Language string
+}
+
+type ListChildrenHandler struct {
+	Repo                content.ContentManagementRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+}
+
+func (h ListChildrenHandler) Handle(ctx context.Context, query ListChildren) ([]ContentTreeReadModel, error) {
+
+	items, err := h.Repo.GetChildren(ctx, query.Id, query.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return treeReadModel(ctx, h.WorkspaceRepository, items, query.WorkspaceId, query.Language)
+}
+
+type ListAncestors struct {
+	Id          uuid.UUID
+	WorkspaceId uuid.UUID
+	// Language of the name, defaults to the default language of the workspace
+	Language string
+}
+
+type ListAncestorsHandler struct {
+	Repo                content.ContentManagementRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+}
+
+// Handle returns the ancestors starting at the root
+func (h ListAncestorsHandler) Handle(ctx context.Context, query ListAncestors) ([]ContentTreeReadModel, error) {
+
+	items, err := h.Repo.GetAncestors(ctx, query.Id, query.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return treeReadModel(ctx, h.WorkspaceRepository, items, query.WorkspaceId, query.Language)
+}
+
+type ListDescendants struct {
+	Id          uuid.UUID
+	WorkspaceId uuid.UUID
+	// Language of the name, defaults to the default language of the workspace
+	Language string
+}
+
+type ListDescendantsHandler struct {
+	Repo                content.ContentManagementRepository
+	WorkspaceRepository workspace.WorkspaceRepository
+}
+
+// Handle returns the descendants ordered by depth
+func (h ListDescendantsHandler) Handle(ctx context.Context, query ListDescendants) ([]ContentTreeReadModel, error) {
+
+	items, err := h.Repo.GetDescendants(ctx, query.Id, query.WorkspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return treeReadModel(ctx, h.WorkspaceRepository, items, query.WorkspaceId, query.Language)
+}
+
+func treeReadModel(ctx context.Context, repo workspace.WorkspaceRepository, items []content.Content, workspaceId uuid.UUID, language string) ([]ContentTreeReadModel, error) {
+
+	ws, err := repo.Get(ctx, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ContentTreeReadModel, 0, len(items))
+	for _, c := range items {
+		result = append(result, ContentTreeReadModel{
+			ID:                  c.ID,
+			ParentID:            c.ParentID,
+			ContentDefinitionID: c.ContentDefinitionID,
+			SortOrder:           c.SortOrder,
+			Name:                contentName(c, language, ws.Languages[0]),
+			Status:              c.Data.Status,
+		})
+	}
+
+	return result, nil
+}
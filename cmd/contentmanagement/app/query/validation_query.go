@@ -0,0 +1,76 @@
+package query
+
+import (
+	"context"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+type ValidateContent struct {
+	ID          uuid.UUID
+	Version     int
+	WorkspaceId uuid.UUID
+}
+
+type ValidateContentHandler struct {
+	Repo                        content.ContentManagementRepository
+	ContentDefinitionRepository contentdefinition.ContentDefinitionRepository
+	WorkspaceRepository         workspace.WorkspaceRepository
+}
+
+// Handle validates a content version like it is validated when it is published, without publishing it.
+// Every failure is returned, including values of unique properties that are used by other content.
+func (h ValidateContentHandler) Handle(ctx context.Context, query ValidateContent) (content.ValidationReport, error) {
+
+	ws, err := h.WorkspaceRepository.Get(ctx, query.WorkspaceId)
+	if err != nil {
+		return content.ValidationReport{}, err
+	}
+
+	c, err := h.Repo.GetContent(ctx, query.ID, query.Version, query.WorkspaceId)
+	if err != nil {
+		return content.ValidationReport{}, err
+	}
+
+	cd, err := h.ContentDefinitionRepository.GetContentDefinition(ctx, c.ContentDefinitionID, query.WorkspaceId)
+	if err != nil {
+		return content.ValidationReport{}, err
+	}
+
+	report, err := content.Validate(ctx, cd, c.Data, ws.Languages)
+	if err != nil {
+		return content.ValidationReport{}, err
+	}
+
+	failures, err := content.ValidateUnique(ctx, h.Repo, cd, c, c.Data, ws)
+	if err != nil {
+		return content.ValidationReport{}, err
+	}
+
+	report.Add(failures...)
+	return report, nil
+}
+
+type ValidatorReadModel struct {
+	Name string
+	// Schema is the configuration of the validator in PropertyDefinition.Validators
+	Schema validator.Schema
+}
+
+type ListValidatorsHandler struct{}
+
+// Handle returns the validators that can be configured on property definitions, ordered by name
+func (h ListValidatorsHandler) Handle(ctx context.Context) []ValidatorReadModel {
+
+	rules := validator.Rules()
+	result := make([]ValidatorReadModel, 0, len(rules))
+	for _, r := range rules {
+		result = append(result, ValidatorReadModel{Name: r.Name, Schema: r.Schema})
+	}
+
+	return result
+}
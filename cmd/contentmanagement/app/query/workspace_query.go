@@ -2,8 +2,13 @@ package query
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/crikke/cms/pkg/db"
 	"github.com/crikke/cms/pkg/workspace"
 	"github.com/google/uuid"
 )
@@ -59,18 +64,25 @@ func (h GetTagHandler) Handle(ctx context.Context, query GetTag) (Tag, error) {
 	return t, nil
 }
 
+type ListTags struct {
+	WorkspaceID uuid.UUID
+	// Page.Sort is name or id, tags are sorted by id if empty
+	Page db.Page
+}
+
 type ListTagsHandler struct {
 	Repo workspace.WorkspaceRepository
 }
 
-func (h ListTagsHandler) Handle(ctx context.Context, workspaceID uuid.UUID) ([]Tag, error) {
+// Tags are stored on the workspace so they are paged in memory, the continuation token is the id of the last tag.
+func (h ListTagsHandler) Handle(ctx context.Context, query ListTags) ([]Tag, db.PageResult, error) {
 
 	tags := make([]Tag, 0)
 
-	ws, err := h.Repo.Get(ctx, workspaceID)
+	ws, err := h.Repo.Get(ctx, query.WorkspaceID)
 
 	if err != nil {
-		return nil, err
+		return nil, db.PageResult{}, err
 	}
 
 	for tagId, tagName := range ws.Tags {
@@ -80,7 +92,58 @@ func (h ListTagsHandler) Handle(ctx context.Context, workspaceID uuid.UUID) ([]T
 		})
 	}
 
-	return tags, nil
+	less := func(a, b Tag) bool { return a.Id < b.Id }
+
+	switch strings.TrimPrefix(query.Page.Sort, "-") {
+	case "", "id":
+	case "name":
+		less = func(a, b Tag) bool { return a.Name < b.Name || (a.Name == b.Name && a.Id < b.Id) }
+	default:
+		return nil, db.PageResult{}, fmt.Errorf("%s: cannot sort by %q", db.ErrInvalidSort, query.Page.Sort)
+	}
+
+	descending := strings.HasPrefix(query.Page.Sort, "-")
+	sort.Slice(tags, func(i, j int) bool {
+		if descending {
+			return less(tags[j], tags[i])
+		}
+		return less(tags[i], tags[j])
+	})
+
+	start := 0
+	if query.Page.Token != "" {
+		last, err := base64.RawURLEncoding.DecodeString(query.Page.Token)
+		if err != nil {
+			return nil, db.PageResult{}, errors.New(db.ErrInvalidPageToken)
+		}
+
+		start = -1
+		for i, tag := range tags {
+			if tag.Id == string(last) {
+				start = i + 1
+			}
+		}
+
+		if start < 0 {
+			return nil, db.PageResult{}, errors.New(db.ErrInvalidPageToken)
+		}
+	}
+
+	size := query.Page.Size
+	if size == 0 {
+		size = db.DefaultPageSize
+	}
+
+	res := db.PageResult{Total: len(tags)}
+	end := start + size
+
+	if end < len(tags) {
+		res.Next = base64.RawURLEncoding.EncodeToString([]byte(tags[end-1].Id))
+	} else {
+		end = len(tags)
+	}
+
+	return tags[start:end], res, nil
 }
 
 type ListWorkspaceResult struct {
@@ -88,15 +151,28 @@ type ListWorkspaceResult struct {
 	Id   uuid.UUID
 }
 
+type ListWorkspace struct {
+	// Page.Sort is name
+	Page db.Page
+}
+
 type ListWorkspaceHandler struct {
 	Repo workspace.WorkspaceRepository
 }
 
-func (h ListWorkspaceHandler) Handle(ctx context.Context) ([]ListWorkspaceResult, error) {
-	workspaces, err := h.Repo.ListAll(ctx)
+func (h ListWorkspaceHandler) Handle(ctx context.Context, query ListWorkspace) ([]ListWorkspaceResult, db.PageResult, error) {
+
+	page, err := query.Page.SortBy(map[string]string{
+		"name": "name",
+	})
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	workspaces, res, err := h.Repo.ListPage(ctx, page)
 
 	if err != nil {
-		return nil, err
+		return nil, db.PageResult{}, err
 	}
 
 	items := make([]ListWorkspaceResult, 0)
@@ -108,5 +184,5 @@ func (h ListWorkspaceHandler) Handle(ctx context.Context) ([]ListWorkspaceResult
 		})
 	}
 
-	return items, nil
+	return items, res, nil
 }
@@ -0,0 +1,53 @@
+//go:build integration
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ListTags(t *testing.T) {
+	c, err := db.Connect(context.Background(), "mongodb://0.0.0.0")
+	assert.NoError(t, err)
+
+	repo := workspace.NewWorkspaceRepository(c)
+	wsId, err := repo.Create(context.Background(), workspace.Workspace{
+		Name: "test",
+		Tags: map[string]string{"a": "c", "b": "b", "c": "a"},
+	})
+	assert.NoError(t, err)
+
+	handler := ListTagsHandler{Repo: repo}
+
+	tags, res, err := handler.Handle(context.Background(), ListTags{
+		WorkspaceID: wsId,
+		Page:        db.Page{Sort: "name", Size: 2},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{Id: "c", Name: "a"}, {Id: "b", Name: "b"}}, tags)
+	assert.Equal(t, 3, res.Total)
+	assert.NotEmpty(t, res.Next)
+
+	tags, res, err = handler.Handle(context.Background(), ListTags{
+		WorkspaceID: wsId,
+		Page:        db.Page{Sort: "name", Size: 2, Token: res.Next},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{Id: "a", Name: "c"}}, tags)
+	assert.Empty(t, res.Next)
+
+	_, _, err = handler.Handle(context.Background(), ListTags{
+		WorkspaceID: wsId,
+		Page:        db.Page{Sort: "created"},
+	})
+	assert.Error(t, err)
+
+	t.Cleanup(func() {
+		repo.Delete(context.Background(), wsId)
+	})
+}
@@ -3,11 +3,17 @@ package main
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/crikke/cms/cmd/contentmanagement/api"
 	_ "github.com/crikke/cms/cmd/contentmanagement/docs"
+	"github.com/crikke/cms/pkg/asset"
 	"github.com/crikke/cms/pkg/config"
+	"github.com/crikke/cms/pkg/content"
 	"github.com/crikke/cms/pkg/db"
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/crikke/cms/pkg/search"
+	"github.com/crikke/cms/pkg/workspace"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -18,8 +24,12 @@ import (
 
 type Server struct {
 	// Configuration config.SiteConfiguration
-	Database *mongo.Client
-	Logger   *zap.SugaredLogger
+	Database          *mongo.Client
+	Logger            *zap.SugaredLogger
+	SchedulerInterval time.Duration
+	Storage           asset.Storage
+	Cache             imaging.Cache
+	Signer            imaging.Signer
 }
 
 // @title           Swagger Example API
@@ -47,9 +57,29 @@ func main() {
 		panic(err)
 	}
 
+	// workspaces created before an index existed get it on start
+	workspaces, err := workspace.NewWorkspaceRepository(c).ListAll(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	for _, ws := range workspaces {
+		if err := content.NewContentRepository(c).EnsureIndexes(context.Background(), ws.ID); err != nil {
+			sugar.Errorw("failed to create indexes", "workspace", ws.ID, "error", err)
+		}
+
+		if err := search.NewSearchRepository(c).EnsureIndexes(context.Background(), ws.ID); err != nil {
+			sugar.Errorw("failed to create search indexes", "workspace", ws.ID, "error", err)
+		}
+	}
+
 	server := Server{
-		Database: c,
-		Logger:   sugar,
+		Database:          c,
+		Logger:            sugar,
+		SchedulerInterval: serverConfig.SchedulerInterval,
+		Storage:           asset.NewFileSystemStorage(serverConfig.AssetPath),
+		Cache:             imaging.NewCache(serverConfig.ImageCachePath),
+		Signer:            imaging.NewSigner(serverConfig.ImageSigningKey),
 	}
 
 	panic(server.Start())
@@ -72,7 +102,14 @@ func (s Server) Start() error {
 		httpSwagger.URL("http://localhost:8080/swagger/doc.json"), //The url pointing to API definition
 	))
 
-	r.Mount("/contentmanagement", api.NewContentManagementAPI(s.Database))
+	r.Mount("/contentmanagement", api.NewContentManagementAPI(s.Database, s.Storage, s.Cache, s.Signer, s.Logger))
+
+	scheduler := Scheduler{
+		Handler:  api.NewApp(s.Database, s.Storage, s.Cache, s.Signer).Commands.ExecuteSchedules,
+		Interval: s.SchedulerInterval,
+		Logger:   s.Logger,
+	}
+	go scheduler.Run(context.Background())
 
 	return http.ListenAndServe(":8080", r)
 }
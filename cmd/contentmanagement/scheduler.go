@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/crikke/cms/cmd/contentmanagement/app/command"
+	"go.uber.org/zap"
+)
+
+// Scheduler is the background worker executing scheduled publishing.
+type Scheduler struct {
+	Handler  command.ExecuteSchedulesHandler
+	Interval time.Duration
+	Logger   *zap.SugaredLogger
+}
+
+// Run executes due schedules every interval until ctx is done.
+func (s Scheduler) Run(ctx context.Context) {
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := s.Handler.Handle(ctx, command.ExecuteSchedules{Now: now})
+
+			if err != nil {
+				s.Logger.Errorw("executing schedules", "error", err)
+			}
+
+			if n > 0 {
+				s.Logger.Infow("executed schedules", "count", n)
+			}
+		}
+	}
+}
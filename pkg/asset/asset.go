@@ -0,0 +1,191 @@
+package asset
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	// decoders of the image formats dimensions are read from
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/crikke/cms/pkg/imaging"
+	"github.com/google/uuid"
+)
+
+const (
+	ErrMissingFile     = "file is required"
+	ErrInvalidFilename = "invalid filename"
+	ErrInvalidLanguage = "alt text language is not a language of the workspace"
+	ErrAssetReferenced = "asset is referenced by published content"
+)
+
+// sniffLength is the number of bytes http.DetectContentType considers
+const sniffLength = 512
+
+// Asset is a file in the asset library. The file is kept in a Storage, the asset only contains its metadata.
+// swagger:model Asset
+type Asset struct {
+	ID       uuid.UUID `bson:"_id"`
+	Filename string    `bson:"filename"`
+	Metadata `bson:",inline"`
+	// Alt is the alternative text of the asset per language
+	Alt map[string]string `bson:"alt,omitempty"`
+	// Focal is the point of interest of an image, it is kept in view when the image is cropped
+	Focal   *imaging.Point `bson:"focal,omitempty"`
+	Created time.Time      `bson:"created"`
+	Updated time.Time      `bson:"updated"`
+}
+
+// Metadata is read from the file when it is uploaded
+type Metadata struct {
+	MimeType string `bson:"mimeType"`
+	// Size in bytes
+	Size int64 `bson:"size"`
+	// Width and Height in pixels are only set for GIF, JPEG and PNG images
+	Width  int `bson:"width,omitempty"`
+	Height int `bson:"height,omitempty"`
+	// Checksum is the hex encoded SHA-256 hash of the file
+	Checksum string `bson:"checksum"`
+}
+
+// IsImage returns true if the mime type of the asset is an image type
+func (a Asset) IsImage() bool {
+	return strings.HasPrefix(a.MimeType, "image/")
+}
+
+// Key is the key of the file of the asset in Storage
+func (a Asset) Key() string {
+	return a.ID.String()
+}
+
+type AssetFactory struct {
+}
+
+// NewAsset creates an asset of an uploaded file. The file is read to the end to extract its metadata.
+func (f AssetFactory) NewAsset(filename string, file io.Reader, alt map[string]string, languages []string, now time.Time) (Asset, error) {
+
+	if file == nil {
+		return Asset{}, errors.New(ErrMissingFile)
+	}
+
+	name, err := CleanFilename(filename)
+	if err != nil {
+		return Asset{}, err
+	}
+
+	if err := ValidateAlt(alt, languages); err != nil {
+		return Asset{}, err
+	}
+
+	meta, err := ReadMetadata(file, name)
+	if err != nil {
+		return Asset{}, err
+	}
+
+	return Asset{
+		ID:       uuid.New(),
+		Filename: name,
+		Metadata: meta,
+		Alt:      alt,
+		Created:  now,
+		Updated:  now,
+	}, nil
+}
+
+// CleanFilename removes the directories of filename, the filename is only used for display and the Content-Disposition of downloads.
+func CleanFilename(filename string) (string, error) {
+
+	// some browsers send the full path of the file, on Windows with backslashes
+	name := path.Base(strings.ReplaceAll(strings.TrimSpace(filename), `\`, "/"))
+	name = strings.TrimPrefix(name, "/")
+
+	if name == "" || name == "." || name == ".." {
+		return "", errors.New(ErrInvalidFilename)
+	}
+
+	for _, r := range name {
+		if r < ' ' || r == 0x7f {
+			return "", errors.New(ErrInvalidFilename)
+		}
+	}
+
+	return name, nil
+}
+
+// ValidateAlt returns an error if alt contains a language that is not one of languages
+func ValidateAlt(alt map[string]string, languages []string) error {
+
+	for lang := range alt {
+		found := false
+		for _, l := range languages {
+			if l == lang {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%s: %s", ErrInvalidLanguage, lang)
+		}
+	}
+
+	return nil
+}
+
+// ReadMetadata reads r to the end and returns its metadata.
+// The mime type is detected from the content, the extension of filename is only used when the content is not recognized.
+func ReadMetadata(r io.Reader, filename string) (Metadata, error) {
+
+	hash := sha256.New()
+	counter := &countWriter{}
+	br := bufio.NewReaderSize(io.TeeReader(r, io.MultiWriter(hash, counter)), sniffLength)
+
+	head, err := br.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return Metadata{}, err
+	}
+
+	meta := Metadata{MimeType: http.DetectContentType(head)}
+	if meta.MimeType == "application/octet-stream" {
+		if t := mime.TypeByExtension(path.Ext(filename)); t != "" {
+			meta.MimeType = t
+		}
+	}
+
+	if strings.HasPrefix(meta.MimeType, "image/") {
+		// images in unsupported formats get no dimensions
+		if cfg, _, err := image.DecodeConfig(br); err == nil {
+			meta.Width = cfg.Width
+			meta.Height = cfg.Height
+		}
+	}
+
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return Metadata{}, err
+	}
+
+	meta.Size = counter.n
+	meta.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	return meta, nil
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,149 @@
+//go:build unit
+
+package asset
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pngFile(t *testing.T, width, height int) []byte {
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, png.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func Test_ReadMetadata(t *testing.T) {
+
+	img := pngFile(t, 30, 20)
+	// larger than the sniffed bytes and the read buffer
+	text := strings.Repeat("lorem ipsum ", 1000)
+
+	checksum := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		expect   Metadata
+	}{
+		{
+			name:     "image dimensions",
+			filename: "image.bin",
+			data:     img,
+			expect: Metadata{
+				MimeType: "image/png",
+				Size:     int64(len(img)),
+				Width:    30,
+				Height:   20,
+				Checksum: checksum(img),
+			},
+		},
+		{
+			name:     "content is detected before extension",
+			filename: "text.png",
+			data:     []byte(text),
+			expect: Metadata{
+				MimeType: "text/plain; charset=utf-8",
+				Size:     int64(len(text)),
+				Checksum: checksum([]byte(text)),
+			},
+		},
+		{
+			name:     "unknown content uses extension",
+			filename: "data.pdf",
+			data:     []byte{0x00, 0x01, 0x02},
+			expect: Metadata{
+				MimeType: "application/pdf",
+				Size:     3,
+				Checksum: checksum([]byte{0x00, 0x01, 0x02}),
+			},
+		},
+		{
+			name:     "empty file",
+			filename: "empty",
+			data:     []byte{},
+			expect: Metadata{
+				MimeType: "text/plain; charset=utf-8",
+				Checksum: checksum([]byte{}),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ReadMetadata(bytes.NewReader(test.data), test.filename)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, actual)
+		})
+	}
+}
+
+func Test_NewAsset(t *testing.T) {
+
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	languages := []string{"sv-SE", "en-US"}
+	f := AssetFactory{}
+
+	a, err := f.NewAsset("../../photos/bike.png", bytes.NewReader(pngFile(t, 2, 1)), map[string]string{"en-US": "A bike"}, languages, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "bike.png", a.Filename)
+	assert.Equal(t, "image/png", a.MimeType)
+	assert.Equal(t, 2, a.Width)
+	assert.Equal(t, map[string]string{"en-US": "A bike"}, a.Alt)
+	assert.Equal(t, now, a.Created)
+	assert.True(t, a.IsImage())
+
+	_, err = f.NewAsset("bike.png", bytes.NewReader(nil), map[string]string{"de-DE": "Ein Fahrrad"}, languages, now)
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), ErrInvalidLanguage))
+	}
+
+	_, err = f.NewAsset("/", bytes.NewReader(nil), nil, languages, now)
+	assert.EqualError(t, err, ErrInvalidFilename)
+
+	_, err = f.NewAsset("bike.png", nil, nil, languages, now)
+	assert.EqualError(t, err, ErrMissingFile)
+}
+
+func Test_CleanFilename(t *testing.T) {
+
+	tests := []struct {
+		input     string
+		expect    string
+		expectErr bool
+	}{
+		{input: "bike.png", expect: "bike.png"},
+		{input: " a b.png ", expect: "a b.png"},
+		{input: `C:\photos\bike.png`, expect: "bike.png"},
+		{input: "../bike.png", expect: "bike.png"},
+		{input: "", expectErr: true},
+		{input: "..", expectErr: true},
+		{input: "a\nb", expectErr: true},
+	}
+
+	for _, test := range tests {
+		actual, err := CleanFilename(test.input)
+
+		if test.expectErr {
+			assert.Error(t, err, test.input)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, test.expect, actual)
+	}
+}
@@ -0,0 +1,149 @@
+package asset
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/crikke/cms/pkg/db"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const assetCollection = "asset"
+
+type AssetRepository struct {
+	client *mongo.Client
+}
+
+func NewAssetRepository(c *mongo.Client) AssetRepository {
+	return AssetRepository{
+		client: c,
+	}
+}
+
+func (r AssetRepository) CreateAsset(ctx context.Context, a Asset, workspace uuid.UUID) error {
+
+	_, err := r.client.Database(workspace.String()).
+		Collection(assetCollection).
+		InsertOne(ctx, a)
+
+	return err
+}
+
+// GetAsset returns mongo.ErrNoDocuments if the asset does not exist.
+func (r AssetRepository) GetAsset(ctx context.Context, id uuid.UUID, workspace uuid.UUID) (Asset, error) {
+
+	res := &Asset{}
+	err := r.client.Database(workspace.String()).
+		Collection(assetCollection).
+		FindOne(ctx, bson.M{"_id": id}).
+		Decode(res)
+
+	if err != nil {
+		return Asset{}, err
+	}
+
+	return *res, nil
+}
+
+// ListAssets returns a page of the assets, filtered by mime type if mimeType is set.
+// A mime type without subtype, ie image, matches every subtype.
+func (r AssetRepository) ListAssets(ctx context.Context, mimeType string, page db.Page, workspace uuid.UUID) ([]Asset, db.PageResult, error) {
+
+	filter := bson.M{}
+	if mimeType != "" {
+		filter["mimeType"] = mimeType
+		if !strings.Contains(mimeType, "/") {
+			filter["mimeType"] = bson.M{"$regex": "^" + regexp.QuoteMeta(mimeType) + "/"}
+		}
+	}
+
+	items := make([]Asset, 0)
+	res, err := db.FindPage(
+		ctx,
+		r.client.Database(workspace.String()).Collection(assetCollection),
+		filter,
+		page,
+		nil,
+		func(cursor *mongo.Cursor) error {
+			a := &Asset{}
+			if err := cursor.Decode(a); err != nil {
+				return err
+			}
+
+			items = append(items, *a)
+			return nil
+		})
+
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	return items, res, nil
+}
+
+// ListAssetsByIDs returns the assets with any of ids, assets that do not exist are left out of the result.
+func (r AssetRepository) ListAssetsByIDs(ctx context.Context, ids []uuid.UUID, workspace uuid.UUID) ([]Asset, error) {
+
+	if len(ids) == 0 {
+		return []Asset{}, nil
+	}
+
+	cursor, err := r.client.Database(workspace.String()).
+		Collection(assetCollection).
+		Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Asset, 0)
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r AssetRepository) UpdateAsset(
+	ctx context.Context,
+	id uuid.UUID,
+	workspace uuid.UUID,
+	updateFn func(context.Context, *Asset) (*Asset, error)) error {
+
+	a, err := r.GetAsset(ctx, id, workspace)
+	if err != nil {
+		return err
+	}
+
+	updated, err := updateFn(ctx, &a)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.Database(workspace.String()).
+		Collection(assetCollection).
+		ReplaceOne(ctx, bson.M{"_id": id}, updated)
+
+	return err
+}
+
+// DeleteAsset returns mongo.ErrNoDocuments if the asset does not exist.
+func (r AssetRepository) DeleteAsset(ctx context.Context, id uuid.UUID, workspace uuid.UUID) error {
+
+	res, err := r.client.Database(workspace.String()).
+		Collection(assetCollection).
+		DeleteOne(ctx, bson.M{"_id": id})
+
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package asset
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const ErrInvalidKey = "invalid storage key"
+
+// Storage stores the files of assets, files are separated per workspace like the databases are.
+// Open and Delete return an error that matches fs.ErrNotExist when the file does not exist.
+type Storage interface {
+	Put(ctx context.Context, workspace uuid.UUID, key string, r io.Reader) error
+	Open(ctx context.Context, workspace uuid.UUID, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, workspace uuid.UUID, key string) error
+}
+
+// FileSystemStorage stores files in a directory per workspace under Root
+type FileSystemStorage struct {
+	Root string
+}
+
+func NewFileSystemStorage(root string) FileSystemStorage {
+	return FileSystemStorage{
+		Root: root,
+	}
+}
+
+// Put writes the file to a temporary file that is renamed when it is complete,
+// so a failed upload never leaves a partial file behind.
+func (s FileSystemStorage) Put(ctx context.Context, workspace uuid.UUID, key string, r io.Reader) error {
+
+	name, err := s.path(workspace, key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, &contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s FileSystemStorage) Open(ctx context.Context, workspace uuid.UUID, key string) (io.ReadSeekCloser, error) {
+
+	name, err := s.path(workspace, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(name)
+}
+
+func (s FileSystemStorage) Delete(ctx context.Context, workspace uuid.UUID, key string) error {
+
+	name, err := s.path(workspace, key)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+// path returns the path of the file, keys cannot contain directories so files never end up outside of Root
+func (s FileSystemStorage) path(workspace uuid.UUID, key string) (string, error) {
+
+	// keys starting with a dot are reserved for temporary files
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, `/\`) {
+		return "", errors.New(ErrInvalidKey)
+	}
+
+	return filepath.Join(s.Root, workspace.String(), key), nil
+}
+
+// contextReader stops reading when the context is done, so cancelled uploads are not written to the end
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
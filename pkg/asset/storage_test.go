@@ -0,0 +1,59 @@
+//go:build unit
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileSystemStorage(t *testing.T) {
+
+	ctx := context.Background()
+	root := t.TempDir()
+	s := NewFileSystemStorage(root)
+	ws := uuid.New()
+	key := uuid.NewString()
+
+	assert.NoError(t, s.Put(ctx, ws, key, strings.NewReader("first")))
+	assert.NoError(t, s.Put(ctx, ws, key, strings.NewReader("second")))
+
+	f, err := s.Open(ctx, ws, key)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	assert.Equal(t, "second", string(data))
+
+	// files of other workspaces are not found
+	_, err = s.Open(ctx, uuid.New(), key)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	assert.NoError(t, s.Delete(ctx, ws, key))
+	_, err = s.Open(ctx, ws, key)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.True(t, errors.Is(s.Delete(ctx, ws, key), fs.ErrNotExist))
+
+	// temporary files are removed
+	entries, err := os.ReadDir(root + "/" + ws.String())
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	for _, invalid := range []string{"", "../key", "a/b", `a\b`, ".upload-1"} {
+		assert.EqualError(t, s.Put(ctx, ws, invalid, strings.NewReader("x")), ErrInvalidKey, invalid)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, s.Put(cancelled, ws, key, strings.NewReader("x")))
+	_, err = s.Open(ctx, ws, key)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
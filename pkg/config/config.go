@@ -1,6 +1,9 @@
 package config
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -10,6 +13,14 @@ type ServerConfiguration struct {
 		RabbitMQ string
 	}
 	LogLevel int
+	// How often scheduled publishing is checked for due schedules
+	SchedulerInterval time.Duration
+	// AssetPath is the directory the files of assets are stored in
+	AssetPath string
+	// ImageCachePath is the directory transformed images are cached in
+	ImageCachePath string
+	// ImageSigningKey signs the options of image URLs, images are not transformed if it is empty
+	ImageSigningKey string
 }
 
 func LoadServerConfiguration() ServerConfiguration {
@@ -21,6 +32,9 @@ func LoadServerConfiguration() ServerConfiguration {
 	viper.AddConfigPath(".")
 
 	viper.SetDefault("ConnectionString.Mongodb", "mongodb://0.0.0.0")
+	viper.SetDefault("SchedulerInterval", "30s")
+	viper.SetDefault("AssetPath", "./assets")
+	viper.SetDefault("ImageCachePath", "./imagecache")
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -36,5 +50,10 @@ func LoadServerConfiguration() ServerConfiguration {
 		panic(err)
 	}
 
+	// the scheduler ticks every interval, a ticker cannot be created without a positive interval
+	if c.SchedulerInterval <= 0 {
+		panic(fmt.Sprintf("SchedulerInterval must be greater than 0, is %s", c.SchedulerInterval))
+	}
+
 	return *c
 }
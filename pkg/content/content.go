@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
 	"github.com/google/uuid"
 )
 
@@ -19,11 +20,21 @@ type ContentVersion struct {
 type PublishStatus string
 
 type Content struct {
-	ID                  uuid.UUID   `bson:"_id"`
-	ContentDefinitionID uuid.UUID   `bson:"contentdefinition_id"`
-	Data                ContentData `bson:"data"`
-	Created             time.Time   `bson:"created"`
-	Updated             time.Time   `bson:"updated"`
+	ID                  uuid.UUID `bson:"_id"`
+	ContentDefinitionID uuid.UUID `bson:"contentdefinition_id"`
+	// ParentID is empty for content at the root of the content tree
+	ParentID uuid.UUID `bson:"parentId"`
+	// SortOrder is the position of the content among its siblings
+	SortOrder int         `bson:"sortOrder"`
+	Data      ContentData `bson:"data"`
+	// Routes are the paths of the published content, one per language
+	Routes []Route `bson:"routes"`
+	// Redirects are previous paths of the content
+	Redirects []Route   `bson:"redirects"`
+	Created   time.Time `bson:"created"`
+	Updated   time.Time `bson:"updated"`
+	// Revision is incremented on every write and is used to detect concurrent updates
+	Revision int `bson:"revision"`
 }
 
 // swagger: model ContentData
@@ -36,6 +47,15 @@ type ContentData struct {
 	Status  PublishStatus `bson:"status"`
 	// Tag IDs
 	Tags []string `bson:"tags,omitempty"`
+	// References are the IDs of the content referenced by reference properties, set when the version is published
+	References []uuid.UUID `bson:"references,omitempty"`
+	// Assets are the IDs of the assets used by asset properties and richtext, set when the version is published
+	Assets []uuid.UUID `bson:"assets,omitempty"`
+	// Locations are the values of geopoint properties, set when the version is published.
+	// Content is found by its nearest location, see ContentManagementRepository.ListPublishedContentNear
+	Locations []validator.GeoPoint `bson:"locations,omitempty"`
+	// Revision is incremented on every write and is used to detect concurrent updates
+	Revision int `bson:"revision"`
 }
 
 //! TODO: Is it better to handle localized values in field directly?
@@ -134,14 +154,18 @@ func (f ContentFactory) AddLanguage(c *ContentData, language string, addLocalize
 }
 
 // Creates a new content version from an existing version.
+// The new version is reconciled against the contentdefinition, values of renamed properties and sub-properties are kept and values of deleted ones are dropped.
 func (f ContentFactory) NewContentVersion(c Content, contentDefinition contentdefinition.ContentDefinition, version int, defaultLanguage string) (*ContentData, error) {
 
 	old := c.Data
 
 	contentData := &ContentData{
+		ContentID:  c.ID,
+		Version:    version,
 		Status:     Draft,
 		Created:    time.Now(),
 		Properties: make(ContentLanguage),
+		Tags:       old.Tags,
 	}
 
 	for lang, oldFields := range old.Properties {
@@ -155,7 +179,7 @@ func (f ContentFactory) NewContentVersion(c Content, contentDefinition contentde
 			// happends if the fields name has changed
 			// checking for ID is edge case when the old fields name has changed and a new field has the old fields name.00
 			if newfield, ok := contentData.Properties[lang][fieldname]; ok && field.ID == newfield.ID {
-				newfield.Value = field.Value
+				newfield.Value = reconcileValue(contentDefinition.Propertydefinitions[fieldname], field.Value)
 				contentData.Properties[lang][fieldname] = newfield
 			} else {
 
@@ -168,9 +192,15 @@ func (f ContentFactory) NewContentVersion(c Content, contentDefinition contentde
 		for newName, field := range contentDefinition.Propertydefinitions {
 
 			// if there is no match, the field is deleted from the contentdefinition
-			if match, ok := lookupfields[field.ID]; ok {
+			match, ok := lookupfields[field.ID]
+			if !ok {
+				continue
+			}
 
-				contentData.Properties[lang][newName] = match
+			// the field only exists in this language if the property is localized or this is the default language
+			if newfield, ok := contentData.Properties[lang][newName]; ok {
+				newfield.Value = reconcileValue(field, match.Value)
+				contentData.Properties[lang][newName] = newfield
 			}
 		}
 	}
@@ -192,7 +222,9 @@ func (c ContentData) CanEdit() bool {
 	return c.Status == Draft
 }
 
-func (f ContentFactory) SetField(contentData *ContentData, lang, fieldname string, value interface{}) error {
+// SetField sets the value of a field in lang. The value is converted to how it is stored by the type of the property,
+// the items of lists and the sub-properties of objects are converted by their definitions in contentDefinition.
+func (f ContentFactory) SetField(contentData *ContentData, lang, fieldname string, value interface{}, contentDefinition contentdefinition.ContentDefinition) error {
 
 	normalizedFieldname := strings.ToLower(fieldname)
 	if !contentData.CanEdit() {
@@ -212,7 +244,21 @@ func (f ContentFactory) SetField(contentData *ContentData, lang, fieldname strin
 	field, ok := contentFields[normalizedFieldname]
 
 	if !ok {
-		return errors.New(ErrMissingField)
+		return FieldError{Field: normalizedFieldname, Language: lang, Err: errors.New(ErrMissingField)}
+	}
+
+	// the property is looked up by ID, the version can be older than a rename of the property
+	pd := contentdefinition.PropertyDefinition{Type: field.Type}
+	for _, p := range contentDefinition.Propertydefinitions {
+		if p.ID == field.ID {
+			pd = p
+			break
+		}
+	}
+
+	value, err := fieldValue(pd, value)
+	if err != nil {
+		return FieldError{Field: normalizedFieldname, Language: lang, Err: err}
 	}
 
 	field.Value = value
@@ -4,8 +4,11 @@ package content
 
 import (
 	"testing"
+	"time"
 
 	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/richtext"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
@@ -158,6 +161,66 @@ func Test_NewContentVersion(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "renamed property uses new definition and deleted property is dropped",
+			contentdef: contentdefinition.ContentDefinition{
+				ID:   uuid.MustParse("d5d2ba13-7ef2-4ed3-b196-1d96ecca3bcb"),
+				Name: "test contentdef",
+				Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+					"heading": {
+						ID:        uuid.MustParse("6973eba3-24b1-44f3-ade1-83a5e3de5d1b"),
+						Type:      "string",
+						Localized: false,
+					},
+				},
+			},
+			existing: Content{
+				ID:                  uuid.New(),
+				ContentDefinitionID: uuid.MustParse("d5d2ba13-7ef2-4ed3-b196-1d96ecca3bcb"),
+				Data: ContentData{
+					Version: 0,
+					Status:  PreviouslyPublished,
+					Properties: ContentLanguage{
+						"defaultlang": ContentFields{
+							"title": ContentField{
+								ID:        uuid.MustParse("6973eba3-24b1-44f3-ade1-83a5e3de5d1b"),
+								Type:      "string",
+								Localized: true,
+								Value:     "title default locale",
+							},
+							"deleted": ContentField{
+								ID:    uuid.MustParse("dfddadc9-0aaa-48e4-8465-43a39559d94d"),
+								Type:  "string",
+								Value: "deleted",
+							},
+						},
+						"other": ContentFields{
+							"title": ContentField{
+								ID:        uuid.MustParse("6973eba3-24b1-44f3-ade1-83a5e3de5d1b"),
+								Type:      "string",
+								Localized: true,
+								Value:     "title other locale",
+							},
+						},
+					},
+				},
+			},
+			expect: Content{
+				Data: ContentData{
+					Properties: ContentLanguage{
+						"defaultlang": ContentFields{
+							"heading": ContentField{
+								ID:        uuid.MustParse("6973eba3-24b1-44f3-ade1-83a5e3de5d1b"),
+								Value:     "title default locale",
+								Type:      "string",
+								Localized: false,
+							},
+						},
+						"other": ContentFields{},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -178,14 +241,44 @@ func Test_NewContentVersion(t *testing.T) {
 
 func Test_SetField(t *testing.T) {
 
+	addressID := uuid.New()
+	streetID := uuid.New()
+	definition := contentdefinition.ContentDefinition{
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"addresses": {
+				ID:   addressID,
+				Type: contentdefinition.PropertyTypeList,
+				Items: &contentdefinition.PropertyDefinition{
+					Type: contentdefinition.PropertyTypeObject,
+					Properties: map[string]contentdefinition.PropertyDefinition{
+						"street": {ID: streetID, Type: contentdefinition.PropertyTypeText},
+					},
+				},
+			},
+		},
+	}
+
+	// typed returns a draft with the field "value" of type typ
+	typed := func(typ string, value interface{}) ContentData {
+		return ContentData{
+			Status: Draft,
+			Properties: ContentLanguage{
+				"default": ContentFields{
+					"value": ContentField{Type: typ, Value: value},
+				},
+			},
+		}
+	}
+
 	tests := []struct {
-		name      string
-		content   ContentData
-		expect    ContentData
-		lang      string
-		fieldname string
-		value     interface{}
-		expectErr string
+		name       string
+		content    ContentData
+		definition contentdefinition.ContentDefinition
+		expect     ContentData
+		lang       string
+		fieldname  string
+		value      interface{}
+		expectErr  string
 	}{
 		{
 			name: "set field ok",
@@ -213,6 +306,333 @@ func Test_SetField(t *testing.T) {
 			fieldname: "field",
 			value:     "bar",
 		},
+		{
+			name: "richtext is converted and sanitized",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"body": ContentField{Type: contentdefinition.PropertyTypeRichText},
+					},
+				},
+			},
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"body": ContentField{
+							Type: contentdefinition.PropertyTypeRichText,
+							Value: richtext.Document{Blocks: []richtext.Block{
+								{Type: richtext.Paragraph, Spans: []richtext.Span{{Text: "foo"}}},
+							}},
+						},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "body",
+			value:     map[string]interface{}{"html": `<p onclick="x()">foo<script>bar</script></p>`},
+		},
+		{
+			name: "datetime is stored in utc",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"start": ContentField{Type: contentdefinition.PropertyTypeDateTime},
+					},
+				},
+			},
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"start": ContentField{
+							Type:  contentdefinition.PropertyTypeDateTime,
+							Value: time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "start",
+			value:     "2022-05-01T10:00:00+02:00",
+		},
+		{
+			name: "date is reduced to the date",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"day": ContentField{Type: contentdefinition.PropertyTypeDate},
+					},
+				},
+			},
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"day": ContentField{Type: contentdefinition.PropertyTypeDate, Value: "2022-05-01"},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "day",
+			value:     "2022-05-01T23:00:00-07:00",
+		},
+		{
+			name: "datetime without offset",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"start": ContentField{Type: contentdefinition.PropertyTypeDateTime},
+					},
+				},
+			},
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"start": ContentField{Type: contentdefinition.PropertyTypeDateTime},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "start",
+			value:     "2022-05-01T10:00:00",
+			expectErr: validator.ErrInvalidDateTime,
+		},
+		{
+			name:      "number from string",
+			content:   typed(contentdefinition.PropertyTypeNumber, nil),
+			expect:    typed(contentdefinition.PropertyTypeNumber, 42.0),
+			lang:      "default",
+			fieldname: "value",
+			value:     " 42 ",
+		},
+		{
+			name:      "number from int",
+			content:   typed(contentdefinition.PropertyTypeNumber, nil),
+			expect:    typed(contentdefinition.PropertyTypeNumber, 7.0),
+			lang:      "default",
+			fieldname: "value",
+			value:     7,
+		},
+		{
+			name:      "empty number",
+			content:   typed(contentdefinition.PropertyTypeNumber, 1.0),
+			expect:    typed(contentdefinition.PropertyTypeNumber, nil),
+			lang:      "default",
+			fieldname: "value",
+			value:     "",
+		},
+		{
+			name:      "not a number",
+			content:   typed(contentdefinition.PropertyTypeNumber, 1.0),
+			expect:    typed(contentdefinition.PropertyTypeNumber, 1.0),
+			lang:      "default",
+			fieldname: "value",
+			value:     "forty two",
+			expectErr: ErrInvalidNumber,
+		},
+		{
+			name:      "bool from string",
+			content:   typed(contentdefinition.PropertyTypeBool, nil),
+			expect:    typed(contentdefinition.PropertyTypeBool, true),
+			lang:      "default",
+			fieldname: "value",
+			value:     "True",
+		},
+		{
+			name:      "not a bool",
+			content:   typed(contentdefinition.PropertyTypeBool, nil),
+			expect:    typed(contentdefinition.PropertyTypeBool, nil),
+			lang:      "default",
+			fieldname: "value",
+			value:     "yes",
+			expectErr: ErrInvalidBool,
+		},
+		{
+			name:      "text from number",
+			content:   typed(contentdefinition.PropertyTypeText, nil),
+			expect:    typed(contentdefinition.PropertyTypeText, "1.5"),
+			lang:      "default",
+			fieldname: "value",
+			value:     1.5,
+		},
+		{
+			name:      "not text",
+			content:   typed(contentdefinition.PropertyTypeText, nil),
+			expect:    typed(contentdefinition.PropertyTypeText, nil),
+			lang:      "default",
+			fieldname: "value",
+			value:     map[string]interface{}{"a": "b"},
+			expectErr: ErrInvalidText,
+		},
+		{
+			name:      "not an asset id",
+			content:   typed(contentdefinition.PropertyTypeAsset, nil),
+			expect:    typed(contentdefinition.PropertyTypeAsset, nil),
+			lang:      "default",
+			fieldname: "value",
+			value:     "logo.png",
+			expectErr: ErrInvalidAsset,
+		},
+		{
+			name:      "not a reference",
+			content:   typed(contentdefinition.PropertyTypeReference, nil),
+			expect:    typed(contentdefinition.PropertyTypeReference, nil),
+			lang:      "default",
+			fieldname: "value",
+			value:     42.0,
+			expectErr: "reference is not a content id",
+		},
+		{
+			name: "enum list",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"colors": ContentField{Type: contentdefinition.PropertyTypeEnum},
+					},
+				},
+			},
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"colors": ContentField{Type: contentdefinition.PropertyTypeEnum, Value: []string{"red", "green"}},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "colors",
+			value:     []interface{}{"red", "green"},
+		},
+		{
+			name: "empty enum list",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"colors": ContentField{Type: contentdefinition.PropertyTypeEnum, Value: "red"},
+					},
+				},
+			},
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"colors": ContentField{Type: contentdefinition.PropertyTypeEnum},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "colors",
+			value:     []interface{}{},
+		},
+		{
+			name: "enum number",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"colors": ContentField{Type: contentdefinition.PropertyTypeEnum},
+					},
+				},
+			},
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"colors": ContentField{Type: contentdefinition.PropertyTypeEnum},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "colors",
+			value:     3.0,
+			expectErr: "enum value is not a string",
+		},
+		{
+			name: "geopoint",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"location": ContentField{Type: contentdefinition.PropertyTypeGeoPoint},
+					},
+				},
+			},
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"location": ContentField{
+							Type:  contentdefinition.PropertyTypeGeoPoint,
+							Value: validator.GeoPoint{Type: "Point", Coordinates: []float64{18.0686, 59.3293}},
+						},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "location",
+			value:     map[string]interface{}{"lat": 59.3293, "lng": 18.0686},
+		},
+		{
+			name: "list of objects",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"addresses": ContentField{ID: addressID, Type: contentdefinition.PropertyTypeList},
+					},
+				},
+			},
+			definition: definition,
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"addresses": ContentField{
+							ID:   addressID,
+							Type: contentdefinition.PropertyTypeList,
+							Value: []interface{}{
+								ContentFields{"street": {ID: streetID, Type: contentdefinition.PropertyTypeText, Value: "Storgatan 1"}},
+							},
+						},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "addresses",
+			value:     []interface{}{map[string]interface{}{"street": "Storgatan 1"}},
+		},
+		{
+			name: "object with unknown sub-property",
+			content: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"addresses": ContentField{ID: addressID, Type: contentdefinition.PropertyTypeList},
+					},
+				},
+			},
+			definition: definition,
+			expect: ContentData{
+				Status: Draft,
+				Properties: ContentLanguage{
+					"default": ContentFields{
+						"addresses": ContentField{ID: addressID, Type: contentdefinition.PropertyTypeList},
+					},
+				},
+			},
+			lang:      "default",
+			fieldname: "addresses",
+			value:     []interface{}{map[string]interface{}{"city": "Stockholm"}},
+			expectErr: "[0]: " + ErrMissingField + ": city",
+		},
 		{
 			name: "missing locale",
 			content: ContentData{
@@ -269,12 +689,19 @@ func Test_SetField(t *testing.T) {
 
 			f := ContentFactory{}
 
-			err := f.SetField(&test.content, test.lang, test.fieldname, test.value)
+			err := f.SetField(&test.content, test.lang, test.fieldname, test.value, test.definition)
 
 			if test.expectErr != "" {
 				if assert.Error(t, err) {
 					assert.Equal(t, test.expectErr, err.Error())
 				}
+
+				// values that cannot be set are reported by field
+				var fieldErr FieldError
+				if test.expectErr != ErrNotDraft && test.expectErr != ErrMissingLanguage && assert.ErrorAs(t, err, &fieldErr) {
+					assert.Equal(t, test.fieldname, fieldErr.Field)
+					assert.Equal(t, test.lang, fieldErr.Language)
+				}
 			} else {
 				assert.NoError(t, err)
 			}
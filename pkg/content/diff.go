@@ -0,0 +1,148 @@
+package content
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// swagger:enum ChangeType
+type ChangeType string
+
+const (
+	FieldAdded   ChangeType = "added"
+	FieldRemoved ChangeType = "removed"
+	FieldChanged ChangeType = "changed"
+	FieldRenamed ChangeType = "renamed"
+)
+
+// FieldChange describes how a field differs between two versions.
+// swagger:model FieldChange
+type FieldChange struct {
+	Type ChangeType
+	ID   uuid.UUID
+	// Name of the field, for removed fields this is the name in the old version
+	Name string
+	// OldName is set when the field has been renamed
+	OldName string `json:",omitempty"`
+	From    interface{}
+	To      interface{}
+}
+
+// ContentDiff contains the changed fields per language between two versions of content
+// swagger:model ContentDiff
+type ContentDiff struct {
+	ContentID uuid.UUID
+	From      int
+	To        int
+	Languages map[string][]FieldChange
+}
+
+// Diff compares two versions field by field.
+// Fields are matched by ContentField.ID the same way as NewContentVersion does, so a field that
+// has changed name is reported as renamed and not as removed and added. A field that has changed
+// both name and value is reported as both renamed and changed.
+func Diff(from, to ContentData) ContentDiff {
+
+	diff := ContentDiff{
+		ContentID: to.ContentID,
+		From:      from.Version,
+		To:        to.Version,
+		Languages: make(map[string][]FieldChange),
+	}
+
+	languages := make(map[string]bool)
+	for lang := range from.Properties {
+		languages[lang] = true
+	}
+	for lang := range to.Properties {
+		languages[lang] = true
+	}
+
+	for lang := range languages {
+		changes := diffFields(from.Properties[lang], to.Properties[lang])
+
+		if len(changes) > 0 {
+			diff.Languages[lang] = changes
+		}
+	}
+
+	return diff
+}
+
+func diffFields(from, to ContentFields) []FieldChange {
+
+	changes := make([]FieldChange, 0)
+
+	// fields without ID are matched by name
+	key := func(name string, field ContentField) interface{} {
+		if field.ID == (uuid.UUID{}) {
+			return name
+		}
+		return field.ID
+	}
+
+	oldNames := make(map[interface{}]string)
+	for name, field := range from {
+		oldNames[key(name, field)] = name
+	}
+
+	for name, field := range to {
+		k := key(name, field)
+		oldName, ok := oldNames[k]
+
+		if !ok {
+			changes = append(changes, FieldChange{
+				Type: FieldAdded,
+				ID:   field.ID,
+				Name: name,
+				To:   field.Value,
+			})
+			continue
+		}
+
+		delete(oldNames, k)
+		old := from[oldName]
+
+		if oldName != name {
+			changes = append(changes, FieldChange{
+				Type:    FieldRenamed,
+				ID:      field.ID,
+				Name:    name,
+				OldName: oldName,
+				From:    old.Value,
+				To:      field.Value,
+			})
+		}
+
+		// a renamed field can have changed value as well, which is reported as a separate change
+		if !reflect.DeepEqual(old.Value, field.Value) {
+			changes = append(changes, FieldChange{
+				Type: FieldChanged,
+				ID:   field.ID,
+				Name: name,
+				From: old.Value,
+				To:   field.Value,
+			})
+		}
+	}
+
+	for _, name := range oldNames {
+		changes = append(changes, FieldChange{
+			Type: FieldRemoved,
+			ID:   from[name].ID,
+			Name: name,
+			From: from[name].Value,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name == changes[j].Name {
+			return changes[i].Type < changes[j].Type
+		}
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
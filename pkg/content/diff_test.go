@@ -0,0 +1,139 @@
+//go:build unit
+
+package content
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Diff(t *testing.T) {
+
+	titleID := uuid.MustParse("6973eba3-24b1-44f3-ade1-83a5e3de5d1b")
+	bodyID := uuid.MustParse("a1f6da93-80c9-4315-a012-1ea4249d7413")
+	priceID := uuid.MustParse("b2184714-4bae-4c50-9642-98fc5cadab86")
+
+	tests := []struct {
+		name   string
+		from   ContentData
+		to     ContentData
+		expect map[string][]FieldChange
+	}{
+		{
+			name: "no changes",
+			from: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {"title": {ID: titleID, Value: "foo"}},
+				},
+			},
+			to: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {"title": {ID: titleID, Value: "foo"}},
+				},
+			},
+			expect: map[string][]FieldChange{},
+		},
+		{
+			name: "added, removed and changed",
+			from: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {
+						"title": {ID: titleID, Value: "foo"},
+						"body":  {ID: bodyID, Value: "body"},
+					},
+				},
+			},
+			to: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {
+						"title": {ID: titleID, Value: "bar"},
+						"price": {ID: priceID, Value: 10},
+					},
+				},
+			},
+			expect: map[string][]FieldChange{
+				"sv-SE": {
+					{Type: FieldRemoved, ID: bodyID, Name: "body", From: "body"},
+					{Type: FieldAdded, ID: priceID, Name: "price", To: 10},
+					{Type: FieldChanged, ID: titleID, Name: "title", From: "foo", To: "bar"},
+				},
+			},
+		},
+		{
+			name: "renamed field is matched by id",
+			from: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {
+						"title": {ID: titleID, Value: "foo"},
+					},
+				},
+			},
+			to: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {
+						"heading": {ID: titleID, Value: "foo"},
+						// new field with the renamed fields old name
+						"title": {ID: bodyID, Value: "new"},
+					},
+				},
+			},
+			expect: map[string][]FieldChange{
+				"sv-SE": {
+					{Type: FieldRenamed, ID: titleID, Name: "heading", OldName: "title", From: "foo", To: "foo"},
+					{Type: FieldAdded, ID: bodyID, Name: "title", To: "new"},
+				},
+			},
+		},
+		{
+			name: "renamed and changed field",
+			from: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {
+						"title": {ID: titleID, Value: "foo"},
+					},
+				},
+			},
+			to: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {
+						"heading": {ID: titleID, Value: "bar"},
+					},
+				},
+			},
+			expect: map[string][]FieldChange{
+				"sv-SE": {
+					{Type: FieldChanged, ID: titleID, Name: "heading", From: "foo", To: "bar"},
+					{Type: FieldRenamed, ID: titleID, Name: "heading", OldName: "title", From: "foo", To: "bar"},
+				},
+			},
+		},
+		{
+			name: "added language",
+			from: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {"title": {ID: titleID, Value: "foo"}},
+				},
+			},
+			to: ContentData{
+				Properties: ContentLanguage{
+					"sv-SE": {"title": {ID: titleID, Value: "foo"}},
+					"en-US": {"title": {ID: titleID, Value: "bar"}},
+				},
+			},
+			expect: map[string][]FieldChange{
+				"en-US": {
+					{Type: FieldAdded, ID: titleID, Name: "title", To: "bar"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := Diff(test.from, test.to)
+			assert.Equal(t, test.expect, actual.Languages)
+		})
+	}
+}
@@ -6,3 +6,19 @@ const ErrMissingVersion = "version of content does not exist"
 const ErrMissingLanguage = "language does not exist for content"
 const ErrMissingField = "field does not exist on content"
 const ErrNotDraft = "content version is not a draft"
+const ErrMoveBelowItself = "content cannot be moved below itself or its descendants"
+const ErrNotSiblings = "order must contain every child of the parent exactly once"
+const ErrPathInUse = "url is already used by other content"
+const ErrMissingReference = "referenced content does not exist"
+const ErrReferenceNotAllowed = "referenced content is not of an allowed contentdefinition"
+const ErrContentReferenced = "content is referenced by published content"
+const ErrMissingAsset = "referenced asset does not exist"
+const ErrNotUnique = "value is already used by other content"
+const ErrValidation = "content is not valid"
+const ErrInvalidText = "value is not text"
+const ErrInvalidNumber = "value is not a number"
+const ErrInvalidBool = "value is not a bool"
+const ErrInvalidAsset = "value is not an asset id"
+const ErrStartPageNotAtRoot = "start page must be at the root of the content tree"
+const ErrVersionExists = "content version already exists"
+const ErrNotPublished = "content version is not the published version"
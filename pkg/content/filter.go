@@ -0,0 +1,480 @@
+package content
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// swagger:enum FilterOperator
+type FilterOperator string
+
+const (
+	FilterEq       FilterOperator = "eq"
+	FilterNe       FilterOperator = "ne"
+	FilterGt       FilterOperator = "gt"
+	FilterGe       FilterOperator = "ge"
+	FilterLt       FilterOperator = "lt"
+	FilterLe       FilterOperator = "le"
+	FilterContains FilterOperator = "contains"
+
+	FilterAnd FilterOperator = "and"
+	FilterOr  FilterOperator = "or"
+	FilterNot FilterOperator = "not"
+)
+
+const ErrInvalidFilter = "invalid filter"
+
+// Filter is a parsed filter expression.
+// A comparison has Field, Operator & Value set, and/or/not have Operator & Filters set.
+//
+// The syntax is <field> <operator> <value>, comparisons are combined with and, or, not and parentheses, ie:
+//
+//	price gt 10 and (title contains "news" or not featured eq true)
+//
+// Values are strings in double quotes, numbers, true, false or null. Dates and datetimes are compared with strings,
+// ie published ge "2022-01-01" or start lt "2022-06-01T12:00:00+02:00".
+type Filter struct {
+	Field    string
+	Operator FilterOperator
+	Value    interface{}
+	Filters  []Filter
+}
+
+var comparisonOperators = map[FilterOperator]string{
+	FilterEq:       "$eq",
+	FilterNe:       "$ne",
+	FilterGt:       "$gt",
+	FilterGe:       "$gte",
+	FilterLt:       "$lt",
+	FilterLe:       "$lte",
+	FilterContains: "$regex",
+}
+
+// ParseFilter parses a filter expression, an empty expression returns an empty Filter.
+func ParseFilter(expr string) (Filter, error) {
+
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if len(tokens) == 0 {
+		return Filter{}, nil
+	}
+
+	p := &filterParser{tokens: tokens}
+	f, err := p.or()
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if !p.done() {
+		return Filter{}, p.unexpected()
+	}
+
+	return f, nil
+}
+
+// IsEmpty returns true if the filter matches everything
+func (f Filter) IsEmpty() bool {
+	return f.Operator == ""
+}
+
+// Compile validates the filter against the property definitions and returns the Mongo query of content documents.
+// Fields must exist on at least one of the content definitions and have the same type on every definition where
+// they exist. Localized fields are compared in language, unlocalized fields in defaultLanguage.
+func (f Filter) Compile(definitions []contentdefinition.ContentDefinition, language, defaultLanguage string) (bson.M, error) {
+
+	switch f.Operator {
+	case "":
+		return bson.M{}, nil
+	case FilterAnd, FilterOr, FilterNot:
+		queries := bson.A{}
+		for _, child := range f.Filters {
+			q, err := child.Compile(definitions, language, defaultLanguage)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, q)
+		}
+
+		switch f.Operator {
+		case FilterAnd:
+			return bson.M{"$and": queries}, nil
+		case FilterOr:
+			return bson.M{"$or": queries}, nil
+		default:
+			return bson.M{"$nor": queries}, nil
+		}
+	}
+
+	propertyType, languages, err := filterProperty(f.Field, definitions, language, defaultLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := filterFieldValue(f.Field, propertyType, f.Operator, f.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := bson.M{comparisonOperators[f.Operator]: value}
+	if f.Operator == FilterContains {
+		condition = bson.M{"$regex": regexp.QuoteMeta(f.Value.(string)), "$options": "i"}
+	}
+
+	// a field that is localized on some content definitions but not on others
+	queries := bson.A{}
+	for _, lang := range languages {
+		queries = append(queries, bson.M{fmt.Sprintf("data.properties.%s.%s.value", lang, f.Field): condition})
+	}
+
+	if len(queries) == 1 {
+		return queries[0].(bson.M), nil
+	}
+
+	return bson.M{"$or": queries}, nil
+}
+
+// filterProperty returns the type of the field and what languages its value is read from
+func filterProperty(field string, definitions []contentdefinition.ContentDefinition, language, defaultLanguage string) (string, []string, error) {
+
+	propertyType := ""
+	languages := make([]string, 0)
+
+	for _, cd := range definitions {
+		pd, ok := cd.Propertydefinitions[field]
+		if !ok {
+			continue
+		}
+
+		if propertyType != "" && propertyType != pd.Type {
+			return "", nil, fmt.Errorf("%s: field %q has different types on the content definitions", ErrInvalidFilter, field)
+		}
+		propertyType = pd.Type
+
+		lang := defaultLanguage
+		if pd.Localized {
+			lang = language
+		}
+
+		if !containsString(languages, lang) {
+			languages = append(languages, lang)
+		}
+	}
+
+	if propertyType == "" {
+		return "", nil, fmt.Errorf("%s: field %q does not exist", ErrInvalidFilter, field)
+	}
+
+	return propertyType, languages, nil
+}
+
+// filterFieldValue checks that the field can be compared with value and returns value as it is stored.
+// Dates and datetimes are written as strings in filters, datetimes are compared in UTC.
+func filterFieldValue(field, propertyType string, op FilterOperator, value interface{}) (interface{}, error) {
+
+	if value == nil {
+		if op == FilterEq || op == FilterNe {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: null can only be compared with eq or ne", ErrInvalidFilter)
+	}
+
+	ok := false
+	switch propertyType {
+	case contentdefinition.PropertyTypeText:
+		_, ok = value.(string)
+	case contentdefinition.PropertyTypeNumber:
+		_, ok = value.(float64)
+		ok = ok && op != FilterContains
+	case contentdefinition.PropertyTypeBool:
+		_, ok = value.(bool)
+		ok = ok && (op == FilterEq || op == FilterNe)
+	case contentdefinition.PropertyTypeReference, contentdefinition.PropertyTypeEnum:
+		// eq matches a single value and any value of a list
+		_, ok = value.(string)
+		ok = ok && (op == FilterEq || op == FilterNe)
+	case contentdefinition.PropertyTypeDate:
+		if s, isString := value.(string); isString && op != FilterContains {
+			date, err := validator.ParseDate(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", ErrInvalidFilter, err)
+			}
+			return date, nil
+		}
+	case contentdefinition.PropertyTypeDateTime:
+		if s, isString := value.(string); isString && op != FilterContains {
+			t, err := validator.ParseDateTime(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", ErrInvalidFilter, err)
+			}
+			return t, nil
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%s: cannot compare %s field %q with %s %v", ErrInvalidFilter, propertyType, field, op, value)
+	}
+
+	return value, nil
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+type filterTokenKind int
+
+const (
+	tokenWord filterTokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	value string
+	pos   int
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+
+	tokens := make([]filterToken, 0)
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenOpen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenClose, value: ")", pos: i})
+			i++
+		case r == '"':
+			start := i
+			sb := strings.Builder{}
+			i++
+			closed := false
+
+			for i < len(runes) {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					sb.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+
+			if !closed {
+				return nil, fmt.Errorf("%s: unterminated string at position %d", ErrInvalidFilter, start)
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, value: sb.String(), pos: start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, value: string(runes[start:i]), pos: start})
+		}
+	}
+
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser, and binds harder than or, and not binds harder than and.
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peekKeyword(keyword FilterOperator) bool {
+	if p.done() {
+		return false
+	}
+	t := p.tokens[p.pos]
+	return t.kind == tokenWord && strings.EqualFold(t.value, string(keyword))
+}
+
+func (p *filterParser) unexpected() error {
+	if p.done() {
+		return fmt.Errorf("%s: unexpected end of filter", ErrInvalidFilter)
+	}
+	t := p.tokens[p.pos]
+	return fmt.Errorf("%s: unexpected %q at position %d", ErrInvalidFilter, t.value, t.pos)
+}
+
+func (p *filterParser) or() (Filter, error) {
+	return p.logical(FilterOr, p.and)
+}
+
+func (p *filterParser) and() (Filter, error) {
+	return p.logical(FilterAnd, p.not)
+}
+
+func (p *filterParser) logical(op FilterOperator, operand func() (Filter, error)) (Filter, error) {
+
+	f, err := operand()
+	if err != nil {
+		return Filter{}, err
+	}
+
+	filters := []Filter{f}
+	for p.peekKeyword(op) {
+		p.pos++
+		f, err := operand()
+		if err != nil {
+			return Filter{}, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+
+	return Filter{Operator: op, Filters: filters}, nil
+}
+
+func (p *filterParser) not() (Filter, error) {
+
+	if p.peekKeyword(FilterNot) {
+		p.pos++
+		f, err := p.not()
+		if err != nil {
+			return Filter{}, err
+		}
+		return Filter{Operator: FilterNot, Filters: []Filter{f}}, nil
+	}
+
+	if !p.done() && p.tokens[p.pos].kind == tokenOpen {
+		p.pos++
+		f, err := p.or()
+		if err != nil {
+			return Filter{}, err
+		}
+
+		if p.done() || p.tokens[p.pos].kind != tokenClose {
+			return Filter{}, p.unexpected()
+		}
+		p.pos++
+		return f, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *filterParser) comparison() (Filter, error) {
+
+	if len(p.tokens)-p.pos < 3 {
+		p.pos = len(p.tokens)
+		return Filter{}, p.unexpected()
+	}
+
+	field, op, value := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+
+	if field.kind != tokenWord {
+		return Filter{}, p.unexpected()
+	}
+	p.pos++
+
+	operator := FilterOperator(strings.ToLower(op.value))
+	if _, ok := comparisonOperators[operator]; op.kind != tokenWord || !ok {
+		return Filter{}, p.unexpected()
+	}
+	p.pos++
+
+	v, err := filterValue(value)
+	if err != nil {
+		return Filter{}, err
+	}
+	p.pos++
+
+	return Filter{Field: field.value, Operator: operator, Value: v}, nil
+}
+
+func filterValue(t filterToken) (interface{}, error) {
+
+	switch t.kind {
+	case tokenString:
+		return t.value, nil
+	case tokenWord:
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+
+		if n, err := strconv.ParseFloat(t.value, 64); err == nil {
+			return n, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: expected value at position %d, got %q", ErrInvalidFilter, t.pos, t.value)
+}
+
+// SortField returns the path of the field to sort content by, see db.Page.
+// Content is sorted by created, updated or by a property, prefix with - for descending order.
+// Properties are sorted in the same language they are filtered in, so a property must have the same type
+// and be localized the same way on every content definition where it exists.
+func SortField(sort string, definitions []contentdefinition.ContentDefinition, language, defaultLanguage string) (string, error) {
+
+	field := strings.TrimPrefix(sort, "-")
+	prefix := sort[:len(sort)-len(field)]
+
+	switch field {
+	case "":
+		return "", nil
+	case "created", "updated":
+		return prefix + field, nil
+	}
+
+	propertyType, languages, err := filterProperty(field, definitions, language, defaultLanguage)
+	if err != nil {
+		return "", fmt.Errorf("%s%s", db.ErrInvalidSort, strings.TrimPrefix(err.Error(), ErrInvalidFilter))
+	}
+
+	if len(languages) > 1 {
+		return "", fmt.Errorf("%s: field %q is localized on some content definitions", db.ErrInvalidSort, field)
+	}
+
+	switch propertyType {
+	case contentdefinition.PropertyTypeText, contentdefinition.PropertyTypeNumber, contentdefinition.PropertyTypeBool,
+		contentdefinition.PropertyTypeDate, contentdefinition.PropertyTypeDateTime:
+	default:
+		return "", fmt.Errorf("%s: cannot sort by %s field %q", db.ErrInvalidSort, propertyType, field)
+	}
+
+	return fmt.Sprintf("%sdata.properties.%s.%s.value", prefix, languages[0], field), nil
+}
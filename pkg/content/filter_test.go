@@ -0,0 +1,248 @@
+//go:build unit
+
+package content
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_ParseFilter(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		expr   string
+		expect Filter
+		err    bool
+	}{
+		{
+			name:   "empty filter",
+			expr:   "  ",
+			expect: Filter{},
+		},
+		{
+			name:   "comparison",
+			expr:   "price gt 10",
+			expect: Filter{Field: "price", Operator: FilterGt, Value: float64(10)},
+		},
+		{
+			name:   "quoted string with escaped quote",
+			expr:   `title contains "a \"b\" c"`,
+			expect: Filter{Field: "title", Operator: FilterContains, Value: `a "b" c`},
+		},
+		{
+			name: "and binds harder than or",
+			expr: "a eq true or b eq null and c ne false",
+			expect: Filter{Operator: FilterOr, Filters: []Filter{
+				{Field: "a", Operator: FilterEq, Value: true},
+				{Operator: FilterAnd, Filters: []Filter{
+					{Field: "b", Operator: FilterEq, Value: nil},
+					{Field: "c", Operator: FilterNe, Value: false},
+				}},
+			}},
+		},
+		{
+			name: "parentheses and not",
+			expr: `NOT (a eq "x" OR b le -1.5)`,
+			expect: Filter{Operator: FilterNot, Filters: []Filter{
+				{Operator: FilterOr, Filters: []Filter{
+					{Field: "a", Operator: FilterEq, Value: "x"},
+					{Field: "b", Operator: FilterLe, Value: -1.5},
+				}},
+			}},
+		},
+		{name: "unknown operator", expr: "a like 1", err: true},
+		{name: "missing value", expr: "a eq", err: true},
+		{name: "unquoted string", expr: "a eq foo", err: true},
+		{name: "unterminated string", expr: `a eq "foo`, err: true},
+		{name: "missing parenthesis", expr: "(a eq 1", err: true},
+		{name: "trailing tokens", expr: "a eq 1 b", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ParseFilter(test.expr)
+
+			if test.err {
+				assert.Error(t, err)
+				assert.True(t, strings.HasPrefix(err.Error(), ErrInvalidFilter))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, actual)
+		})
+	}
+}
+
+func Test_CompileFilter(t *testing.T) {
+
+	definitions := []contentdefinition.ContentDefinition{
+		{
+			Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+				"title":    {Type: contentdefinition.PropertyTypeText, Localized: true},
+				"price":    {Type: contentdefinition.PropertyTypeNumber},
+				"featured": {Type: contentdefinition.PropertyTypeBool},
+				"author":   {Type: contentdefinition.PropertyTypeReference},
+				"day":      {Type: contentdefinition.PropertyTypeDate},
+				"start":    {Type: contentdefinition.PropertyTypeDateTime},
+				"color":    {Type: contentdefinition.PropertyTypeEnum},
+			},
+		},
+		{
+			Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+				"title": {Type: contentdefinition.PropertyTypeText},
+				"price": {Type: contentdefinition.PropertyTypeNumber},
+				"label": {Type: contentdefinition.PropertyTypeText},
+			},
+		},
+		{
+			Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+				"label": {Type: contentdefinition.PropertyTypeNumber},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		expr   string
+		expect bson.M
+		err    bool
+	}{
+		{
+			name:   "empty filter matches everything",
+			expect: bson.M{},
+		},
+		{
+			name:   "unlocalized field is compared in default language",
+			expr:   "price ge 10",
+			expect: bson.M{"data.properties.sv-SE.price.value": bson.M{"$gte": float64(10)}},
+		},
+		{
+			name: "field localized on some definitions is compared in both languages",
+			expr: `title contains "a.b"`,
+			expect: bson.M{"$or": bson.A{
+				bson.M{"data.properties.en-US.title.value": bson.M{"$regex": `a\.b`, "$options": "i"}},
+				bson.M{"data.properties.sv-SE.title.value": bson.M{"$regex": `a\.b`, "$options": "i"}},
+			}},
+		},
+		{
+			name: "logical operators",
+			expr: "not featured eq true and price lt 5",
+			expect: bson.M{"$and": bson.A{
+				bson.M{"$nor": bson.A{bson.M{"data.properties.sv-SE.featured.value": bson.M{"$eq": true}}}},
+				bson.M{"data.properties.sv-SE.price.value": bson.M{"$lt": float64(5)}},
+			}},
+		},
+		{
+			name:   "null matches missing values",
+			expr:   "featured eq null",
+			expect: bson.M{"data.properties.sv-SE.featured.value": bson.M{"$eq": nil}},
+		},
+		{
+			name:   "reference",
+			expr:   `author eq "9b0ffd5e-1c7b-4e5b-9b2c-56e6f3c0d8a1"`,
+			expect: bson.M{"data.properties.sv-SE.author.value": bson.M{"$eq": "9b0ffd5e-1c7b-4e5b-9b2c-56e6f3c0d8a1"}},
+		},
+		{
+			name:   "enum",
+			expr:   `color ne "red"`,
+			expect: bson.M{"data.properties.sv-SE.color.value": bson.M{"$ne": "red"}},
+		},
+		{
+			name:   "date",
+			expr:   `day ge "2022-05-01"`,
+			expect: bson.M{"data.properties.sv-SE.day.value": bson.M{"$gte": "2022-05-01"}},
+		},
+		{
+			name:   "datetime is compared in utc",
+			expr:   `start lt "2022-05-01T10:00:00+02:00"`,
+			expect: bson.M{"data.properties.sv-SE.start.value": bson.M{"$lt": time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)}},
+		},
+		{name: "unknown field", expr: "missing eq 1", err: true},
+		{name: "invalid date", expr: `day eq "2022-13-01"`, err: true},
+		{name: "datetime without offset", expr: `start gt "2022-05-01T10:00:00"`, err: true},
+		{name: "contains on date", expr: `day contains "2022"`, err: true},
+		{name: "date compared with number", expr: "day gt 2022", err: true},
+		{name: "contains on reference", expr: `author contains "9b0f"`, err: true},
+		{name: "enum compared with gt", expr: `color gt "red"`, err: true},
+		{name: "number compared with string", expr: `price eq "10"`, err: true},
+		{name: "contains on number", expr: "price contains 1", err: true},
+		{name: "bool compared with gt", expr: "featured gt true", err: true},
+		{name: "null compared with gt", expr: "price gt null", err: true},
+		{name: "field with different types", expr: `label eq "x"`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := ParseFilter(test.expr)
+			assert.NoError(t, err)
+
+			actual, err := f.Compile(definitions, "en-US", "sv-SE")
+
+			if test.err {
+				assert.Error(t, err)
+				assert.True(t, strings.HasPrefix(err.Error(), ErrInvalidFilter))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, actual)
+		})
+	}
+}
+
+func Test_SortField(t *testing.T) {
+
+	definitions := []contentdefinition.ContentDefinition{
+		{
+			Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+				"name":  {Type: contentdefinition.PropertyTypeText, Localized: true},
+				"price": {Type: contentdefinition.PropertyTypeNumber},
+				"label": {Type: contentdefinition.PropertyTypeText, Localized: true},
+				"start": {Type: contentdefinition.PropertyTypeDateTime},
+			},
+		},
+		{
+			Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+				"label": {Type: contentdefinition.PropertyTypeText},
+			},
+		},
+	}
+
+	tests := []struct {
+		sort   string
+		expect string
+		err    bool
+	}{
+		{sort: "", expect: ""},
+		{sort: "-created", expect: "-created"},
+		{sort: "updated", expect: "updated"},
+		{sort: "name", expect: "data.properties.en-US.name.value"},
+		{sort: "-price", expect: "-data.properties.sv-SE.price.value"},
+		{sort: "start", expect: "data.properties.sv-SE.start.value"},
+		{sort: "missing", err: true},
+		{sort: "label", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sort, func(t *testing.T) {
+			actual, err := SortField(test.sort, definitions, "en-US", "sv-SE")
+
+			if test.err {
+				assert.Error(t, err)
+				assert.True(t, strings.HasPrefix(err.Error(), db.ErrInvalidSort))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, actual)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package content
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const ErrInvalidFieldName = "field name cannot contain '.' or start with '$'"
+
+// FieldProjection limits which properties of the content are read from the database.
+// Properties are stored per language, so the projection contains every field in every language.
+// An empty FieldProjection reads every property.
+type FieldProjection struct {
+	Fields    []string
+	Languages []string
+}
+
+func (p FieldProjection) Validate() error {
+
+	for _, f := range p.Fields {
+		if f == "" || strings.Contains(f, ".") || strings.HasPrefix(f, "$") {
+			return errors.New(ErrInvalidFieldName)
+		}
+	}
+
+	return nil
+}
+
+// Document returns the projection of content documents, nil if every property should be read.
+func (p FieldProjection) Document() bson.M {
+
+	if len(p.Fields) == 0 {
+		return nil
+	}
+
+	projection := bson.M{
+		"contentdefinition_id": 1,
+		"parentId":             1,
+		"sortOrder":            1,
+		"routes":               1,
+		"created":              1,
+		"updated":              1,
+		"revision":             1,
+		"data.contentId":       1,
+		"data.version":         1,
+		"data.status":          1,
+		"data.created":         1,
+		"data.tags":            1,
+		"data.references":      1,
+		"data.assets":          1,
+		"data.locations":       1,
+		"data.revision":        1,
+	}
+
+	for _, lang := range p.Languages {
+		for _, f := range p.Fields {
+			projection["data.properties."+lang+"."+f] = 1
+		}
+	}
+
+	return projection
+}
@@ -0,0 +1,45 @@
+//go:build unit
+
+package content
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FieldProjection(t *testing.T) {
+
+	t.Run("empty projection reads every property", func(t *testing.T) {
+		p := FieldProjection{Languages: []string{"sv-SE"}}
+
+		assert.NoError(t, p.Validate())
+		assert.Nil(t, p.Document())
+	})
+
+	t.Run("fields in every language", func(t *testing.T) {
+		p := FieldProjection{
+			Fields:    []string{"name", "price"},
+			Languages: []string{"sv-SE", "en-US"},
+		}
+
+		assert.NoError(t, p.Validate())
+
+		doc := p.Document()
+		assert.Equal(t, 1, doc["data.properties.sv-SE.name"])
+		assert.Equal(t, 1, doc["data.properties.sv-SE.price"])
+		assert.Equal(t, 1, doc["data.properties.en-US.name"])
+		assert.Equal(t, 1, doc["data.properties.en-US.price"])
+		assert.Equal(t, 1, doc["data.status"])
+		assert.NotContains(t, doc, "data.properties")
+	})
+
+	for _, field := range []string{"a.b", "$where", ""} {
+		t.Run("invalid field "+field, func(t *testing.T) {
+			p := FieldProjection{Fields: []string{field}}
+
+			assert.Equal(t, errors.New(ErrInvalidFieldName), p.Validate())
+		})
+	}
+}
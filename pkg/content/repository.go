@@ -2,7 +2,11 @@ package content
 
 import (
 	"context"
+	"fmt"
+	"sort"
 
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/db"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -12,17 +16,25 @@ import (
 const contentCollection = "content"
 const contentVersionCollection = "contentversion"
 
+// childrenCollection has a document per parent that is written when the children of the parent are ordered, see LockChildren
+const childrenCollection = "children"
+
+// uniqueValueCollection has a document per value of a unique property claimed by published content, see UniqueValue
+const uniqueValueCollection = "uniquevalues"
+
 type ContentManagementRepository struct {
 	client *mongo.Client
+	uow    db.UnitOfWork
 }
 
 func NewContentRepository(c *mongo.Client) ContentManagementRepository {
 	return ContentManagementRepository{
 		client: c,
+		uow:    db.NewUnitOfWork(c),
 	}
 }
 
-//! TODO: This should be done in a transaction
+// Content and its first version is inserted in the same transaction, so a version is never orphaned.
 func (c ContentManagementRepository) CreateContent(ctx context.Context, content Content, workspace uuid.UUID) (uuid.UUID, error) {
 
 	if content.ID == (uuid.UUID{}) {
@@ -31,17 +43,21 @@ func (c ContentManagementRepository) CreateContent(ctx context.Context, content
 
 	content.Data.ContentID = content.ID
 
-	_, err := c.client.Database(workspace.String()).
-		Collection(contentCollection).
-		InsertOne(ctx, content)
+	err := c.uow.Do(ctx, func(ctx context.Context) error {
+		_, err := c.client.Database(workspace.String()).
+			Collection(contentCollection).
+			InsertOne(ctx, content)
 
-	if err != nil {
-		return uuid.UUID{}, err
-	}
+		if err != nil {
+			return err
+		}
 
-	_, err = c.client.Database(workspace.String()).
-		Collection(contentVersionCollection).
-		InsertOne(ctx, content.Data)
+		_, err = c.client.Database(workspace.String()).
+			Collection(contentVersionCollection).
+			InsertOne(ctx, content.Data)
+
+		return err
+	})
 
 	if err != nil {
 		return uuid.UUID{}, err
@@ -81,6 +97,114 @@ func (c ContentManagementRepository) GetContent(ctx context.Context, id uuid.UUI
 	return *content, nil
 }
 
+// GetContentNode returns content without its data, for changes to the content tree that do not depend on a version.
+// Returns mongo.ErrNoDocuments if the content does not exist.
+func (c ContentManagementRepository) GetContentNode(ctx context.Context, id uuid.UUID, workspace uuid.UUID) (Content, error) {
+
+	content := &Content{}
+	err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		FindOne(
+			ctx,
+			bson.M{"_id": id},
+			options.FindOne().SetProjection(bson.M{"data": 0})).
+		Decode(content)
+
+	if err != nil {
+		return Content{}, err
+	}
+
+	return *content, nil
+}
+
+// GetPublishedContent returns content with its published version, only the properties in projection are read.
+// Returns mongo.ErrNoDocuments if the content does not exist or is not published.
+func (c ContentManagementRepository) GetPublishedContent(ctx context.Context, id uuid.UUID, projection FieldProjection, workspace uuid.UUID) (Content, error) {
+
+	opts := options.FindOne()
+	if doc := projection.Document(); doc != nil {
+		opts.SetProjection(doc)
+	}
+
+	content := &Content{}
+	err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		FindOne(ctx, bson.M{"_id": id, "data.status": Published}, opts).
+		Decode(content)
+
+	if err != nil {
+		return Content{}, err
+	}
+
+	return *content, nil
+}
+
+// ListPublishedContent returns a page of published content that has any of tags and matches filter, only the properties in projection are read.
+func (c ContentManagementRepository) ListPublishedContent(ctx context.Context, tags []string, filter bson.M, projection FieldProjection, page db.Page, workspace uuid.UUID) ([]Content, db.PageResult, error) {
+
+	query := bson.M{"data.status": Published}
+
+	if len(tags) > 0 {
+		query["data.tags"] = bson.M{"$in": tags}
+	}
+
+	if len(filter) > 0 {
+		query["$and"] = bson.A{filter}
+	}
+
+	return c.findPage(ctx, query, page, projection.Document(), workspace)
+}
+
+// NearbyContent is published content and the distance in meters from its nearest location to a point
+type NearbyContent struct {
+	Content  `bson:",inline"`
+	Distance float64 `bson:"distance"`
+}
+
+// ListPublishedContentNear returns published content with a location within maxDistance meters of point, nearest first.
+// Like ListPublishedContent the content must have any of tags, at most limit items are returned.
+func (c ContentManagementRepository) ListPublishedContentNear(ctx context.Context, point validator.GeoPoint, maxDistance float64, tags []string, projection FieldProjection, limit int, workspace uuid.UUID) ([]NearbyContent, error) {
+
+	query := bson.M{"data.status": Published}
+
+	if len(tags) > 0 {
+		query["data.tags"] = bson.M{"$in": tags}
+	}
+
+	// $geoNear sorts by distance and must be the first stage, it uses the 2dsphere index of data.locations
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: bson.M{
+			"near":          point,
+			"key":           "data.locations",
+			"distanceField": "distance",
+			"maxDistance":   maxDistance,
+			"spherical":     true,
+			"query":         query,
+		}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	if p := projection.Document(); p != nil {
+		p["distance"] = 1
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: p}})
+	}
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Aggregate(ctx, pipeline)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := []NearbyContent{}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (c ContentManagementRepository) UpdateContentData(
 	ctx context.Context,
 	id uuid.UUID,
@@ -101,17 +225,35 @@ func (c ContentManagementRepository) UpdateContentData(
 		return err
 	}
 
-	_, err = c.client.Database(workspace.String()).
-		Collection(contentVersionCollection).
-		UpdateOne(
-			ctx,
-			bson.M{"contentId": id, "version": updated.Version},
-			bson.M{"$set": updated}, options.Update().SetUpsert(true))
+	col := c.client.Database(workspace.String()).Collection(contentVersionCollection)
+
+	// updateFn returning another version than the one read creates a new version.
+	// The version number is unique per content, so two writers creating the same version conflict.
+	if updated.Version != contentData.Version {
+		updated.Revision = 0
+
+		_, err = col.InsertOne(ctx, updated)
+
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%s: version %d", ErrVersionExists, updated.Version)
+		}
+
+		return err
+	}
+
+	filter := bson.M{"contentId": id, "version": updated.Version, "revision": db.RevisionFilter(contentData.Revision)}
+	updated.Revision = contentData.Revision + 1
+
+	res, err := col.UpdateOne(ctx, filter, bson.M{"$set": updated})
 
 	if err != nil {
 		return err
 	}
 
+	if res.MatchedCount == 0 {
+		return db.RevisionConflict(ctx, col, bson.M{"contentId": id, "version": updated.Version})
+	}
+
 	return nil
 }
 
@@ -134,61 +276,73 @@ func (c ContentManagementRepository) UpdateContent(
 		return err
 	}
 
-	_, err = c.client.Database(workspace.String()).
-		Collection(contentCollection).
-		UpdateOne(
-			ctx,
-			bson.M{"_id": id},
-			bson.M{"$set": updated})
+	col := c.client.Database(workspace.String()).Collection(contentCollection)
+	filter := bson.M{"_id": id, "revision": db.RevisionFilter(content.Revision)}
+	updated.Revision = content.Revision + 1
+
+	res, err := col.UpdateOne(ctx, filter, bson.M{"$set": updated})
 
 	if err != nil {
 		return err
 	}
 
+	if res.MatchedCount == 0 {
+		return db.RevisionConflict(ctx, col, bson.M{"_id": id})
+	}
+
 	return nil
 }
 
-func (c ContentManagementRepository) ListContent(ctx context.Context, contentDefinitionTypes []uuid.UUID, tags []string, workspace uuid.UUID) ([]Content, error) {
+// ListContent returns a page of content that is not archived, filter is a compiled Filter and is ignored if empty.
+func (c ContentManagementRepository) ListContent(ctx context.Context, contentDefinitionTypes []uuid.UUID, tags []string, filter bson.M, page db.Page, workspace uuid.UUID) ([]Content, db.PageResult, error) {
 
 	query := bson.M{}
 
-	query["status"] = bson.M{"$ne": Archived}
+	query["data.status"] = bson.M{"$ne": Archived}
 
 	if len(contentDefinitionTypes) > 0 {
 		query["contentdefinition_id"] = bson.M{
-			"$in": bson.A{contentDefinitionTypes},
+			"$in": contentDefinitionTypes,
 		}
 	}
 
 	if len(tags) > 0 {
-		query["tags"] = bson.M{
-			"$in": bson.A{tags},
+		query["data.tags"] = bson.M{
+			"$in": tags,
 		}
 	}
 
-	cur, err := c.client.Database(workspace.String()).
-		Collection(contentCollection).
-		Find(
-			ctx,
-			query)
-
-	if err != nil {
-		return nil, err
+	if len(filter) > 0 {
+		query["$and"] = bson.A{filter}
 	}
 
-	result := []Content{}
-	for cur.Next(ctx) {
-		data := &Content{}
-		err = cur.Decode(data)
+	return c.findPage(ctx, query, page, nil, workspace)
+}
 
-		if err != nil {
-			return nil, err
-		}
+func (c ContentManagementRepository) findPage(ctx context.Context, query bson.M, page db.Page, projection bson.M, workspace uuid.UUID) ([]Content, db.PageResult, error) {
 
-		result = append(result, *data)
+	result := []Content{}
+	res, err := db.FindPage(
+		ctx,
+		c.client.Database(workspace.String()).Collection(contentCollection),
+		query,
+		page,
+		projection,
+		func(cursor *mongo.Cursor) error {
+			data := &Content{}
+			if err := cursor.Decode(data); err != nil {
+				return err
+			}
+
+			result = append(result, *data)
+			return nil
+		})
+
+	if err != nil {
+		return nil, db.PageResult{}, err
 	}
 
-	return result, nil
+	return result, res, nil
 }
 
 func (c ContentManagementRepository) ListContentByTags(ctx context.Context, tags []string, workspace uuid.UUID) ([]Content, error) {
@@ -230,3 +384,381 @@ func (c ContentManagementRepository) ListContentVersions(ctx context.Context, id
 	}
 	return items, nil
 }
+
+// GetChildren returns the children of content ordered by SortOrder.
+// An empty id returns the content at the root of the content tree.
+func (c ContentManagementRepository) GetChildren(ctx context.Context, id uuid.UUID, workspace uuid.UUID) ([]Content, error) {
+
+	filter := bson.M{"parentId": id}
+
+	// content created before the content tree existed has no parentId
+	if id == (uuid.UUID{}) {
+		filter["parentId"] = bson.M{"$in": bson.A{id, nil}}
+	}
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "sortOrder", Value: 1}, {Key: "_id", Value: 1}}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeContent(ctx, cursor)
+}
+
+// LockChildren writes to a document of the parent, so units of work that change the order of the same children conflict
+// and are retried instead of reading the same children and giving content the same sort order.
+// It must be called in a unit of work before the children are read.
+func (c ContentManagementRepository) LockChildren(ctx context.Context, id uuid.UUID, workspace uuid.UUID) error {
+
+	_, err := c.client.Database(workspace.String()).
+		Collection(childrenCollection).
+		UpdateOne(
+			ctx,
+			bson.M{"_id": id},
+			bson.M{"$inc": bson.M{"revision": 1}},
+			options.Update().SetUpsert(true))
+
+	return err
+}
+
+// GetAncestors returns the ancestors of content, starting at the root.
+// Returns mongo.ErrNoDocuments if the content does not exist.
+func (c ContentManagementRepository) GetAncestors(ctx context.Context, id uuid.UUID, workspace uuid.UUID) ([]Content, error) {
+
+	return c.graphLookup(ctx, id, workspace, "parentId", "_id", true)
+}
+
+// GetDescendants returns every descendant of content, ordered by depth and SortOrder.
+// Returns mongo.ErrNoDocuments if the content does not exist.
+func (c ContentManagementRepository) GetDescendants(ctx context.Context, id uuid.UUID, workspace uuid.UUID) ([]Content, error) {
+
+	return c.graphLookup(ctx, id, workspace, "_id", "parentId", false)
+}
+
+// graphLookup walks the content tree from content with id, the result is ordered by distance from the content.
+func (c ContentManagementRepository) graphLookup(ctx context.Context, id uuid.UUID, workspace uuid.UUID, from, to string, furthestFirst bool) ([]Content, error) {
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": id}}},
+		{{Key: "$graphLookup", Value: bson.M{
+			"from":             contentCollection,
+			"startWith":        "$" + from,
+			"connectFromField": from,
+			"connectToField":   to,
+			"as":               "nodes",
+			"depthField":       "depth",
+		}}},
+		{{Key: "$project", Value: bson.M{"nodes": 1}}},
+	}
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Aggregate(ctx, pipeline)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return nil, err
+		}
+		return nil, mongo.ErrNoDocuments
+	}
+
+	doc := &struct {
+		Nodes []struct {
+			Content `bson:",inline"`
+			Depth   int `bson:"depth"`
+		} `bson:"nodes"`
+	}{}
+
+	if err := cursor.Decode(doc); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(doc.Nodes, func(i, j int) bool {
+		a, b := doc.Nodes[i], doc.Nodes[j]
+
+		if a.Depth != b.Depth {
+			return (a.Depth > b.Depth) == furthestFirst
+		}
+		return a.SortOrder < b.SortOrder
+	})
+
+	result := make([]Content, 0, len(doc.Nodes))
+	for _, n := range doc.Nodes {
+		result = append(result, n.Content)
+	}
+
+	return result, nil
+}
+func decodeContent(ctx context.Context, cursor *mongo.Cursor) ([]Content, error) {
+
+	result := make([]Content, 0)
+	for cursor.Next(ctx) {
+		item := &Content{}
+		err := cursor.Decode(item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *item)
+	}
+
+	return result, nil
+}
+
+// ListContentByIDs returns the content with any of ids, content that does not exist is left out of the result.
+func (c ContentManagementRepository) ListContentByIDs(ctx context.Context, ids []uuid.UUID, workspace uuid.UUID) ([]Content, error) {
+
+	if len(ids) == 0 {
+		return []Content{}, nil
+	}
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeContent(ctx, cursor)
+}
+
+// ListReferencingContent returns the published content that references content with id.
+func (c ContentManagementRepository) ListReferencingContent(ctx context.Context, id uuid.UUID, workspace uuid.UUID) ([]Content, error) {
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Find(ctx, bson.M{"data.references": id, "data.status": Published})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeContent(ctx, cursor)
+}
+
+// ListContentUsingAsset returns the published content that uses the asset with id.
+func (c ContentManagementRepository) ListContentUsingAsset(ctx context.Context, id uuid.UUID, workspace uuid.UUID) ([]Content, error) {
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Find(ctx, bson.M{"data.assets": id, "data.status": Published})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeContent(ctx, cursor)
+}
+
+// IsAssetPublished returns true if the asset with id is used by published content.
+func (c ContentManagementRepository) IsAssetPublished(ctx context.Context, id uuid.UUID, workspace uuid.UUID) (bool, error) {
+
+	n, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		CountDocuments(ctx, bson.M{"data.assets": id, "data.status": Published}, options.Count().SetLimit(1))
+
+	return n > 0, err
+}
+
+// ListContentWithValues returns the published content of the contentdefinition where the field has one of values in one of languages.
+// A field with a list of values matches if any of them is one of values.
+func (c ContentManagementRepository) ListContentWithValues(ctx context.Context, contentDefinitionID uuid.UUID, field string, languages []string, values []string, workspace uuid.UUID) ([]Content, error) {
+
+	queries := bson.A{}
+	for _, lang := range languages {
+		queries = append(queries, bson.M{fmt.Sprintf("data.properties.%s.%s.value", lang, field): bson.M{"$in": values}})
+	}
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Find(ctx, bson.M{"contentdefinition_id": contentDefinitionID, "data.status": Published, "$or": queries})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeContent(ctx, cursor)
+}
+
+// ListPublishedContentByDefinition returns the published content of the contentdefinition.
+func (c ContentManagementRepository) ListPublishedContentByDefinition(ctx context.Context, contentDefinitionID uuid.UUID, workspace uuid.UUID) ([]Content, error) {
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Find(ctx, bson.M{"contentdefinition_id": contentDefinitionID, "data.status": Published})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeContent(ctx, cursor)
+}
+
+// uniqueKey is the filter matching the unique index of the unique values collection
+func uniqueKey(v UniqueValue) bson.M {
+	return bson.M{
+		"contentdefinition_id": v.ContentDefinitionID,
+		"propertyId":           v.PropertyID,
+		"language":             v.Language,
+		"perparent":            v.PerParent,
+		"parentId":             v.ParentID,
+		"value":                v.Value,
+	}
+}
+
+// FindUniqueValue returns the value claimed by other content than v.ContentID with the same key as v.
+// Returns mongo.ErrNoDocuments if the value is not claimed by other content.
+func (c ContentManagementRepository) FindUniqueValue(ctx context.Context, v UniqueValue, workspace uuid.UUID) (UniqueValue, error) {
+
+	filter := uniqueKey(v)
+	filter["contentId"] = bson.M{"$ne": v.ContentID}
+
+	result := UniqueValue{}
+	err := c.client.Database(workspace.String()).
+		Collection(uniqueValueCollection).
+		FindOne(ctx, filter).
+		Decode(&result)
+
+	return result, err
+}
+
+// ClaimUniqueValues claims values for the content they belong to. Every value that is not claimed by other content is claimed,
+// if any value is already claimed an error starting with ErrNotUnique is returned. In a unit of work nothing is claimed then.
+func (c ContentManagementRepository) ClaimUniqueValues(ctx context.Context, values []UniqueValue, workspace uuid.UUID) error {
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		docs = append(docs, v)
+	}
+
+	_, err := c.client.Database(workspace.String()).
+		Collection(uniqueValueCollection).
+		InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%s: %s", ErrNotUnique, err)
+	}
+
+	return err
+}
+
+// ReleaseUniqueValues removes every value claimed by content with id, so other content can be published with them.
+func (c ContentManagementRepository) ReleaseUniqueValues(ctx context.Context, id uuid.UUID, workspace uuid.UUID) error {
+
+	_, err := c.client.Database(workspace.String()).
+		Collection(uniqueValueCollection).
+		DeleteMany(ctx, bson.M{"contentId": id})
+
+	return err
+}
+
+// MoveUniqueValues sets the parent of the values of content with id that are unique per parent.
+// Returns an error starting with ErrNotUnique if a value is already claimed by content below the new parent.
+func (c ContentManagementRepository) MoveUniqueValues(ctx context.Context, id uuid.UUID, parentID uuid.UUID, workspace uuid.UUID) error {
+
+	_, err := c.client.Database(workspace.String()).
+		Collection(uniqueValueCollection).
+		UpdateMany(ctx, bson.M{"contentId": id, "perparent": true}, bson.M{"$set": bson.M{"parentId": parentID}})
+
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%s: %s", ErrNotUnique, err)
+	}
+
+	return err
+}
+
+// CountContent returns how much content, including archived content, is of the contentdefinition
+func (c ContentManagementRepository) CountContent(ctx context.Context, contentDefinitionID uuid.UUID, workspace uuid.UUID) (int, error) {
+
+	n, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		CountDocuments(ctx, bson.M{"contentdefinition_id": contentDefinitionID})
+
+	return int(n), err
+}
+
+// FindByPath returns content that has path as a route or redirect in any language.
+func (c ContentManagementRepository) FindByPath(ctx context.Context, path string, workspace uuid.UUID) ([]Content, error) {
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"routes.path": path},
+			bson.M{"redirects.path": path},
+		},
+	}
+
+	cursor, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Find(ctx, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeContent(ctx, cursor)
+}
+
+// EnsureIndexes creates the indexes used by the content tree, routing, references, assets and locations,
+// and the unique indexes on the version number of content versions and on unique values. It is safe to call on every start.
+func (c ContentManagementRepository) EnsureIndexes(ctx context.Context, workspace uuid.UUID) error {
+
+	_, err := c.client.Database(workspace.String()).
+		Collection(contentCollection).
+		Indexes().
+		CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "parentId", Value: 1}, {Key: "sortOrder", Value: 1}}},
+			{Keys: bson.D{{Key: "routes.path", Value: 1}, {Key: "routes.language", Value: 1}}},
+			{Keys: bson.D{{Key: "redirects.path", Value: 1}, {Key: "redirects.language", Value: 1}}},
+			{Keys: bson.D{{Key: "data.references", Value: 1}}},
+			{Keys: bson.D{{Key: "data.assets", Value: 1}}},
+			{Keys: bson.D{{Key: "data.locations", Value: "2dsphere"}}},
+		})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Database(workspace.String()).
+		Collection(contentVersionCollection).
+		Indexes().
+		CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "contentId", Value: 1}, {Key: "version", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Database(workspace.String()).
+		Collection(uniqueValueCollection).
+		Indexes().
+		CreateMany(ctx, []mongo.IndexModel{
+			{
+				Keys: bson.D{
+					{Key: "contentdefinition_id", Value: 1},
+					{Key: "propertyId", Value: 1},
+					{Key: "language", Value: 1},
+					{Key: "perparent", Value: 1},
+					{Key: "parentId", Value: 1},
+					{Key: "value", Value: 1},
+				},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "contentId", Value: 1}}},
+		})
+
+	return err
+}
@@ -0,0 +1,156 @@
+package content
+
+import (
+	"strings"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+// Route is the path of published content in a language.
+// Routes are rebuilt when content is published, archived or moved so content can be found by path with a single indexed lookup.
+type Route struct {
+	Language string `bson:"language"`
+	Path     string `bson:"path"`
+}
+
+// NormalizePath lower cases path and removes empty segments and the trailing slash.
+func NormalizePath(path string) string {
+
+	segments := make([]string, 0)
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, strings.ToLower(s))
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+// URLSegment returns the url segment of the content in language.
+// If the language has no url segment, the segment of the default language is used.
+func (c ContentData) URLSegment(language, defaultLanguage string) string {
+
+	for _, lang := range []string{language, defaultLanguage} {
+		segment, _ := c.Properties[lang][contentdefinition.PROPFIELD_URLSEGMENT].Value.(string)
+
+		if segment = strings.Trim(segment, "/"); segment != "" {
+			return segment
+		}
+	}
+
+	return ""
+}
+
+// BuildRoutes returns the routes of content in every language of ws. parent is nil for content at the root.
+// Only published content with a url segment and a published parent is routable, except the start page of ws which has the path /.
+func BuildRoutes(c Content, parent *Content, ws workspace.Workspace) []Route {
+
+	routes := make([]Route, 0)
+
+	if c.Data.Status != Published || len(ws.Languages) == 0 {
+		return routes
+	}
+
+	for _, lang := range ws.Languages {
+
+		if parent == nil && ws.StartPage != (uuid.UUID{}) && c.ID == ws.StartPage {
+			routes = append(routes, Route{Language: lang, Path: "/"})
+			continue
+		}
+
+		segment := c.Data.URLSegment(lang, ws.Languages[0])
+		if segment == "" {
+			continue
+		}
+
+		if parent == nil {
+			routes = append(routes, Route{Language: lang, Path: NormalizePath(segment)})
+			continue
+		}
+
+		parentPath, ok := parent.Route(lang)
+		if !ok {
+			continue
+		}
+
+		routes = append(routes, Route{Language: lang, Path: NormalizePath(parentPath + "/" + segment)})
+	}
+
+	return routes
+}
+
+// Route returns the path of the content in language.
+func (c Content) Route(language string) (string, bool) {
+
+	for _, r := range c.Routes {
+		if r.Language == language {
+			return r.Path, true
+		}
+	}
+
+	return "", false
+}
+
+// UpdateRedirects returns the redirects after the routes of content has changed from old to new.
+// Previous routes are kept as redirects so old URLs answer with a redirect to the new URL.
+func UpdateRedirects(redirects, old, new []Route) []Route {
+
+	current := make(map[Route]bool)
+	for _, r := range new {
+		current[r] = true
+	}
+
+	result := make([]Route, 0)
+	seen := make(map[Route]bool)
+	for _, r := range append(redirects, old...) {
+		if current[r] || seen[r] {
+			continue
+		}
+
+		seen[r] = true
+		result = append(result, r)
+	}
+
+	return result
+}
+
+// ResolveRoute finds the content that has path in language among candidates and returns its canonical path.
+// If path is a redirect, or the path of the content in another language, the canonical path differs from path.
+func ResolveRoute(candidates []Content, path, language string) (Content, string, bool) {
+
+	path = NormalizePath(path)
+
+	// route in language, redirect in language, route in other language, redirect in other language
+	lookups := []struct {
+		redirect    bool
+		anyLanguage bool
+	}{
+		{false, false},
+		{true, false},
+		{false, true},
+		{true, true},
+	}
+
+	for _, l := range lookups {
+		for _, c := range candidates {
+			routes := c.Routes
+			if l.redirect {
+				routes = c.Redirects
+			}
+
+			for _, r := range routes {
+				if r.Path != path || (!l.anyLanguage && r.Language != language) {
+					continue
+				}
+
+				if canonical, ok := c.Route(language); ok {
+					return c, canonical, true
+				}
+			}
+		}
+	}
+
+	return Content{}, "", false
+}
@@ -0,0 +1,204 @@
+//go:build unit
+
+package content
+
+import (
+	"testing"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NormalizePath(t *testing.T) {
+
+	tests := map[string]string{
+		"":               "/",
+		"/":              "/",
+		"/About/Team/":   "/about/team",
+		"about//team":    "/about/team",
+		"/om-oss/Förstå": "/om-oss/förstå",
+	}
+
+	for path, expected := range tests {
+		t.Run(path, func(t *testing.T) {
+			assert.Equal(t, expected, NormalizePath(path))
+		})
+	}
+}
+
+func segments(values map[string]string) ContentLanguage {
+
+	properties := make(ContentLanguage)
+	for lang, v := range values {
+		properties[lang] = ContentFields{
+			contentdefinition.PROPFIELD_URLSEGMENT: ContentField{Value: v},
+		}
+	}
+	return properties
+}
+
+func Test_BuildRoutes(t *testing.T) {
+
+	startPage := uuid.New()
+	ws := workspace.Workspace{Languages: []string{"sv-SE", "en-US"}, StartPage: startPage}
+
+	tests := []struct {
+		name     string
+		content  Content
+		parent   *Content
+		expected []Route
+	}{
+		{
+			name: "draft is not routable",
+			content: Content{
+				Data: ContentData{Status: Draft, Properties: segments(map[string]string{"sv-SE": "om-oss"})},
+			},
+			expected: []Route{},
+		},
+		{
+			name: "start page",
+			content: Content{
+				ID:   startPage,
+				Data: ContentData{Status: Published},
+			},
+			expected: []Route{{Language: "sv-SE", Path: "/"}, {Language: "en-US", Path: "/"}},
+		},
+		{
+			name: "root without segment is not routable",
+			content: Content{
+				ID:   uuid.New(),
+				Data: ContentData{Status: Published},
+			},
+			expected: []Route{},
+		},
+		{
+			name: "root with segment",
+			content: Content{
+				ID:   uuid.New(),
+				Data: ContentData{Status: Published, Properties: segments(map[string]string{"sv-SE": "om-oss"})},
+			},
+			expected: []Route{{Language: "sv-SE", Path: "/om-oss"}, {Language: "en-US", Path: "/om-oss"}},
+		},
+		{
+			name: "start page below the root",
+			content: Content{
+				ID:   startPage,
+				Data: ContentData{Status: Published},
+			},
+			parent: &Content{
+				Routes: []Route{{Language: "sv-SE", Path: "/"}},
+			},
+			expected: []Route{},
+		},
+		{
+			name: "localized segment with fallback to default language",
+			content: Content{
+				Data: ContentData{Status: Published, Properties: segments(map[string]string{"sv-SE": "Om-Oss"})},
+			},
+			parent: &Content{
+				Routes: []Route{{Language: "sv-SE", Path: "/"}, {Language: "en-US", Path: "/start"}},
+			},
+			expected: []Route{{Language: "sv-SE", Path: "/om-oss"}, {Language: "en-US", Path: "/start/om-oss"}},
+		},
+		{
+			name: "per language segment",
+			content: Content{
+				Data: ContentData{Status: Published, Properties: segments(map[string]string{"sv-SE": "om-oss", "en-US": "about"})},
+			},
+			parent: &Content{
+				Routes: []Route{{Language: "sv-SE", Path: "/"}, {Language: "en-US", Path: "/"}},
+			},
+			expected: []Route{{Language: "sv-SE", Path: "/om-oss"}, {Language: "en-US", Path: "/about"}},
+		},
+		{
+			name: "parent not routable in language",
+			content: Content{
+				Data: ContentData{Status: Published, Properties: segments(map[string]string{"sv-SE": "om-oss"})},
+			},
+			parent: &Content{
+				Routes: []Route{{Language: "sv-SE", Path: "/"}},
+			},
+			expected: []Route{{Language: "sv-SE", Path: "/om-oss"}},
+		},
+		{
+			name: "child without segment is not routable",
+			content: Content{
+				Data: ContentData{Status: Published},
+			},
+			parent: &Content{
+				Routes: []Route{{Language: "sv-SE", Path: "/"}},
+			},
+			expected: []Route{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, BuildRoutes(test.content, test.parent, ws))
+		})
+	}
+}
+
+func Test_UpdateRedirects(t *testing.T) {
+
+	a := Route{Language: "sv-SE", Path: "/a"}
+	b := Route{Language: "sv-SE", Path: "/b"}
+	c := Route{Language: "sv-SE", Path: "/c"}
+
+	// renamed from a to b
+	assert.Equal(t, []Route{a}, UpdateRedirects(nil, []Route{a}, []Route{b}))
+	// renamed from b to c
+	assert.Equal(t, []Route{a, b}, UpdateRedirects([]Route{a}, []Route{b}, []Route{c}))
+	// renamed back to a
+	assert.Equal(t, []Route{b, c}, UpdateRedirects([]Route{a, b}, []Route{c}, []Route{a}))
+	// unchanged
+	assert.Equal(t, []Route{}, UpdateRedirects(nil, []Route{a}, []Route{a}))
+}
+
+func Test_ResolveRoute(t *testing.T) {
+
+	about := Content{
+		ID: uuid.New(),
+		Routes: []Route{
+			{Language: "sv-SE", Path: "/om-oss"},
+			{Language: "en-US", Path: "/about"},
+		},
+		Redirects: []Route{
+			{Language: "en-US", Path: "/about-us"},
+		},
+	}
+	svOnly := Content{
+		ID: uuid.New(),
+		Routes: []Route{
+			{Language: "sv-SE", Path: "/nyheter"},
+		},
+	}
+	candidates := []Content{about, svOnly}
+
+	tests := []struct {
+		name      string
+		path      string
+		language  string
+		expected  uuid.UUID
+		canonical string
+		ok        bool
+	}{
+		{name: "route", path: "/About/", language: "en-US", expected: about.ID, canonical: "/about", ok: true},
+		{name: "redirect", path: "/about-us", language: "en-US", expected: about.ID, canonical: "/about", ok: true},
+		{name: "route in other language", path: "/om-oss", language: "en-US", expected: about.ID, canonical: "/about", ok: true},
+		{name: "not routable in language", path: "/nyheter", language: "en-US", ok: false},
+		{name: "not found", path: "/missing", language: "sv-SE", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, canonical, ok := ResolveRoute(candidates, test.path, test.language)
+
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, c.ID)
+			assert.Equal(t, test.canonical, canonical)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package content
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// CheckMove returns an error if moving content below parent would create a cycle in the content tree.
+// ancestors are the ancestors of the new parent.
+func CheckMove(id, parentID uuid.UUID, ancestors []Content) error {
+
+	if id == parentID {
+		return errors.New(ErrMoveBelowItself)
+	}
+
+	for _, a := range ancestors {
+		if a.ID == id {
+			return errors.New(ErrMoveBelowItself)
+		}
+	}
+
+	return nil
+}
+
+// InsertAt moves id to position in order, if id is not in order it is added.
+// A negative position or a position after the last item puts id last.
+func InsertAt(order []uuid.UUID, id uuid.UUID, position int) []uuid.UUID {
+
+	result := make([]uuid.UUID, 0, len(order)+1)
+	for _, o := range order {
+		if o != id {
+			result = append(result, o)
+		}
+	}
+
+	if position < 0 || position > len(result) {
+		position = len(result)
+	}
+
+	result = append(result, uuid.UUID{})
+	copy(result[position+1:], result[position:])
+	result[position] = id
+
+	return result
+}
+
+// CheckOrder returns an error if order is not a permutation of the children.
+func CheckOrder(children []Content, order []uuid.UUID) error {
+
+	if len(children) != len(order) {
+		return errors.New(ErrNotSiblings)
+	}
+
+	lookup := make(map[uuid.UUID]bool)
+	for _, c := range children {
+		lookup[c.ID] = true
+	}
+
+	for _, id := range order {
+		if !lookup[id] {
+			return errors.New(ErrNotSiblings)
+		}
+		delete(lookup, id)
+	}
+
+	return nil
+}
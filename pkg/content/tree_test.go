@@ -0,0 +1,132 @@
+//go:build unit
+
+package content
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheckMove(t *testing.T) {
+
+	root := uuid.New()
+	child := uuid.New()
+	grandchild := uuid.New()
+
+	tests := []struct {
+		name      string
+		id        uuid.UUID
+		parent    uuid.UUID
+		ancestors []Content
+		expected  error
+	}{
+		{
+			name:     "move to root",
+			id:       child,
+			parent:   uuid.UUID{},
+			expected: nil,
+		},
+		{
+			name:      "move to sibling subtree",
+			id:        child,
+			parent:    uuid.New(),
+			ancestors: []Content{{ID: root}},
+			expected:  nil,
+		},
+		{
+			name:     "move below itself",
+			id:       child,
+			parent:   child,
+			expected: errors.New(ErrMoveBelowItself),
+		},
+		{
+			name:      "move below descendant",
+			id:        root,
+			parent:    grandchild,
+			ancestors: []Content{{ID: root}, {ID: child}},
+			expected:  errors.New(ErrMoveBelowItself),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, CheckMove(test.id, test.parent, test.ancestors))
+		})
+	}
+}
+
+func Test_InsertAt(t *testing.T) {
+
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		order    []uuid.UUID
+		id       uuid.UUID
+		position int
+		expected []uuid.UUID
+	}{
+		{
+			name:     "new item first",
+			order:    []uuid.UUID{a, b, c},
+			id:       d,
+			position: 0,
+			expected: []uuid.UUID{d, a, b, c},
+		},
+		{
+			name:     "new item last when position is negative",
+			order:    []uuid.UUID{a, b, c},
+			id:       d,
+			position: -1,
+			expected: []uuid.UUID{a, b, c, d},
+		},
+		{
+			name:     "position after last item",
+			order:    []uuid.UUID{a, b},
+			id:       d,
+			position: 10,
+			expected: []uuid.UUID{a, b, d},
+		},
+		{
+			name:     "move existing item down",
+			order:    []uuid.UUID{a, b, c},
+			id:       a,
+			position: 2,
+			expected: []uuid.UUID{b, c, a},
+		},
+		{
+			name:     "move existing item up",
+			order:    []uuid.UUID{a, b, c},
+			id:       c,
+			position: 1,
+			expected: []uuid.UUID{a, c, b},
+		},
+		{
+			name:     "empty",
+			order:    []uuid.UUID{},
+			id:       a,
+			position: 0,
+			expected: []uuid.UUID{a},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, InsertAt(test.order, test.id, test.position))
+		})
+	}
+}
+
+func Test_CheckOrder(t *testing.T) {
+
+	a, b := uuid.New(), uuid.New()
+	children := []Content{{ID: a}, {ID: b}}
+
+	assert.NoError(t, CheckOrder(children, []uuid.UUID{b, a}))
+	assert.Equal(t, errors.New(ErrNotSiblings), CheckOrder(children, []uuid.UUID{a}))
+	assert.Equal(t, errors.New(ErrNotSiblings), CheckOrder(children, []uuid.UUID{a, a}))
+	assert.Equal(t, errors.New(ErrNotSiblings), CheckOrder(children, []uuid.UUID{a, uuid.New()}))
+}
@@ -0,0 +1,97 @@
+package content
+
+import (
+	"reflect"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+)
+
+// UniqueValue is a value of a property with the unique validator that is claimed by published content.
+// Values are claimed in a collection with a unique index, so two content cannot be published with the same value
+// even when they are published at the same time.
+type UniqueValue struct {
+	ContentDefinitionID uuid.UUID `bson:"contentdefinition_id"`
+	// PropertyID is used instead of the name of the property, so renaming the property keeps the values
+	PropertyID uuid.UUID `bson:"propertyId"`
+	// Language is empty when the value is compared with the values of every language
+	Language string `bson:"language"`
+	// PerParent values are only compared with content that has the same parent, ParentID is empty otherwise
+	PerParent bool        `bson:"perparent"`
+	ParentID  uuid.UUID   `bson:"parentId"`
+	Value     interface{} `bson:"value"`
+	ContentID uuid.UUID   `bson:"contentId"`
+
+	// property and language the value was read from, used to report the failure
+	property string
+	language string
+}
+
+// UniqueValues returns the values of the properties with the unique validator of the content version.
+// Empty values are never compared, and a value used in several languages is returned once unless it is unique per language.
+func UniqueValues(cd contentdefinition.ContentDefinition, c Content, data ContentData, ws workspace.Workspace) ([]UniqueValue, error) {
+
+	result := []UniqueValue{}
+	for _, propName := range propertyNames(cd.Propertydefinitions) {
+		pd := cd.Propertydefinitions[propName]
+		v, ok := pd.Validators[validator.RuleUnique]
+		if !ok {
+			continue
+		}
+
+		parsed, err := validator.Parse(validator.RuleUnique, v)
+		if err != nil {
+			return nil, err
+		}
+
+		rule := parsed.(validator.Unique)
+		if !rule.Enabled {
+			continue
+		}
+
+		languages := ws.Languages[:1]
+		if pd.Localized {
+			languages = ws.Languages
+		}
+
+		seen := make(map[interface{}]bool)
+		for _, l := range languages {
+			value := data.Properties[l][propName].Value
+
+			// lists and objects cannot be unique, they are reported by the unique validator
+			if value == nil || value == "" || !reflect.TypeOf(value).Comparable() {
+				continue
+			}
+
+			uv := UniqueValue{
+				ContentDefinitionID: cd.ID,
+				PropertyID:          pd.ID,
+				PerParent:           rule.PerParent,
+				Value:               value,
+				ContentID:           c.ID,
+				property:            propName,
+			}
+
+			if pd.Localized {
+				uv.language = l
+			}
+
+			if rule.PerLanguage {
+				uv.Language = l
+			} else if seen[value] {
+				continue
+			}
+			seen[value] = true
+
+			if rule.PerParent {
+				uv.ParentID = c.ParentID
+			}
+
+			result = append(result, uv)
+		}
+	}
+
+	return result, nil
+}
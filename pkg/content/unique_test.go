@@ -0,0 +1,66 @@
+//go:build unit
+
+package content
+
+import (
+	"testing"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/workspace"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UniqueValues(t *testing.T) {
+
+	skuID := uuid.MustParse("4bd8a1d4-0c26-4a8c-9a56-2b0a3c0a6c11")
+	slugID := uuid.MustParse("0f3c8f1e-4f3a-4d2c-8a3e-1c9e5e6b7a22")
+
+	cd := contentdefinition.ContentDefinition{
+		ID: uuid.MustParse("6d1c1a4e-51b4-4b7e-9d0e-3f2a1b0c9d33"),
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"sku": {
+				ID:         skuID,
+				Type:       contentdefinition.PropertyTypeText,
+				Localized:  true,
+				Validators: map[string]interface{}{validator.RuleUnique: true},
+			},
+			"slug": {
+				ID:         slugID,
+				Type:       contentdefinition.PropertyTypeText,
+				Localized:  true,
+				Validators: map[string]interface{}{validator.RuleUnique: validator.Unique{Enabled: true, PerLanguage: true, PerParent: true}},
+			},
+			"title": {
+				Type:      contentdefinition.PropertyTypeText,
+				Localized: true,
+			},
+		},
+	}
+
+	c := Content{ID: uuid.New(), ParentID: uuid.New()}
+	data := ContentData{
+		Properties: ContentLanguage{
+			"sv-SE": {
+				"sku":   {Value: "A-1"},
+				"slug":  {Value: "stol"},
+				"title": {Value: "Stol"},
+			},
+			"en-US": {
+				"sku":  {Value: "A-1"},
+				"slug": {Value: ""},
+			},
+		},
+	}
+	ws := workspace.Workspace{Languages: []string{"sv-SE", "en-US"}}
+
+	actual, err := UniqueValues(cd, c, data, ws)
+	assert.NoError(t, err)
+
+	// the same sku in both languages is claimed once, the empty slug is not claimed
+	assert.Equal(t, []UniqueValue{
+		{ContentDefinitionID: cd.ID, PropertyID: skuID, Value: "A-1", ContentID: c.ID, property: "sku", language: "sv-SE"},
+		{ContentDefinitionID: cd.ID, PropertyID: slugID, Language: "sv-SE", PerParent: true, ParentID: c.ParentID, Value: "stol", ContentID: c.ID, property: "slug", language: "sv-SE"},
+	}, actual)
+}
@@ -0,0 +1,378 @@
+package content
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/richtext"
+	"github.com/crikke/cms/pkg/workspace"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Failure is a value of a content version that does not pass a validator, or a rule of the contentdefinition that is not satisfied
+type Failure struct {
+	// Property is the path of the property, items of lists are indexed and sub-properties of objects are named like stops[1].departure.
+	// It is empty for rules of the contentdefinition, since they span properties.
+	Property string `json:"property,omitempty"`
+	// Language is empty for properties that are not localized
+	Language string `json:"language,omitempty"`
+	// Rule is the name of the validator or of the rule of the contentdefinition, empty if the value is not of the type of the property
+	Rule    string `json:"rule,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationReport contains every failure of a content version, not only the first one
+type ValidationReport struct {
+	Valid    bool      `json:"valid"`
+	Failures []Failure `json:"failures"`
+}
+
+func NewValidationReport() ValidationReport {
+	return ValidationReport{Valid: true, Failures: []Failure{}}
+}
+
+// Add adds failures to the report
+func (r *ValidationReport) Add(failures ...Failure) {
+	r.Failures = append(r.Failures, failures...)
+	r.Valid = len(r.Failures) == 0
+}
+
+// Err returns a ValidationError if the report has failures
+func (r ValidationReport) Err() error {
+
+	if r.Valid {
+		return nil
+	}
+
+	return ValidationError{Report: r}
+}
+
+// ValidationError is returned when a content version with failures is published, the message starts with ErrValidation
+type ValidationError struct {
+	Report ValidationReport
+}
+
+func (e ValidationError) Error() string {
+
+	failures := make([]string, 0, len(e.Report.Failures))
+	for _, f := range e.Report.Failures {
+		property := f.Property
+		if f.Language != "" {
+			property = fmt.Sprintf("%s (%s)", property, f.Language)
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", property, f.Message))
+	}
+
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(failures, "; "))
+}
+
+// FieldError is a value that cannot be set on a field, either because the content has no such field
+// or because the value is not of the type of the property. The message is the message of Err.
+type FieldError struct {
+	Field    string
+	Language string
+	Err      error
+}
+
+func (e FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Failure returns the error as a failure of a validation report
+func (e FieldError) Failure() Failure {
+
+	code := validator.Code(e.Err)
+	if e.Err.Error() == ErrMissingField {
+		code = validator.CodeUnknownProperty
+	}
+
+	return Failure{
+		Property: e.Field,
+		Language: e.Language,
+		Code:     code,
+		Message:  e.Err.Error(),
+	}
+}
+
+// Validate checks the values of the content version against the validators of the properties of cd. Every property is validated
+// in every language of the workspace if it is localized, otherwise in the default language. The items of lists and the sub-properties
+// of objects are validated against their own definitions. Last the rules of cd are evaluated, see contentdefinition.Rule.
+//
+// Values that do not pass are added to the report, an error is only returned if the configuration of a validator
+// or a rule cannot be parsed.
+func Validate(ctx context.Context, cd contentdefinition.ContentDefinition, data ContentData, languages []string) (ValidationReport, error) {
+
+	report := NewValidationReport()
+
+	for _, name := range propertyNames(cd.Propertydefinitions) {
+		pd := cd.Propertydefinitions[name]
+
+		if pd.Localized {
+			for _, l := range languages {
+				if err := validateValue(ctx, &report, name, l, pd, data.Properties[l][name].Value); err != nil {
+					return ValidationReport{}, err
+				}
+			}
+			continue
+		}
+
+		if err := validateValue(ctx, &report, name, "", pd, data.Properties[languages[0]][name].Value); err != nil {
+			return ValidationReport{}, err
+		}
+	}
+
+	if err := validateRules(ctx, &report, cd, data, languages); err != nil {
+		return ValidationReport{}, err
+	}
+
+	return report, nil
+}
+
+func validateValue(ctx context.Context, report *ValidationReport, path, language string, pd contentdefinition.PropertyDefinition, value interface{}) error {
+
+	rules := make([]string, 0, len(pd.Validators))
+	for name := range pd.Validators {
+		rules = append(rules, name)
+	}
+	sort.Strings(rules)
+
+	for _, name := range rules {
+		rule, err := validator.Parse(name, pd.Validators[name])
+		if err != nil {
+			return fmt.Errorf("%s: %s: %s", path, name, err)
+		}
+
+		if err := rule.Validate(ctx, value); err != nil {
+			report.Add(Failure{
+				Property: path,
+				Language: language,
+				Rule:     name,
+				Code:     validator.Code(err),
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	switch pd.Type {
+	case contentdefinition.PropertyTypeList:
+		if pd.Items == nil {
+			return nil
+		}
+
+		items, err := ListItems(value)
+		if err != nil {
+			report.Add(Failure{Property: path, Language: language, Code: validator.CodeInvalidType, Message: err.Error()})
+			return nil
+		}
+
+		for i, item := range items {
+			if err := validateValue(ctx, report, fmt.Sprintf("%s[%d]", path, i), language, *pd.Items, item); err != nil {
+				return err
+			}
+		}
+	case contentdefinition.PropertyTypeObject:
+		fields, err := ObjectFields(value)
+		if err != nil {
+			report.Add(Failure{Property: path, Language: language, Code: validator.CodeInvalidType, Message: err.Error()})
+			return nil
+		}
+
+		// an object without a value is checked by the required validator of the object
+		if fields == nil {
+			return nil
+		}
+
+		for _, name := range propertyNames(pd.Properties) {
+			sub := pd.Properties[name]
+			field, _ := fields.fieldByID(sub.ID)
+			if err := validateValue(ctx, report, fmt.Sprintf("%s.%s", path, name), language, sub, field.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateUnique checks the values of the properties with the unique validator of the content version against the values
+// claimed by other published content of cd. Drafts do not claim values. A failure is returned for every value that is used by other content.
+func ValidateUnique(ctx context.Context, repo ContentManagementRepository, cd contentdefinition.ContentDefinition, c Content, data ContentData, ws workspace.Workspace) ([]Failure, error) {
+
+	values, err := UniqueValues(cd, c, data, ws)
+	if err != nil {
+		return nil, err
+	}
+
+	failures := []Failure{}
+	for _, v := range values {
+		other, err := repo.FindUniqueValue(ctx, v, ws.ID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		failures = append(failures, Failure{
+			Property: v.property,
+			Language: v.language,
+			Rule:     validator.RuleUnique,
+			Code:     validator.CodeNotUnique,
+			Message:  fmt.Sprintf("%s: %s", ErrNotUnique, other.ContentID),
+		})
+	}
+
+	return failures, nil
+}
+
+// validateRules evaluates the rules of cd with the values of the content version. Rules that use localized properties are
+// evaluated in every language, with the unlocalized values of the default language.
+func validateRules(ctx context.Context, report *ValidationReport, cd contentdefinition.ContentDefinition, data ContentData, languages []string) error {
+
+	rules, err := cd.CompileRules()
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		evaluated := languages[:1]
+		if rule.Localized {
+			evaluated = languages
+		}
+
+		for _, l := range evaluated {
+			values := make(map[string]interface{}, len(cd.Propertydefinitions))
+			for name, pd := range cd.Propertydefinitions {
+				lang := languages[0]
+				if pd.Localized {
+					lang = l
+				}
+				values[name] = ruleValue(pd, data.Properties[lang][name].Value)
+			}
+
+			f := Failure{Rule: rule.Name, Code: validator.CodeRuleFailed, Message: rule.Message}
+			if rule.Localized {
+				f.Language = l
+			}
+			if f.Message == "" {
+				f.Message = rule.Name
+			}
+
+			valid, err := rule.Eval(ctx, values)
+			if err != nil {
+				f.Message = fmt.Sprintf("%s: %s", f.Message, err)
+				report.Add(f)
+				continue
+			}
+
+			if !valid {
+				report.Add(f)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ruleValue returns value as a variable of a rule expression, see contentdefinition.Rule. Nil is returned for values that are not set.
+func ruleValue(pd contentdefinition.PropertyDefinition, value interface{}) interface{} {
+
+	if value == nil {
+		return nil
+	}
+
+	switch pd.Type {
+	case contentdefinition.PropertyTypeNumber:
+		switch n := value.(type) {
+		case int:
+			return float64(n)
+		case int32:
+			return float64(n)
+		case int64:
+			return float64(n)
+		}
+	case contentdefinition.PropertyTypeRichText:
+		doc, err := richtext.Parse(value)
+		if err != nil || doc.IsEmpty() {
+			return nil
+		}
+		return doc.PlainText()
+	case contentdefinition.PropertyTypeDate:
+		d, err := validator.ParseDate(value)
+		if err != nil {
+			return nil
+		}
+		t, _ := time.Parse(validator.DateLayout, d)
+		return t
+	case contentdefinition.PropertyTypeDateTime:
+		t, err := validator.ParseDateTime(value)
+		if err != nil {
+			return nil
+		}
+		return t
+	case contentdefinition.PropertyTypeGeoPoint:
+		p, err := validator.ParseGeoPoint(value)
+		if err != nil {
+			return nil
+		}
+		return map[string]interface{}{"lat": p.Lat(), "lng": p.Lng()}
+	case contentdefinition.PropertyTypeList:
+		items, err := ListItems(value)
+		if err != nil || len(items) == 0 {
+			return nil
+		}
+
+		values := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			if pd.Items != nil {
+				item = ruleValue(*pd.Items, item)
+			}
+			values = append(values, item)
+		}
+		return values
+	case contentdefinition.PropertyTypeObject:
+		fields, err := ObjectFields(value)
+		if err != nil || fields == nil {
+			return nil
+		}
+
+		values := make(map[string]interface{}, len(pd.Properties))
+		for name, sub := range pd.Properties {
+			field, _ := fields.fieldByID(sub.ID)
+			if v := ruleValue(sub, field.Value); v != nil {
+				values[name] = v
+			}
+		}
+		return values
+	case contentdefinition.PropertyTypeEnum, contentdefinition.PropertyTypeReference:
+		// lists are read from the database as primitive.A
+		if items, err := ListItems(value); err == nil {
+			return items
+		}
+	}
+
+	return value
+}
+
+func propertyNames(properties map[string]contentdefinition.PropertyDefinition) []string {
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
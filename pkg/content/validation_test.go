@@ -0,0 +1,173 @@
+//go:build unit
+
+package content
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+
+	min, max := 1, 2
+	stops := contentdefinition.PropertyDefinition{
+		Type: contentdefinition.PropertyTypeList,
+		Validators: map[string]interface{}{
+			validator.RuleItemCount: validator.ItemCount{Min: &min, Max: &max},
+		},
+		Items: &contentdefinition.PropertyDefinition{
+			Type: contentdefinition.PropertyTypeObject,
+			Properties: map[string]contentdefinition.PropertyDefinition{
+				"day": {
+					Type: contentdefinition.PropertyTypeDate,
+					Validators: map[string]interface{}{
+						validator.RuleRequired:  true,
+						validator.RuleDateRange: validator.DateRange{Min: "2022-01-01"},
+					},
+				},
+			},
+		},
+	}
+
+	cd := contentdefinition.ContentDefinition{
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"title": {
+				Type:      contentdefinition.PropertyTypeText,
+				Localized: true,
+				Validators: map[string]interface{}{
+					validator.RuleRequired: validator.Required(true),
+					validator.RuleRegex:    "^[A-Z]",
+					validator.RuleRange:    map[string]interface{}{"max": 5.0},
+				},
+			},
+			"stops": stops,
+		},
+	}
+
+	value := func(days ...interface{}) interface{} {
+		v, err := fieldValue(stops, days)
+		assert.NoError(t, err)
+		return v
+	}
+	day := func(d interface{}) map[string]interface{} {
+		return map[string]interface{}{"day": d}
+	}
+	data := func(sv, en string, stops interface{}) ContentData {
+		return ContentData{Properties: ContentLanguage{
+			"sv-SE": {"title": {Value: sv}, "stops": {Value: stops}},
+			"en-US": {"title": {Value: en}},
+		}}
+	}
+	languages := []string{"sv-SE", "en-US"}
+
+	tests := []struct {
+		name     string
+		data     ContentData
+		expected []Failure
+	}{
+		{name: "valid", data: data("Stol", "Chair", value(day("2022-05-01"), day("2022-06-01"))), expected: []Failure{}},
+		{
+			name: "every failure is reported",
+			data: data("stolar", "", nil),
+			expected: []Failure{
+				{Property: "stops", Rule: validator.RuleItemCount, Code: validator.CodeLessThanMin, Message: "list has less than 1 items"},
+				{Property: "title", Language: "sv-SE", Rule: validator.RuleRange, Code: validator.CodeGreaterThanMax, Message: "field greater than"},
+				{Property: "title", Language: "sv-SE", Rule: validator.RuleRegex, Code: validator.CodePatternMismatch, Message: "pattern do not match"},
+				{Property: "title", Language: "en-US", Rule: validator.RuleRegex, Code: validator.CodePatternMismatch, Message: "pattern do not match"},
+				{Property: "title", Language: "en-US", Rule: validator.RuleRequired, Code: validator.CodeRequired, Message: "required"},
+			},
+		},
+		{
+			name: "items and sub-properties are validated",
+			data: data("Stol", "Chair", value(day("2022-05-01"), day("2021-05-01"))),
+			expected: []Failure{
+				{Property: "stops[1].day", Rule: validator.RuleDateRange, Code: validator.CodeLessThanMin, Message: "date is before 2022-01-01"},
+			},
+		},
+		{
+			name: "required sub-property",
+			data: data("Stol", "Chair", value(map[string]interface{}{})),
+			expected: []Failure{
+				{Property: "stops[0].day", Rule: validator.RuleRequired, Code: validator.CodeRequired, Message: "required"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := Validate(context.Background(), cd, test.data, languages)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, report.Failures)
+			assert.Equal(t, len(test.expected) == 0, report.Valid)
+
+			if report.Valid {
+				assert.NoError(t, report.Err())
+			} else {
+				assert.ErrorAs(t, report.Err(), &ValidationError{})
+			}
+		})
+	}
+
+	invalid := contentdefinition.ContentDefinition{
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"title": {Validators: map[string]interface{}{validator.RuleRegex: 1}},
+		},
+	}
+	_, err := Validate(context.Background(), invalid, ContentData{}, languages)
+	assert.Error(t, err)
+}
+
+func Test_ValidateRules(t *testing.T) {
+
+	cd := contentdefinition.ContentDefinition{
+		Propertydefinitions: map[string]contentdefinition.PropertyDefinition{
+			"title":    {Type: contentdefinition.PropertyTypeText, Localized: true},
+			"price":    {Type: contentdefinition.PropertyTypeNumber},
+			"discount": {Type: contentdefinition.PropertyTypeNumber},
+			"start":    {Type: contentdefinition.PropertyTypeDate},
+			"end":      {Type: contentdefinition.PropertyTypeDate},
+		},
+		Rules: []contentdefinition.Rule{
+			{Name: "discount", Expression: "discount == 0.0 || price > discount", Message: "discount requires a higher price"},
+			{Name: "dates", Expression: "end >= start"},
+			{Name: "title", Expression: `title != "draft"`},
+		},
+	}
+
+	data := ContentData{Properties: ContentLanguage{
+		"sv-SE": {"title": {Value: "draft"}, "discount": {Value: int32(10)}, "start": {Value: "2022-05-02"}, "end": {Value: "2022-05-01"}},
+		"en-US": {"title": {Value: "Chair"}},
+	}}
+
+	report, err := Validate(context.Background(), cd, data, []string{"sv-SE", "en-US"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Failure{
+		{Rule: "discount", Code: validator.CodeRuleFailed, Message: "discount requires a higher price"},
+		{Rule: "dates", Code: validator.CodeRuleFailed, Message: "dates"},
+		{Rule: "title", Language: "sv-SE", Code: validator.CodeRuleFailed, Message: "title"},
+	}, report.Failures)
+
+	cd.Rules = []contentdefinition.Rule{{Name: "removed", Expression: "cost > 0.0"}}
+	_, err = Validate(context.Background(), cd, data, []string{"sv-SE", "en-US"})
+	assert.Error(t, err)
+}
+
+func Test_FieldErrorFailure(t *testing.T) {
+
+	report := NewValidationReport()
+	report.Add(
+		FieldError{Field: "price", Language: "sv-SE", Err: errors.New(ErrInvalidNumber)}.Failure(),
+		FieldError{Field: "cost", Language: "sv-SE", Err: errors.New(ErrMissingField)}.Failure(),
+	)
+
+	assert.Equal(t, []Failure{
+		{Property: "price", Language: "sv-SE", Code: validator.CodeInvalidType, Message: ErrInvalidNumber},
+		{Property: "cost", Language: "sv-SE", Code: validator.CodeUnknownProperty, Message: ErrMissingField},
+	}, report.Failures)
+	assert.EqualError(t, report.Err(), "content is not valid: price (sv-SE): value is not a number; cost (sv-SE): field does not exist on content")
+}
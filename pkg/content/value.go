@@ -0,0 +1,359 @@
+package content
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/contentdefinition/validator"
+	"github.com/crikke/cms/pkg/richtext"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The value of a list property is a list of item values. The value of an object property is ContentFields,
+// so the values of sub-properties keep their ID and can follow renames like the values of properties do.
+
+// ListItems returns the items of a list value, nil has no items
+func ListItems(value interface{}) ([]interface{}, error) {
+
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return v, nil
+	case primitive.A:
+		return v, nil
+	}
+
+	return nil, errors.New("value is not a list")
+}
+
+// ObjectFields returns the fields of an object value, nil has no fields.
+// Objects read from the database are documents and are decoded into ContentFields.
+func ObjectFields(value interface{}) (ContentFields, error) {
+
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case ContentFields:
+		return v, nil
+	case primitive.D, primitive.M:
+		data, err := bson.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := ContentFields{}
+		if err := bson.Unmarshal(data, &fields); err != nil {
+			return nil, errors.New("value is not an object")
+		}
+		return fields, nil
+	}
+
+	return nil, errors.New("value is not an object")
+}
+
+// fieldByID returns the field with id, fields are looked up by ID since sub-properties can be renamed
+func (f ContentFields) fieldByID(id uuid.UUID) (ContentField, bool) {
+
+	for _, field := range f {
+		if field.ID == id {
+			return field, true
+		}
+	}
+
+	return ContentField{}, false
+}
+
+// ValuesOfType returns the values of value, a value of a property of pd, that are of propertyType.
+// The items of lists and the sub-properties of objects are included, nil values are left out.
+func ValuesOfType(pd contentdefinition.PropertyDefinition, value interface{}, propertyType string) ([]interface{}, error) {
+
+	if value == nil {
+		return nil, nil
+	}
+
+	if pd.Type == propertyType {
+		return []interface{}{value}, nil
+	}
+
+	result := make([]interface{}, 0)
+	switch pd.Type {
+	case contentdefinition.PropertyTypeList:
+		if pd.Items == nil {
+			return result, nil
+		}
+
+		items, err := ListItems(value)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			values, err := ValuesOfType(*pd.Items, item, propertyType)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, values...)
+		}
+	case contentdefinition.PropertyTypeObject:
+		fields, err := ObjectFields(value)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range propertyNames(pd.Properties) {
+			sub := pd.Properties[name]
+			field, _ := fields.fieldByID(sub.ID)
+
+			values, err := ValuesOfType(sub, field.Value, propertyType)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, values...)
+		}
+	}
+
+	return result, nil
+}
+
+// fieldValue returns value as it is stored for a property of pd.
+// An object is set with the values of its sub-properties by name, sub-properties that are left out are set to nil.
+func fieldValue(pd contentdefinition.PropertyDefinition, value interface{}) (interface{}, error) {
+
+	switch pd.Type {
+	case contentdefinition.PropertyTypeText:
+		switch v := value.(type) {
+		case nil, string:
+			return value, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case int:
+			return strconv.Itoa(v), nil
+		}
+		return nil, errors.New(ErrInvalidText)
+	case contentdefinition.PropertyTypeNumber:
+		return numberValue(value)
+	case contentdefinition.PropertyTypeBool:
+		switch v := value.(type) {
+		case nil, bool:
+			return value, nil
+		case string:
+			// only the JSON literals are read from strings, so "1" and "yes" are not taken for true
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "":
+				return nil, nil
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+		}
+		return nil, errors.New(ErrInvalidBool)
+	case contentdefinition.PropertyTypeReference:
+		// whether the property can reference more than one content is checked when content is published
+		if _, err := (validator.Reference{Multiple: true}).IDs(value); err != nil {
+			return nil, err
+		}
+
+		if value == "" {
+			return nil, nil
+		}
+		return value, nil
+	case contentdefinition.PropertyTypeAsset:
+		if value == nil || value == "" {
+			return nil, nil
+		}
+
+		if s, ok := value.(string); ok {
+			if _, err := uuid.Parse(s); err == nil {
+				return s, nil
+			}
+		}
+		return nil, errors.New(ErrInvalidAsset)
+	case contentdefinition.PropertyTypeRichText:
+		// richtext is converted from html or markdown and sanitized before it is stored, empty documents are stored as nil
+		doc, err := richtext.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if doc.IsEmpty() {
+			return nil, nil
+		}
+		return doc, nil
+	case contentdefinition.PropertyTypeDate:
+		if value == nil || value == "" {
+			return nil, nil
+		}
+
+		return validator.ParseDate(value)
+	case contentdefinition.PropertyTypeDateTime:
+		// datetimes are stored in UTC so they sort and compare correctly regardless of the offset they were set with
+		if value == nil || value == "" {
+			return nil, nil
+		}
+
+		return validator.ParseDateTime(value)
+	case contentdefinition.PropertyTypeEnum:
+		// a single value is stored as a string and a list as a list, whether the values are options is checked when content is published
+		values, err := validator.Enum{Multiple: true}.Values(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, single := value.(string); single || len(values) == 0 {
+			if len(values) > 0 {
+				return values[0], nil
+			}
+			return nil, nil
+		}
+		return values, nil
+	case contentdefinition.PropertyTypeGeoPoint:
+		// geopoints are stored as GeoJSON so they can be indexed, they are set as GeoJSON or with lat and lng
+		if value == nil {
+			return nil, nil
+		}
+
+		return validator.ParseGeoPoint(value)
+	case contentdefinition.PropertyTypeList:
+		if pd.Items == nil {
+			return nil, errors.New(contentdefinition.ErrMissingItemType)
+		}
+
+		items, err := ListItems(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(items) == 0 {
+			return nil, nil
+		}
+
+		values := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			v, err := fieldValue(*pd.Items, item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case contentdefinition.PropertyTypeObject:
+		if value == nil {
+			return nil, nil
+		}
+
+		input, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("value is not an object")
+		}
+
+		for name := range input {
+			if _, ok := pd.Properties[name]; !ok {
+				return nil, fmt.Errorf("%s: %s", ErrMissingField, name)
+			}
+		}
+
+		fields := make(ContentFields, len(pd.Properties))
+		for name, sub := range pd.Properties {
+			v, err := fieldValue(sub, input[name])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err)
+			}
+
+			fields[name] = ContentField{ID: sub.ID, Type: sub.Type, Value: v}
+		}
+		return fields, nil
+	}
+
+	return value, nil
+}
+
+// numberValue returns value as a float64, numbers set as strings are parsed. Numbers are stored as float64
+// so values of the same property compare and sort the same regardless of how they were set.
+func numberValue(value interface{}) (interface{}, error) {
+
+	var n float64
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case float64:
+		n = v
+	case float32:
+		n = float64(v)
+	case int:
+		n = float64(v)
+	case int32:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, errors.New(ErrInvalidNumber)
+		}
+		n = f
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return nil, nil
+		}
+
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.New(ErrInvalidNumber)
+		}
+		n = f
+	default:
+		return nil, errors.New(ErrInvalidNumber)
+	}
+
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, errors.New(ErrInvalidNumber)
+	}
+
+	return n, nil
+}
+
+// reconcileValue returns value with the sub-properties of objects set to their current names,
+// values of deleted sub-properties are dropped and added sub-properties are nil.
+func reconcileValue(pd contentdefinition.PropertyDefinition, value interface{}) interface{} {
+
+	switch pd.Type {
+	case contentdefinition.PropertyTypeList:
+		items, err := ListItems(value)
+		if err != nil || items == nil || pd.Items == nil {
+			return value
+		}
+
+		values := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			values = append(values, reconcileValue(*pd.Items, item))
+		}
+		return values
+	case contentdefinition.PropertyTypeObject:
+		old, err := ObjectFields(value)
+		if err != nil || old == nil {
+			return value
+		}
+
+		fields := make(ContentFields, len(pd.Properties))
+		for name, sub := range pd.Properties {
+			field := ContentField{ID: sub.ID, Type: sub.Type}
+			if match, ok := old.fieldByID(sub.ID); ok {
+				field.Value = reconcileValue(sub, match.Value)
+			}
+			fields[name] = field
+		}
+		return fields
+	}
+
+	return value
+}
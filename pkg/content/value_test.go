@@ -0,0 +1,106 @@
+//go:build unit
+
+package content
+
+import (
+	"testing"
+
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_ObjectFields(t *testing.T) {
+
+	id := uuid.New()
+	fields := ContentFields{"street": {ID: id, Type: contentdefinition.PropertyTypeText, Value: "Storgatan 1"}}
+
+	// objects are documents when they are read from the database
+	data, err := bson.Marshal(bson.M{"value": fields})
+	assert.NoError(t, err)
+	var stored struct{ Value interface{} }
+	assert.NoError(t, bson.Unmarshal(data, &stored))
+
+	actual, err := ObjectFields(stored.Value)
+	assert.NoError(t, err)
+	assert.Equal(t, fields, actual)
+
+	actual, err = ObjectFields(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, actual)
+
+	_, err = ObjectFields("street")
+	assert.Error(t, err)
+}
+
+func Test_ValuesOfType(t *testing.T) {
+
+	positionID := uuid.New()
+	stops := contentdefinition.PropertyDefinition{
+		Type: contentdefinition.PropertyTypeList,
+		Items: &contentdefinition.PropertyDefinition{
+			Type: contentdefinition.PropertyTypeObject,
+			Properties: map[string]contentdefinition.PropertyDefinition{
+				"name":     {ID: uuid.New(), Type: contentdefinition.PropertyTypeText},
+				"position": {ID: positionID, Type: contentdefinition.PropertyTypeGeoPoint},
+			},
+		},
+	}
+
+	first := bson.M{"type": "Point", "coordinates": bson.A{18.06, 59.33}}
+	second := bson.M{"type": "Point", "coordinates": bson.A{11.97, 57.71}}
+	value := primitive.A{
+		ContentFields{"position": {ID: positionID, Value: first}},
+		// a stop without a position has no value
+		ContentFields{},
+		ContentFields{"position": {ID: positionID, Value: second}},
+	}
+
+	actual, err := ValuesOfType(stops, value, contentdefinition.PropertyTypeGeoPoint)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{first, second}, actual)
+
+	actual, err = ValuesOfType(stops, nil, contentdefinition.PropertyTypeGeoPoint)
+	assert.NoError(t, err)
+	assert.Empty(t, actual)
+
+	_, err = ValuesOfType(stops, "stops", contentdefinition.PropertyTypeGeoPoint)
+	assert.Error(t, err)
+}
+
+func Test_ReconcileValue(t *testing.T) {
+
+	streetID := uuid.New()
+	deletedID := uuid.New()
+	zipID := uuid.New()
+
+	pd := contentdefinition.PropertyDefinition{
+		Type: contentdefinition.PropertyTypeList,
+		Items: &contentdefinition.PropertyDefinition{
+			Type: contentdefinition.PropertyTypeObject,
+			Properties: map[string]contentdefinition.PropertyDefinition{
+				"streetname": {ID: streetID, Type: contentdefinition.PropertyTypeText},
+				"zip":        {ID: zipID, Type: contentdefinition.PropertyTypeText},
+			},
+		},
+	}
+
+	old := primitive.A{
+		ContentFields{
+			"street":  {ID: streetID, Type: contentdefinition.PropertyTypeText, Value: "Storgatan 1"},
+			"deleted": {ID: deletedID, Type: contentdefinition.PropertyTypeText, Value: "x"},
+		},
+	}
+
+	expected := []interface{}{
+		ContentFields{
+			"streetname": {ID: streetID, Type: contentdefinition.PropertyTypeText, Value: "Storgatan 1"},
+			"zip":        {ID: zipID, Type: contentdefinition.PropertyTypeText},
+		},
+	}
+
+	assert.Equal(t, expected, reconcileValue(pd, old))
+	assert.Nil(t, reconcileValue(pd, nil))
+}
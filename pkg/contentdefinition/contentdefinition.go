@@ -2,6 +2,7 @@ package contentdefinition
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/crikke/cms/pkg/contentdefinition/validator"
@@ -10,13 +11,38 @@ import (
 
 const (
 	PROPFIELD_NAME = "name"
+	// PROPFIELD_URLSEGMENT is the localized segment of the contents URL
+	PROPFIELD_URLSEGMENT = "urlsegment"
 
 	PropertyTypeText   = "text"
 	PropertyTypeNumber = "number"
 	PropertyTypeBool   = "bool"
+	// PropertyTypeReference points to other content in the same workspace, see validator.Reference
+	PropertyTypeReference = "reference"
+	// PropertyTypeRichText is formatted text stored as a richtext.Document
+	PropertyTypeRichText = "richtext"
+	// PropertyTypeAsset is the ID of an asset in the asset library of the workspace
+	PropertyTypeAsset = "asset"
+	// PropertyTypeDate is a calendar date without time, stored as YYYY-MM-DD
+	PropertyTypeDate = "date"
+	// PropertyTypeDateTime is a point in time, set in RFC 3339 with any time zone offset and stored in UTC
+	PropertyTypeDateTime = "datetime"
+	// PropertyTypeEnum is one of the options of the property, or a list of options, see validator.Enum
+	PropertyTypeEnum = "enum"
+	// PropertyTypeList is a list of values of the type of PropertyDefinition.Items, see validator.ItemCount
+	PropertyTypeList = "list"
+	// PropertyTypeObject is a group of named sub-properties, see PropertyDefinition.Properties
+	PropertyTypeObject = "object"
+	// PropertyTypeGeoPoint is a coordinate stored as a GeoJSON point, see validator.GeoPoint and validator.Boundary
+	PropertyTypeGeoPoint = "geopoint"
 
 	ErrPropertyAlreadyExists = "propertydefinition already exists on contentdefinition"
 	ErrPropertyTypeNotExists = "propertydefinition type does not exist"
+	ErrEnumOptionInUse       = "removed enum option is used by published content"
+	ErrMissingItemType       = "list property requires an item type"
+	ErrNestedPropertyType    = "property type cannot be the item of a list or the sub-property of an object"
+	ErrNotObject             = "parent property is not an object or a list of objects"
+	ErrContentDefinitionUsed = "contentdefinition is used by content"
 )
 
 // swagger:model ContentDefinition
@@ -26,6 +52,10 @@ type ContentDefinition struct {
 	Description         string    `bson:"description,omitempty"`
 	Created             time.Time
 	Propertydefinitions map[string]PropertyDefinition
+	// Rules are validation rules that span properties, see Rule
+	Rules []Rule `bson:"rules,omitempty"`
+	// Revision is incremented on every write and is used to detect concurrent updates
+	Revision int `bson:"revision"`
 }
 
 // swagger:model PropertyDefinition
@@ -38,6 +68,12 @@ type PropertyDefinition struct {
 	// instead of using map[strin]validator.Validator, interface{} is used
 	// this wont be a problem becuase they will be translated to validator.Validator in GetValidatorQueury
 	Validators map[string]interface{} `bson:"validators,omitempty"`
+	// Items is the definition of every item of a list property.
+	// Items cannot be references, assets, richtext or lists, a list of lists is a list of objects with a list sub-property.
+	Items *PropertyDefinition `bson:"items,omitempty"`
+	// Properties are the sub-properties of an object property. Sub-properties are localized with the property they belong to.
+	// Sub-properties cannot be references, assets or richtext.
+	Properties map[string]PropertyDefinition `bson:"properties,omitempty"`
 }
 
 type ContentDefinitionFactory struct {
@@ -63,7 +99,34 @@ func (f ContentDefinitionFactory) NewContentDefinition(name, desc string) (Conte
 		}}, nil
 }
 
+// NewRoutableContentDefinition returns a contentdefinition with a url segment, so its content has a path in the delivery API.
+// Content of other contentdefinitions, like products or blocks, is only found by ID.
+func (f ContentDefinitionFactory) NewRoutableContentDefinition(name, desc string) (ContentDefinition, error) {
+
+	cd, err := f.NewContentDefinition(name, desc)
+	if err != nil {
+		return ContentDefinition{}, err
+	}
+
+	cd.Propertydefinitions[PROPFIELD_URLSEGMENT] = PropertyDefinition{
+		ID:        uuid.New(),
+		Type:      PropertyTypeText,
+		Localized: true,
+	}
+
+	return cd, nil
+}
+
 func (f ContentDefinitionFactory) NewPropertyDefinition(cd *ContentDefinition, name, propertyType, description string, localized bool) error {
+	return f.addPropertyDefinition(cd, name, propertyType, "", description, localized)
+}
+
+// NewListPropertyDefinition adds a list property with items of itemType
+func (f ContentDefinitionFactory) NewListPropertyDefinition(cd *ContentDefinition, name, itemType, description string, localized bool) error {
+	return f.addPropertyDefinition(cd, name, PropertyTypeList, itemType, description, localized)
+}
+
+func (f ContentDefinitionFactory) addPropertyDefinition(cd *ContentDefinition, name, propertyType, itemType, description string, localized bool) error {
 
 	if _, exist := cd.Propertydefinitions[name]; exist {
 		return errors.New(ErrPropertyAlreadyExists)
@@ -73,11 +136,92 @@ func (f ContentDefinitionFactory) NewPropertyDefinition(cd *ContentDefinition, n
 		cd.Propertydefinitions = make(map[string]PropertyDefinition)
 	}
 
+	pd, err := newPropertyDefinition(propertyType, itemType, description)
+	if err != nil {
+		return err
+	}
+
+	pd.Localized = localized
+	cd.Propertydefinitions[name] = pd
+
+	return nil
+}
+
+// NewSubPropertyDefinition adds a sub-property to the object property with parentID, or to the items of the list of objects with parentID.
+// The parent can itself be the sub-property of an object. Returns the ID of the sub-property.
+func (f ContentDefinitionFactory) NewSubPropertyDefinition(cd *ContentDefinition, parentID uuid.UUID, name, propertyType, itemType, description string) (uuid.UUID, error) {
+
+	if name == "" {
+		return uuid.UUID{}, errors.New("name required")
+	}
+
+	if !nestable(propertyType) {
+		return uuid.UUID{}, fmt.Errorf("%s: %s", ErrNestedPropertyType, propertyType)
+	}
+
+	pd, err := newPropertyDefinition(propertyType, itemType, description)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	// values are unique among the values of other content, which is only checked for top level properties
+	delete(pd.Validators, validator.RuleUnique)
+
+	found, err := addSubProperty(cd.Propertydefinitions, parentID, name, pd)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if !found {
+		return uuid.UUID{}, errors.New("property not found")
+	}
+
+	return pd.ID, nil
+}
+
+// addSubProperty adds pd to the parent with parentID among properties and their sub-properties, returns false if the parent is not found
+func addSubProperty(properties map[string]PropertyDefinition, parentID uuid.UUID, name string, pd PropertyDefinition) (bool, error) {
+
+	for n, p := range properties {
+		parent := &p
+		if p.Type == PropertyTypeList && p.Items != nil {
+			parent = p.Items
+		}
+
+		if p.ID == parentID || parent.ID == parentID {
+			if parent.Type != PropertyTypeObject {
+				return false, errors.New(ErrNotObject)
+			}
+
+			if _, exists := parent.Properties[name]; exists {
+				return false, errors.New(ErrPropertyAlreadyExists)
+			}
+
+			if parent.Properties == nil {
+				parent.Properties = make(map[string]PropertyDefinition)
+			}
+
+			parent.Properties[name] = pd
+			properties[n] = p
+			return true, nil
+		}
+
+		found, err := addSubProperty(parent.Properties, parentID, name, pd)
+		if found || err != nil {
+			return found, err
+		}
+	}
+
+	return false, nil
+}
+
+// newPropertyDefinition returns a property definition of propertyType with its default validators
+func newPropertyDefinition(propertyType, itemType, description string) (PropertyDefinition, error) {
+
 	pd := PropertyDefinition{
 		ID:          uuid.New(),
 		Description: description,
 		Type:        propertyType,
-		Localized:   localized,
 		Validators: map[string]interface{}{
 			validator.RuleRequired: validator.Required(false),
 		},
@@ -87,32 +231,80 @@ func (f ContentDefinitionFactory) NewPropertyDefinition(cd *ContentDefinition, n
 	case PropertyTypeText:
 		pd.Validators[validator.RuleRegex] = validator.Regex("")
 		pd.Validators[validator.RuleRange] = validator.Range{}
+		pd.Validators[validator.RuleUnique] = validator.Unique{}
 	case PropertyTypeBool:
 		break
 	case PropertyTypeNumber:
 		pd.Validators[validator.RuleRange] = validator.Range{}
+		pd.Validators[validator.RuleUnique] = validator.Unique{}
+	case PropertyTypeReference:
+		pd.Validators[validator.RuleReference] = validator.Reference{}
+	case PropertyTypeRichText, PropertyTypeAsset:
+		break
+	case PropertyTypeDate, PropertyTypeDateTime:
+		pd.Validators[validator.RuleDateRange] = validator.DateRange{}
+		pd.Validators[validator.RuleUnique] = validator.Unique{}
+	case PropertyTypeEnum:
+		pd.Validators[validator.RuleEnum] = validator.Enum{}
+	case PropertyTypeGeoPoint:
+		pd.Validators[validator.RuleBoundary] = validator.Boundary{}
+	case PropertyTypeList:
+		if itemType == "" {
+			return PropertyDefinition{}, errors.New(ErrMissingItemType)
+		}
+
+		// a list of lists is a list of objects with a list sub-property
+		if !nestable(itemType) || itemType == PropertyTypeList {
+			return PropertyDefinition{}, fmt.Errorf("%s: %s", ErrNestedPropertyType, itemType)
+		}
+
+		items, err := newPropertyDefinition(itemType, "", "")
+		if err != nil {
+			return PropertyDefinition{}, err
+		}
+		delete(items.Validators, validator.RuleUnique)
+
+		pd.Items = &items
+		pd.Validators[validator.RuleItemCount] = validator.ItemCount{}
+	case PropertyTypeObject:
+		pd.Properties = make(map[string]PropertyDefinition)
 	default:
-		return errors.New(ErrPropertyTypeNotExists)
+		return PropertyDefinition{}, errors.New(ErrPropertyTypeNotExists)
 	}
 
-	cd.Propertydefinitions[name] = pd
+	return pd, nil
+}
 
-	return nil
+// nestable returns true if propertyType can be the item of a list or the sub-property of an object.
+// The content a version references and the assets it uses are read from its top level properties when it is saved,
+// and the delivery API only expands and serves those, so references, assets and richtext cannot be nested.
+func nestable(propertyType string) bool {
+
+	switch propertyType {
+	case PropertyTypeReference, PropertyTypeAsset, PropertyTypeRichText:
+		return false
+	}
+
+	return true
 }
 
 func (f ContentDefinitionFactory) UpdatePropertyDefinitionName(cd *ContentDefinition, id uuid.UUID, name string) error {
+	return renamePropertyDefinition(cd.Propertydefinitions, id, name)
+}
+
+func renamePropertyDefinition(properties map[string]PropertyDefinition, id uuid.UUID, name string) error {
 
 	if name == "" {
 		return errors.New("name required")
 	}
 
-	if _, exists := cd.Propertydefinitions[name]; exists {
+	if _, exists := properties[name]; exists {
 		return errors.New("property with name already exists")
 	}
 
 	pd := PropertyDefinition{}
 	pdName := ""
-	for n, p := range cd.Propertydefinitions {
+	for n, p := range properties {
 		if p.ID == id {
 			pd = p
 			pdName = n
@@ -123,21 +315,26 @@ func (f ContentDefinitionFactory) UpdatePropertyDefinitionName(cd *ContentDefini
 		return errors.New("property not found")
 	}
 
-	delete(cd.Propertydefinitions, pdName)
-	cd.Propertydefinitions[name] = pd
+	delete(properties, pdName)
+	properties[name] = pd
 
 	return nil
 }
 
 // This is essentialy an HTTP PUT, every propertydefinition that must be included in the argument, otherwise it is assumed to be deleted.
+// The items of lists and the sub-properties of objects are updated the same way, sub-properties are left as they are if Properties is nil.
 func (f ContentDefinitionFactory) UpdatePropertyDefinitions(cd *ContentDefinition, propertyDefinitions map[string]PropertyDefinition) error {
+	return f.updatePropertyDefinitions(cd.Propertydefinitions, propertyDefinitions)
+}
+
+func (f ContentDefinitionFactory) updatePropertyDefinitions(properties map[string]PropertyDefinition, propertyDefinitions map[string]PropertyDefinition) error {
 
 	// All propertydefinitions that havent been updated will be deleted.
 	updatedProps := make(map[uuid.UUID]PropertyDefinition, 0)
 
 	for _, prop := range propertyDefinitions {
-		err := f.UpdatePropertyDefinition(
-			cd,
+		err := f.updatePropertyDefinition(
+			properties,
 			prop.ID,
 			prop.Description,
 			prop.Localized,
@@ -148,16 +345,20 @@ func (f ContentDefinitionFactory) UpdatePropertyDefinitions(cd *ContentDefinitio
 		if err != nil {
 			return err
 		}
+
+		if err := f.updateNestedPropertyDefinitions(properties, prop); err != nil {
+			return err
+		}
 	}
 
 	// check for props that should be deleted
-	for name, prop := range cd.Propertydefinitions {
+	for name, prop := range properties {
 
 		if _, ok := updatedProps[prop.ID]; ok {
 			continue
 		}
 
-		delete(cd.Propertydefinitions, name)
+		delete(properties, name)
 
 	}
 
@@ -168,7 +369,7 @@ func (f ContentDefinitionFactory) UpdatePropertyDefinitions(cd *ContentDefinitio
 	// update property names
 	for name, prop := range propertyDefinitions {
 
-		existing, ok := cd.Propertydefinitions[name]
+		existing, ok := properties[name]
 
 		if ok {
 
@@ -182,7 +383,7 @@ func (f ContentDefinitionFactory) UpdatePropertyDefinitions(cd *ContentDefinitio
 			continue
 		}
 
-		if err := f.UpdatePropertyDefinitionName(cd, prop.ID, name); err != nil {
+		if err := renamePropertyDefinition(properties, prop.ID, name); err != nil {
 			return err
 		}
 	}
@@ -190,10 +391,52 @@ func (f ContentDefinitionFactory) UpdatePropertyDefinitions(cd *ContentDefinitio
 	return nil
 }
 
+// updateNestedPropertyDefinitions updates the items and sub-properties of the property with the ID of updated
+func (f ContentDefinitionFactory) updateNestedPropertyDefinitions(properties map[string]PropertyDefinition, updated PropertyDefinition) error {
+
+	for name, pd := range properties {
+		if pd.ID != updated.ID {
+			continue
+		}
+
+		if pd.Items != nil && updated.Items != nil {
+			// the items are a single property, so they are updated as a map of one
+			items := map[string]PropertyDefinition{"items": *pd.Items}
+			updatedItems := *updated.Items
+			updatedItems.ID = pd.Items.ID
+			if err := f.updatePropertyDefinitions(items, map[string]PropertyDefinition{"items": updatedItems}); err != nil {
+				return err
+			}
+
+			item := items["items"]
+			pd.Items = &item
+		}
+
+		if pd.Type == PropertyTypeObject && updated.Properties != nil {
+			if pd.Properties == nil {
+				pd.Properties = make(map[string]PropertyDefinition)
+			}
+
+			if err := f.updatePropertyDefinitions(pd.Properties, updated.Properties); err != nil {
+				return err
+			}
+		}
+
+		properties[name] = pd
+		return nil
+	}
+
+	return nil
+}
+
 func (f ContentDefinitionFactory) UpdatePropertyDefinition(cd *ContentDefinition, id uuid.UUID, desc string, localized bool, validationRules map[string]interface{}) error {
+	return f.updatePropertyDefinition(cd.Propertydefinitions, id, desc, localized, validationRules)
+}
+
+func (f ContentDefinitionFactory) updatePropertyDefinition(properties map[string]PropertyDefinition, id uuid.UUID, desc string, localized bool, validationRules map[string]interface{}) error {
 	pd := PropertyDefinition{}
 	pdName := ""
-	for n, p := range cd.Propertydefinitions {
+	for n, p := range properties {
 		if p.ID == id {
 			pd = p
 			pdName = n
@@ -206,9 +449,8 @@ func (f ContentDefinitionFactory) UpdatePropertyDefinition(cd *ContentDefinition
 		return errors.New("property not found")
 	}
 
-	pd.Localized = localized
-	pd.Description = desc
-
+	// every rule is decoded before any is stored, so content is never validated against a configuration that cannot be parsed
+	parsed := make(map[string]validator.Validator, len(validationRules))
 	for k, v := range validationRules {
 		_, ok := pd.Validators[k]
 
@@ -216,10 +458,21 @@ func (f ContentDefinitionFactory) UpdatePropertyDefinition(cd *ContentDefinition
 			return errors.New("validator not found")
 		}
 
-		pd.Validators[k] = v
+		rule, err := validator.Parse(k, v)
+		if err != nil {
+			return err
+		}
+		parsed[k] = rule
+	}
+
+	pd.Localized = localized
+	pd.Description = desc
+
+	for k, rule := range parsed {
+		pd.Validators[k] = rule
 	}
 
-	cd.Propertydefinitions[pdName] = pd
+	properties[pdName] = pd
 	return nil
 }
 
@@ -35,6 +35,89 @@ func Test_NewPropertyDefinition(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "reference property",
+			contentDef: ContentDefinition{
+				Name: "test",
+			},
+			typ: PropertyTypeReference,
+			expect: PropertyDefinition{
+				Type: PropertyTypeReference,
+				Validators: map[string]interface{}{
+					validator.RuleRequired:  validator.Required(false),
+					validator.RuleReference: validator.Reference{},
+				},
+			},
+		},
+		{
+			name: "richtext property",
+			contentDef: ContentDefinition{
+				Name: "test",
+			},
+			typ: PropertyTypeRichText,
+			expect: PropertyDefinition{
+				Type: PropertyTypeRichText,
+				Validators: map[string]interface{}{
+					validator.RuleRequired: validator.Required(false),
+				},
+			},
+		},
+		{
+			name: "asset property",
+			contentDef: ContentDefinition{
+				Name: "test",
+			},
+			typ: PropertyTypeAsset,
+			expect: PropertyDefinition{
+				Type: PropertyTypeAsset,
+				Validators: map[string]interface{}{
+					validator.RuleRequired: validator.Required(false),
+				},
+			},
+		},
+		{
+			name: "datetime property",
+			contentDef: ContentDefinition{
+				Name: "test",
+			},
+			typ: PropertyTypeDateTime,
+			expect: PropertyDefinition{
+				Type: PropertyTypeDateTime,
+				Validators: map[string]interface{}{
+					validator.RuleRequired:  validator.Required(false),
+					validator.RuleDateRange: validator.DateRange{},
+					validator.RuleUnique:    validator.Unique{},
+				},
+			},
+		},
+		{
+			name: "enum property",
+			contentDef: ContentDefinition{
+				Name: "test",
+			},
+			typ: PropertyTypeEnum,
+			expect: PropertyDefinition{
+				Type: PropertyTypeEnum,
+				Validators: map[string]interface{}{
+					validator.RuleRequired: validator.Required(false),
+					validator.RuleEnum:     validator.Enum{},
+				},
+			},
+		},
+		{
+			name: "geopoint property",
+			contentDef: ContentDefinition{
+				Name: "test",
+			},
+			typ: PropertyTypeGeoPoint,
+			expect: PropertyDefinition{
+				Type: PropertyTypeGeoPoint,
+				Validators: map[string]interface{}{
+					validator.RuleRequired: validator.Required(false),
+					validator.RuleBoundary: validator.Boundary{},
+				},
+			},
+		},
 		{
 			name: "prop already exist",
 			contentDef: ContentDefinition{
@@ -201,7 +284,120 @@ func Test_UpdatePropertyDefinition(t *testing.T) {
 				Type:        "text",
 				Localized:   true,
 				Validators: map[string]interface{}{
-					validator.RuleRequired: true,
+					validator.RuleRequired: validator.Required(true),
+				},
+			},
+		},
+		{
+			name: "enum options are parsed",
+			id:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+			validators: map[string]interface{}{
+				validator.RuleEnum: map[string]interface{}{
+					"options": []interface{}{
+						map[string]interface{}{"value": "red", "labels": map[string]interface{}{"sv-SE": "Röd"}},
+					},
+				},
+			},
+			contentDef: ContentDefinition{
+				Propertydefinitions: map[string]PropertyDefinition{
+					"prop": {
+						ID:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+						Type: PropertyTypeEnum,
+						Validators: map[string]interface{}{
+							validator.RuleEnum: validator.Enum{},
+						},
+					},
+				},
+			},
+			expect: PropertyDefinition{
+				ID:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+				Type: PropertyTypeEnum,
+				Validators: map[string]interface{}{
+					validator.RuleEnum: validator.Enum{
+						Options: []validator.EnumOption{{Value: "red", Labels: map[string]string{"sv-SE": "Röd"}}},
+					},
+				},
+			},
+		},
+		{
+			name: "duplicate enum options",
+			id:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+			validators: map[string]interface{}{
+				validator.RuleEnum: validator.Enum{
+					Options: []validator.EnumOption{{Value: "red"}, {Value: "red"}},
+				},
+			},
+			contentDef: ContentDefinition{
+				Propertydefinitions: map[string]PropertyDefinition{
+					"prop": {
+						ID:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+						Type: PropertyTypeEnum,
+						Validators: map[string]interface{}{
+							validator.RuleEnum: validator.Enum{},
+						},
+					},
+				},
+			},
+			expectedErr: "enum: option red is not unique",
+			expect: PropertyDefinition{
+				ID:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+				Type: PropertyTypeEnum,
+				Validators: map[string]interface{}{
+					validator.RuleEnum: validator.Enum{},
+				},
+			},
+		},
+		{
+			name: "unique is parsed",
+			id:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+			validators: map[string]interface{}{
+				validator.RuleUnique: true,
+			},
+			contentDef: ContentDefinition{
+				Propertydefinitions: map[string]PropertyDefinition{
+					"prop": {
+						ID:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+						Type: PropertyTypeText,
+						Validators: map[string]interface{}{
+							validator.RuleUnique: validator.Unique{},
+						},
+					},
+				},
+			},
+			expect: PropertyDefinition{
+				ID:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+				Type: PropertyTypeText,
+				Validators: map[string]interface{}{
+					validator.RuleUnique: validator.Unique{Enabled: true},
+				},
+			},
+		},
+		{
+			name: "boundary is parsed",
+			id:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+			validators: map[string]interface{}{
+				validator.RuleBoundary: map[string]interface{}{
+					"points": []interface{}{
+						[]interface{}{17.75, 59.23}, []interface{}{18.2, 59.23}, []interface{}{18.2, 59.43},
+					},
+				},
+			},
+			contentDef: ContentDefinition{
+				Propertydefinitions: map[string]PropertyDefinition{
+					"prop": {
+						ID:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+						Type: PropertyTypeGeoPoint,
+						Validators: map[string]interface{}{
+							validator.RuleBoundary: validator.Boundary{},
+						},
+					},
+				},
+			},
+			expect: PropertyDefinition{
+				ID:   uuid.MustParse("ae4b4e24-d3e5-4efa-83a5-f9d6eeadabe9"),
+				Type: PropertyTypeGeoPoint,
+				Validators: map[string]interface{}{
+					validator.RuleBoundary: validator.Boundary{Points: [][]float64{{17.75, 59.23}, {18.2, 59.23}, {18.2, 59.43}}},
 				},
 			},
 		},
@@ -301,3 +497,100 @@ func Test_UpdatePropertyDefinitionRange(t *testing.T) {
 		})
 	}
 }
+
+func Test_NewListPropertyDefinition(t *testing.T) {
+
+	f := ContentDefinitionFactory{}
+	cd := ContentDefinition{}
+
+	assert.NoError(t, f.NewListPropertyDefinition(&cd, "tags", PropertyTypeText, "", false))
+	tags := cd.Propertydefinitions["tags"]
+	assert.Equal(t, PropertyTypeList, tags.Type)
+	assert.Equal(t, validator.ItemCount{}, tags.Validators[validator.RuleItemCount])
+	if assert.NotNil(t, tags.Items) {
+		assert.Equal(t, PropertyTypeText, tags.Items.Type)
+	}
+
+	assert.EqualError(t, f.NewPropertyDefinition(&cd, "list", PropertyTypeList, "", false), ErrMissingItemType)
+	assert.EqualError(t, f.NewListPropertyDefinition(&cd, "lists", PropertyTypeList, "", false), ErrNestedPropertyType+": list")
+	assert.EqualError(t, f.NewListPropertyDefinition(&cd, "refs", PropertyTypeReference, "", false), ErrNestedPropertyType+": reference")
+	assert.EqualError(t, f.NewListPropertyDefinition(&cd, "other", "some random string", "", false), ErrPropertyTypeNotExists)
+}
+
+func Test_NewSubPropertyDefinition(t *testing.T) {
+
+	f := ContentDefinitionFactory{}
+	cd := ContentDefinition{}
+
+	assert.NoError(t, f.NewPropertyDefinition(&cd, "address", PropertyTypeObject, "", true))
+	assert.NoError(t, f.NewListPropertyDefinition(&cd, "stops", PropertyTypeObject, "", false))
+	assert.NoError(t, f.NewPropertyDefinition(&cd, "title", PropertyTypeText, "", false))
+
+	address := cd.Propertydefinitions["address"].ID
+	stops := cd.Propertydefinitions["stops"].ID
+
+	street, err := f.NewSubPropertyDefinition(&cd, address, "street", PropertyTypeText, "", "")
+	assert.NoError(t, err)
+	position, err := f.NewSubPropertyDefinition(&cd, address, "position", PropertyTypeObject, "", "")
+	assert.NoError(t, err)
+	_, err = f.NewSubPropertyDefinition(&cd, stops, "arrival", PropertyTypeDateTime, "", "")
+	assert.NoError(t, err)
+
+	// sub-properties of sub-properties
+	_, err = f.NewSubPropertyDefinition(&cd, position, "lines", PropertyTypeList, PropertyTypeNumber, "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, street, cd.Propertydefinitions["address"].Properties["street"].ID)
+	assert.NotContains(t, cd.Propertydefinitions["address"].Properties["street"].Validators, validator.RuleUnique)
+	assert.Equal(t, PropertyTypeText, cd.Propertydefinitions["address"].Properties["street"].Type)
+	assert.Equal(t, PropertyTypeDateTime, cd.Propertydefinitions["stops"].Items.Properties["arrival"].Type)
+	assert.Equal(t, PropertyTypeNumber, cd.Propertydefinitions["address"].Properties["position"].Properties["lines"].Items.Type)
+
+	_, err = f.NewSubPropertyDefinition(&cd, address, "street", PropertyTypeText, "", "")
+	assert.EqualError(t, err, ErrPropertyAlreadyExists)
+	_, err = f.NewSubPropertyDefinition(&cd, address, "body", PropertyTypeRichText, "", "")
+	assert.EqualError(t, err, ErrNestedPropertyType+": richtext")
+	_, err = f.NewSubPropertyDefinition(&cd, address, "images", PropertyTypeList, PropertyTypeAsset, "")
+	assert.EqualError(t, err, ErrNestedPropertyType+": asset")
+	_, err = f.NewSubPropertyDefinition(&cd, cd.Propertydefinitions["title"].ID, "x", PropertyTypeText, "", "")
+	assert.EqualError(t, err, ErrNotObject)
+	_, err = f.NewSubPropertyDefinition(&cd, uuid.New(), "x", PropertyTypeText, "", "")
+	assert.EqualError(t, err, "property not found")
+}
+
+func Test_UpdateNestedPropertyDefinitions(t *testing.T) {
+
+	f := ContentDefinitionFactory{}
+	cd := ContentDefinition{}
+
+	assert.NoError(t, f.NewListPropertyDefinition(&cd, "stops", PropertyTypeObject, "", false))
+	stops := cd.Propertydefinitions["stops"]
+	_, err := f.NewSubPropertyDefinition(&cd, stops.ID, "arrival", PropertyTypeDateTime, "", "")
+	assert.NoError(t, err)
+	_, err = f.NewSubPropertyDefinition(&cd, stops.ID, "platform", PropertyTypeText, "", "")
+	assert.NoError(t, err)
+
+	arrival := cd.Propertydefinitions["stops"].Items.Properties["arrival"]
+	min := 1
+
+	// platform is deleted, arrival is renamed and the item count is set
+	err = f.UpdatePropertyDefinitions(&cd, map[string]PropertyDefinition{
+		"stops": {
+			ID:         stops.ID,
+			Validators: map[string]interface{}{validator.RuleItemCount: validator.ItemCount{Min: &min}},
+			Items: &PropertyDefinition{
+				Properties: map[string]PropertyDefinition{
+					"arrives": {ID: arrival.ID, Description: "arrival time"},
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	updated := cd.Propertydefinitions["stops"]
+	assert.Equal(t, validator.ItemCount{Min: &min}, updated.Validators[validator.RuleItemCount])
+	assert.Len(t, updated.Items.Properties, 1)
+	assert.Equal(t, arrival.ID, updated.Items.Properties["arrives"].ID)
+	assert.Equal(t, "arrival time", updated.Items.Properties["arrives"].Description)
+	assert.Equal(t, PropertyTypeDateTime, updated.Items.Properties["arrives"].Type)
+}
@@ -3,6 +3,7 @@ package contentdefinition
 import (
 	"context"
 
+	"github.com/crikke/cms/pkg/db"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -47,21 +48,57 @@ func (r ContentDefinitionRepository) UpdateContentDefinition(ctx context.Context
 		return err
 	}
 
-	_, err = r.client.Database(workspaceId.String()).
-		Collection("contentdefinition").
-		UpdateOne(
-			ctx,
-			bson.D{bson.E{Key: "_id", Value: id}},
-			bson.M{"$set": e})
+	col := r.client.Database(workspaceId.String()).Collection(contentdefinitionCollection)
+	e.Revision = entry.Revision + 1
+
+	res, err := col.UpdateOne(
+		ctx,
+		bson.D{
+			bson.E{Key: "_id", Value: id},
+			bson.E{Key: "revision", Value: db.RevisionFilter(entry.Revision)}},
+		bson.M{"$set": e})
 
 	if err != nil {
 		return err
 	}
 
+	if res.MatchedCount == 0 {
+		return db.RevisionConflict(ctx, col, bson.D{bson.E{Key: "_id", Value: id}})
+	}
+
 	return nil
 }
 
-func (r ContentDefinitionRepository) DeleteContentDefinition(ctx context.Context, id uuid.UUID) error {
+// DeleteContentDefinition deletes the contentdefinition if deleteFn does not return an error.
+// The delete fails with a RevisionMismatchError if the contentdefinition is modified after it was read.
+func (r ContentDefinitionRepository) DeleteContentDefinition(ctx context.Context, id uuid.UUID, workspaceId uuid.UUID, deleteFn func(ctx context.Context, cd *ContentDefinition) error) error {
+
+	entry := &ContentDefinition{}
+	col := r.client.Database(workspaceId.String()).Collection(contentdefinitionCollection)
+
+	err := col.FindOne(ctx, bson.M{"_id": id}).Decode(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteFn(ctx, entry); err != nil {
+		return err
+	}
+
+	res, err := col.DeleteOne(
+		ctx,
+		bson.D{
+			bson.E{Key: "_id", Value: id},
+			bson.E{Key: "revision", Value: db.RevisionFilter(entry.Revision)}})
+
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return db.RevisionConflict(ctx, col, bson.D{bson.E{Key: "_id", Value: id}})
+	}
+
 	return nil
 }
 
@@ -104,6 +141,33 @@ func (r ContentDefinitionRepository) ListContentDefinitions(ctx context.Context,
 	return items, nil
 }
 
+// ListContentDefinitionsPage returns a page of the content definitions, sorted by name or created
+func (r ContentDefinitionRepository) ListContentDefinitionsPage(ctx context.Context, page db.Page, workspaceId uuid.UUID) ([]ContentDefinition, db.PageResult, error) {
+
+	items := make([]ContentDefinition, 0)
+	res, err := db.FindPage(
+		ctx,
+		r.client.Database(workspaceId.String()).Collection(contentdefinitionCollection),
+		bson.M{},
+		page,
+		nil,
+		func(cursor *mongo.Cursor) error {
+			item := &ContentDefinition{}
+			if err := cursor.Decode(item); err != nil {
+				return err
+			}
+
+			items = append(items, *item)
+			return nil
+		})
+
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	return items, res, nil
+}
+
 func (r ContentDefinitionRepository) CreatePropertyDefinition(ctx context.Context, cid uuid.UUID, workspaceId uuid.UUID, pd *PropertyDefinition) (uuid.UUID, error) {
 	pd.ID = uuid.New()
 
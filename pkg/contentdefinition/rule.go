@@ -0,0 +1,206 @@
+package contentdefinition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+const (
+	ErrInvalidRule = "invalid rule"
+
+	// RuleFieldsVariable is the variable of rule expressions that contains the properties that have a value,
+	// has(fields.price) is true if price is set
+	RuleFieldsVariable = "fields"
+
+	// ruleCostLimit stops the evaluation of expressions that iterate over large lists
+	ruleCostLimit = 100000
+)
+
+// identifier matches the property names that can be variables of rule expressions
+var identifier = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+
+// Rule is a validation rule that spans properties of the contentdefinition, like an end date that must be after a start date.
+// The expression is CEL, https://github.com/google/cel-spec, and is evaluated when content is published.
+//
+// Every property is a variable of its type: text, richtext and asset are strings, numbers are doubles, date and datetime are timestamps,
+// lists are lists and objects and geopoints are maps. Enum and reference properties are a string or a list of strings.
+// Properties without a value are the zero value of their type, use has(fields.name) to check whether a property is set.
+type Rule struct {
+	// Name identifies the rule in validation reports
+	Name string `bson:"name" json:"name"`
+	// Expression must evaluate to true for content to be valid, for example: !has(fields.discount) || price > discount
+	Expression string `bson:"expression" json:"expression"`
+	// Message describes why content is not valid, defaults to the name of the rule
+	Message string `bson:"message,omitempty" json:"message,omitempty"`
+}
+
+// CompiledRule is a rule that has been type checked against the properties of the contentdefinition
+type CompiledRule struct {
+	Rule
+	// Localized is set if the expression uses a localized property, the rule is then evaluated in every language
+	Localized bool
+
+	program cel.Program
+	zero    map[string]interface{}
+}
+
+// UpdateRules replaces the rules of cd, the expressions are compiled so invalid rules are never stored
+func (f ContentDefinitionFactory) UpdateRules(cd *ContentDefinition, rules []Rule) error {
+
+	previous := cd.Rules
+	cd.Rules = rules
+
+	if _, err := cd.CompileRules(); err != nil {
+		cd.Rules = previous
+		return err
+	}
+
+	return nil
+}
+
+// CompileRules type checks the expressions of the rules of cd against its properties. It fails if an expression uses
+// a property that has been removed or renamed, or compares it with a value of another type.
+func (cd ContentDefinition) CompileRules() ([]CompiledRule, error) {
+
+	if len(cd.Rules) == 0 {
+		return nil, nil
+	}
+
+	opts := []cel.EnvOption{cel.Variable(RuleFieldsVariable, cel.MapType(cel.StringType, cel.DynType))}
+	zero := make(map[string]interface{})
+	for name, pd := range cd.Propertydefinitions {
+		if name == RuleFieldsVariable || !identifier.MatchString(name) {
+			continue
+		}
+
+		t, z := ruleType(pd)
+		opts = append(opts, cel.Variable(name, t))
+		zero[name] = z
+	}
+
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(cd.Rules))
+	compiled := make([]CompiledRule, 0, len(cd.Rules))
+	for _, r := range cd.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("%s: name is required", ErrInvalidRule)
+		}
+
+		if names[r.Name] {
+			return nil, fmt.Errorf("%s: %s: name is not unique", ErrInvalidRule, r.Name)
+		}
+		names[r.Name] = true
+
+		ast, iss := env.Compile(r.Expression)
+		if iss.Err() != nil {
+			return nil, fmt.Errorf("%s: %s: %s", ErrInvalidRule, r.Name, iss.Err())
+		}
+
+		// dyn is a value of fields, it is checked when the rule is evaluated
+		if out := ast.OutputType().String(); out != cel.BoolType.String() && out != cel.DynType.String() {
+			return nil, fmt.Errorf("%s: %s: expression must be a bool, not %s", ErrInvalidRule, r.Name, out)
+		}
+
+		program, err := env.Program(ast, cel.CostLimit(ruleCostLimit))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %s", ErrInvalidRule, r.Name, err)
+		}
+
+		localized, err := cd.localizedRule(ast)
+		if err != nil {
+			return nil, err
+		}
+
+		compiled = append(compiled, CompiledRule{
+			Rule:      r,
+			Localized: localized,
+			program:   program,
+			zero:      zero,
+		})
+	}
+
+	return compiled, nil
+}
+
+// localizedRule returns true if the expression uses a localized property, or every property through the fields variable
+func (cd ContentDefinition) localizedRule(ast *cel.Ast) (bool, error) {
+
+	checked, err := cel.AstToCheckedExpr(ast)
+	if err != nil {
+		return false, err
+	}
+
+	for _, ref := range checked.ReferenceMap {
+		if ref.Name == RuleFieldsVariable {
+			return true, nil
+		}
+
+		if pd, ok := cd.Propertydefinitions[ref.Name]; ok && pd.Localized {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Eval evaluates the rule with values of the properties by name, see Rule for the types of the values.
+func (r CompiledRule) Eval(ctx context.Context, values map[string]interface{}) (bool, error) {
+
+	vars := make(map[string]interface{}, len(r.zero)+1)
+	for name, z := range r.zero {
+		vars[name] = z
+		if v, ok := values[name]; ok && v != nil {
+			vars[name] = v
+		}
+	}
+
+	fields := make(map[string]interface{}, len(values))
+	for name, v := range values {
+		if v != nil {
+			fields[name] = v
+		}
+	}
+	vars[RuleFieldsVariable] = fields
+
+	out, _, err := r.program.ContextEval(ctx, vars)
+	if err != nil {
+		return false, err
+	}
+
+	valid, ok := out.Value().(bool)
+	if !ok {
+		return false, errors.New("expression is not a bool")
+	}
+
+	return valid, nil
+}
+
+// ruleType returns the type of a property in rule expressions and the value of the property when it is not set
+func ruleType(pd PropertyDefinition) (*cel.Type, interface{}) {
+
+	switch pd.Type {
+	case PropertyTypeText, PropertyTypeRichText, PropertyTypeAsset:
+		return cel.StringType, ""
+	case PropertyTypeNumber:
+		return cel.DoubleType, 0.0
+	case PropertyTypeBool:
+		return cel.BoolType, false
+	case PropertyTypeDate, PropertyTypeDateTime:
+		return cel.TimestampType, time.Time{}
+	case PropertyTypeList:
+		return cel.ListType(cel.DynType), []interface{}{}
+	case PropertyTypeObject, PropertyTypeGeoPoint:
+		return cel.MapType(cel.StringType, cel.DynType), map[string]interface{}{}
+	}
+
+	return cel.DynType, nil
+}
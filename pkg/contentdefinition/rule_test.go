@@ -0,0 +1,127 @@
+//go:build unit
+
+package contentdefinition
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ruleContentDefinition(rules ...Rule) ContentDefinition {
+	return ContentDefinition{
+		Propertydefinitions: map[string]PropertyDefinition{
+			"title":    {Type: PropertyTypeText, Localized: true},
+			"price":    {Type: PropertyTypeNumber},
+			"discount": {Type: PropertyTypeNumber},
+			"start":    {Type: PropertyTypeDate},
+			"end":      {Type: PropertyTypeDateTime},
+			"tags":     {Type: PropertyTypeList},
+			"color":    {Type: PropertyTypeEnum},
+			"not-used": {Type: PropertyTypeText},
+		},
+		Rules: rules,
+	}
+}
+
+func Test_CompileRules(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		expression string
+		localized  bool
+		err        string
+	}{
+		{name: "numbers", expression: "discount < price"},
+		{name: "dates", expression: "!has(fields.end) || end > start", localized: true},
+		{name: "localized", expression: `title != ""`, localized: true},
+		{name: "fields", expression: `has(fields.title)`, localized: true},
+		{name: "dyn", expression: `color == "red" || size(tags) > 0`},
+		{name: "unknown property", expression: "cost > 0.0", err: "undeclared reference to 'cost'"},
+		{name: "type mismatch", expression: `price > "10"`, err: "found no matching overload"},
+		{name: "not a bool", expression: "price + discount", err: "expression must be a bool, not double"},
+		{name: "syntax", expression: "price >", err: "Syntax error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cd := ruleContentDefinition(Rule{Name: test.name, Expression: test.expression})
+
+			rules, err := cd.CompileRules()
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.True(t, strings.HasPrefix(err.Error(), ErrInvalidRule))
+					assert.Contains(t, err.Error(), test.err)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			if assert.Len(t, rules, 1) {
+				assert.Equal(t, test.localized, rules[0].Localized)
+			}
+		})
+	}
+
+	_, err := ruleContentDefinition(Rule{Name: "a", Expression: "true"}, Rule{Name: "a", Expression: "true"}).CompileRules()
+	assert.EqualError(t, err, "invalid rule: a: name is not unique")
+
+	_, err = ruleContentDefinition(Rule{Expression: "true"}).CompileRules()
+	assert.EqualError(t, err, "invalid rule: name is required")
+}
+
+func Test_RuleEval(t *testing.T) {
+
+	cd := ruleContentDefinition(
+		Rule{Name: "discount requires price", Expression: "!has(fields.discount) || price > discount"},
+		Rule{Name: "end after start", Expression: "!has(fields.end) || end > start"},
+	)
+
+	rules, err := cd.CompileRules()
+	assert.NoError(t, err)
+
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		values   map[string]interface{}
+		expected []bool
+	}{
+		{name: "not set", values: map[string]interface{}{}, expected: []bool{true, true}},
+		{name: "discount without price", values: map[string]interface{}{"discount": 10.0}, expected: []bool{false, true}},
+		{name: "discount with price", values: map[string]interface{}{"discount": 10.0, "price": 20.0}, expected: []bool{true, true}},
+		{name: "end before start", values: map[string]interface{}{"start": start, "end": start.Add(-time.Hour)}, expected: []bool{true, false}},
+		{name: "end after start", values: map[string]interface{}{"start": start, "end": start.Add(time.Hour)}, expected: []bool{true, true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i, r := range rules {
+				valid, err := r.Eval(context.Background(), test.values)
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected[i], valid, r.Name)
+			}
+		})
+	}
+}
+
+func Test_UpdateRules(t *testing.T) {
+
+	f := ContentDefinitionFactory{}
+	cd := ruleContentDefinition()
+	valid := []Rule{{Name: "price", Expression: "price >= 0.0"}}
+
+	assert.NoError(t, f.UpdateRules(&cd, valid))
+	assert.Equal(t, valid, cd.Rules)
+
+	assert.Error(t, f.UpdateRules(&cd, []Rule{{Name: "cost", Expression: "cost >= 0.0"}}))
+	assert.Equal(t, valid, cd.Rules)
+
+	// a property used by a rule cannot be removed
+	delete(cd.Propertydefinitions, "price")
+	_, err := cd.CompileRules()
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// RuleDateRange configures the earliest and latest value of a date or datetime property
+	RuleDateRange = "daterange"
+
+	// DateLayout is the format of date values
+	DateLayout = "2006-01-02"
+
+	ErrInvalidDate     = "invalid date, expected YYYY-MM-DD"
+	ErrInvalidDateTime = "invalid datetime, expected RFC 3339 with time zone offset"
+)
+
+// DateRange is the earliest and latest value of a date or datetime property.
+// Min and Max are dates, YYYY-MM-DD, or RFC 3339 datetimes. A date bound is compared with the UTC date of datetime values,
+// a datetime bound is compared with date values at midnight UTC.
+type DateRange struct {
+	Min string `bson:"min,omitempty" json:"min,omitempty"`
+	Max string `bson:"max,omitempty" json:"max,omitempty"`
+}
+
+// ParseDate returns the date of value in DateLayout. An RFC 3339 datetime is reduced to the date in its own time zone,
+// so 2022-01-01T23:00:00-05:00 is 2022-01-01.
+func ParseDate(value interface{}) (string, error) {
+
+	switch v := value.(type) {
+	case string:
+		if d, err := time.Parse(DateLayout, v); err == nil {
+			return d.Format(DateLayout), nil
+		}
+
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t.Format(DateLayout), nil
+		}
+	case time.Time:
+		return v.Format(DateLayout), nil
+	}
+
+	return "", errors.New(ErrInvalidDate)
+}
+
+// ParseDateTime returns value in UTC, truncated to milliseconds as that is the precision it is stored with.
+// Strings must include a time zone offset, since the time zone of the author is not known.
+func ParseDateTime(value interface{}) (time.Time, error) {
+
+	var t time.Time
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, errors.New(ErrInvalidDateTime)
+		}
+		t = parsed
+	case time.Time:
+		t = v
+	case primitive.DateTime:
+		t = v.Time()
+	default:
+		return time.Time{}, errors.New(ErrInvalidDateTime)
+	}
+
+	return t.UTC().Truncate(time.Millisecond), nil
+}
+
+func init() {
+	Register(Rule{
+		Name: RuleDateRange,
+		Schema: Schema{Type: "object", Properties: map[string]Schema{
+			"min": {Type: "string"},
+			"max": {Type: "string"},
+		}},
+		Decode: func(val interface{}) (Validator, error) {
+			return parseDateRange(val)
+		},
+	})
+}
+
+// parseDateRange reads a DateRange that has been stored in the database or decoded from a request body
+func parseDateRange(val interface{}) (DateRange, error) {
+
+	r := DateRange{}
+	if err := decode(val, &r); err != nil {
+		return DateRange{}, errors.New("parse error: cannot parse into type DateRange")
+	}
+
+	for _, bound := range []string{r.Min, r.Max} {
+		if bound == "" {
+			continue
+		}
+
+		if _, err := time.Parse(DateLayout, bound); err == nil {
+			continue
+		}
+
+		if _, err := ParseDateTime(bound); err != nil {
+			return DateRange{}, fmt.Errorf("daterange: %s is not a date or datetime", bound)
+		}
+	}
+
+	return r, nil
+}
+
+// Validate checks that a date or datetime value is within the range, empty values are valid.
+func (r DateRange) Validate(ctx context.Context, field interface{}) error {
+
+	if field == nil || field == "" {
+		return nil
+	}
+
+	// a date string is a date, everything else must be a datetime
+	var value time.Time
+	isDate := false
+	if s, ok := field.(string); ok {
+		if d, err := time.Parse(DateLayout, s); err == nil {
+			value = d
+			isDate = true
+		}
+	}
+
+	if !isDate {
+		t, err := ParseDateTime(field)
+		if err != nil {
+			return err
+		}
+		value = t
+	}
+
+	if r.Min != "" && compareDate(value, r.Min) < 0 {
+		return errorf(CodeLessThanMin, "date is before %s", r.Min)
+	}
+
+	if r.Max != "" && compareDate(value, r.Max) > 0 {
+		return errorf(CodeGreaterThanMax, "date is after %s", r.Max)
+	}
+
+	return nil
+}
+
+// compareDate compares value with a bound, returns -1 if value is before the bound, 1 if it is after and otherwise 0
+func compareDate(value time.Time, bound string) int {
+
+	if d, err := time.Parse(DateLayout, bound); err == nil {
+		value, _ = time.Parse(DateLayout, value.UTC().Format(DateLayout))
+		return compareTime(value, d)
+	}
+
+	t, err := ParseDateTime(bound)
+	if err != nil {
+		// bounds are validated when the range is parsed
+		return 0
+	}
+
+	return compareTime(value, t)
+}
+
+func compareTime(a, b time.Time) int {
+
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+
+	return 0
+}
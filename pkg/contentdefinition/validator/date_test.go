@@ -0,0 +1,129 @@
+//go:build unit
+
+package validator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_ParseDate(t *testing.T) {
+
+	tests := map[interface{}]string{
+		"2022-03-01":                "2022-03-01",
+		"2022-03-01T23:30:00-05:00": "2022-03-01",
+		"2022-03-01T00:30:00+02:00": "2022-03-01",
+		"2022-02-30":                "",
+		"01/03/2022":                "",
+		20220301:                    "",
+	}
+
+	for input, expected := range tests {
+		actual, err := ParseDate(input)
+		if expected == "" {
+			assert.EqualError(t, err, ErrInvalidDate, input)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, input)
+	}
+}
+
+func Test_ParseDateTime(t *testing.T) {
+
+	expected := time.Date(2022, 3, 1, 22, 30, 0, 123000000, time.UTC)
+
+	inputs := []interface{}{
+		"2022-03-01T22:30:00.123Z",
+		"2022-03-01T23:30:00.123456+01:00",
+		expected.In(time.FixedZone("x", -3*3600)),
+		primitive.NewDateTimeFromTime(expected),
+	}
+
+	for _, input := range inputs {
+		actual, err := ParseDateTime(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, input)
+	}
+
+	// without offset the time zone is unknown
+	for _, input := range []interface{}{"2022-03-01T22:30:00", "2022-03-01", 1646173800} {
+		_, err := ParseDateTime(input)
+		assert.EqualError(t, err, ErrInvalidDateTime, input)
+	}
+}
+
+func Test_DateRangeRule(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		rule   DateRange
+		inputs map[interface{}]bool
+	}{
+		{
+			name: "date bounds",
+			rule: DateRange{Min: "2022-01-01", Max: "2022-12-31"},
+			inputs: map[interface{}]bool{
+				nil:                         true,
+				"":                          true,
+				"2022-01-01":                true,
+				"2022-12-31":                true,
+				"2021-12-31":                false,
+				"2023-01-01":                false,
+				"2022-12-31T23:59:59Z":      true,
+				"2022-12-31T23:00:00-02:00": false,
+				"not a date":                false,
+			},
+		},
+		{
+			name: "datetime bounds",
+			rule: DateRange{Min: "2022-06-01T12:00:00+02:00"},
+			inputs: map[interface{}]bool{
+				"2022-06-01T10:00:00Z": true,
+				"2022-06-01T09:59:59Z": false,
+				"2022-06-01":           false,
+				"2022-06-02":           true,
+				primitive.NewDateTimeFromTime(time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)): true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for input, valid := range test.inputs {
+				err := test.rule.Validate(context.Background(), input)
+				if valid {
+					assert.NoError(t, err, input)
+				} else {
+					assert.Error(t, err, input)
+				}
+			}
+		})
+	}
+}
+
+func Test_ParseDateRange(t *testing.T) {
+
+	expected := DateRange{Min: "2022-01-01", Max: "2022-12-31T00:00:00Z"}
+
+	inputs := []interface{}{
+		expected,
+		bson.M{"min": "2022-01-01", "max": "2022-12-31T00:00:00Z"},
+		map[string]interface{}{"min": "2022-01-01", "max": "2022-12-31T00:00:00Z"},
+	}
+
+	for _, input := range inputs {
+		actual, err := Parse(RuleDateRange, input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err := Parse(RuleDateRange, bson.M{"min": "tomorrow"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// RuleEnum configures the options of an enum property
+	RuleEnum = "enum"
+
+	ErrInvalidEnumValue = "value is not an option"
+)
+
+// EnumOption is a value an enum property can have
+type EnumOption struct {
+	Value string `bson:"value" json:"value"`
+	// Labels are the display names of the option by language
+	Labels map[string]string `bson:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// Enum restricts an enum property to a fixed set of options.
+// The value of an enum property is the value of an option, or a list of values if Multiple is set.
+type Enum struct {
+	Options []EnumOption `bson:"options,omitempty" json:"options,omitempty"`
+	// Multiple allows more than one option to be selected
+	Multiple bool `bson:"multiple,omitempty" json:"multiple,omitempty"`
+}
+
+func init() {
+	Register(Rule{
+		Name: RuleEnum,
+		Schema: Schema{Type: "object", Properties: map[string]Schema{
+			"options": {Type: "array", Items: &Schema{Type: "object", Properties: map[string]Schema{
+				"value":  {Type: "string"},
+				"labels": {Type: "object"},
+			}}},
+			"multiple": {Type: "boolean"},
+		}},
+		Decode: func(val interface{}) (Validator, error) {
+			return parseEnum(val)
+		},
+	})
+}
+
+// parseEnum reads an Enum that has been stored in the database or decoded from a request body
+func parseEnum(val interface{}) (Enum, error) {
+
+	e := Enum{}
+	if err := decode(val, &e); err != nil {
+		return Enum{}, errors.New("parse error: cannot parse into type Enum")
+	}
+
+	seen := make(map[string]bool, len(e.Options))
+	for _, o := range e.Options {
+		if o.Value == "" {
+			return Enum{}, errors.New("enum: option value is empty")
+		}
+
+		if seen[o.Value] {
+			return Enum{}, fmt.Errorf("enum: option %s is not unique", o.Value)
+		}
+		seen[o.Value] = true
+	}
+
+	return e, nil
+}
+
+// Validate checks that field is the value of an option, or a list of values if Multiple is set.
+func (e Enum) Validate(ctx context.Context, field interface{}) error {
+
+	values, err := e.Values(field)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range values {
+		if _, ok := e.Option(v); !ok {
+			return errorf(CodeNotAnOption, "%s: %s", ErrInvalidEnumValue, v)
+		}
+	}
+
+	return nil
+}
+
+// Values returns the selected values of an enum property value. Nil and empty values have no values.
+// Whether the values are options is not checked, see Validate.
+func (e Enum) Values(field interface{}) ([]string, error) {
+
+	var items []interface{}
+
+	switch v := field.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []string{v}, nil
+	case []string:
+		for _, s := range v {
+			items = append(items, s)
+		}
+	case []interface{}:
+		items = v
+	case primitive.A:
+		items = v
+	default:
+		return nil, errorf(CodeInvalidType, "enum value is not a string")
+	}
+
+	if len(items) > 1 && !e.Multiple {
+		return nil, errorf(CodeMultipleValues, "property can only have one value")
+	}
+
+	values := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errorf(CodeInvalidType, "enum value is not a string")
+		}
+
+		if seen[s] {
+			return nil, errorf(CodeDuplicateValue, "enum value %s is selected more than once", s)
+		}
+		seen[s] = true
+
+		values = append(values, s)
+	}
+
+	return values, nil
+}
+
+// Option returns the option with value
+func (e Enum) Option(value string) (EnumOption, bool) {
+
+	for _, o := range e.Options {
+		if o.Value == value {
+			return o, true
+		}
+	}
+
+	return EnumOption{}, false
+}
+
+// Label returns the label of the option with value in the first of languages it has a label in.
+// The value is returned if the option has no label or has been removed.
+func (e Enum) Label(value string, languages ...string) string {
+
+	o, ok := e.Option(value)
+	if !ok {
+		return value
+	}
+
+	for _, l := range languages {
+		if label, ok := o.Labels[l]; ok && label != "" {
+			return label
+		}
+	}
+
+	return value
+}
+
+// Removed returns the values of the options of e that are not options of updated
+func (e Enum) Removed(updated Enum) []string {
+
+	removed := make([]string, 0)
+	for _, o := range e.Options {
+		if _, ok := updated.Option(o.Value); !ok {
+			removed = append(removed, o.Value)
+		}
+	}
+
+	return removed
+}
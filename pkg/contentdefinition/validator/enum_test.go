@@ -0,0 +1,112 @@
+//go:build unit
+
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_EnumRule(t *testing.T) {
+
+	options := []EnumOption{{Value: "red"}, {Value: "green"}, {Value: "blue"}}
+
+	tests := []struct {
+		name     string
+		multiple bool
+		input    interface{}
+		expected []string
+		err      bool
+	}{
+		{name: "nil", input: nil},
+		{name: "empty string", input: ""},
+		{name: "single", input: "red", expected: []string{"red"}},
+		{name: "not an option", input: "yellow", expected: []string{"yellow"}, err: true},
+		{name: "number", input: 1.0, err: true},
+		{name: "list when single", input: []interface{}{"red", "green"}, err: true},
+		{name: "list when multiple", multiple: true, input: primitive.A{"red", "green"}, expected: []string{"red", "green"}},
+		{name: "empty list", multiple: true, input: []interface{}{}, expected: []string{}},
+		{name: "duplicate values", multiple: true, input: []string{"red", "red"}, err: true},
+		{name: "list with number", multiple: true, input: []interface{}{"red", 1}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := Enum{Options: options, Multiple: test.multiple}
+
+			err := e.Validate(context.Background(), test.input)
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			values, err := e.Values(test.input)
+			if err == nil {
+				assert.Equal(t, test.expected, values)
+			}
+		})
+	}
+}
+
+func Test_EnumLabel(t *testing.T) {
+
+	e := Enum{Options: []EnumOption{
+		{Value: "red", Labels: map[string]string{"sv-SE": "Röd", "en-US": "Red"}},
+		{Value: "green", Labels: map[string]string{"en-US": "Green"}},
+		{Value: "blue"},
+	}}
+
+	assert.Equal(t, "Röd", e.Label("red", "sv-SE", "en-US"))
+	assert.Equal(t, "Green", e.Label("green", "sv-SE", "en-US"))
+	assert.Equal(t, "blue", e.Label("blue", "sv-SE", "en-US"))
+	assert.Equal(t, "removed", e.Label("removed", "sv-SE"))
+}
+
+func Test_EnumRemoved(t *testing.T) {
+
+	e := Enum{Options: []EnumOption{{Value: "red"}, {Value: "green"}, {Value: "blue"}}}
+
+	assert.Equal(t, []string{"green"}, e.Removed(Enum{Options: []EnumOption{{Value: "red"}, {Value: "blue"}, {Value: "yellow"}}}))
+	assert.Equal(t, []string{}, e.Removed(e))
+}
+
+func Test_ParseEnum(t *testing.T) {
+
+	expected := Enum{
+		Options: []EnumOption{
+			{Value: "red", Labels: map[string]string{"sv-SE": "Röd"}},
+			{Value: "green"},
+		},
+		Multiple: true,
+	}
+
+	inputs := []interface{}{
+		expected,
+		bson.M{"options": bson.A{bson.M{"value": "red", "labels": bson.M{"sv-SE": "Röd"}}, bson.M{"value": "green"}}, "multiple": true},
+		map[string]interface{}{
+			"options": []interface{}{
+				map[string]interface{}{"value": "red", "labels": map[string]interface{}{"sv-SE": "Röd"}},
+				map[string]interface{}{"value": "green"},
+			},
+			"multiple": true,
+		},
+	}
+
+	for _, input := range inputs {
+		actual, err := Parse(RuleEnum, input)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err := Parse(RuleEnum, bson.M{"options": bson.A{bson.M{"value": ""}}})
+	assert.Error(t, err)
+
+	_, err = Parse(RuleEnum, bson.M{"options": bson.A{bson.M{"value": "red"}, bson.M{"value": "red"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// RuleBoundary configures the polygon the value of a geopoint property must be inside
+	RuleBoundary = "boundary"
+
+	ErrInvalidGeoPoint = "invalid geopoint"
+	ErrOutsideBoundary = "geopoint is outside the boundary"
+	ErrInvalidBoundary = "invalid boundary"
+)
+
+// geoJSONPoint is the GeoJSON type of a point
+const geoJSONPoint = "Point"
+
+// GeoPoint is a GeoJSON point, the value of a geopoint property. Coordinates are longitude followed by latitude.
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewGeoPoint returns the point at lat, lng
+func NewGeoPoint(lat, lng float64) (GeoPoint, error) {
+
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return GeoPoint{}, fmt.Errorf("%s: latitude must be between -90 and 90 and longitude between -180 and 180", ErrInvalidGeoPoint)
+	}
+
+	return GeoPoint{Type: geoJSONPoint, Coordinates: []float64{lng, lat}}, nil
+}
+
+func (p GeoPoint) Lat() float64 {
+	return p.Coordinates[1]
+}
+
+func (p GeoPoint) Lng() float64 {
+	return p.Coordinates[0]
+}
+
+// ParseGeoPoint reads a GeoJSON point, or an object with lat and lng, that has been stored in the database or decoded from a request body.
+func ParseGeoPoint(value interface{}) (GeoPoint, error) {
+
+	if m, ok := value.(map[string]interface{}); ok {
+		lat, hasLat := m["lat"].(float64)
+		lng, hasLng := m["lng"].(float64)
+		if hasLat && hasLng && len(m) == 2 {
+			return NewGeoPoint(lat, lng)
+		}
+	}
+
+	p := GeoPoint{}
+	if err := decode(value, &p); err != nil || p.Type != geoJSONPoint || len(p.Coordinates) != 2 {
+		return GeoPoint{}, errors.New(ErrInvalidGeoPoint)
+	}
+
+	return NewGeoPoint(p.Lat(), p.Lng())
+}
+
+// Boundary is the polygon the value of a geopoint property must be inside, an empty boundary allows every point.
+// Points are longitude followed by latitude like GeoJSON, the polygon is closed from the last point to the first.
+type Boundary struct {
+	Points [][]float64 `bson:"points,omitempty" json:"points,omitempty"`
+}
+
+func init() {
+	Register(Rule{
+		Name: RuleBoundary,
+		Schema: Schema{Type: "object", Properties: map[string]Schema{
+			"points": {Type: "array", Items: &Schema{Type: "array", Items: &Schema{Type: "number"}}},
+		}},
+		Decode: func(val interface{}) (Validator, error) {
+			return parseBoundary(val)
+		},
+	})
+}
+
+// parseBoundary reads a Boundary that has been stored in the database or decoded from a request body
+func parseBoundary(val interface{}) (Boundary, error) {
+
+	b := Boundary{}
+	if err := decode(val, &b); err != nil {
+		return Boundary{}, errors.New("parse error: cannot parse into type Boundary")
+	}
+
+	if len(b.Points) == 0 {
+		return b, nil
+	}
+
+	if len(b.Points) < 3 {
+		return Boundary{}, fmt.Errorf("%s: a polygon has at least 3 points", ErrInvalidBoundary)
+	}
+
+	for _, p := range b.Points {
+		if len(p) != 2 {
+			return Boundary{}, fmt.Errorf("%s: a point is a longitude and a latitude", ErrInvalidBoundary)
+		}
+
+		if _, err := NewGeoPoint(p[1], p[0]); err != nil {
+			return Boundary{}, fmt.Errorf("%s: %s", ErrInvalidBoundary, err)
+		}
+	}
+
+	return b, nil
+}
+
+// Validate checks that a geopoint value is inside the boundary
+func (b Boundary) Validate(ctx context.Context, field interface{}) error {
+
+	if field == nil {
+		return nil
+	}
+
+	p, err := ParseGeoPoint(field)
+	if err != nil {
+		return err
+	}
+
+	if len(b.Points) == 0 || b.Contains(p) {
+		return nil
+	}
+
+	return errorf(CodeOutsideBoundary, ErrOutsideBoundary)
+}
+
+// Contains returns true if p is inside the polygon. Edges are straight lines between the coordinates,
+// which is close enough to the great circles between them for boundaries that are not too large.
+func (b Boundary) Contains(p GeoPoint) bool {
+
+	// a ray from p crosses the edges of the polygon an odd number of times if p is inside
+	inside := false
+	x, y := p.Lng(), p.Lat()
+
+	for i, j := 0, len(b.Points)-1; i < len(b.Points); j, i = i, i+1 {
+		xi, yi := b.Points[i][0], b.Points[i][1]
+		xj, yj := b.Points[j][0], b.Points[j][1]
+
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
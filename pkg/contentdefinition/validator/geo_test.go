@@ -0,0 +1,96 @@
+//go:build unit
+
+package validator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_ParseGeoPoint(t *testing.T) {
+
+	stockholm := GeoPoint{Type: "Point", Coordinates: []float64{18.0686, 59.3293}}
+
+	tests := []struct {
+		name  string
+		input interface{}
+		err   bool
+	}{
+		{name: "geopoint", input: stockholm},
+		{name: "lat and lng", input: map[string]interface{}{"lat": 59.3293, "lng": 18.0686}},
+		{name: "geojson", input: map[string]interface{}{"type": "Point", "coordinates": []interface{}{18.0686, 59.3293}}},
+		{name: "stored", input: primitive.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: primitive.A{18.0686, 59.3293}}}},
+		{name: "latitude out of range", input: map[string]interface{}{"lat": 91.0, "lng": 18.0686}, err: true},
+		{name: "not a point", input: map[string]interface{}{"type": "LineString", "coordinates": []interface{}{18.0686, 59.3293}}, err: true},
+		{name: "missing coordinate", input: map[string]interface{}{"type": "Point", "coordinates": []interface{}{18.0686}}, err: true},
+		{name: "string", input: "59.3293,18.0686", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := ParseGeoPoint(test.input)
+			if test.err {
+				assert.True(t, strings.HasPrefix(err.Error(), ErrInvalidGeoPoint))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, stockholm, p)
+			assert.Equal(t, 59.3293, p.Lat())
+		})
+	}
+}
+
+func Test_BoundaryRule(t *testing.T) {
+
+	// roughly the municipality of Stockholm
+	boundary := Boundary{Points: [][]float64{{17.75, 59.23}, {18.2, 59.23}, {18.2, 59.43}, {17.75, 59.43}}}
+
+	stored, err := bson.Marshal(bson.M{"boundary": boundary})
+	assert.NoError(t, err)
+	var doc struct{ Boundary interface{} }
+	assert.NoError(t, bson.Unmarshal(stored, &doc))
+
+	rule, err := Parse(RuleBoundary, doc.Boundary)
+	assert.NoError(t, err)
+	assert.Equal(t, boundary, rule)
+
+	tests := []struct {
+		name  string
+		input interface{}
+		err   string
+	}{
+		{name: "nil", input: nil},
+		{name: "inside", input: map[string]interface{}{"lat": 59.3293, "lng": 18.0686}},
+		{name: "outside", input: map[string]interface{}{"lat": 57.7089, "lng": 11.9746}, err: ErrOutsideBoundary},
+		{name: "invalid point", input: "x", err: ErrInvalidGeoPoint},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := rule.Validate(context.Background(), test.input)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+
+	// an empty boundary allows every point
+	assert.NoError(t, Boundary{}.Validate(context.Background(), map[string]interface{}{"lat": 57.7089, "lng": 11.9746}))
+
+	for _, invalid := range []Boundary{
+		{Points: [][]float64{{17.75, 59.23}, {18.2, 59.23}}},
+		{Points: [][]float64{{17.75, 59.23}, {18.2, 59.23}, {18.2}}},
+		{Points: [][]float64{{17.75, 59.23}, {18.2, 59.23}, {18.2, 91}}},
+	} {
+		_, err := Parse(RuleBoundary, invalid)
+		assert.True(t, strings.HasPrefix(err.Error(), ErrInvalidBoundary))
+	}
+}
@@ -0,0 +1,70 @@
+package validator
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RuleItemCount configures the minimum and maximum number of items of a list property
+const RuleItemCount = "itemcount"
+
+// ItemCount is the minimum and maximum number of items of a list property, an empty list has no items.
+type ItemCount struct {
+	Min *int `bson:"min,omitempty" json:"min,omitempty"`
+	Max *int `bson:"max,omitempty" json:"max,omitempty"`
+}
+
+func init() {
+	Register(Rule{
+		Name: RuleItemCount,
+		Schema: Schema{Type: "object", Properties: map[string]Schema{
+			"min": {Type: "number"},
+			"max": {Type: "number"},
+		}},
+		Decode: func(val interface{}) (Validator, error) {
+			return parseItemCount(val)
+		},
+	})
+}
+
+// parseItemCount reads an ItemCount that has been stored in the database or decoded from a request body
+func parseItemCount(val interface{}) (ItemCount, error) {
+
+	c := ItemCount{}
+	if err := decode(val, &c); err != nil {
+		return ItemCount{}, errors.New("parse error: cannot parse into type ItemCount")
+	}
+
+	if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+		return ItemCount{}, errors.New("itemcount: min is greater than max")
+	}
+
+	return c, nil
+}
+
+// Validate checks the number of items of a list value
+func (c ItemCount) Validate(ctx context.Context, field interface{}) error {
+
+	n := 0
+	switch v := field.(type) {
+	case nil:
+	case []interface{}:
+		n = len(v)
+	case primitive.A:
+		n = len(v)
+	default:
+		return errorf(CodeInvalidType, "value is not a list")
+	}
+
+	if c.Min != nil && n < *c.Min {
+		return errorf(CodeLessThanMin, "list has less than %d items", *c.Min)
+	}
+
+	if c.Max != nil && n > *c.Max {
+		return errorf(CodeGreaterThanMax, "list has more than %d items", *c.Max)
+	}
+
+	return nil
+}
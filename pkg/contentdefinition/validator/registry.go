@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Rule is a validator that can be configured on a property definition, the name is the key of the validator in PropertyDefinition.Validators.
+type Rule struct {
+	Name string
+	// Schema describes the configuration of the rule
+	Schema Schema
+	// Decode reads the configuration of the rule. It is either the validator itself, a document that has been stored
+	// in the database or an object decoded from a request body.
+	Decode func(val interface{}) (Validator, error)
+}
+
+// Schema is the JSON type of the configuration of a rule, like JSON schema.
+type Schema struct {
+	// Type is boolean, number, string, array or object
+	Type string `json:"type"`
+	// Properties are the settings of an object
+	Properties map[string]Schema `json:"properties,omitempty"`
+	// Items is the type of the items of an array
+	Items *Schema `json:"items,omitempty"`
+}
+
+var registry = make(map[string]Rule)
+
+// Register makes a rule available by its name. Rules are registered when the program starts, so registering
+// a name twice is a programming error and panics.
+func Register(r Rule) {
+
+	if r.Name == "" || r.Decode == nil {
+		panic("validator: rule requires a name and a decoder")
+	}
+
+	if _, ok := registry[r.Name]; ok {
+		panic(fmt.Sprintf("validator: rule %s is already registered", r.Name))
+	}
+
+	registry[r.Name] = r
+}
+
+// Lookup returns the rule with name
+func Lookup(name string) (Rule, bool) {
+	r, ok := registry[name]
+	return r, ok
+}
+
+// Rules returns every registered rule ordered by name
+func Rules() []Rule {
+
+	rules := make([]Rule, 0, len(registry))
+	for _, r := range registry {
+		rules = append(rules, r)
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules
+}
+
+// Parse decodes the configuration val of the rule with name
+func Parse(name string, val interface{}) (Validator, error) {
+
+	r, ok := registry[name]
+	if !ok {
+		return nil, errors.New("validator not found")
+	}
+
+	return r.Decode(val)
+}
+
+// Codes of the failures of validators, they are stable so clients can show their own messages
+const (
+	CodeRequired        = "required"
+	CodeInvalidType     = "invalid_type"
+	CodePatternMismatch = "pattern_mismatch"
+	CodeLessThanMin     = "less_than_min"
+	CodeGreaterThanMax  = "greater_than_max"
+	CodeNotAnOption     = "not_an_option"
+	CodeMultipleValues  = "multiple_values"
+	CodeDuplicateValue  = "duplicate_value"
+	CodeOutsideBoundary = "outside_boundary"
+	CodeNotUnique       = "not_unique"
+	// CodeUnknownProperty is the code of values set on a property that the contentdefinition does not have
+	CodeUnknownProperty = "unknown_property"
+	// CodeRuleFailed is the code of the cross-field rules of contentdefinitions, see contentdefinition.Rule
+	CodeRuleFailed = "rule_failed"
+)
+
+// Error is a value that does not pass a validator, Code identifies why.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+// errorf returns an Error with code and a formatted message
+func errorf(code, format string, args ...interface{}) Error {
+	return Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Code returns the code of err, errors that are not returned by a validator are CodeInvalidType
+func Code(err error) string {
+
+	var e Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return CodeInvalidType
+}
@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"context"
+	"errors"
+)
+
+// RuleUnique configures whether the value of a property must differ from the values of other content
+const RuleUnique = "unique"
+
+// Unique requires the value of a property to be unique among the content of the same contentdefinition in the workspace.
+// It is set with true or with the scope of the check, empty values are never compared.
+//
+// Other content is needed to check a value, so Validate only checks the configuration and the value is checked
+// against published content when the content is published. Drafts do not reserve values.
+type Unique struct {
+	Enabled bool `bson:"enabled,omitempty" json:"enabled,omitempty"`
+	// PerLanguage only compares values of localized properties with values in the same language
+	PerLanguage bool `bson:"perlanguage,omitempty" json:"perlanguage,omitempty"`
+	// PerParent only compares values with content that has the same parent in the content tree
+	PerParent bool `bson:"perparent,omitempty" json:"perparent,omitempty"`
+}
+
+func init() {
+	Register(Rule{
+		Name: RuleUnique,
+		Schema: Schema{Type: "object", Properties: map[string]Schema{
+			"enabled":     {Type: "boolean"},
+			"perlanguage": {Type: "boolean"},
+			"perparent":   {Type: "boolean"},
+		}},
+		Decode: func(val interface{}) (Validator, error) {
+			return parseUnique(val)
+		},
+	})
+}
+
+// parseUnique reads a Unique that has been stored in the database or decoded from a request body, true enables the check
+func parseUnique(val interface{}) (Unique, error) {
+
+	if b, ok := val.(bool); ok {
+		return Unique{Enabled: b}, nil
+	}
+
+	u := Unique{}
+	if err := decode(val, &u); err != nil {
+		return Unique{}, errors.New("parse error: cannot parse into type Unique")
+	}
+
+	return u, nil
+}
+
+// Validate checks that the value can be compared, lists and objects cannot be unique
+func (u Unique) Validate(ctx context.Context, field interface{}) error {
+
+	if !u.Enabled {
+		return nil
+	}
+
+	switch field.(type) {
+	case []interface{}, map[string]interface{}:
+		return errorf(CodeInvalidType, "unique value must be a single value")
+	}
+
+	return nil
+}
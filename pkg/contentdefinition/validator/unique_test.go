@@ -0,0 +1,41 @@
+//go:build unit
+
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_UniqueRule(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		input  interface{}
+		expect Unique
+	}{
+		{name: "true", input: true, expect: Unique{Enabled: true}},
+		{name: "false", input: false, expect: Unique{}},
+		{name: "scope", input: map[string]interface{}{"enabled": true, "perlanguage": true}, expect: Unique{Enabled: true, PerLanguage: true}},
+		{name: "stored", input: primitive.D{{Key: "enabled", Value: true}, {Key: "perparent", Value: true}}, expect: Unique{Enabled: true, PerParent: true}},
+		{name: "unique", input: Unique{Enabled: true}, expect: Unique{Enabled: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := Parse(RuleUnique, test.input)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, rule)
+		})
+	}
+
+	_, err := Parse(RuleUnique, "yes")
+	assert.Error(t, err)
+
+	assert.NoError(t, Unique{Enabled: true}.Validate(context.Background(), "value"))
+	assert.Error(t, Unique{Enabled: true}.Validate(context.Background(), []interface{}{"a", "b"}))
+	assert.NoError(t, Unique{}.Validate(context.Background(), []interface{}{"a", "b"}))
+}
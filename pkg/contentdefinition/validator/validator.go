@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
-	"strconv"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 /*
@@ -15,7 +19,7 @@ import (
 				"required": true
 			},
 			{
-				"pattern": "^foo"
+				"regex": "^foo"
 			},
 		]
 	}
@@ -26,39 +30,122 @@ const (
 	RuleRequired = "required"
 	RuleRegex    = "regex"
 	RuleRange    = "range"
+	// RuleReference configures the content a reference property can point to
+	RuleReference = "reference"
 )
 
 type Required bool
 type Regex string
 type Range struct {
-	Min *float64 `bson:"min, omitempty"`
-	Max *float64 `bson:"max, omitempty"`
+	Min *float64 `bson:"min,omitempty" json:"min,omitempty"`
+	Max *float64 `bson:"max,omitempty" json:"max,omitempty"`
+}
+
+// Reference restricts which content a reference property can point to.
+// The value of a reference property is the ID of the referenced content, or a list of IDs if Multiple is set.
+type Reference struct {
+	// ContentDefinitions are the IDs of the content definitions the referenced content can be of, empty allows every content definition
+	ContentDefinitions []string `bson:"contentdefinitions,omitempty" json:"contentdefinitions,omitempty"`
+	// Multiple allows the property to reference more than one content
+	Multiple bool `bson:"multiple,omitempty" json:"multiple,omitempty"`
 }
 
 type Validator interface {
 	Validate(ctx context.Context, field interface{}) error
 }
 
-func Parse(name string, val interface{}) (Validator, error) {
+func init() {
+	Register(Rule{
+		Name:   RuleRequired,
+		Schema: Schema{Type: "boolean"},
+		Decode: func(val interface{}) (Validator, error) {
+			switch v := val.(type) {
+			case bool:
+				return Required(v), nil
+			case Required:
+				return v, nil
+			}
+			return nil, errors.New("parse error: cannot parse into type RequiredField")
+		},
+	})
+	Register(Rule{
+		Name:   RuleRegex,
+		Schema: Schema{Type: "string"},
+		Decode: func(val interface{}) (Validator, error) {
+			var pattern string
+			switch v := val.(type) {
+			case string:
+				pattern = v
+			case Regex:
+				pattern = string(v)
+			default:
+				return nil, errors.New("pattern is not of type string")
+			}
 
-	switch name {
-	case "required":
-		if b, ok := val.(bool); ok {
-			return Required(b), nil
-		}
-		return nil, errors.New("parse error: cannot parse into type RequiredField")
-	case "pattern":
-		if str, ok := val.(string); ok {
-			return Regex(str), nil
-		}
-		return nil, errors.New("pattern is not of type string")
-	case "range":
-		if r, ok := val.(Range); ok {
-			return r, nil
-		}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("pattern is not valid: %s", err)
+			}
+			return Regex(pattern), nil
+		},
+	})
+	Register(Rule{
+		Name: RuleRange,
+		Schema: Schema{Type: "object", Properties: map[string]Schema{
+			"min": {Type: "number"},
+			"max": {Type: "number"},
+		}},
+		Decode: func(val interface{}) (Validator, error) {
+			return parseRange(val)
+		},
+	})
+	Register(Rule{
+		Name: RuleReference,
+		Schema: Schema{Type: "object", Properties: map[string]Schema{
+			"contentdefinitions": {Type: "array", Items: &Schema{Type: "string"}},
+			"multiple":           {Type: "boolean"},
+		}},
+		Decode: func(val interface{}) (Validator, error) {
+			return parseReference(val)
+		},
+	})
+}
+
+// parseRange reads a Range that has been stored in the database or decoded from a request body
+func parseRange(val interface{}) (Range, error) {
+
+	r := Range{}
+	if err := decode(val, &r); err != nil {
+		return Range{}, errors.New("parse error: cannot parse into type Range")
+	}
+
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return Range{}, errors.New("parse error: min is greater than max")
 	}
 
-	return nil, errors.New("validator not found")
+	return r, nil
+}
+
+// parseReference reads a Reference that has been stored in the database or decoded from a request body
+func parseReference(val interface{}) (Reference, error) {
+
+	r := Reference{}
+	if err := decode(val, &r); err != nil {
+		return Reference{}, errors.New("parse error: cannot parse into type Reference")
+	}
+
+	return r, nil
+}
+
+// decode reads the validator configuration val into out, val is either of the type of out,
+// a document read from the database or an object decoded from a request body
+func decode(val interface{}, out interface{}) error {
+
+	data, err := bson.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(data, out)
 }
 
 // Validators
@@ -71,22 +158,23 @@ func (r Required) Validate(ctx context.Context, field interface{}) error {
 	}
 	// check for nil
 	if field == nil {
-		return errors.New("required")
+		return errorf(CodeRequired, "required")
 	}
 
 	// check for empty string
 
 	if str, ok := field.(string); ok && str == "" {
-		return errors.New("required")
+		return errorf(CodeRequired, "required")
 	}
 
 	return nil
 }
 
+// Validate matches field against the pattern, empty values are checked by Required
 func (r Regex) Validate(ctx context.Context, field interface{}) error {
 
-	if field == nil {
-		return errors.New("pattern do not match")
+	if field == nil || r == "" {
+		return nil
 	}
 	str := fmt.Sprintf("%v", field)
 
@@ -97,35 +185,96 @@ func (r Regex) Validate(ctx context.Context, field interface{}) error {
 	}
 
 	if !match {
-		return errors.New("pattern do not match")
+		return errorf(CodePatternMismatch, "pattern do not match")
 
 	}
 	return nil
 }
 
+// Validate checks the character count of text and the value of numbers, empty values are checked by Required
 func (r Range) Validate(ctx context.Context, field interface{}) error {
 
-	ln := 0.0
+	var ln float64
 
-	// if field is string, then check character count
-	if str, ok := field.(string); ok {
-		ln = float64(len(str))
-	}
-
-	// if field is number, parse it and convert it to integer
-
-	str := fmt.Sprintf("%v", field)
-	if n, ok := strconv.ParseFloat(str, 32); ok == nil {
-		ln = n
+	switch v := field.(type) {
+	case nil:
+		return nil
+	case string:
+		ln = float64(utf8.RuneCountInString(v))
+	case int:
+		ln = float64(v)
+	case int32:
+		ln = float64(v)
+	case int64:
+		ln = float64(v)
+	case float64:
+		ln = v
+	default:
+		return errorf(CodeInvalidType, "field is not text or a number")
 	}
 
 	if r.Max != nil && ln > *r.Max {
-		return errors.New("field greater than")
+		return errorf(CodeGreaterThanMax, "field greater than")
 	}
 
 	if r.Min != nil && ln < *r.Min {
-		return errors.New("field less than")
+		return errorf(CodeLessThanMin, "field less than")
 	}
 
 	return nil
 }
+
+// Validate checks that field is a content ID, or a list of content IDs if Multiple is set.
+// Whether the referenced content exists is checked when content is published.
+func (r Reference) Validate(ctx context.Context, field interface{}) error {
+
+	_, err := r.IDs(field)
+	return err
+}
+
+// IDs returns the content IDs of a reference property value. Nil and empty values have no IDs.
+func (r Reference) IDs(field interface{}) ([]uuid.UUID, error) {
+
+	var values []interface{}
+
+	switch v := field.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		values = []interface{}{v}
+	case []string:
+		for _, s := range v {
+			values = append(values, s)
+		}
+	case []interface{}:
+		values = v
+	case primitive.A:
+		values = v
+	default:
+		return nil, errorf(CodeInvalidType, "reference is not a content id")
+	}
+
+	if len(values) > 1 && !r.Multiple {
+		return nil, errorf(CodeMultipleValues, "property can only reference one content")
+	}
+
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errorf(CodeInvalidType, "reference is not a content id")
+		}
+
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, errorf(CodeInvalidType, "reference is not a content id")
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
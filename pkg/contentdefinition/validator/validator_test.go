@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func Test_RangeRule(t *testing.T) {
@@ -43,8 +45,10 @@ func Test_RangeRule(t *testing.T) {
 				"aaaaa": true,
 				3.14:    false,
 				3:       false,
-				nil:     false,
+				nil:     true,
 				"":      false,
+				"12":    false,
+				"åäöåä": true,
 			},
 		},
 	}
@@ -83,7 +87,7 @@ func Test_RegexRule(t *testing.T) {
 				3.14:     true,
 				"!!foo":  false,
 				"":       false,
-				nil:      false,
+				nil:      true,
 			},
 		},
 	}
@@ -151,3 +155,132 @@ func Test_RequiredRule(t *testing.T) {
 		}
 	}
 }
+
+func Test_ReferenceRule(t *testing.T) {
+
+	id := "9b0ffd5e-1c7b-4e5b-9b2c-56e6f3c0d8a1"
+
+	tests := []struct {
+		name     string
+		multiple bool
+		input    interface{}
+		expected int
+		err      bool
+	}{
+		{name: "nil", input: nil},
+		{name: "empty string", input: ""},
+		{name: "single", input: id, expected: 1},
+		{name: "not an id", input: "foo", err: true},
+		{name: "number", input: 3.14, err: true},
+		{name: "list of one", input: []interface{}{id}, expected: 1},
+		{name: "list when single", input: []interface{}{id, id}, err: true},
+		{name: "list when multiple", multiple: true, input: primitive.A{id, id}, expected: 2},
+		{name: "list with number", multiple: true, input: []interface{}{id, 1}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := Reference{Multiple: test.multiple}
+
+			ids, err := r.IDs(test.input)
+			if test.err {
+				assert.Error(t, err)
+				assert.Error(t, r.Validate(context.Background(), test.input))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, ids, test.expected)
+		})
+	}
+}
+
+func Test_ParseReference(t *testing.T) {
+
+	expected := Reference{ContentDefinitions: []string{"a", "b"}, Multiple: true}
+
+	inputs := []interface{}{
+		expected,
+		bson.M{"contentdefinitions": bson.A{"a", "b"}, "multiple": true},
+		bson.D{{Key: "contentdefinitions", Value: bson.A{"a", "b"}}, {Key: "multiple", Value: true}},
+		map[string]interface{}{"contentdefinitions": []interface{}{"a", "b"}, "multiple": true},
+	}
+
+	for _, input := range inputs {
+		actual, err := Parse(RuleReference, input)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err := Parse(RuleReference, "foo")
+	assert.Error(t, err)
+}
+
+func Test_Parse(t *testing.T) {
+
+	stored := func(val interface{}) interface{} {
+		data, err := bson.Marshal(bson.M{"rule": val})
+		assert.NoError(t, err)
+		var doc struct{ Rule interface{} }
+		assert.NoError(t, bson.Unmarshal(data, &doc))
+		return doc.Rule
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		input    interface{}
+		expected Validator
+		err      bool
+	}{
+		{name: "required", rule: RuleRequired, input: true, expected: Required(true)},
+		{name: "required validator", rule: RuleRequired, input: Required(false), expected: Required(false)},
+		{name: "required string", rule: RuleRequired, input: "true", err: true},
+		{name: "regex", rule: RuleRegex, input: "^foo", expected: Regex("^foo")},
+		{name: "regex validator", rule: RuleRegex, input: Regex("^foo"), expected: Regex("^foo")},
+		{name: "invalid regex", rule: RuleRegex, input: "(foo", err: true},
+		{name: "range", rule: RuleRange, input: Range{Min: makeFloat64(1)}, expected: Range{Min: makeFloat64(1)}},
+		{name: "stored range", rule: RuleRange, input: stored(Range{Min: makeFloat64(1), Max: makeFloat64(2)}), expected: Range{Min: makeFloat64(1), Max: makeFloat64(2)}},
+		{name: "range from request", rule: RuleRange, input: map[string]interface{}{"max": 2.0}, expected: Range{Max: makeFloat64(2)}},
+		{name: "range min greater than max", rule: RuleRange, input: map[string]interface{}{"min": 3.0, "max": 2.0}, err: true},
+		{name: "not found", rule: "pattern", input: "^foo", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Parse(test.rule, test.input)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func Test_Rules(t *testing.T) {
+
+	rules := Rules()
+	names := make([]string, 0, len(rules))
+	for _, r := range rules {
+		names = append(names, r.Name)
+		assert.NotEmpty(t, r.Schema.Type)
+	}
+
+	assert.Equal(t, []string{RuleBoundary, RuleDateRange, RuleEnum, RuleItemCount, RuleRange, RuleReference, RuleRegex, RuleRequired, RuleUnique}, names)
+	assert.Panics(t, func() {
+		Register(Rule{Name: RuleRequired, Decode: func(val interface{}) (Validator, error) { return nil, nil }})
+	})
+}
+
+func Test_Code(t *testing.T) {
+
+	min := Range{Min: makeFloat64(5)}
+	assert.Equal(t, CodeLessThanMin, Code(min.Validate(context.Background(), "foo")))
+	assert.Equal(t, CodeRequired, Code(Required(true).Validate(context.Background(), "")))
+	assert.Equal(t, CodePatternMismatch, Code(Regex("^a").Validate(context.Background(), "b")))
+	assert.Equal(t, CodeInvalidType, Code(min.Validate(context.Background(), true)))
+}
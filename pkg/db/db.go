@@ -19,11 +19,13 @@ var (
 	tUUID       = reflect.TypeOf(uuid.UUID{})
 	tTag        = reflect.TypeOf(language.Tag{})
 	uuidSubtype = byte(0x04)
-	registry    = bson.NewRegistryBuilder().
+	// documents in interface{} values, ie ContentField.Value, are decoded as maps so they are encoded as JSON objects
+	registry = bson.NewRegistryBuilder().
 			RegisterTypeEncoder(tUUID, bsoncodec.ValueEncoderFunc(encodeUUID)).
 			RegisterTypeDecoder(tUUID, bsoncodec.ValueDecoderFunc(decodeUUID)).
 			RegisterTypeEncoder(tTag, bsoncodec.ValueEncoderFunc(encodeTag)).
 			RegisterTypeDecoder(tTag, bsoncodec.ValueDecoderFunc(decodeTag)).
+			RegisterTypeMapEntry(bsontype.EmbeddedDocument, reflect.TypeOf(bson.M{})).
 			Build()
 )
 
@@ -0,0 +1,290 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+
+	ErrInvalidPageSize  = "page size must be between 1 and 100"
+	ErrInvalidPageToken = "invalid page token"
+	ErrInvalidSort      = "invalid sort"
+)
+
+// Page is a request for a page of a list.
+// Items are sorted by Sort and then by _id, so the order is stable even when the sort values are equal.
+type Page struct {
+	// Sort is the path of the field to sort by, prefixed with - for descending order. Empty sorts by _id only
+	Sort string
+	// Size is the max number of items, DefaultPageSize if 0
+	Size int
+	// Token is the continuation token of the previous page, empty for the first page
+	Token string
+}
+
+// PageResult contains the continuation token of the next page and how many items there are in total
+type PageResult struct {
+	// Next is empty if there are no more items
+	Next  string
+	Total int
+}
+
+// pageToken is the sort value and _id of the last item of a page.
+// Since the next page starts after the last item, items that are added or removed between requests do not
+// cause items to be skipped or returned twice.
+type pageToken struct {
+	Sort  string        `bson:"s"`
+	Value bson.RawValue `bson:"v"`
+	ID    bson.RawValue `bson:"i"`
+}
+
+// NewPage parses the size of the page, an empty size is DefaultPageSize
+func NewPage(sort, size, token string) (Page, error) {
+
+	p := Page{
+		Sort:  sort,
+		Size:  DefaultPageSize,
+		Token: token,
+	}
+
+	if size == "" {
+		return p, nil
+	}
+
+	n, err := strconv.Atoi(size)
+	if err != nil || n < 1 || n > MaxPageSize {
+		return Page{}, errors.New(ErrInvalidPageSize)
+	}
+
+	p.Size = n
+	return p, nil
+}
+
+// SortBy replaces the sort name with the path of the field in the database, fields maps sort names to paths.
+func (p Page) SortBy(fields map[string]string) (Page, error) {
+
+	name := strings.TrimPrefix(p.Sort, "-")
+	if name == "" {
+		return p, nil
+	}
+
+	path, ok := fields[name]
+	if !ok {
+		return Page{}, fmt.Errorf("%s: cannot sort by %q", ErrInvalidSort, name)
+	}
+
+	p.Sort = p.Sort[:len(p.Sort)-len(name)] + path
+	return p, nil
+}
+
+// FindPage finds a page of the documents in col that match filter. decode is called with the cursor positioned at
+// each document of the page.
+func FindPage(ctx context.Context, col *mongo.Collection, filter bson.M, page Page, projection bson.M, decode func(*mongo.Cursor) error) (PageResult, error) {
+
+	if page.Size == 0 {
+		page.Size = DefaultPageSize
+	}
+
+	field, direction := page.sortField()
+
+	query := filter
+	if page.Token != "" {
+		after, err := page.after(field, direction)
+		if err != nil {
+			return PageResult{}, err
+		}
+
+		query = bson.M{"$and": bson.A{filter, after}}
+	}
+
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if field != "_id" {
+		sort = append(bson.D{{Key: field, Value: direction}}, sort...)
+	}
+
+	// one more item than the page size is read to know if there is a next page
+	opts := options.Find().
+		SetSort(sort).
+		SetLimit(int64(page.Size + 1))
+
+	if projection != nil {
+		opts.SetProjection(withSortField(projection, field))
+	}
+
+	total, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return PageResult{}, err
+	}
+
+	cursor, err := col.Find(ctx, query, opts)
+	if err != nil {
+		return PageResult{}, err
+	}
+	defer cursor.Close(ctx)
+
+	result := PageResult{Total: int(total)}
+	var last bson.Raw
+
+	for n := 0; cursor.Next(ctx); n++ {
+		if n == page.Size {
+			result.Next, err = page.token(last, field)
+			if err != nil {
+				return PageResult{}, err
+			}
+			break
+		}
+
+		if err := decode(cursor); err != nil {
+			return PageResult{}, err
+		}
+		// the document is copied since the cursor reuses its buffer
+		last = append(bson.Raw{}, cursor.Current...)
+	}
+
+	return result, cursor.Err()
+}
+
+// withSortField adds the sort field to projection, it is needed to create the continuation token.
+// MongoDB rejects a projection of both a path and a path below it, so the sort field is not added if it or a
+// parent of it is already projected, and projected paths below the sort field are replaced by it.
+func withSortField(projection bson.M, field string) bson.M {
+
+	result := bson.M{field: 1}
+	for k, v := range projection {
+		if k == field || strings.HasPrefix(field, k+".") {
+			return projection
+		}
+
+		if strings.HasPrefix(k, field+".") {
+			continue
+		}
+
+		result[k] = v
+	}
+
+	return result
+}
+
+func (p Page) sortField() (string, int) {
+
+	if p.Sort == "" {
+		return "_id", 1
+	}
+
+	if strings.HasPrefix(p.Sort, "-") {
+		return strings.TrimPrefix(p.Sort, "-"), -1
+	}
+
+	return p.Sort, 1
+}
+
+func (p Page) token(last bson.Raw, field string) (string, error) {
+
+	t := pageToken{
+		Sort:  p.Sort,
+		Value: bson.RawValue{Type: bsontype.Null},
+		ID:    last.Lookup("_id"),
+	}
+
+	if v, err := last.LookupErr(strings.Split(field, ".")...); err == nil {
+		t.Value = v
+	}
+
+	data, err := bson.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// after returns the filter of the items after the token in sort order.
+// Missing and null values are sorted before every other value.
+func (p Page) after(field string, direction int) (bson.M, error) {
+
+	data, err := base64.RawURLEncoding.DecodeString(p.Token)
+	if err != nil {
+		return nil, errors.New(ErrInvalidPageToken)
+	}
+
+	t := pageToken{}
+	if err := bson.Unmarshal(data, &t); err != nil || t.Sort != p.Sort || t.ID.Type == 0 {
+		return nil, errors.New(ErrInvalidPageToken)
+	}
+
+	cmp := "$gt"
+	if direction < 0 {
+		cmp = "$lt"
+	}
+
+	if field == "_id" {
+		return bson.M{"_id": bson.M{cmp: t.ID}}, nil
+	}
+
+	sameValue := bson.M{field: t.Value, "_id": bson.M{cmp: t.ID}}
+	if t.Value.Type == bsontype.Null {
+		sameValue[field] = nil
+	}
+
+	switch {
+	case t.Value.Type == bsontype.Null && direction > 0:
+		return bson.M{"$or": bson.A{sameValue, bson.M{field: bson.M{"$ne": nil}}}}, nil
+	case t.Value.Type == bsontype.Null:
+		return sameValue, nil
+	case direction > 0:
+		return bson.M{"$or": bson.A{sameValue, bson.M{field: bson.M{cmp: t.Value}}}}, nil
+	default:
+		return bson.M{"$or": bson.A{sameValue, bson.M{field: bson.M{cmp: t.Value}}, bson.M{field: nil}}}, nil
+	}
+}
+
+type key string
+
+const pageKey = key("page")
+
+// PageHandler parses the sort, limit & token query parameters of list endpoints
+func PageHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		q := r.URL.Query()
+		page, err := NewPage(q.Get("sort"), q.Get("limit"), q.Get("token"))
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), pageKey, page)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// PageFromContext returns the page set by PageHandler, or the first page if there is none.
+func PageFromContext(ctx context.Context) Page {
+	if page, ok := ctx.Value(pageKey).(Page); ok {
+		return page
+	}
+	return Page{Size: DefaultPageSize}
+}
+
+// SetPageHeaders writes the total number of items and the continuation token of the next page
+func SetPageHeaders(w http.ResponseWriter, res PageResult) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(res.Total))
+
+	if res.Next != "" {
+		w.Header().Set("X-Continuation-Token", res.Next)
+	}
+}
@@ -0,0 +1,196 @@
+//go:build unit
+
+package db
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_NewPage(t *testing.T) {
+
+	p, err := NewPage("-name", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, Page{Sort: "-name", Size: DefaultPageSize}, p)
+
+	p, err = NewPage("", "5", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, Page{Size: 5, Token: "abc"}, p)
+
+	for _, size := range []string{"0", "101", "-1", "foo"} {
+		_, err = NewPage("", size, "")
+		assert.EqualError(t, err, ErrInvalidPageSize, size)
+	}
+}
+
+func Test_PageHandler(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	PageHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, Page{Sort: "name", Size: 5, Token: "abc"}, PageFromContext(r.Context()))
+	})).ServeHTTP(w, httptest.NewRequest("GET", "/?sort=name&limit=5&token=abc", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	PageHandler(http.NotFoundHandler()).ServeHTTP(w, httptest.NewRequest("GET", "/?limit=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Equal(t, Page{Size: DefaultPageSize}, PageFromContext(context.Background()))
+}
+
+func Test_SortBy(t *testing.T) {
+
+	fields := map[string]string{"name": "data.name"}
+
+	p, err := Page{Sort: "-name"}.SortBy(fields)
+	assert.NoError(t, err)
+	assert.Equal(t, "-data.name", p.Sort)
+
+	p, err = Page{}.SortBy(fields)
+	assert.NoError(t, err)
+	assert.Equal(t, "", p.Sort)
+
+	_, err = Page{Sort: "created"}.SortBy(fields)
+	assert.Error(t, err)
+}
+
+func Test_PageToken(t *testing.T) {
+
+	doc, err := bson.Marshal(bson.M{"_id": 3, "data": bson.M{"name": "foo"}})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		sort   string
+		doc    bson.M
+		expect func(id, value bson.RawValue) bson.M
+	}{
+		{
+			name: "sorted by _id",
+			expect: func(id, value bson.RawValue) bson.M {
+				return bson.M{"_id": bson.M{"$gt": id}}
+			},
+		},
+		{
+			name: "ascending",
+			sort: "data.name",
+			expect: func(id, value bson.RawValue) bson.M {
+				return bson.M{"$or": bson.A{
+					bson.M{"data.name": value, "_id": bson.M{"$gt": id}},
+					bson.M{"data.name": bson.M{"$gt": value}},
+				}}
+			},
+		},
+		{
+			name: "descending includes null values",
+			sort: "-data.name",
+			expect: func(id, value bson.RawValue) bson.M {
+				return bson.M{"$or": bson.A{
+					bson.M{"data.name": value, "_id": bson.M{"$lt": id}},
+					bson.M{"data.name": bson.M{"$lt": value}},
+					bson.M{"data.name": nil},
+				}}
+			},
+		},
+		{
+			name: "missing value ascending",
+			sort: "data.missing",
+			expect: func(id, value bson.RawValue) bson.M {
+				return bson.M{"$or": bson.A{
+					bson.M{"data.missing": nil, "_id": bson.M{"$gt": id}},
+					bson.M{"data.missing": bson.M{"$ne": nil}},
+				}}
+			},
+		},
+		{
+			name: "missing value descending",
+			sort: "-data.missing",
+			expect: func(id, value bson.RawValue) bson.M {
+				return bson.M{"data.missing": nil, "_id": bson.M{"$lt": id}}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := Page{Sort: test.sort}
+			field, direction := p.sortField()
+
+			p.Token, err = p.token(bson.Raw(doc), field)
+			assert.NoError(t, err)
+
+			actual, err := p.after(field, direction)
+			assert.NoError(t, err)
+
+			id := bson.Raw(doc).Lookup("_id")
+			value := bson.Raw(doc).Lookup("data", "name")
+			assert.Equal(t, test.expect(id, value), actual)
+		})
+	}
+
+	t.Run("token of other sort is invalid", func(t *testing.T) {
+		p := Page{Sort: "data.name"}
+		token, err := p.token(bson.Raw(doc), "data.name")
+		assert.NoError(t, err)
+
+		p = Page{Sort: "-data.name", Token: token}
+		_, err = p.after("data.name", -1)
+		assert.EqualError(t, err, ErrInvalidPageToken)
+
+		p = Page{Sort: "data.name", Token: "not a token"}
+		_, err = p.after("data.name", 1)
+		assert.EqualError(t, err, ErrInvalidPageToken)
+	})
+}
+
+func Test_WithSortField(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		projection bson.M
+		field      string
+		expected   bson.M
+	}{
+		{
+			name:       "sort field is added",
+			projection: bson.M{"data.properties.sv-SE.name": 1},
+			field:      "data.properties.sv-SE.price.value",
+			expected:   bson.M{"data.properties.sv-SE.name": 1, "data.properties.sv-SE.price.value": 1},
+		},
+		{
+			name:       "sort field is projected",
+			projection: bson.M{"created": 1},
+			field:      "created",
+			expected:   bson.M{"created": 1},
+		},
+		{
+			name:       "parent of sort field is projected",
+			projection: bson.M{"data.properties.sv-SE.price": 1},
+			field:      "data.properties.sv-SE.price.value",
+			expected:   bson.M{"data.properties.sv-SE.price": 1},
+		},
+		{
+			name:       "path below sort field is projected",
+			projection: bson.M{"data.properties.sv-SE.price.value.x": 1, "created": 1},
+			field:      "data.properties.sv-SE.price.value",
+			expected:   bson.M{"data.properties.sv-SE.price.value": 1, "created": 1},
+		},
+		{
+			name:       "path with the same prefix is not a parent",
+			projection: bson.M{"data.properties.sv-SE.pri": 1},
+			field:      "data.properties.sv-SE.price.value",
+			expected:   bson.M{"data.properties.sv-SE.pri": 1, "data.properties.sv-SE.price.value": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, withSortField(test.projection, test.field))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RevisionMismatchError is returned when a document has been modified since it was read.
+// Current is the revision the document has now.
+type RevisionMismatchError struct {
+	Current int
+}
+
+func (e RevisionMismatchError) Error() string {
+	return fmt.Sprintf("revision mismatch: current revision is %d", e.Current)
+}
+
+// MatchRevision checks the expected revision against the current revision.
+// A nil expected revision always matches.
+func MatchRevision(expected *int, current int) error {
+	if expected != nil && *expected != current {
+		return RevisionMismatchError{Current: current}
+	}
+
+	return nil
+}
+
+// RevisionFilter returns the filter value matching a document with given revision.
+// Documents created before revisions were introduced have no revision field, they are treated as revision 0.
+func RevisionFilter(revision int) interface{} {
+	if revision == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+
+	return revision
+}
+
+// RevisionConflict is used when a guarded write did not match any document,
+// it reads the current revision of the document matching filter.
+func RevisionConflict(ctx context.Context, col *mongo.Collection, filter interface{}) error {
+
+	var res struct {
+		Revision int `bson:"revision"`
+	}
+
+	err := col.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"revision": 1})).Decode(&res)
+	if err != nil {
+		return err
+	}
+
+	return RevisionMismatchError{Current: res.Revision}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UnitOfWork groups repository calls into a single MongoDB transaction.
+// Every repository call made with the context passed to fn participates in the transaction,
+// so if fn returns an error all writes done by fn are rolled back.
+//
+// Transactions requires MongoDB to run as a replica set.
+type UnitOfWork struct {
+	client *mongo.Client
+}
+
+func NewUnitOfWork(client *mongo.Client) UnitOfWork {
+	return UnitOfWork{client: client}
+}
+
+func (u UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+
+	// already inside a unit of work, the outermost unit of work is responsible for committing.
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	return u.client.UseSession(ctx, func(sc mongo.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (interface{}, error) {
+			return nil, fn(sc)
+		})
+
+		return err
+	})
+}
@@ -0,0 +1,69 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// Cache keeps rendered images on disk. Images are grouped in a directory per asset so they can be removed together.
+type Cache struct {
+	Root string
+}
+
+func NewCache(root string) Cache {
+	return Cache{Root: root}
+}
+
+// Key returns the key of the image rendered with opts from the source with checksum.
+// Changing the source, ie its file or focal point, results in a new key.
+func Key(checksum string, opts Options) string {
+
+	hash := sha256.Sum256([]byte(checksum + "?" + opts.Values().Encode()))
+	return hex.EncodeToString(hash[:]) + "." + string(opts.Format)
+}
+
+func (c Cache) dir(ws, asset uuid.UUID) string {
+	return filepath.Join(c.Root, ws.String(), asset.String())
+}
+
+// Open opens a cached image, fs.ErrNotExist is returned if it is not cached
+func (c Cache) Open(ws, asset uuid.UUID, key string) (*os.File, error) {
+	return os.Open(filepath.Join(c.dir(ws, asset), filepath.Base(key)))
+}
+
+// Put writes an image to the cache. The image is written to a temporary file first,
+// so a failed render is never cached and readers never see a partially written image.
+func (c Cache) Put(ws, asset uuid.UUID, key string, render func(w io.Writer) error) error {
+
+	dir := c.dir(ws, asset)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".render-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := render(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, filepath.Base(key)))
+}
+
+// Remove removes every cached image of the asset
+func (c Cache) Remove(ws, asset uuid.UUID) error {
+	return os.RemoveAll(c.dir(ws, asset))
+}
@@ -0,0 +1,58 @@
+//go:build unit
+
+package imaging
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Cache(t *testing.T) {
+
+	root := t.TempDir()
+	c := NewCache(root)
+	ws, asset := uuid.New(), uuid.New()
+
+	key := Key("checksum", Options{Width: 10, Format: FormatPNG})
+	assert.NotEqual(t, key, Key("other", Options{Width: 10, Format: FormatPNG}))
+	assert.NotEqual(t, key, Key("checksum", Options{Width: 10, Format: FormatPNG, Focal: &Point{X: 0.1, Y: 0.1}}))
+
+	_, err := c.Open(ws, asset, key)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	// failed renders are not cached
+	err = c.Put(ws, asset, key, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errors.New("render failed")
+	})
+	assert.EqualError(t, err, "render failed")
+	_, err = c.Open(ws, asset, key)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	assert.NoError(t, c.Put(ws, asset, key, func(w io.Writer) error {
+		_, err := w.Write([]byte("image"))
+		return err
+	}))
+
+	f, err := c.Open(ws, asset, key)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	assert.Equal(t, "image", string(data))
+
+	// temporary files are removed
+	entries, err := os.ReadDir(root + "/" + ws.String() + "/" + asset.String())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.NoError(t, c.Remove(ws, asset))
+	_, err = c.Open(ws, asset, key)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
@@ -0,0 +1,411 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	// gif sources are decoded, but only written as png
+	_ "image/gif"
+)
+
+const (
+	ErrInvalidSize         = "width and height must be between 0 and 4096"
+	ErrInvalidFit          = "fit must be cover, contain or fill"
+	ErrInvalidFormat       = "format must be jpeg, png or webp"
+	ErrInvalidQuality      = "quality must be between 1 and 100"
+	ErrInvalidCrop         = "crop must be x,y,width,height in pixels of the source image"
+	ErrInvalidFocal        = "focal must be x,y between 0 and 1"
+	ErrUnsupportedImage    = "image format is not supported"
+	ErrSourceImageTooLarge = "source image is too large"
+)
+
+const (
+	// MaxSize is the max width and height of a transformed image
+	MaxSize = 4096
+	// MaxSourcePixels is the max number of pixels of a source image, larger images are not decoded to bound memory use
+	MaxSourcePixels = 50_000_000
+	// DefaultQuality is the jpeg quality used when none is requested
+	DefaultQuality = 80
+)
+
+// Fit is how the image is fitted into the requested width and height when both are set
+type Fit string
+
+const (
+	// FitContain scales the image to fit within width and height, the aspect ratio is kept so one side may be smaller
+	FitContain Fit = "contain"
+	// FitCover scales the image to cover width and height and crops what is outside around the focal point
+	FitCover Fit = "cover"
+	// FitFill stretches the image to width and height
+	FitFill Fit = "fill"
+)
+
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	// FormatWebP is always lossless, quality is ignored
+	FormatWebP Format = "webp"
+)
+
+// ContentType returns the mime type of the format
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// FormatOf returns the output format of source images of mimeType, false is returned if the source cannot be decoded
+func FormatOf(mimeType string) (Format, bool) {
+
+	switch mimeType {
+	case "image/jpeg":
+		return FormatJPEG, true
+	case "image/png", "image/gif":
+		return FormatPNG, true
+	}
+
+	return "", false
+}
+
+// IsInline returns true if files of mimeType can be shown inline by browsers. Only raster images are shown inline,
+// other files such as html or svg can contain scripts and are downloaded as attachments.
+func IsInline(mimeType string) bool {
+
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+
+	return false
+}
+
+// Point is a position relative to the size of the image, 0,0 is the top left corner and 1,1 the bottom right corner
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Options of an image transformation, the zero value returns the image unchanged
+type Options struct {
+	// Width and Height in pixels, if only one is set the other is computed from the aspect ratio
+	Width  int
+	Height int
+	// Fit defaults to FitContain
+	Fit Fit
+	// Format defaults to the format of the source image
+	Format Format
+	// Quality of jpeg images 1-100, defaults to DefaultQuality
+	Quality int
+	// Crop is applied to the source image before it is resized
+	Crop image.Rectangle
+	// Focal is the point FitCover crops around, defaults to the center of the image
+	Focal *Point
+}
+
+// IsZero returns true if the options do not transform the image
+func (o Options) IsZero() bool {
+	return o == Options{}
+}
+
+// ParseOptions parses the options from the query parameters width, height, fit, format, quality, crop and focal.
+// Other parameters are ignored.
+func ParseOptions(values url.Values) (Options, error) {
+
+	o := Options{}
+
+	size := func(name string) (int, error) {
+		v := values.Get(name)
+		if v == "" {
+			return 0, nil
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > MaxSize {
+			return 0, errors.New(ErrInvalidSize)
+		}
+		return n, nil
+	}
+
+	var err error
+	if o.Width, err = size("width"); err != nil {
+		return Options{}, err
+	}
+	if o.Height, err = size("height"); err != nil {
+		return Options{}, err
+	}
+
+	switch fit := Fit(values.Get("fit")); fit {
+	case "", FitContain, FitCover, FitFill:
+		o.Fit = fit
+	default:
+		return Options{}, errors.New(ErrInvalidFit)
+	}
+
+	switch format := Format(strings.ToLower(values.Get("format"))); format {
+	case "jpg":
+		o.Format = FormatJPEG
+	case "", FormatJPEG, FormatPNG, FormatWebP:
+		o.Format = format
+	default:
+		return Options{}, errors.New(ErrInvalidFormat)
+	}
+
+	if v := values.Get("quality"); v != "" {
+		q, err := strconv.Atoi(v)
+		if err != nil || q < 1 || q > 100 {
+			return Options{}, errors.New(ErrInvalidQuality)
+		}
+		o.Quality = q
+	}
+
+	if v := values.Get("crop"); v != "" {
+		n, err := parseNumbers(v, 4)
+		if err != nil || n[2] <= 0 || n[3] <= 0 || n[0] < 0 || n[1] < 0 {
+			return Options{}, errors.New(ErrInvalidCrop)
+		}
+
+		x, y, w, h := int(n[0]), int(n[1]), int(n[2]), int(n[3])
+		if float64(x) != n[0] || float64(y) != n[1] || float64(w) != n[2] || float64(h) != n[3] {
+			return Options{}, errors.New(ErrInvalidCrop)
+		}
+		o.Crop = image.Rect(x, y, x+w, y+h)
+	}
+
+	if v := values.Get("focal"); v != "" {
+		n, err := parseNumbers(v, 2)
+		if err != nil {
+			return Options{}, errors.New(ErrInvalidFocal)
+		}
+
+		p := Point{X: n[0], Y: n[1]}
+		if err := p.Validate(); err != nil {
+			return Options{}, err
+		}
+		o.Focal = &p
+	}
+
+	return o, nil
+}
+
+// Validate returns an error if the point is outside of the image
+func (p Point) Validate() error {
+
+	if p.X < 0 || p.X > 1 || p.Y < 0 || p.Y > 1 || math.IsNaN(p.X) || math.IsNaN(p.Y) {
+		return errors.New(ErrInvalidFocal)
+	}
+
+	return nil
+}
+
+func parseNumbers(s string, count int) ([]float64, error) {
+
+	parts := strings.Split(s, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("expected %d numbers", count)
+	}
+
+	result := make([]float64, count)
+	for i, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = n
+	}
+
+	return result, nil
+}
+
+// Values returns the options as query parameters, zero values are left out.
+// Encode of the result is the canonical form of the options, equal options always have the same encoding.
+func (o Options) Values() url.Values {
+
+	v := url.Values{}
+
+	if o.Width > 0 {
+		v.Set("width", strconv.Itoa(o.Width))
+	}
+	if o.Height > 0 {
+		v.Set("height", strconv.Itoa(o.Height))
+	}
+	if o.Fit != "" {
+		v.Set("fit", string(o.Fit))
+	}
+	if o.Format != "" {
+		v.Set("format", string(o.Format))
+	}
+	if o.Quality > 0 {
+		v.Set("quality", strconv.Itoa(o.Quality))
+	}
+	if !o.Crop.Empty() {
+		v.Set("crop", fmt.Sprintf("%d,%d,%d,%d", o.Crop.Min.X, o.Crop.Min.Y, o.Crop.Dx(), o.Crop.Dy()))
+	}
+	if o.Focal != nil {
+		v.Set("focal", strconv.FormatFloat(o.Focal.X, 'f', -1, 64)+","+strconv.FormatFloat(o.Focal.Y, 'f', -1, 64))
+	}
+
+	return v
+}
+
+// Decode decodes a gif, jpeg or png image. The size of the image is read first so large images are rejected before they are decoded.
+func Decode(r io.ReadSeeker) (image.Image, error) {
+
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", ErrUnsupportedImage, err)
+	}
+
+	if cfg.Width*cfg.Height > MaxSourcePixels {
+		return nil, errors.New(ErrSourceImageTooLarge)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", ErrUnsupportedImage, err)
+	}
+
+	return img, nil
+}
+
+// Transform crops and resizes img according to the options, the format and quality are used when it is encoded.
+func Transform(img image.Image, opts Options) (image.Image, error) {
+
+	if !opts.Crop.Empty() {
+		b := img.Bounds()
+		crop := opts.Crop.Add(b.Min).Intersect(b)
+		if crop.Empty() {
+			return nil, errors.New(ErrInvalidCrop)
+		}
+		img = subImage(img, crop)
+	}
+
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	width, height := opts.Width, opts.Height
+
+	switch {
+	case width == 0 && height == 0:
+		return img, nil
+	case width == 0:
+		width = scaled(sw, height, sh)
+	case height == 0:
+		height = scaled(sh, width, sw)
+	default:
+		switch opts.Fit {
+		case FitFill:
+		case FitCover:
+			img = subImage(img, coverRect(b, width, height, opts.Focal))
+		default:
+			if sw*height > sh*width {
+				height = scaled(sh, width, sw)
+			} else {
+				width = scaled(sw, height, sh)
+			}
+		}
+	}
+
+	return resize(img, width, height), nil
+}
+
+// scaled returns n scaled by num/den rounded to the nearest pixel, at least 1
+func scaled(n, num, den int) int {
+
+	v := int(math.Round(float64(n) * float64(num) / float64(den)))
+	if v < 1 {
+		return 1
+	}
+	if v > MaxSize {
+		return MaxSize
+	}
+	return v
+}
+
+// coverRect returns the largest part of b with the aspect ratio of width x height, centered on the focal point as far as possible
+func coverRect(b image.Rectangle, width, height int, focal *Point) image.Rectangle {
+
+	sw, sh := b.Dx(), b.Dy()
+	cw, ch := sw, scaled(sw, height, width)
+	if ch > sh {
+		cw, ch = scaled(sh, width, height), sh
+	}
+	if cw > sw {
+		cw = sw
+	}
+
+	f := Point{X: 0.5, Y: 0.5}
+	if focal != nil {
+		f = *focal
+	}
+
+	x := clamp(int(math.Round(f.X*float64(sw)-float64(cw)/2)), 0, sw-cw)
+	y := clamp(int(math.Round(f.Y*float64(sh)-float64(ch)/2)), 0, sh-ch)
+
+	return image.Rect(x, y, x+cw, y+ch).Add(b.Min)
+}
+
+func subImage(img image.Image, r image.Rectangle) image.Image {
+
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Rect, img, r.Min, draw.Src)
+	return dst
+}
+
+// Encode writes img in format. Jpeg has no transparency, transparent areas become white.
+func Encode(w io.Writer, img image.Image, format Format, quality int) error {
+
+	switch format {
+	case FormatJPEG:
+		if quality == 0 {
+			quality = DefaultQuality
+		}
+
+		b := img.Bounds()
+		flat := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(flat, flat.Rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Rect, img, b.Min, draw.Over)
+
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatWebP:
+		return EncodeWebP(w, img)
+	}
+
+	return errors.New(ErrInvalidFormat)
+}
+
+// Render decodes the image of r, transforms it and writes it to w. Format must be set.
+func Render(w io.Writer, r io.ReadSeeker, opts Options) error {
+
+	img, err := Decode(r)
+	if err != nil {
+		return err
+	}
+
+	img, err = Transform(img, opts)
+	if err != nil {
+		return err
+	}
+
+	return Encode(w, img, opts.Format, opts.Quality)
+}
@@ -0,0 +1,188 @@
+//go:build unit
+
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseOptions(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		query  string
+		expect Options
+		err    string
+	}{
+		{
+			name:   "no options",
+			query:  "sig=abc",
+			expect: Options{},
+		},
+		{
+			name:  "all options",
+			query: "width=200&height=100&fit=cover&format=jpg&quality=60&crop=10,20,300,200&focal=0.25,0.5",
+			expect: Options{
+				Width:   200,
+				Height:  100,
+				Fit:     FitCover,
+				Format:  FormatJPEG,
+				Quality: 60,
+				Crop:    image.Rect(10, 20, 310, 220),
+				Focal:   &Point{X: 0.25, Y: 0.5},
+			},
+		},
+		{name: "width too large", query: "width=4097", err: ErrInvalidSize},
+		{name: "negative height", query: "height=-1", err: ErrInvalidSize},
+		{name: "unknown fit", query: "fit=stretch", err: ErrInvalidFit},
+		{name: "unknown format", query: "format=gif", err: ErrInvalidFormat},
+		{name: "quality out of range", query: "quality=0", err: ErrInvalidQuality},
+		{name: "crop without size", query: "crop=0,0,0,10", err: ErrInvalidCrop},
+		{name: "crop with fractions", query: "crop=0.5,0,10,10", err: ErrInvalidCrop},
+		{name: "focal outside of image", query: "focal=1.5,0", err: ErrInvalidFocal},
+		{name: "focal with one number", query: "focal=0.5", err: ErrInvalidFocal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := url.ParseQuery(test.query)
+			assert.NoError(t, err)
+
+			opts, err := ParseOptions(values)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, opts)
+
+			// the canonical form parses to the same options
+			again, err := ParseOptions(opts.Values())
+			assert.NoError(t, err)
+			assert.Equal(t, opts, again)
+		})
+	}
+}
+
+func Test_Transform(t *testing.T) {
+
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	tests := []struct {
+		name   string
+		opts   Options
+		expect image.Point
+	}{
+		{name: "no size keeps image", opts: Options{}, expect: image.Pt(400, 200)},
+		{name: "width keeps aspect ratio", opts: Options{Width: 100}, expect: image.Pt(100, 50)},
+		{name: "height keeps aspect ratio", opts: Options{Height: 100}, expect: image.Pt(200, 100)},
+		{name: "contain fits within size", opts: Options{Width: 100, Height: 100}, expect: image.Pt(100, 50)},
+		{name: "cover fills size", opts: Options{Width: 100, Height: 100, Fit: FitCover}, expect: image.Pt(100, 100)},
+		{name: "fill stretches", opts: Options{Width: 100, Height: 100, Fit: FitFill}, expect: image.Pt(100, 100)},
+		{name: "crop before resize", opts: Options{Crop: image.Rect(0, 0, 100, 100), Width: 50}, expect: image.Pt(50, 50)},
+		{name: "crop is limited to image", opts: Options{Crop: image.Rect(300, 100, 500, 300)}, expect: image.Pt(100, 100)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img, err := Transform(src, test.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, img.Bounds().Size())
+		})
+	}
+
+	_, err := Transform(src, Options{Crop: image.Rect(500, 0, 600, 100)})
+	assert.EqualError(t, err, ErrInvalidCrop)
+}
+
+func Test_Transform_CoverFocalPoint(t *testing.T) {
+
+	// left half red, right half blue
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 100 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	left, err := Transform(src, Options{Width: 10, Height: 10, Fit: FitCover, Focal: &Point{X: 0, Y: 0.5}})
+	assert.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, left.At(5, 5))
+
+	right, err := Transform(src, Options{Width: 10, Height: 10, Fit: FitCover, Focal: &Point{X: 0.9, Y: 0.5}})
+	assert.NoError(t, err)
+	assert.Equal(t, color.RGBA{B: 255, A: 255}, right.At(5, 5))
+}
+
+func Test_Resize_KeepsUniformColor(t *testing.T) {
+
+	src := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	for i := 0; i < len(src.Pix); i += 4 {
+		copy(src.Pix[i:], []byte{200, 100, 50, 255})
+	}
+
+	for _, size := range []image.Point{{10, 7}, {37, 23}, {80, 61}} {
+		img := resize(src, size.X, size.Y)
+		assert.Equal(t, size, img.Bounds().Size())
+		for _, p := range []image.Point{{0, 0}, {size.X - 1, size.Y - 1}, {size.X / 2, size.Y / 2}} {
+			assert.Equal(t, color.RGBA{R: 200, G: 100, B: 50, A: 255}, img.RGBAAt(p.X, p.Y))
+		}
+	}
+}
+
+func Test_Render(t *testing.T) {
+
+	src := &bytes.Buffer{}
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 128})
+	assert.NoError(t, png.Encode(src, img))
+
+	t.Run("jpeg", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		assert.NoError(t, Render(out, bytes.NewReader(src.Bytes()), Options{Width: 20, Format: FormatJPEG, Quality: 50}))
+
+		cfg, err := jpeg.DecodeConfig(out)
+		assert.NoError(t, err)
+		assert.Equal(t, 20, cfg.Width)
+		assert.Equal(t, 10, cfg.Height)
+	})
+
+	t.Run("png", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		assert.NoError(t, Render(out, bytes.NewReader(src.Bytes()), Options{Height: 40, Format: FormatPNG}))
+
+		cfg, err := png.DecodeConfig(out)
+		assert.NoError(t, err)
+		assert.Equal(t, 80, cfg.Width)
+		assert.Equal(t, 40, cfg.Height)
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		err := Render(&bytes.Buffer{}, bytes.NewReader([]byte("plain text")), Options{Width: 10, Format: FormatPNG})
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), ErrUnsupportedImage)
+		}
+	})
+}
+
+func Test_IsInline(t *testing.T) {
+
+	assert.True(t, IsInline("image/png"))
+	assert.True(t, IsInline("image/webp"))
+	assert.False(t, IsInline("image/svg+xml"))
+	assert.False(t, IsInline("text/html; charset=utf-8"))
+	assert.False(t, IsInline("application/pdf"))
+}
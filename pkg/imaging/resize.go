@@ -0,0 +1,156 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// catmullRom is the resampling kernel, it is sharper than bilinear and has less ringing than Lanczos.
+func catmullRom(x float64) float64 {
+
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return (1.5*x-2.5)*x*x + 1
+	case x < 2:
+		return ((-0.5*x+2.5)*x-4)*x + 2
+	}
+	return 0
+}
+
+// weights are the contributions of source pixels start.. to one destination pixel
+type weights struct {
+	start  int
+	values []float64
+}
+
+// resampleWeights computes the weights of every destination pixel along one axis.
+// When downscaling the kernel is stretched so every source pixel contributes to the result.
+func resampleWeights(dst, src int) []weights {
+
+	scale := float64(src) / float64(dst)
+	filterScale := math.Max(scale, 1)
+	support := 2 * filterScale
+
+	result := make([]weights, dst)
+	for i := range result {
+		center := (float64(i)+0.5)*scale - 0.5
+		start := int(math.Ceil(center - support))
+		end := int(math.Floor(center + support))
+
+		w := weights{start: start, values: make([]float64, 0, end-start+1)}
+		sum := 0.0
+		for j := start; j <= end; j++ {
+			v := catmullRom((float64(j) - center) / filterScale)
+			w.values = append(w.values, v)
+			sum += v
+		}
+
+		if sum != 0 {
+			for j := range w.values {
+				w.values[j] /= sum
+			}
+		}
+		result[i] = w
+	}
+
+	return result
+}
+
+// toRGBA returns the image as premultiplied RGBA starting at 0,0, so pixels can be mixed without halos around transparent areas
+func toRGBA(img image.Image) *image.RGBA {
+
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, b.Min, draw.Src)
+
+	return rgba
+}
+
+// resize scales img to width x height. The image is resampled horizontally and then vertically.
+func resize(img image.Image, width, height int) *image.RGBA {
+
+	src := toRGBA(img)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+
+	if sw == width && sh == height {
+		return src
+	}
+
+	// horizontal pass, the intermediate result is kept as floats to not lose precision between the passes
+	tmp := make([]float64, width*sh*4)
+	horizontal := resampleWeights(width, sw)
+	for y := 0; y < sh; y++ {
+		for x, w := range horizontal {
+			var r, g, b, a float64
+			for k, v := range w.values {
+				sx := clamp(w.start+k, 0, sw-1)
+				i := src.PixOffset(sx, y)
+				r += float64(src.Pix[i]) * v
+				g += float64(src.Pix[i+1]) * v
+				b += float64(src.Pix[i+2]) * v
+				a += float64(src.Pix[i+3]) * v
+			}
+
+			j := (y*width + x) * 4
+			tmp[j], tmp[j+1], tmp[j+2], tmp[j+3] = r, g, b, a
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, w := range resampleWeights(height, sh) {
+		for x := 0; x < width; x++ {
+			var r, g, b, a float64
+			for k, v := range w.values {
+				sy := clamp(w.start+k, 0, sh-1)
+				j := (sy*width + x) * 4
+				r += tmp[j] * v
+				g += tmp[j+1] * v
+				b += tmp[j+2] * v
+				a += tmp[j+3] * v
+			}
+
+			// premultiplied color can never exceed alpha
+			alpha := clampByte(a)
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = min8(clampByte(r), alpha)
+			dst.Pix[i+1] = min8(clampByte(g), alpha)
+			dst.Pix[i+2] = min8(clampByte(b), alpha)
+			dst.Pix[i+3] = alpha
+		}
+	}
+
+	return dst
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+func clampByte(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+func min8(a, b uint8) uint8 {
+	if a < b {
+		return a
+	}
+	return b
+}
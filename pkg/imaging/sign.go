@@ -0,0 +1,69 @@
+package imaging
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// SignatureParam is the query parameter of the signature
+const SignatureParam = "sig"
+
+const (
+	ErrInvalidSignature  = "invalid signature"
+	ErrMissingSigningKey = "image signing key is not configured"
+)
+
+// Signer signs the options of image URLs so only transformations created by the content management API are rendered.
+// A Signer without key signs nothing and never verifies a signature.
+type Signer struct {
+	Key []byte
+}
+
+func NewSigner(key string) Signer {
+	return Signer{Key: []byte(key)}
+}
+
+// Sign returns the signature of the options for the image of the asset
+func (s Signer) Sign(ws, asset uuid.UUID, opts Options) string {
+
+	if len(s.Key) == 0 {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, s.Key)
+	fmt.Fprintf(mac, "%s/%s?%s", ws, asset, opts.Values().Encode())
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true if signature is the signature of the options for the image of the asset
+func (s Signer) Verify(ws, asset uuid.UUID, opts Options, signature string) bool {
+
+	if len(s.Key) == 0 || signature == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(s.Sign(ws, asset, opts)), []byte(signature))
+}
+
+// SignedValues returns the options as query parameters together with their signature
+func (s Signer) SignedValues(ws, asset uuid.UUID, opts Options) (url.Values, error) {
+
+	v := opts.Values()
+	if opts.IsZero() {
+		return v, nil
+	}
+
+	if len(s.Key) == 0 {
+		return nil, errors.New(ErrMissingSigningKey)
+	}
+
+	v.Set(SignatureParam, s.Sign(ws, asset, opts))
+	return v, nil
+}
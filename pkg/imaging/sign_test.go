@@ -0,0 +1,41 @@
+//go:build unit
+
+package imaging
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Signer(t *testing.T) {
+
+	s := NewSigner("secret")
+	ws, asset := uuid.New(), uuid.New()
+	opts := Options{Width: 100, Format: FormatWebP}
+
+	values, err := s.SignedValues(ws, asset, opts)
+	assert.NoError(t, err)
+
+	parsed, err := ParseOptions(values)
+	assert.NoError(t, err)
+	assert.True(t, s.Verify(ws, asset, parsed, values.Get(SignatureParam)))
+
+	sig := values.Get(SignatureParam)
+	assert.False(t, s.Verify(ws, asset, Options{Width: 101, Format: FormatWebP}, sig), "other options")
+	assert.False(t, s.Verify(ws, uuid.New(), opts, sig), "other asset")
+	assert.False(t, s.Verify(uuid.New(), asset, opts, sig), "other workspace")
+	assert.False(t, NewSigner("other").Verify(ws, asset, opts, sig), "other key")
+	assert.False(t, s.Verify(ws, asset, opts, ""))
+
+	// without key nothing is signed
+	_, err = NewSigner("").SignedValues(ws, asset, opts)
+	assert.EqualError(t, err, ErrMissingSigningKey)
+	assert.False(t, NewSigner("").Verify(ws, asset, opts, ""))
+
+	// the original file needs no signature
+	values, err = NewSigner("").SignedValues(ws, asset, Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+}
@@ -0,0 +1,350 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// The encoder writes the lossless WebP format (VP8L). It uses the subtract green transform and a single set of
+// prefix codes without backward references, which keeps it small while files are still considerably smaller than PNG
+// for photos. See https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification
+
+const (
+	vp8lSignature      = 0x2f
+	vp8lMaxSize        = 1 << 14
+	vp8lSubtractGreen  = 2
+	vp8lMaxCodeLength  = 15
+	vp8lMaxCLCodeLen   = 7
+	vp8lNumLengthCodes = 19
+	vp8lGreenAlphabet  = 256 + 24
+	vp8lDistAlphabet   = 40
+)
+
+// order the lengths of the code length code are written in
+var vp8lCodeLengthOrder = [vp8lNumLengthCodes]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+const ErrImageTooLarge = "image is too large to encode as webp"
+
+// EncodeWebP writes img as a lossless WebP image
+func EncodeWebP(w io.Writer, img image.Image) error {
+
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxSize || height > vp8lMaxSize {
+		return errors.New(ErrImageTooLarge)
+	}
+
+	src := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Rect, img, b.Min, draw.Src)
+
+	// subtract green, the decoder adds green back to red and blue
+	pix := src.Pix
+	alphaUsed := false
+	for i := 0; i < len(pix); i += 4 {
+		pix[i] -= pix[i+1]
+		pix[i+2] -= pix[i+1]
+		if pix[i+3] != 0xff {
+			alphaUsed = true
+		}
+	}
+
+	green := make([]int, vp8lGreenAlphabet)
+	red := make([]int, 256)
+	blue := make([]int, 256)
+	alpha := make([]int, 256)
+	for i := 0; i < len(pix); i += 4 {
+		red[pix[i]]++
+		green[pix[i+1]]++
+		blue[pix[i+2]]++
+		alpha[pix[i+3]]++
+	}
+
+	bw := &bitWriter{}
+	bw.write(vp8lSignature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(boolBit(alphaUsed), 1)
+	bw.write(0, 3) // version
+
+	bw.write(1, 1) // transform present
+	bw.write(vp8lSubtractGreen, 2)
+	bw.write(0, 1) // no more transforms
+
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // a single set of prefix codes for the whole image
+
+	greenCodes := writePrefixCode(bw, green)
+	redCodes := writePrefixCode(bw, red)
+	blueCodes := writePrefixCode(bw, blue)
+	alphaCodes := writePrefixCode(bw, alpha)
+	writePrefixCode(bw, make([]int, vp8lDistAlphabet))
+
+	for i := 0; i < len(pix); i += 4 {
+		bw.writeCode(greenCodes[pix[i+1]])
+		bw.writeCode(redCodes[pix[i]])
+		bw.writeCode(blueCodes[pix[i+2]])
+		bw.writeCode(alphaCodes[pix[i+3]])
+	}
+
+	data := bw.bytes()
+	pad := len(data) & 1
+
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	if pad == 1 {
+		data = append(data, 0)
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+type huffCode struct {
+	// bits are reversed since the bitstream is read least significant bit first
+	bits   uint32
+	length uint
+}
+
+// writePrefixCode writes the prefix code of the symbol frequencies and returns the code of every symbol
+func writePrefixCode(bw *bitWriter, freq []int) []huffCode {
+
+	used := make([]int, 0, 2)
+	for s, f := range freq {
+		if f > 0 {
+			used = append(used, s)
+		}
+	}
+
+	// a simple code with a single symbol, reading the symbol takes no bits
+	if len(used) <= 1 {
+		symbol := 0
+		if len(used) == 1 {
+			symbol = used[0]
+		}
+
+		bw.write(1, 1) // simple code
+		bw.write(0, 1) // one symbol
+		if symbol < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(symbol), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(symbol), 8)
+		}
+
+		return make([]huffCode, len(freq))
+	}
+
+	lengths := huffmanLengths(freq, vp8lMaxCodeLength)
+	codes := canonicalCodes(lengths)
+
+	clFreq := make([]int, vp8lNumLengthCodes)
+	for _, l := range lengths {
+		clFreq[l]++
+	}
+	clLengths := huffmanLengths(clFreq, vp8lMaxCLCodeLen)
+	clCodes := canonicalCodes(clLengths)
+
+	n := vp8lNumLengthCodes
+	for n > 4 && clLengths[vp8lCodeLengthOrder[n-1]] == 0 {
+		n--
+	}
+
+	bw.write(0, 1) // normal code
+	bw.write(uint32(n-4), 4)
+	for _, s := range vp8lCodeLengthOrder[:n] {
+		bw.write(uint32(clLengths[s]), 3)
+	}
+
+	bw.write(0, 1) // the lengths of every symbol of the alphabet are written
+	for _, l := range lengths {
+		bw.writeCode(clCodes[l])
+	}
+
+	return codes
+}
+
+// huffmanLengths returns the code length of every symbol, symbols with frequency 0 get no code.
+// When the tree is deeper than maxLength the smallest frequencies are raised until it fits.
+// A single used symbol gets a sibling so the code is always complete.
+func huffmanLengths(freq []int, maxLength int) []uint8 {
+
+	type node struct {
+		weight      int
+		symbol      int
+		left, right *node
+	}
+
+	lengths := make([]uint8, len(freq))
+
+	used := 0
+	last := 0
+	for s, f := range freq {
+		if f > 0 {
+			used++
+			last = s
+		}
+	}
+
+	switch used {
+	case 0:
+		return lengths
+	case 1:
+		lengths[last] = 1
+		if last == 0 {
+			lengths[1] = 1
+		} else {
+			lengths[0] = 1
+		}
+		return lengths
+	}
+
+	for minWeight := 1; ; minWeight *= 2 {
+		leaves := make([]*node, 0, used)
+		for s, f := range freq {
+			if f == 0 {
+				continue
+			}
+			if f < minWeight {
+				f = minWeight
+			}
+			leaves = append(leaves, &node{weight: f, symbol: s})
+		}
+
+		sort.SliceStable(leaves, func(i, j int) bool {
+			return leaves[i].weight < leaves[j].weight
+		})
+
+		// two queue construction, internal nodes are created in increasing weight order
+		internal := make([]*node, 0, len(leaves))
+		pop := func() *node {
+			if len(internal) == 0 || (len(leaves) > 0 && leaves[0].weight <= internal[0].weight) {
+				n := leaves[0]
+				leaves = leaves[1:]
+				return n
+			}
+			n := internal[0]
+			internal = internal[1:]
+			return n
+		}
+
+		for len(leaves)+len(internal) > 1 {
+			a := pop()
+			b := pop()
+			internal = append(internal, &node{weight: a.weight + b.weight, left: a, right: b})
+		}
+
+		maxDepth := 0
+		var walk func(n *node, depth int)
+		walk = func(n *node, depth int) {
+			if n.left == nil {
+				lengths[n.symbol] = uint8(depth)
+				if depth > maxDepth {
+					maxDepth = depth
+				}
+				return
+			}
+			walk(n.left, depth+1)
+			walk(n.right, depth+1)
+		}
+		walk(internal[0], 0)
+
+		if maxDepth <= maxLength {
+			return lengths
+		}
+	}
+}
+
+// canonicalCodes assigns codes in order of length and then symbol, like deflate
+func canonicalCodes(lengths []uint8) []huffCode {
+
+	var count [vp8lMaxCodeLength + 1]int
+	for _, l := range lengths {
+		if l > 0 {
+			count[l]++
+		}
+	}
+
+	var next [vp8lMaxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= vp8lMaxCodeLength; l++ {
+		code = (code + uint32(count[l-1])) << 1
+		next[l] = code
+	}
+
+	codes := make([]huffCode, len(lengths))
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+
+		codes[s] = huffCode{bits: reverseBits(next[l], uint(l)), length: uint(l)}
+		next[l]++
+	}
+
+	return codes
+}
+
+func reverseBits(v uint32, n uint) uint32 {
+
+	r := uint32(0)
+	for i := uint(0); i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+
+	return r
+}
+
+// bitWriter writes bits least significant bit first
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+
+	w.acc |= uint64(v) << w.nacc
+	w.nacc += n
+
+	for w.nacc >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nacc -= 8
+	}
+}
+
+func (w *bitWriter) writeCode(c huffCode) {
+	w.write(c.bits, c.length)
+}
+
+func (w *bitWriter) bytes() []byte {
+
+	if w.nacc > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc = 0
+		w.nacc = 0
+	}
+
+	return w.buf
+}
+
+func boolBit(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
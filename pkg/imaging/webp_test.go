@@ -0,0 +1,138 @@
+//go:build unit
+
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/webp"
+)
+
+func Test_EncodeWebP(t *testing.T) {
+
+	r := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 33, 17))
+	for i := 0; i < len(img.Pix); i++ {
+		img.Pix[i] = byte(r.Intn(256))
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, EncodeWebP(buf, img))
+	data := buf.Bytes()
+
+	assert.Equal(t, "RIFF", string(data[0:4]))
+	assert.Equal(t, len(data)-8, int(binary.LittleEndian.Uint32(data[4:8])))
+	assert.Equal(t, "WEBPVP8L", string(data[8:16]))
+	assert.Equal(t, 0, len(data)%2)
+
+	chunk := binary.LittleEndian.Uint32(data[16:20])
+	assert.LessOrEqual(t, int(chunk), len(data)-20)
+
+	// signature, 14 bit width - 1, 14 bit height - 1 and alpha is used
+	assert.Equal(t, byte(vp8lSignature), data[20])
+	header := binary.LittleEndian.Uint32(data[21:25])
+	assert.Equal(t, uint32(33), header&0x3fff+1)
+	assert.Equal(t, uint32(17), (header>>14)&0x3fff+1)
+	assert.Equal(t, uint32(1), (header>>28)&1)
+
+	// lossless, so every pixel is decoded as it was encoded
+	decoded, err := webp.Decode(bytes.NewReader(data))
+	if assert.NoError(t, err) {
+		assertSamePixels(t, img, decoded)
+	}
+
+	// alpha is not used by opaque images
+	opaque := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i+3] = 0xff
+	}
+	opaque.Set(1, 1, color.White)
+
+	buf.Reset()
+	assert.NoError(t, EncodeWebP(buf, opaque))
+	assert.Equal(t, uint32(0), (binary.LittleEndian.Uint32(buf.Bytes()[21:25])>>28)&1)
+
+	decoded, err = webp.Decode(bytes.NewReader(buf.Bytes()))
+	if assert.NoError(t, err) {
+		assertSamePixels(t, opaque, decoded)
+	}
+
+	assert.Error(t, EncodeWebP(buf, image.NewNRGBA(image.Rect(0, 0, vp8lMaxSize+1, 1))))
+}
+
+func assertSamePixels(t *testing.T, expected, actual image.Image) {
+	t.Helper()
+
+	if !assert.Equal(t, expected.Bounds(), actual.Bounds()) {
+		return
+	}
+
+	b := expected.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			e := color.NRGBAModel.Convert(expected.At(x, y))
+			a := color.NRGBAModel.Convert(actual.At(x, y))
+			if !assert.Equal(t, e, a, "pixel %d,%d", x, y) {
+				return
+			}
+		}
+	}
+}
+
+func Test_HuffmanLengths(t *testing.T) {
+
+	// kraft sum of a complete prefix code is exactly 1
+	kraft := func(lengths []uint8) float64 {
+		sum := 0.0
+		for _, l := range lengths {
+			if l > 0 {
+				sum += 1 / float64(uint(1)<<l)
+			}
+		}
+		return sum
+	}
+
+	t.Run("lengths are limited", func(t *testing.T) {
+		// fibonacci frequencies give the deepest possible tree
+		freq := make([]int, 30)
+		a, b := 1, 1
+		for i := range freq {
+			freq[i] = a
+			a, b = b, a+b
+		}
+
+		lengths := huffmanLengths(freq, 7)
+		for _, l := range lengths {
+			assert.LessOrEqual(t, int(l), 7)
+			assert.Greater(t, int(l), 0)
+		}
+		assert.Equal(t, 1.0, kraft(lengths))
+	})
+
+	t.Run("single symbol gets a sibling", func(t *testing.T) {
+		lengths := huffmanLengths([]int{0, 0, 5}, 15)
+		assert.Equal(t, []uint8{1, 0, 1}, lengths)
+		assert.Equal(t, 1.0, kraft(lengths))
+	})
+
+	t.Run("canonical codes are prefix free", func(t *testing.T) {
+		lengths := huffmanLengths([]int{10, 1, 1, 5, 0, 3}, 15)
+		codes := canonicalCodes(lengths)
+
+		for i, a := range codes {
+			for j, b := range codes {
+				if i == j || a.length == 0 || b.length == 0 || a.length > b.length {
+					continue
+				}
+				mask := uint32(1)<<a.length - 1
+				assert.NotEqual(t, a.bits, b.bits&mask, "code %d is a prefix of %d", i, j)
+			}
+		}
+	})
+}
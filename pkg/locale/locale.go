@@ -1,27 +1,99 @@
 package locale
 
-// func GetLocale(r *http.Request, cfg *siteconfiguration.SiteConfiguration) (language.Tag, error) {
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
 
-// 	accept := r.Header.Get("Accept-Language")
+	"github.com/crikke/cms/pkg/workspace"
+	"golang.org/x/text/language"
+)
 
-// 	t, _, err := language.ParseAcceptLanguage(accept)
+type key string
 
-// 	if err != nil {
+const localeKey = key("locale")
 
-// 		return language.Tag{}, errors.New("Accept-Language")
-// 	}
+// QueryParam overrides Accept-Language
+const QueryParam = "locale"
 
-// 	matcher := language.NewMatcher(cfg.Languages)
+const ErrNotConfiguredLocale = "locale: language is not configured in workspace"
+const ErrAcceptLanguage = "Accept-Language: bad format"
 
-// 	tag, _, _ := matcher.Match(t...)
+// GetLocale returns the language of languages that best matches the request.
+// An explicit ?locale= must be one of languages, otherwise Accept-Language is matched against languages.
+// The first language is used when nothing matches.
+func GetLocale(r *http.Request, languages []string) (string, error) {
 
-// 	base, _ := tag.Base()
-// 	region, _ := tag.Region()
-// 	tag, err = language.Compose(base, region)
+	if len(languages) == 0 {
+		return "", errors.New(ErrNotConfiguredLocale)
+	}
 
-// 	if err != nil {
-// 		return language.Tag{}, errors.New("Accept-Language")
-// 	}
+	if l := r.URL.Query().Get(QueryParam); l != "" {
+		for _, lang := range languages {
+			if strings.EqualFold(l, lang) {
+				return lang, nil
+			}
+		}
 
-// 	return tag, nil
-// }
+		return "", errors.New(ErrNotConfiguredLocale)
+	}
+
+	accept := r.Header.Get("Accept-Language")
+	if accept == "" {
+		return languages[0], nil
+	}
+
+	desired, _, err := language.ParseAcceptLanguage(accept)
+	if err != nil {
+		return "", errors.New(ErrAcceptLanguage)
+	}
+
+	supported := make([]language.Tag, 0, len(languages))
+	for _, lang := range languages {
+		supported = append(supported, language.Make(lang))
+	}
+
+	// the index is used instead of the returned tag, since the tag can contain extensions from the desired language
+	_, index, confidence := language.NewMatcher(supported).Match(desired...)
+	if confidence == language.No {
+		return languages[0], nil
+	}
+
+	return languages[index], nil
+}
+
+// Handler negotiates the language of the request against the languages returned by languages,
+// usually the languages of the workspace. The language is set as Content-Language on the response.
+func Handler(languages func(r *http.Request) []string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			l, err := GetLocale(r, languages(r))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Language", l)
+
+			ctx := context.WithValue(r.Context(), localeKey, l)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WorkspaceHandler negotiates the language of the request against the languages of the workspace returned by ws, see Handler
+func WorkspaceHandler(ws func(ctx context.Context) workspace.Workspace) func(next http.Handler) http.Handler {
+	return Handler(func(r *http.Request) []string {
+		return ws(r.Context()).Languages
+	})
+}
+
+// FromContext returns the language negotiated by Handler
+func FromContext(ctx context.Context) string {
+	if l := ctx.Value(localeKey); l != nil {
+		return l.(string)
+	}
+	return ""
+}
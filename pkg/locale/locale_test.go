@@ -0,0 +1,111 @@
+//go:build unit
+
+package locale
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetLocale(t *testing.T) {
+
+	languages := []string{"sv-SE", "en-US", "nb-NO"}
+
+	tests := []struct {
+		name           string
+		acceptlanguage string
+		query          string
+		expected       string
+		ok             bool
+	}{
+		{
+			name:     "default language",
+			expected: "sv-SE",
+			ok:       true,
+		},
+		{
+			name:           "exact match",
+			acceptlanguage: "en-US",
+			expected:       "en-US",
+			ok:             true,
+		},
+		{
+			name:           "quality",
+			acceptlanguage: "sv-SE;q=0.1, nb-NO;q=0.5",
+			expected:       "nb-NO",
+			ok:             true,
+		},
+		{
+			name:           "same quality uses order",
+			acceptlanguage: "sv-SE;q=0.1, nb-NO;q=0.1",
+			expected:       "sv-SE",
+			ok:             true,
+		},
+		{
+			name:           "language without region",
+			acceptlanguage: "en",
+			expected:       "en-US",
+			ok:             true,
+		},
+		{
+			name:           "no match uses default language",
+			acceptlanguage: "ja-JP",
+			expected:       "sv-SE",
+			ok:             true,
+		},
+		{
+			name:           "query overrides accept-language",
+			acceptlanguage: "en-US",
+			query:          "nb-no",
+			expected:       "nb-NO",
+			ok:             true,
+		},
+		{
+			name:  "query with not configured language",
+			query: "de-DE",
+			ok:    false,
+		},
+		{
+			name:           "malformed",
+			acceptlanguage: "malformed;q=x",
+			ok:             false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Add("Accept-Language", test.acceptlanguage)
+
+			if test.query != "" {
+				q := req.URL.Query()
+				q.Set(QueryParam, test.query)
+				req.URL.RawQuery = q.Encode()
+			}
+
+			actual, err := GetLocale(req, languages)
+
+			assert.Equal(t, test.ok, err == nil)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func Test_Handler(t *testing.T) {
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Add("Accept-Language", "en-US")
+	w := httptest.NewRecorder()
+
+	languages := func(r *http.Request) []string { return []string{"sv-SE", "en-US"} }
+
+	Handler(languages)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "en-US", FromContext(r.Context()))
+	})).ServeHTTP(w, req)
+
+	assert.Equal(t, "en-US", w.Header().Get("Content-Language"))
+}
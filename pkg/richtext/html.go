@@ -0,0 +1,389 @@
+package richtext
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	attrContentID = "data-content-id"
+	attrAssetID   = "data-asset-id"
+)
+
+// dropped elements are removed together with their content
+var dropped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Template: true,
+	atom.Noscript: true,
+	atom.Svg:      true,
+	atom.Math:     true,
+	atom.Head:     true,
+	atom.Title:    true,
+	atom.Textarea: true,
+	atom.Select:   true,
+	atom.Button:   true,
+}
+
+// blockElements start a new block, block elements that are not converted to a block type are treated as containers
+var blockElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+	atom.Ul:         true,
+	atom.Ol:         true,
+	atom.Li:         true,
+	atom.Hr:         true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Header:     true,
+	atom.Footer:     true,
+	atom.Main:       true,
+	atom.Aside:      true,
+	atom.Nav:        true,
+	atom.Figure:     true,
+	atom.Table:      true,
+	atom.Tr:         true,
+	atom.Td:         true,
+	atom.Th:         true,
+}
+
+var inlineMarks = map[atom.Atom]MarkType{
+	atom.Strong: Bold,
+	atom.B:      Bold,
+	atom.Em:     Italic,
+	atom.I:      Italic,
+	atom.U:      Underline,
+	atom.S:      Strike,
+	atom.Strike: Strike,
+	atom.Del:    Strike,
+	atom.Code:   Code,
+	atom.Kbd:    Code,
+	atom.Samp:   Code,
+	atom.A:      Link,
+}
+
+// FromHTML converts HTML to a document. Elements without a corresponding block or mark are replaced with their content,
+// elements that can contain scripts or styles are removed. The document is not sanitized.
+func FromHTML(input string) (Document, error) {
+
+	root, err := nethtml.Parse(strings.NewReader(input))
+	if err != nil {
+		return Document{}, fmt.Errorf("%s: %s", ErrInvalidDocument, err)
+	}
+
+	body := findElement(root, atom.Body)
+	if body == nil {
+		return Document{}, nil
+	}
+
+	return Document{Blocks: htmlBlocks(body)}, nil
+}
+
+func findElement(n *nethtml.Node, a atom.Atom) *nethtml.Node {
+
+	if n.Type == nethtml.ElementNode && n.DataAtom == a {
+		return n
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// htmlBlocks converts the children of n, inline content between blocks becomes paragraphs
+func htmlBlocks(n *nethtml.Node) []Block {
+
+	blocks := make([]Block, 0)
+	inline := make([]Span, 0)
+
+	flush := func() {
+		if spans := normalizeSpans(inline); len(spans) > 0 {
+			blocks = append(blocks, Block{Type: Paragraph, Spans: spans})
+		}
+		inline = inline[:0]
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == nethtml.ElementNode && (blockElements[c.DataAtom] || htmlEmbed(c) != nil) {
+			flush()
+			blocks = append(blocks, htmlBlock(c)...)
+			continue
+		}
+
+		inline = append(inline, htmlSpans(c, nil)...)
+	}
+	flush()
+
+	return blocks
+}
+
+func htmlBlock(n *nethtml.Node) []Block {
+
+	if embed := htmlEmbed(n); embed != nil {
+		return []Block{{Type: EmbedBlock, Embed: embed}}
+	}
+
+	switch n.DataAtom {
+	case atom.P:
+		return []Block{{Type: Paragraph, Spans: normalizeSpans(htmlChildSpans(n, nil))}}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return []Block{{Type: Heading, Level: int(n.Data[1] - '0'), Spans: normalizeSpans(htmlChildSpans(n, nil))}}
+	case atom.Blockquote:
+		return []Block{{Type: Quote, Blocks: htmlBlocks(n)}}
+	case atom.Pre:
+		return []Block{{Type: CodeBlock, Spans: []Span{{Text: strings.TrimSuffix(textContent(n), "\n")}}}}
+	case atom.Ul:
+		return []Block{{Type: BulletList, Blocks: htmlBlocks(n)}}
+	case atom.Ol:
+		return []Block{{Type: OrderedList, Blocks: htmlBlocks(n)}}
+	case atom.Li:
+		return []Block{{Type: ListItem, Blocks: htmlBlocks(n)}}
+	case atom.Hr:
+		return []Block{{Type: Rule}}
+	}
+
+	return htmlBlocks(n)
+}
+
+// htmlEmbed returns the embed of elements rendered by ToHTML
+func htmlEmbed(n *nethtml.Node) *Embed {
+
+	for _, a := range n.Attr {
+		switch a.Key {
+		case attrContentID:
+			return &Embed{Type: EmbedContent, ID: a.Val}
+		case attrAssetID:
+			return &Embed{Type: EmbedAsset, ID: a.Val}
+		}
+	}
+
+	return nil
+}
+
+func htmlSpans(n *nethtml.Node, marks []Mark) []Span {
+
+	switch n.Type {
+	case nethtml.TextNode:
+		return []Span{{Text: n.Data, Marks: marks}}
+	case nethtml.ElementNode:
+	default:
+		return nil
+	}
+
+	if dropped[n.DataAtom] {
+		return nil
+	}
+
+	if n.DataAtom == atom.Br {
+		return []Span{{Text: "\n", Marks: marks}}
+	}
+
+	if t, ok := inlineMarks[n.DataAtom]; ok {
+		m := Mark{Type: t}
+		if t == Link {
+			m.Href = attr(n, "href")
+		}
+
+		marks = append(append(make([]Mark, 0, len(marks)+1), marks...), m)
+	}
+
+	return htmlChildSpans(n, marks)
+}
+
+func htmlChildSpans(n *nethtml.Node, marks []Mark) []Span {
+
+	spans := make([]Span, 0)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		spans = append(spans, htmlSpans(c, marks)...)
+	}
+
+	return spans
+}
+
+// normalizeSpans collapses whitespace like a browser does, line breaks are kept
+func normalizeSpans(spans []Span) []Span {
+
+	result := make([]Span, 0, len(spans))
+	space := true
+
+	for _, s := range spans {
+		sb := strings.Builder{}
+		for _, r := range s.Text {
+			switch {
+			case r == '\n' && s.Text == "\n":
+				sb.WriteRune(r)
+				space = true
+			case unicode.IsSpace(r):
+				if !space {
+					sb.WriteRune(' ')
+				}
+				space = true
+			default:
+				sb.WriteRune(r)
+				space = false
+			}
+		}
+
+		if sb.Len() > 0 {
+			result = append(result, Span{Text: sb.String(), Marks: s.Marks})
+		}
+	}
+
+	// remove trailing whitespace
+	for len(result) > 0 {
+		last := &result[len(result)-1]
+		last.Text = strings.TrimRight(last.Text, " \n")
+		if last.Text != "" {
+			break
+		}
+		result = result[:len(result)-1]
+	}
+
+	return result
+}
+
+func textContent(n *nethtml.Node) string {
+
+	if n.Type == nethtml.TextNode {
+		return n.Data
+	}
+
+	sb := strings.Builder{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+
+	return sb.String()
+}
+
+func attr(n *nethtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// ToHTML renders the document as HTML. Text and attributes are escaped, embeds are rendered as empty elements
+// with a data-content-id or data-asset-id attribute.
+func ToHTML(doc Document) string {
+
+	sb := &strings.Builder{}
+	writeBlocks(sb, doc.Blocks)
+
+	return sb.String()
+}
+
+func writeBlocks(sb *strings.Builder, blocks []Block) {
+	for _, b := range blocks {
+		writeBlock(sb, b)
+	}
+}
+
+func writeBlock(sb *strings.Builder, b Block) {
+
+	switch b.Type {
+	case Paragraph:
+		sb.WriteString("<p>")
+		writeSpans(sb, b.Spans)
+		sb.WriteString("</p>")
+	case Heading:
+		level := clamp(b.Level, 1, 6)
+		fmt.Fprintf(sb, "<h%d>", level)
+		writeSpans(sb, b.Spans)
+		fmt.Fprintf(sb, "</h%d>", level)
+	case Quote:
+		sb.WriteString("<blockquote>")
+		writeBlocks(sb, b.Blocks)
+		sb.WriteString("</blockquote>")
+	case CodeBlock:
+		sb.WriteString("<pre><code>")
+		sb.WriteString(html.EscapeString(spansText(b.Spans)))
+		sb.WriteString("</code></pre>")
+	case BulletList:
+		sb.WriteString("<ul>")
+		writeBlocks(sb, b.Blocks)
+		sb.WriteString("</ul>")
+	case OrderedList:
+		sb.WriteString("<ol>")
+		writeBlocks(sb, b.Blocks)
+		sb.WriteString("</ol>")
+	case ListItem:
+		sb.WriteString("<li>")
+		// a list item with a single paragraph is rendered without the paragraph
+		if len(b.Blocks) == 1 && b.Blocks[0].Type == Paragraph {
+			writeSpans(sb, b.Blocks[0].Spans)
+		} else {
+			writeBlocks(sb, b.Blocks)
+		}
+		sb.WriteString("</li>")
+	case Rule:
+		sb.WriteString("<hr>")
+	case EmbedBlock:
+		if b.Embed == nil {
+			return
+		}
+
+		if b.Embed.Type == EmbedAsset {
+			fmt.Fprintf(sb, `<figure %s="%s"></figure>`, attrAssetID, html.EscapeString(b.Embed.ID))
+		} else {
+			fmt.Fprintf(sb, `<div %s="%s"></div>`, attrContentID, html.EscapeString(b.Embed.ID))
+		}
+	}
+}
+
+var markElements = map[MarkType]string{
+	Bold:      "strong",
+	Italic:    "em",
+	Underline: "u",
+	Strike:    "s",
+	Code:      "code",
+	Link:      "a",
+}
+
+func writeSpans(sb *strings.Builder, spans []Span) {
+
+	for _, s := range spans {
+		marks := make([]Mark, 0, len(s.Marks))
+		for _, m := range s.Marks {
+			if _, ok := markElements[m.Type]; ok {
+				marks = append(marks, m)
+			}
+		}
+
+		for _, m := range marks {
+			if m.Type == Link {
+				fmt.Fprintf(sb, `<a href="%s">`, html.EscapeString(m.Href))
+				continue
+			}
+			fmt.Fprintf(sb, "<%s>", markElements[m.Type])
+		}
+
+		sb.WriteString(strings.ReplaceAll(html.EscapeString(s.Text), "\n", "<br>"))
+
+		for i := len(marks) - 1; i >= 0; i-- {
+			fmt.Fprintf(sb, "</%s>", markElements[marks[i].Type])
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package richtext
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdHeading = regexp.MustCompile(`^(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	mdRule    = regexp.MustCompile(`^(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdFence   = regexp.MustCompile("^(```|~~~)")
+	mdItem    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	mdEmbed   = regexp.MustCompile(`^!\[[^\]]*\]\((content|asset):([0-9a-fA-F-]+)\)$`)
+)
+
+// FromMarkdown converts Markdown to a document. Headings, paragraphs, quotes, fenced code blocks, lists, rules,
+// emphasis, strong, strikethrough, code and links are supported. Content and assets are embedded with
+// ![alt](content:id) and ![alt](asset:id) on a line of their own. The document is not sanitized.
+func FromMarkdown(input string) Document {
+
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+	return Document{Blocks: markdownBlocks(strings.Split(input, "\n"))}
+}
+
+func markdownBlocks(lines []string) []Block {
+
+	blocks := make([]Block, 0)
+	paragraph := make([]string, 0)
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, Block{Type: Paragraph, Spans: markdownSpans(strings.Join(paragraph, ""), nil)})
+			paragraph = paragraph[:0]
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+			i++
+
+		case mdFence.MatchString(trimmed):
+			flush()
+			fence := trimmed[:3]
+			code := make([]string, 0)
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			blocks = append(blocks, Block{Type: CodeBlock, Spans: []Span{{Text: strings.Join(code, "\n")}}})
+
+		case mdHeading.MatchString(trimmed):
+			flush()
+			m := mdHeading.FindStringSubmatch(trimmed)
+			blocks = append(blocks, Block{Type: Heading, Level: len(m[1]), Spans: markdownSpans(m[2], nil)})
+			i++
+
+		case mdRule.MatchString(trimmed):
+			flush()
+			blocks = append(blocks, Block{Type: Rule})
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			quote := make([]string, 0)
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				l := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(l, " "))
+			}
+			blocks = append(blocks, Block{Type: Quote, Blocks: markdownBlocks(quote)})
+
+		case mdItem.MatchString(line):
+			flush()
+			var list Block
+			list, i = markdownList(lines, i)
+			blocks = append(blocks, list)
+
+		case mdEmbed.MatchString(trimmed):
+			flush()
+			m := mdEmbed.FindStringSubmatch(trimmed)
+			blocks = append(blocks, Block{Type: EmbedBlock, Embed: &Embed{Type: EmbedType(m[1]), ID: m[2]}})
+			i++
+
+		default:
+			// two trailing spaces is a line break, otherwise lines in a paragraph are joined
+			if strings.HasSuffix(line, "  ") {
+				paragraph = append(paragraph, trimmed+"\n")
+			} else if len(paragraph) > 0 && !strings.HasSuffix(paragraph[len(paragraph)-1], "\n") {
+				paragraph[len(paragraph)-1] += " "
+				paragraph = append(paragraph, trimmed)
+			} else {
+				paragraph = append(paragraph, trimmed)
+			}
+			i++
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// markdownList reads the list starting at lines[start] and returns it together with the index of the line after the list.
+// Lines indented deeper than the list marker belong to the current item, which makes nested lists possible.
+func markdownList(lines []string, start int) (Block, int) {
+
+	first := mdItem.FindStringSubmatch(lines[start])
+	indent := len(first[1])
+	ordered := !strings.ContainsAny(first[2], "-*+")
+
+	list := Block{Type: BulletList}
+	if ordered {
+		list.Type = OrderedList
+	}
+
+	var item []string
+	contentIndent := 0
+
+	flush := func() {
+		if item != nil {
+			list.Blocks = append(list.Blocks, Block{Type: ListItem, Blocks: markdownBlocks(item)})
+		}
+	}
+
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := mdItem.FindStringSubmatch(line); m != nil && len(m[1]) == indent && ordered == !strings.ContainsAny(m[2], "-*+") {
+			flush()
+			item = []string{m[3]}
+			contentIndent = len(m[1]) + len(m[2]) + 1
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			// a blank line ends the list unless the next line is indented or is another item
+			next := i + 1
+			if next < len(lines) && (leadingSpaces(lines[next]) > indent || mdItem.MatchString(lines[next]) && leadingSpaces(lines[next]) == indent) {
+				item = append(item, "")
+				continue
+			}
+			break
+		}
+
+		if leadingSpaces(line) > indent {
+			item = append(item, line[min(leadingSpaces(line), contentIndent):])
+			continue
+		}
+
+		// lazy continuation of the paragraph of the item
+		if prev := lines[i-1]; strings.TrimSpace(prev) != "" && !mdItem.MatchString(line) && !mdHeading.MatchString(strings.TrimSpace(line)) {
+			item = append(item, line)
+			continue
+		}
+
+		break
+	}
+	flush()
+
+	return list, i
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// markdownDelimiters are the delimiters of marks, longer delimiters are matched first
+var markdownDelimiters = []struct {
+	delim string
+	mark  MarkType
+}{
+	{"**", Bold},
+	{"__", Bold},
+	{"~~", Strike},
+	{"*", Italic},
+	{"_", Italic},
+}
+
+// markdownSpans parses the inline content of a block, delimiters without a closing delimiter are kept as text
+func markdownSpans(text string, marks []Mark) []Span {
+
+	spans := make([]Span, 0)
+	sb := strings.Builder{}
+
+	flush := func() {
+		if sb.Len() > 0 {
+			spans = append(spans, Span{Text: sb.String(), Marks: marks})
+			sb.Reset()
+		}
+	}
+
+	withMark := func(m Mark) []Mark {
+		return append(append(make([]Mark, 0, len(marks)+1), marks...), m)
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+
+		// escaped punctuation
+		if c == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_{}[]()#+-.!~>", text[i+1]) >= 0 {
+			sb.WriteByte(text[i+1])
+			i += 2
+			continue
+		}
+
+		// code spans are not parsed further
+		if c == '`' {
+			if end := strings.IndexByte(text[i+1:], '`'); end >= 0 {
+				flush()
+				spans = append(spans, Span{Text: text[i+1 : i+1+end], Marks: withMark(Mark{Type: Code})})
+				i += end + 2
+				continue
+			}
+		}
+
+		if c == '[' {
+			if label, href, n, ok := markdownLink(text[i:]); ok {
+				flush()
+				spans = append(spans, markdownSpans(label, withMark(Mark{Type: Link, Href: href}))...)
+				i += n
+				continue
+			}
+		}
+
+		matched := false
+		for _, d := range markdownDelimiters {
+			if !strings.HasPrefix(text[i:], d.delim) {
+				continue
+			}
+
+			// underscores inside words are not emphasis, ie snake_case
+			if d.delim[0] == '_' && i > 0 && isWordByte(text[i-1]) {
+				break
+			}
+
+			rest := text[i+len(d.delim):]
+			end := strings.Index(rest, d.delim)
+			if end <= 0 || rest[0] == ' ' {
+				break
+			}
+
+			flush()
+			spans = append(spans, markdownSpans(rest[:end], withMark(Mark{Type: d.mark}))...)
+			i += len(d.delim)*2 + end
+			matched = true
+			break
+		}
+
+		if matched {
+			continue
+		}
+
+		sb.WriteByte(c)
+		i++
+	}
+	flush()
+
+	return spans
+}
+
+// markdownLink parses [label](href) at the start of text and returns the number of bytes it consists of
+func markdownLink(text string) (string, string, int, bool) {
+
+	close := strings.Index(text, "](")
+	if close < 0 {
+		return "", "", 0, false
+	}
+
+	end := strings.IndexByte(text[close+2:], ')')
+	if end < 0 {
+		return "", "", 0, false
+	}
+
+	href := strings.TrimSpace(text[close+2 : close+2+end])
+	return text[1:close], href, close + 3 + end, true
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
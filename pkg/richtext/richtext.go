@@ -0,0 +1,173 @@
+package richtext
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	ErrInvalidDocument = "invalid richtext document"
+	ErrInvalidFormat   = "richtext value must have blocks, html or markdown"
+)
+
+// swagger:enum BlockType
+type BlockType string
+
+const (
+	Paragraph   BlockType = "paragraph"
+	Heading     BlockType = "heading"
+	Quote       BlockType = "quote"
+	CodeBlock   BlockType = "code"
+	BulletList  BlockType = "bulletlist"
+	OrderedList BlockType = "orderedlist"
+	ListItem    BlockType = "listitem"
+	Rule        BlockType = "rule"
+	// EmbedBlock is content or an asset embedded in the document, see Embed
+	EmbedBlock BlockType = "embed"
+)
+
+// swagger:enum MarkType
+type MarkType string
+
+const (
+	Bold      MarkType = "bold"
+	Italic    MarkType = "italic"
+	Underline MarkType = "underline"
+	Strike    MarkType = "strike"
+	Code      MarkType = "code"
+	Link      MarkType = "link"
+)
+
+// swagger:enum EmbedType
+type EmbedType string
+
+const (
+	EmbedContent EmbedType = "content"
+	EmbedAsset   EmbedType = "asset"
+)
+
+// Document is the value of a richtext property.
+// Documents are always sanitized before they are stored, see Sanitize.
+// swagger:model RichTextDocument
+type Document struct {
+	Blocks []Block `bson:"blocks" json:"blocks"`
+}
+
+// Block is a paragraph, heading, code block or rule, or a quote or list containing other blocks.
+type Block struct {
+	Type BlockType `bson:"type" json:"type"`
+	// Level of headings, 1-6
+	Level int `bson:"level,omitempty" json:"level,omitempty"`
+	// Spans are the text of paragraphs, headings and code blocks
+	Spans []Span `bson:"spans,omitempty" json:"spans,omitempty"`
+	// Blocks are the children of quotes, lists and list items
+	Blocks []Block `bson:"blocks,omitempty" json:"blocks,omitempty"`
+	// Embed is the embedded content or asset of embed blocks
+	Embed *Embed `bson:"embed,omitempty" json:"embed,omitempty"`
+}
+
+// Span is text with the same marks
+type Span struct {
+	Text  string `bson:"text" json:"text"`
+	Marks []Mark `bson:"marks,omitempty" json:"marks,omitempty"`
+}
+
+type Mark struct {
+	Type MarkType `bson:"type" json:"type"`
+	// Href is the URL of links
+	Href string `bson:"href,omitempty" json:"href,omitempty"`
+}
+
+// Embed references content or an asset in the same workspace
+type Embed struct {
+	Type EmbedType `bson:"type" json:"type"`
+	ID   string    `bson:"id" json:"id"`
+}
+
+// Parse reads a richtext value from a request body or the database and sanitizes it with DefaultPolicy.
+// The value is either a Document, or an object with one of the keys blocks, html or markdown.
+func Parse(value interface{}) (Document, error) {
+
+	doc := Document{}
+
+	switch v := value.(type) {
+	case nil:
+		return doc, nil
+	case Document:
+		doc = v
+	case *Document:
+		doc = *v
+	default:
+		data, err := bson.Marshal(value)
+		if err != nil {
+			return Document{}, errors.New(ErrInvalidFormat)
+		}
+
+		input := struct {
+			Blocks   []Block `bson:"blocks"`
+			HTML     *string `bson:"html"`
+			Markdown *string `bson:"markdown"`
+		}{}
+
+		if err := bson.Unmarshal(data, &input); err != nil {
+			return Document{}, errors.New(ErrInvalidDocument)
+		}
+
+		switch {
+		case input.HTML != nil:
+			doc, err = FromHTML(*input.HTML)
+		case input.Markdown != nil:
+			doc = FromMarkdown(*input.Markdown)
+		case input.Blocks != nil:
+			doc.Blocks = input.Blocks
+		default:
+			return Document{}, errors.New(ErrInvalidFormat)
+		}
+
+		if err != nil {
+			return Document{}, err
+		}
+	}
+
+	return Sanitize(doc, DefaultPolicy), nil
+}
+
+func (d Document) IsEmpty() bool {
+	return len(d.Blocks) == 0
+}
+
+// PlainText returns the text of the document, blocks are separated by new lines.
+func (d Document) PlainText() string {
+
+	lines := make([]string, 0)
+	walk(d.Blocks, func(b Block) {
+		if len(b.Spans) > 0 {
+			lines = append(lines, spansText(b.Spans))
+		}
+	})
+
+	return strings.Join(lines, "\n")
+}
+
+// Embeds returns the IDs of the embedded content or assets of type t
+func (d Document) Embeds(t EmbedType) []string {
+
+	ids := make([]string, 0)
+	walk(d.Blocks, func(b Block) {
+		if b.Embed != nil && b.Embed.Type == t {
+			ids = append(ids, b.Embed.ID)
+		}
+	})
+
+	return ids
+}
+
+// walk calls fn for every block, parents before their children
+func walk(blocks []Block, fn func(Block)) {
+	for _, b := range blocks {
+		fn(b)
+		walk(b.Blocks, fn)
+	}
+}
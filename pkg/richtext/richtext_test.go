@@ -0,0 +1,220 @@
+//go:build unit
+
+package richtext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const embedID = "9b0ffd5e-1c7b-4e5b-9b2c-56e6f3c0d8a1"
+
+func Test_FromHTML(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		input  string
+		expect []Block
+	}{
+		{
+			name:  "paragraphs and marks",
+			input: "<p>Hello <strong>bold <em>world</em></strong>\n  again</p>",
+			expect: []Block{
+				{Type: Paragraph, Spans: []Span{
+					{Text: "Hello "},
+					{Text: "bold ", Marks: []Mark{{Type: Bold}}},
+					{Text: "world", Marks: []Mark{{Type: Bold}, {Type: Italic}}},
+					{Text: " again"},
+				}},
+			},
+		},
+		{
+			name:  "scripts are removed and unknown elements unwrapped",
+			input: `<div><script>alert(1)</script><span style="color:red">text</span><br><a href="/a" onclick="x()">link</a></div>`,
+			expect: []Block{
+				{Type: Paragraph, Spans: []Span{
+					{Text: "text"},
+					{Text: "\n"},
+					{Text: "link", Marks: []Mark{{Type: Link, Href: "/a"}}},
+				}},
+			},
+		},
+		{
+			name:  "headings lists and code",
+			input: "<h2>Title</h2><ul><li>one</li><li><p>two</p></li></ul><pre>a\n  b</pre><hr>",
+			expect: []Block{
+				{Type: Heading, Level: 2, Spans: []Span{{Text: "Title"}}},
+				{Type: BulletList, Blocks: []Block{
+					{Type: ListItem, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "one"}}}}},
+					{Type: ListItem, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "two"}}}}},
+				}},
+				{Type: CodeBlock, Spans: []Span{{Text: "a\n  b"}}},
+				{Type: Rule},
+			},
+		},
+		{
+			name:  "embeds",
+			input: `<div data-content-id="` + embedID + `"></div>`,
+			expect: []Block{
+				{Type: EmbedBlock, Embed: &Embed{Type: EmbedContent, ID: embedID}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := FromHTML(test.input)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, actual.Blocks)
+		})
+	}
+}
+
+func Test_FromMarkdown(t *testing.T) {
+
+	input := "# Title #\n\n" +
+		"Some **bold** and _italic_ text\n" +
+		"with a [link](https://example.com) and `code`, snake_case stays.\n\n" +
+		"> quoted\n\n" +
+		"1. first\n" +
+		"2. second\n" +
+		"   - nested\n\n" +
+		"```\nfmt.Println(\"*\")\n```\n\n" +
+		"---\n" +
+		"![](asset:" + embedID + ")\n"
+
+	expect := []Block{
+		{Type: Heading, Level: 1, Spans: []Span{{Text: "Title"}}},
+		{Type: Paragraph, Spans: []Span{
+			{Text: "Some "},
+			{Text: "bold", Marks: []Mark{{Type: Bold}}},
+			{Text: " and "},
+			{Text: "italic", Marks: []Mark{{Type: Italic}}},
+			{Text: " text with a "},
+			{Text: "link", Marks: []Mark{{Type: Link, Href: "https://example.com"}}},
+			{Text: " and "},
+			{Text: "code", Marks: []Mark{{Type: Code}}},
+			{Text: ", snake_case stays."},
+		}},
+		{Type: Quote, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "quoted"}}}}},
+		{Type: OrderedList, Blocks: []Block{
+			{Type: ListItem, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "first"}}}}},
+			{Type: ListItem, Blocks: []Block{
+				{Type: Paragraph, Spans: []Span{{Text: "second"}}},
+				{Type: BulletList, Blocks: []Block{
+					{Type: ListItem, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "nested"}}}}},
+				}},
+			}},
+		}},
+		{Type: CodeBlock, Spans: []Span{{Text: `fmt.Println("*")`}}},
+		{Type: Rule},
+		{Type: EmbedBlock, Embed: &Embed{Type: EmbedAsset, ID: embedID}},
+	}
+
+	assert.Equal(t, expect, FromMarkdown(input).Blocks)
+}
+
+func Test_Sanitize(t *testing.T) {
+
+	doc := Document{Blocks: []Block{
+		{Type: "table", Spans: []Span{{Text: "unknown block"}}},
+		{Type: Heading, Level: 9, Spans: []Span{{Text: "h"}}},
+		{Type: Paragraph, Spans: []Span{
+			{Text: "a", Marks: []Mark{{Type: Bold}, {Type: Bold}}},
+			{Text: "b", Marks: []Mark{{Type: Bold}, {Type: "blink"}}},
+			{Text: "c", Marks: []Mark{{Type: Link, Href: "javascript:alert(1)"}}},
+			{Text: "d", Marks: []Mark{{Type: Link, Href: "HTTPS://example.com"}}},
+		}},
+		{Type: Paragraph},
+		{Type: BulletList, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "not an item"}}}}},
+		{Type: ListItem, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "not in a list"}}}}},
+		{Type: EmbedBlock, Embed: &Embed{Type: EmbedContent, ID: "not an id"}},
+		{Type: EmbedBlock, Embed: &Embed{Type: EmbedContent, ID: "9B0FFD5E-1C7B-4E5B-9B2C-56E6F3C0D8A1"}},
+	}}
+
+	expect := []Block{
+		{Type: Paragraph, Spans: []Span{{Text: "unknown block"}}},
+		{Type: Heading, Level: 6, Spans: []Span{{Text: "h"}}},
+		{Type: Paragraph, Spans: []Span{
+			{Text: "ab", Marks: []Mark{{Type: Bold}}},
+			{Text: "c"},
+			{Text: "d", Marks: []Mark{{Type: Link, Href: "HTTPS://example.com"}}},
+		}},
+		{Type: BulletList, Blocks: []Block{
+			{Type: ListItem, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "not an item"}}}}},
+		}},
+		{Type: Paragraph, Spans: []Span{{Text: "not in a list"}}},
+		{Type: EmbedBlock, Embed: &Embed{Type: EmbedContent, ID: embedID}},
+	}
+
+	assert.Equal(t, expect, Sanitize(doc, DefaultPolicy).Blocks)
+
+	// only paragraphs and bold
+	policy := Policy{Blocks: []BlockType{Paragraph}, Marks: []MarkType{Bold}}
+	actual := Sanitize(Document{Blocks: []Block{
+		{Type: Quote, Blocks: []Block{{Type: Heading, Level: 1, Spans: []Span{{Text: "x", Marks: []Mark{{Type: Italic}}}}}}},
+	}}, policy)
+	assert.Equal(t, []Block{{Type: Paragraph, Spans: []Span{{Text: "x"}}}}, actual.Blocks)
+}
+
+func Test_ToHTML(t *testing.T) {
+
+	doc := Document{Blocks: []Block{
+		{Type: Heading, Level: 3, Spans: []Span{{Text: "a < b"}}},
+		{Type: Paragraph, Spans: []Span{
+			{Text: "line\nbreak", Marks: []Mark{{Type: Bold}, {Type: Link, Href: `/x?a=1&b="2"`}}},
+		}},
+		{Type: OrderedList, Blocks: []Block{
+			{Type: ListItem, Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "one"}}}}},
+		}},
+		{Type: CodeBlock, Spans: []Span{{Text: "<script>"}}},
+		{Type: EmbedBlock, Embed: &Embed{Type: EmbedAsset, ID: embedID}},
+	}}
+
+	expect := `<h3>a &lt; b</h3>` +
+		`<p><strong><a href="/x?a=1&amp;b=&#34;2&#34;">line<br>break</a></strong></p>` +
+		`<ol><li>one</li></ol>` +
+		`<pre><code>&lt;script&gt;</code></pre>` +
+		`<figure data-asset-id="` + embedID + `"></figure>`
+
+	html := ToHTML(doc)
+	assert.Equal(t, expect, html)
+
+	// rendered HTML is converted back to the same document
+	parsed, err := FromHTML(html)
+	assert.NoError(t, err)
+	assert.Equal(t, doc, Sanitize(parsed, DefaultPolicy))
+}
+
+func Test_Parse(t *testing.T) {
+
+	expect := Document{Blocks: []Block{{Type: Paragraph, Spans: []Span{{Text: "x", Marks: []Mark{{Type: Bold}}}}}}}
+
+	inputs := []interface{}{
+		expect,
+		map[string]interface{}{"html": "<p><b>x</b></p>"},
+		map[string]interface{}{"markdown": "**x**"},
+		map[string]interface{}{"blocks": []interface{}{
+			map[string]interface{}{"type": "paragraph", "spans": []interface{}{
+				map[string]interface{}{"text": "x", "marks": []interface{}{map[string]interface{}{"type": "bold"}}},
+			}},
+		}},
+		bson.M{"blocks": bson.A{bson.M{"type": "paragraph", "spans": bson.A{bson.M{"text": "x", "marks": bson.A{bson.M{"type": "bold"}}}}}}},
+	}
+
+	for _, input := range inputs {
+		actual, err := Parse(input)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expect, actual)
+	}
+
+	_, err := Parse("<p>x</p>")
+	assert.Error(t, err)
+
+	_, err = Parse(map[string]interface{}{"text": "x"})
+	assert.Error(t, err)
+}
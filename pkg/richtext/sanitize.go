@@ -0,0 +1,231 @@
+package richtext
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Policy is the allow-list documents are sanitized against
+type Policy struct {
+	Blocks []BlockType
+	Marks  []MarkType
+	// URLSchemes are the schemes allowed in links, relative URLs are always allowed
+	URLSchemes []string
+}
+
+// DefaultPolicy allows every block and mark, links can only be http, https or mailto
+var DefaultPolicy = Policy{
+	Blocks:     []BlockType{Paragraph, Heading, Quote, CodeBlock, BulletList, OrderedList, ListItem, Rule, EmbedBlock},
+	Marks:      []MarkType{Bold, Italic, Underline, Strike, Code, Link},
+	URLSchemes: []string{"http", "https", "mailto"},
+}
+
+// Sanitize returns a copy of doc that only contains what the policy allows.
+// Blocks that are not allowed are replaced with their text, marks and links that are not allowed are removed.
+// The structure is repaired so lists only contain list items and empty blocks are removed.
+func Sanitize(doc Document, p Policy) Document {
+	return Document{Blocks: p.blocks(doc.Blocks, "")}
+}
+
+func (p Policy) blocks(blocks []Block, parent BlockType) []Block {
+
+	result := make([]Block, 0, len(blocks))
+	for _, b := range blocks {
+		result = append(result, p.block(b, parent)...)
+	}
+
+	return result
+}
+
+func (p Policy) block(b Block, parent BlockType) []Block {
+
+	if !p.allowsBlock(b.Type) || (b.Type == ListItem && parent != BulletList && parent != OrderedList) {
+		return p.unwrap(b)
+	}
+
+	switch b.Type {
+	case Paragraph, Heading:
+		spans := p.spans(b.Spans)
+		if len(spans) == 0 {
+			return nil
+		}
+
+		block := Block{Type: b.Type, Spans: spans}
+		if b.Type == Heading {
+			block.Level = clamp(b.Level, 1, 6)
+		}
+		return []Block{block}
+
+	case CodeBlock:
+		text := spansText(b.Spans)
+		if text == "" {
+			return nil
+		}
+		return []Block{{Type: CodeBlock, Spans: []Span{{Text: text}}}}
+
+	case Rule:
+		return []Block{{Type: Rule}}
+
+	case Quote, ListItem:
+		children := p.unwrap(Block{Spans: b.Spans})
+		children = append(children, p.blocks(b.Blocks, b.Type)...)
+		if len(children) == 0 {
+			return nil
+		}
+		return []Block{{Type: b.Type, Blocks: children}}
+
+	case BulletList, OrderedList:
+		items := make([]Block, 0)
+		for _, child := range p.blocks(b.Blocks, b.Type) {
+			if child.Type != ListItem {
+				child = Block{Type: ListItem, Blocks: []Block{child}}
+			}
+			items = append(items, child)
+		}
+
+		if len(items) == 0 {
+			return nil
+		}
+		return []Block{{Type: b.Type, Blocks: items}}
+
+	case EmbedBlock:
+		if b.Embed == nil || (b.Embed.Type != EmbedContent && b.Embed.Type != EmbedAsset) {
+			return nil
+		}
+
+		id, err := uuid.Parse(b.Embed.ID)
+		if err != nil {
+			return nil
+		}
+		return []Block{{Type: EmbedBlock, Embed: &Embed{Type: b.Embed.Type, ID: id.String()}}}
+	}
+
+	return nil
+}
+
+// unwrap replaces a block that is not allowed with a paragraph of its text followed by its children
+func (p Policy) unwrap(b Block) []Block {
+
+	result := make([]Block, 0)
+	if spans := p.spans(b.Spans); len(spans) > 0 && p.allowsBlock(Paragraph) {
+		result = append(result, Block{Type: Paragraph, Spans: spans})
+	}
+
+	return append(result, p.blocks(b.Blocks, "")...)
+}
+
+// spans removes marks that are not allowed and merges spans with the same marks
+func (p Policy) spans(spans []Span) []Span {
+
+	result := make([]Span, 0, len(spans))
+	for _, s := range spans {
+		if s.Text == "" {
+			continue
+		}
+
+		marks := make([]Mark, 0, len(s.Marks))
+		for _, m := range s.Marks {
+			if !p.allowsMark(m.Type) || hasMark(marks, m.Type) {
+				continue
+			}
+
+			if m.Type != Link {
+				marks = append(marks, Mark{Type: m.Type})
+				continue
+			}
+
+			if href, ok := p.href(m.Href); ok {
+				marks = append(marks, Mark{Type: Link, Href: href})
+			}
+		}
+
+		if len(marks) == 0 {
+			marks = nil
+		}
+
+		if last := len(result) - 1; last >= 0 && reflect.DeepEqual(result[last].Marks, marks) {
+			result[last].Text += s.Text
+			continue
+		}
+
+		result = append(result, Span{Text: s.Text, Marks: marks})
+	}
+
+	return result
+}
+
+// href returns the URL if it is relative or its scheme is allowed
+func (p Policy) href(href string) (string, bool) {
+
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return "", false
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+
+	if u.Scheme == "" {
+		return href, true
+	}
+
+	for _, s := range p.URLSchemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return href, true
+		}
+	}
+
+	return "", false
+}
+
+func (p Policy) allowsBlock(t BlockType) bool {
+	for _, b := range p.Blocks {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) allowsMark(t MarkType) bool {
+	for _, m := range p.Marks {
+		if m == t {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMark(marks []Mark, t MarkType) bool {
+	for _, m := range marks {
+		if m.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+func spansText(spans []Span) string {
+
+	sb := strings.Builder{}
+	for _, s := range spans {
+		sb.WriteString(s.Text)
+	}
+
+	return sb.String()
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
@@ -0,0 +1,180 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const scheduleCollection = "schedule"
+
+type ScheduleRepository struct {
+	client *mongo.Client
+}
+
+func NewScheduleRepository(c *mongo.Client) ScheduleRepository {
+	return ScheduleRepository{
+		client: c,
+	}
+}
+
+func (r ScheduleRepository) CreateSchedules(ctx context.Context, schedules []Schedule, workspace uuid.UUID) error {
+
+	docs := make([]interface{}, 0, len(schedules))
+	for _, s := range schedules {
+		docs = append(docs, s)
+	}
+
+	_, err := r.client.Database(workspace.String()).
+		Collection(scheduleCollection).
+		InsertMany(ctx, docs)
+
+	return err
+}
+
+func (r ScheduleRepository) GetSchedule(ctx context.Context, id uuid.UUID, workspace uuid.UUID) (Schedule, error) {
+
+	res := &Schedule{}
+	err := r.client.Database(workspace.String()).
+		Collection(scheduleCollection).
+		FindOne(ctx, bson.M{"_id": id}).
+		Decode(res)
+
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	return *res, nil
+}
+
+// ListSchedules returns the schedules of content ordered by time, or of every content in the workspace if contentID is empty.
+func (r ScheduleRepository) ListSchedules(ctx context.Context, contentID uuid.UUID, workspace uuid.UUID) ([]Schedule, error) {
+
+	filter := bson.M{}
+	if contentID != (uuid.UUID{}) {
+		filter["contentId"] = contentID
+	}
+
+	cursor, err := r.client.Database(workspace.String()).
+		Collection(scheduleCollection).
+		Find(ctx, filter, options.Find().SetSort(bson.M{"at": 1}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Schedule, 0)
+	for cursor.Next(ctx) {
+		item := &Schedule{}
+		err := cursor.Decode(item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, *item)
+	}
+
+	return items, nil
+}
+
+func (r ScheduleRepository) UpdateSchedule(
+	ctx context.Context,
+	id uuid.UUID,
+	workspace uuid.UUID,
+	updateFn func(context.Context, *Schedule) (*Schedule, error)) error {
+
+	s := &Schedule{}
+	err := r.client.Database(workspace.String()).
+		Collection(scheduleCollection).
+		FindOne(ctx, bson.M{"_id": id}).
+		Decode(s)
+
+	if err != nil {
+		return err
+	}
+
+	updated, err := updateFn(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.Database(workspace.String()).
+		Collection(scheduleCollection).
+		UpdateOne(
+			ctx,
+			bson.M{"_id": id},
+			bson.M{"$set": updated})
+
+	return err
+}
+
+// ClaimDue atomically sets the earliest pending schedule that is due to running and returns it.
+// Since the schedule is claimed, several workers can run against the same database without executing a schedule twice.
+// A schedule that has been running for longer than timeout is claimed again, the worker running it is assumed to have stopped.
+// Returns mongo.ErrNoDocuments when no schedule is due.
+func (r ScheduleRepository) ClaimDue(ctx context.Context, now time.Time, timeout time.Duration, workspace uuid.UUID) (Schedule, error) {
+
+	res := &Schedule{}
+	err := r.client.Database(workspace.String()).
+		Collection(scheduleCollection).
+		FindOneAndUpdate(
+			ctx,
+			bson.M{
+				"at": bson.M{"$lte": now.UTC()},
+				"$or": bson.A{
+					bson.M{"status": Pending},
+					bson.M{"status": Running, "claimed": bson.M{"$lte": now.UTC().Add(-timeout)}},
+					// running before the claim time was stored
+					bson.M{"status": Running, "claimed": nil},
+				},
+			},
+			bson.M{"$set": bson.M{"status": Running, "claimed": now.UTC()}},
+			options.FindOneAndUpdate().
+				SetSort(bson.M{"at": 1}).
+				SetReturnDocument(options.After)).
+		Decode(res)
+
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	return *res, nil
+}
+
+// CancelSchedule cancels the schedule of content if it is pending, the status is checked by the update so a schedule
+// that a worker has claimed is never cancelled. Returns mongo.ErrNoDocuments if content has no schedule with id.
+func (r ScheduleRepository) CancelSchedule(ctx context.Context, id, contentID uuid.UUID, workspace uuid.UUID) error {
+
+	col := r.client.Database(workspace.String()).Collection(scheduleCollection)
+	filter := bson.M{"_id": id, "contentId": contentID}
+
+	res, err := col.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "contentId": contentID, "status": Pending},
+		bson.M{"$set": bson.M{"status": Cancelled}})
+
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	n, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return errors.New(ErrNotPending)
+}
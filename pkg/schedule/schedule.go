@@ -0,0 +1,143 @@
+package schedule
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// swagger:enum Action
+type Action string
+
+// swagger:enum Status
+type Status string
+
+const (
+	Publish   Action = "publish"
+	Unpublish Action = "unpublish"
+
+	Pending   Status = "pending"
+	Running   Status = "running"
+	Completed Status = "completed"
+	Failed    Status = "failed"
+	Cancelled Status = "cancelled"
+
+	ErrScheduleInPast   = "scheduled time has already passed"
+	ErrUnpublishBefore  = "unpublish must be scheduled after publish"
+	ErrNotPending       = "schedule is not pending"
+	ErrMissingTimestamp = "publish or unpublish time is required"
+
+	// DefaultClaimTimeout is how long a schedule can be running before it is claimed again,
+	// a worker that stops while it runs a schedule otherwise leaves it running forever
+	DefaultClaimTimeout = 10 * time.Minute
+)
+
+// localLayout is used when a timestamp is given without offset, it is then interpreted in the schedules time zone.
+const localLayout = "2006-01-02T15:04:05"
+
+// Schedule is a publish or unpublish of a content version that is executed at a given time.
+// swagger:model Schedule
+type Schedule struct {
+	ID        uuid.UUID `bson:"_id"`
+	ContentID uuid.UUID `bson:"contentId"`
+	Version   int       `bson:"version"`
+	Action    Action    `bson:"action"`
+	// At is stored in UTC
+	At time.Time `bson:"at"`
+	// TimeZone is the IANA time zone the schedule was created in
+	TimeZone string    `bson:"timezone"`
+	Status   Status    `bson:"status"`
+	Created  time.Time `bson:"created"`
+	// Claimed is when a worker started running the schedule
+	Claimed  time.Time `bson:"claimed,omitempty"`
+	Executed time.Time `bson:"executed,omitempty"`
+	// Error is set when the schedule failed to execute
+	Error string `bson:"error,omitempty"`
+}
+
+type ScheduleFactory struct {
+}
+
+// NewSchedules creates a publish and/or an unpublish schedule for a content version.
+// Timestamps are RFC 3339, a timestamp without offset is interpreted in timeZone, which defaults to UTC.
+func (f ScheduleFactory) NewSchedules(contentID uuid.UUID, version int, publishAt, unpublishAt, timeZone string, now time.Time) ([]Schedule, error) {
+
+	if publishAt == "" && unpublishAt == "" {
+		return nil, errors.New(ErrMissingTimestamp)
+	}
+
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Schedule, 0)
+	var publish time.Time
+
+	if publishAt != "" {
+		publish, err = ParseTime(publishAt, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		if publish.Before(now) {
+			return nil, errors.New(ErrScheduleInPast)
+		}
+
+		result = append(result, newSchedule(contentID, version, Publish, publish, loc, now))
+	}
+
+	if unpublishAt != "" {
+		unpublish, err := ParseTime(unpublishAt, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		if unpublish.Before(now) {
+			return nil, errors.New(ErrScheduleInPast)
+		}
+
+		if !publish.IsZero() && !unpublish.After(publish) {
+			return nil, errors.New(ErrUnpublishBefore)
+		}
+
+		result = append(result, newSchedule(contentID, version, Unpublish, unpublish, loc, now))
+	}
+
+	return result, nil
+}
+
+func newSchedule(contentID uuid.UUID, version int, action Action, at time.Time, loc *time.Location, now time.Time) Schedule {
+	return Schedule{
+		ID:        uuid.New(),
+		ContentID: contentID,
+		Version:   version,
+		Action:    action,
+		At:        at.UTC(),
+		TimeZone:  loc.String(),
+		Status:    Pending,
+		Created:   now.UTC(),
+	}
+}
+
+// ParseTime parses an RFC 3339 timestamp. If the timestamp has no offset it is parsed in loc.
+func ParseTime(value string, loc *time.Location) (time.Time, error) {
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.ParseInLocation(localLayout, value, loc)
+}
+
+// Local returns the scheduled time in the time zone the schedule was created in.
+func (s Schedule) Local() time.Time {
+
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return s.At
+	}
+
+	return s.At.In(loc)
+}
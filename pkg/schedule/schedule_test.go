@@ -0,0 +1,95 @@
+//go:build unit
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewSchedules(t *testing.T) {
+
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		publishAt   string
+		unpublishAt string
+		timeZone    string
+		expect      []Schedule
+		expectErr   string
+	}{
+		{
+			name:      "publish with offset",
+			publishAt: "2022-03-02T10:00:00+01:00",
+			expect: []Schedule{
+				{Action: Publish, At: time.Date(2022, 3, 2, 9, 0, 0, 0, time.UTC), TimeZone: "UTC"},
+			},
+		},
+		{
+			name:      "publish without offset is in time zone",
+			publishAt: "2022-03-02T10:00:00",
+			timeZone:  "Europe/Stockholm",
+			expect: []Schedule{
+				{Action: Publish, At: time.Date(2022, 3, 2, 9, 0, 0, 0, time.UTC), TimeZone: "Europe/Stockholm"},
+			},
+		},
+		{
+			name:        "publish and unpublish",
+			publishAt:   "2022-03-02T10:00:00Z",
+			unpublishAt: "2022-03-03T10:00:00Z",
+			expect: []Schedule{
+				{Action: Publish, At: time.Date(2022, 3, 2, 10, 0, 0, 0, time.UTC), TimeZone: "UTC"},
+				{Action: Unpublish, At: time.Date(2022, 3, 3, 10, 0, 0, 0, time.UTC), TimeZone: "UTC"},
+			},
+		},
+		{
+			name:        "unpublish before publish",
+			publishAt:   "2022-03-03T10:00:00Z",
+			unpublishAt: "2022-03-02T10:00:00Z",
+			expectErr:   ErrUnpublishBefore,
+		},
+		{
+			name:      "in the past",
+			publishAt: "2022-02-01T10:00:00Z",
+			expectErr: ErrScheduleInPast,
+		},
+		{
+			name:      "missing timestamp",
+			expectErr: ErrMissingTimestamp,
+		},
+		{
+			name:      "unknown time zone",
+			publishAt: "2022-03-02T10:00:00",
+			timeZone:  "Foo/Bar",
+			expectErr: "unknown time zone Foo/Bar",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			f := ScheduleFactory{}
+			actual, err := f.NewSchedules(uuid.New(), 1, test.publishAt, test.unpublishAt, test.timeZone, now)
+
+			if test.expectErr != "" {
+				assert.EqualError(t, err, test.expectErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, actual, len(test.expect))
+
+			for i, expect := range test.expect {
+				assert.Equal(t, expect.Action, actual[i].Action)
+				assert.True(t, expect.At.Equal(actual[i].At), actual[i].At)
+				assert.Equal(t, expect.TimeZone, actual[i].TimeZone)
+				assert.Equal(t, Pending, actual[i].Status)
+				assert.Equal(t, 1, actual[i].Version)
+			}
+		})
+	}
+}
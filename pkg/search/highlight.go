@@ -0,0 +1,179 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SnippetLength is the default length of highlighted snippets
+const SnippetLength = 160
+
+const (
+	highlightStart = "<em>"
+	highlightEnd   = "</em>"
+	ellipsis       = "…"
+)
+
+// Terms returns the words of a search query that should be highlighted.
+// Excluded terms (prefixed with -) are skipped and phrases in quotes are split into words.
+func Terms(query string) []string {
+
+	terms := make([]string, 0)
+	for _, word := range strings.Fields(query) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+
+		for _, w := range strings.FieldsFunc(word, isSeparator) {
+			terms = append(terms, strings.ToLower(w))
+		}
+	}
+
+	return terms
+}
+
+// Highlight returns a snippet of at most length runes of text around the first match of terms, with every match
+// wrapped in <em>. The text is HTML escaped. Returns false if no term matches.
+//
+// Terms are matched as word prefixes of their stem, so words that MongoDB matched after stemming are
+// highlighted in most cases, ie "running" highlights "runner".
+func Highlight(text string, terms []string, length int) (string, bool) {
+
+	runes := []rune(text)
+	words := splitWords(runes)
+
+	first := -1
+	matches := make([]bool, len(words))
+	for i, w := range words {
+		word := strings.ToLower(string(runes[w[0]:w[1]]))
+		for _, term := range terms {
+			if strings.HasPrefix(word, stem(term)) {
+				matches[i] = true
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+
+	if first < 0 {
+		return "", false
+	}
+
+	// start the snippet a few words before the first match
+	start := 0
+	if len(runes) > length {
+		start = words[first][0] - length/4
+		if start < 0 {
+			start = 0
+		}
+		for i := first; i >= 0; i-- {
+			if words[i][0] <= start {
+				start = words[i][0]
+				break
+			}
+		}
+	}
+
+	end := start + length
+	if end >= len(runes) {
+		end = len(runes)
+	} else {
+		// do not cut a word in half
+		for i := len(words) - 1; i >= 0; i-- {
+			if words[i][1] <= end {
+				end = words[i][1]
+				break
+			}
+		}
+	}
+
+	sb := strings.Builder{}
+	if start > 0 {
+		sb.WriteString(ellipsis)
+	}
+
+	pos := start
+	for i, w := range words {
+		if !matches[i] || w[0] < start || w[1] > end {
+			continue
+		}
+
+		sb.WriteString(html.EscapeString(string(runes[pos:w[0]])))
+		sb.WriteString(highlightStart)
+		sb.WriteString(html.EscapeString(string(runes[w[0]:w[1]])))
+		sb.WriteString(highlightEnd)
+		pos = w[1]
+	}
+	sb.WriteString(html.EscapeString(string(runes[pos:end])))
+
+	if end < len(runes) {
+		sb.WriteString(ellipsis)
+	}
+
+	return sb.String(), true
+}
+
+// stem removes the last characters of longer terms, it is a rough approximation of the stemming done by MongoDB
+func stem(term string) string {
+
+	runes := []rune(term)
+	if len(runes) <= 4 {
+		return term
+	}
+
+	cut := len(runes) - 3
+	if cut < 4 {
+		cut = 4
+	}
+
+	return string(runes[:cut])
+}
+
+// splitWords returns the start and end of every word in text
+func splitWords(text []rune) [][2]int {
+
+	words := make([][2]int, 0)
+	start := -1
+
+	for i, r := range text {
+		if isSeparator(r) {
+			if start >= 0 {
+				words = append(words, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, [2]int{start, len(text)})
+	}
+
+	return words
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// Highlights returns a highlighted snippet of every field of the hit that matches query
+func (h Hit) Highlights(query string, length int) map[string]string {
+
+	terms := Terms(query)
+	result := make(map[string]string)
+
+	for name, value := range h.Fields {
+		if snippet, ok := Highlight(value, terms, length); ok {
+			result[name] = snippet
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/db"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const searchCollection = "search"
+
+type SearchRepository struct {
+	client *mongo.Client
+}
+
+func NewSearchRepository(c *mongo.Client) SearchRepository {
+	return SearchRepository{
+		client: c,
+	}
+}
+
+// EnsureIndexes creates the text index of the workspace.
+// A collection can only have one text index, the language of each entry is read from textLanguage.
+func (r SearchRepository) EnsureIndexes(ctx context.Context, workspace uuid.UUID) error {
+
+	_, err := r.client.Database(workspace.String()).
+		Collection(searchCollection).
+		Indexes().
+		CreateMany(ctx, []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "name", Value: "text"}, {Key: "text", Value: "text"}},
+				Options: options.Index().
+					SetWeights(bson.M{"name": 3, "text": 1}).
+					SetDefaultLanguage("none").
+					SetLanguageOverride("textLanguage"),
+			},
+			{
+				Keys: bson.D{{Key: "contentId", Value: 1}, {Key: "channel", Value: 1}},
+			},
+		})
+
+	return err
+}
+
+// Index replaces the entries of the content in channel with the text of data.
+func (r SearchRepository) Index(ctx context.Context, data content.ContentData, channel Channel, languages []string, workspace uuid.UUID) error {
+
+	col := r.client.Database(workspace.String()).Collection(searchCollection)
+
+	_, err := col.DeleteMany(ctx, bson.M{"contentId": data.ContentID, "channel": channel})
+	if err != nil {
+		return err
+	}
+
+	entries := NewEntries(data, channel, languages)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(entries))
+	for _, e := range entries {
+		docs = append(docs, e)
+	}
+
+	_, err = col.InsertMany(ctx, docs)
+	return err
+}
+
+// Remove removes the entries of the content in channel
+func (r SearchRepository) Remove(ctx context.Context, contentID uuid.UUID, channel Channel, workspace uuid.UUID) error {
+
+	_, err := r.client.Database(workspace.String()).
+		Collection(searchCollection).
+		DeleteMany(ctx, bson.M{"contentId": contentID, "channel": channel})
+
+	return err
+}
+
+// Search returns the entries in language that match query, ordered by relevance.
+// Since the order is by relevance page.Sort is ignored and the continuation token is the offset of the next page.
+func (r SearchRepository) Search(ctx context.Context, query, language string, channel Channel, page db.Page, workspace uuid.UUID) ([]Hit, db.PageResult, error) {
+
+	if strings.TrimSpace(query) == "" {
+		return nil, db.PageResult{}, errors.New(ErrMissingQuery)
+	}
+
+	if page.Size == 0 {
+		page.Size = db.DefaultPageSize
+	}
+
+	offset := 0
+	if page.Token != "" {
+		data, err := base64.RawURLEncoding.DecodeString(page.Token)
+		if err != nil {
+			return nil, db.PageResult{}, errors.New(db.ErrInvalidPageToken)
+		}
+
+		offset, err = strconv.Atoi(string(data))
+		if err != nil || offset < 0 {
+			return nil, db.PageResult{}, errors.New(db.ErrInvalidPageToken)
+		}
+	}
+
+	filter := bson.M{
+		"$text": bson.M{
+			"$search":   query,
+			"$language": TextLanguage(language),
+		},
+		"language": language,
+		"channel":  channel,
+	}
+
+	col := r.client.Database(workspace.String()).Collection(searchCollection)
+
+	total, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	cursor, err := col.Find(ctx, filter, options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(page.Size)))
+
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	hits := make([]Hit, 0)
+	if err := cursor.All(ctx, &hits); err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	res := db.PageResult{Total: int(total)}
+	if next := offset + len(hits); next < res.Total {
+		res.Next = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(next)))
+	}
+
+	return hits, res, nil
+}
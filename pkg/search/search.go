@@ -0,0 +1,157 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/crikke/cms/pkg/contentdefinition"
+	"github.com/crikke/cms/pkg/richtext"
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+)
+
+// swagger:enum Channel
+type Channel string
+
+const (
+	// Latest is the most recently edited version of content and is searched in the content management API
+	Latest Channel = "latest"
+	// Published is the published version of content and is searched in the content delivery API
+	Published Channel = "published"
+)
+
+const ErrMissingQuery = "search query is required"
+
+// textLanguages are the languages MongoDB text indexes can stem, other languages are indexed without stemming.
+var textLanguages = map[string]string{
+	"da": "danish",
+	"de": "german",
+	"en": "english",
+	"es": "spanish",
+	"fi": "finnish",
+	"fr": "french",
+	"hu": "hungarian",
+	"it": "italian",
+	"nb": "norwegian",
+	"nl": "dutch",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sv": "swedish",
+	"tr": "turkish",
+}
+
+// Entry is the searchable text of a content version in one language.
+// Every content has at most one entry per channel and language.
+type Entry struct {
+	ID        string    `bson:"_id"`
+	ContentID uuid.UUID `bson:"contentId"`
+	Version   int       `bson:"version"`
+	Channel   Channel   `bson:"channel"`
+	Language  string    `bson:"language"`
+	// TextLanguage is the language used to stem the text, see TextLanguage
+	TextLanguage string `bson:"textLanguage"`
+	// Name is weighted higher than the other fields
+	Name string `bson:"name"`
+	// Text is every text field concatenated
+	Text string `bson:"text"`
+	// Fields are the text fields, used to highlight the matches
+	Fields map[string]string `bson:"fields"`
+}
+
+// Hit is an entry that matches a search
+type Hit struct {
+	Entry `bson:",inline"`
+	Score float64 `bson:"score"`
+}
+
+// TextLanguage returns the name of the language MongoDB uses to stem text in lang, or none if it is not supported
+func TextLanguage(lang string) string {
+
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return "none"
+	}
+
+	base, _ := tag.Base()
+	if name, ok := textLanguages[base.String()]; ok {
+		return name
+	}
+
+	return "none"
+}
+
+// NewEntries returns the entries of every language of the content version that has text.
+// Unlocalized fields are only stored in the default language but are searchable in every language.
+func NewEntries(data content.ContentData, channel Channel, languages []string) []Entry {
+
+	entries := make([]Entry, 0)
+	if len(languages) == 0 {
+		return entries
+	}
+
+	shared := textFields(data.Properties[languages[0]], false)
+
+	for _, lang := range languages {
+		fields := textFields(data.Properties[lang], true)
+		for name, value := range shared {
+			fields[name] = value
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		text := make([]string, 0, len(fields))
+		for _, name := range names {
+			text = append(text, fields[name])
+		}
+
+		entries = append(entries, Entry{
+			ID:           fmt.Sprintf("%s/%s/%s", data.ContentID, channel, lang),
+			ContentID:    data.ContentID,
+			Version:      data.Version,
+			Channel:      channel,
+			Language:     lang,
+			TextLanguage: TextLanguage(lang),
+			Name:         fields[contentdefinition.PROPFIELD_NAME],
+			Text:         strings.Join(text, "\n"),
+			Fields:       fields,
+		})
+	}
+
+	return entries
+}
+
+func textFields(fields content.ContentFields, localized bool) map[string]string {
+
+	result := make(map[string]string)
+	for name, f := range fields {
+		if f.Localized != localized || name == contentdefinition.PROPFIELD_URLSEGMENT {
+			continue
+		}
+
+		switch f.Type {
+		case contentdefinition.PropertyTypeText:
+			if s, ok := f.Value.(string); ok && s != "" {
+				result[name] = s
+			}
+		case contentdefinition.PropertyTypeRichText:
+			// richtext is indexed by its text, markup is not searchable
+			if doc, err := richtext.Parse(f.Value); err == nil && !doc.IsEmpty() {
+				result[name] = doc.PlainText()
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,144 @@
+//go:build unit
+
+package search
+
+import (
+	"testing"
+
+	"github.com/crikke/cms/pkg/content"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_TextLanguage(t *testing.T) {
+
+	assert.Equal(t, "swedish", TextLanguage("sv-SE"))
+	assert.Equal(t, "english", TextLanguage("en"))
+	assert.Equal(t, "norwegian", TextLanguage("nb-NO"))
+	assert.Equal(t, "none", TextLanguage("pl-PL"))
+	assert.Equal(t, "none", TextLanguage("not a language"))
+}
+
+func Test_NewEntries(t *testing.T) {
+
+	id := uuid.New()
+	data := content.ContentData{
+		ContentID: id,
+		Version:   2,
+		Properties: content.ContentLanguage{
+			"sv-SE": content.ContentFields{
+				"name":       {Type: "text", Localized: true, Value: "Namn"},
+				"urlsegment": {Type: "text", Localized: true, Value: "namn"},
+				"body":       {Type: "text", Localized: true, Value: "Brödtext"},
+				"brand":      {Type: "text", Value: "Acme"},
+				"price":      {Type: "number", Value: 10.0},
+				"empty":      {Type: "text", Localized: true, Value: ""},
+				"summary": {Type: "richtext", Localized: true, Value: bson.M{"blocks": bson.A{
+					bson.M{"type": "heading", "level": 2, "spans": bson.A{bson.M{"text": "Kort"}}},
+					bson.M{"type": "paragraph", "spans": bson.A{bson.M{"text": "fet", "marks": bson.A{bson.M{"type": "bold"}}}, bson.M{"text": " text"}}},
+				}}},
+			},
+			"en-US": content.ContentFields{
+				"name": {Type: "text", Localized: true, Value: "Name"},
+			},
+		},
+	}
+
+	entries := NewEntries(data, Published, []string{"sv-SE", "en-US", "de-DE"})
+
+	assert.Equal(t, []Entry{
+		{
+			ID:           id.String() + "/published/sv-SE",
+			ContentID:    id,
+			Version:      2,
+			Channel:      Published,
+			Language:     "sv-SE",
+			TextLanguage: "swedish",
+			Name:         "Namn",
+			Text:         "Brödtext\nAcme\nNamn\nKort\nfet text",
+			Fields:       map[string]string{"name": "Namn", "body": "Brödtext", "brand": "Acme", "summary": "Kort\nfet text"},
+		},
+		{
+			ID:           id.String() + "/published/en-US",
+			ContentID:    id,
+			Version:      2,
+			Channel:      Published,
+			Language:     "en-US",
+			TextLanguage: "english",
+			Name:         "Name",
+			Text:         "Acme\nName",
+			Fields:       map[string]string{"name": "Name", "brand": "Acme"},
+		},
+		{
+			ID:           id.String() + "/published/de-DE",
+			ContentID:    id,
+			Version:      2,
+			Channel:      Published,
+			Language:     "de-DE",
+			TextLanguage: "german",
+			Text:         "Acme",
+			Fields:       map[string]string{"brand": "Acme"},
+		},
+	}, entries)
+}
+
+func Test_Highlight(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		text   string
+		query  string
+		length int
+		expect string
+		match  bool
+	}{
+		{
+			name:   "no match",
+			text:   "foo bar",
+			query:  "baz",
+			length: 100,
+		},
+		{
+			name:   "every match is highlighted",
+			text:   "Running shoes for runners",
+			query:  "running",
+			length: 100,
+			expect: "<em>Running</em> shoes for <em>runners</em>",
+			match:  true,
+		},
+		{
+			name:   "excluded terms are not highlighted",
+			text:   "red shoes, blue shoes",
+			query:  `"red shoes" -blue`,
+			length: 100,
+			expect: "<em>red</em> <em>shoes</em>, blue <em>shoes</em>",
+			match:  true,
+		},
+		{
+			name:   "text is escaped",
+			text:   "<b>shoe</b> & sock",
+			query:  "sock",
+			length: 100,
+			expect: "&lt;b&gt;shoe&lt;/b&gt; &amp; <em>sock</em>",
+			match:  true,
+		},
+		{
+			name:   "snippet around first match",
+			text:   "one two three four five six seven eight nine ten",
+			query:  "seven",
+			length: 20,
+			expect: "…five six <em>seven</em> eight…",
+			match:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, ok := Highlight(test.text, Terms(test.query), test.length)
+
+			assert.Equal(t, test.match, ok)
+			assert.Equal(t, test.expect, actual)
+		})
+	}
+}
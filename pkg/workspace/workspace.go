@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/crikke/cms/pkg/db"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,6 +20,10 @@ type Workspace struct {
 	Languages       []string          `bson:"languages"`
 	DefaultLanguage string            `bson:"defaultlanguage"`
 	Tags            map[string]string `bson:"tags"`
+	// StartPage is the content at the root that has the path /, content without url segment is otherwise not routable
+	StartPage uuid.UUID `bson:"startpage"`
+	// Revision is incremented on every write and is used to detect concurrent updates
+	Revision int `bson:"revision"`
 }
 
 func NewWorkspace(name, description, defaultLocale string) (Workspace, error) {
@@ -106,14 +111,23 @@ func (r WorkspaceRepository) Update(ctx context.Context, id uuid.UUID, updateFn
 		return err
 	}
 
-	_, err = r.client.Database("cms").
-		Collection(workspaceCollection).
-		UpdateOne(
-			ctx,
-			bson.M{"_id": id},
-			bson.M{"$set": updated})
+	col := r.client.Database("cms").Collection(workspaceCollection)
+	updated.Revision = ws.Revision + 1
+
+	res, err := col.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "revision": db.RevisionFilter(ws.Revision)},
+		bson.M{"$set": updated})
+
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return db.RevisionConflict(ctx, col, bson.M{"_id": id})
+	}
 
-	return err
+	return nil
 }
 
 func (r WorkspaceRepository) ListAll(ctx context.Context) ([]Workspace, error) {
@@ -140,6 +154,33 @@ func (r WorkspaceRepository) ListAll(ctx context.Context) ([]Workspace, error) {
 	return items, nil
 }
 
+// ListPage returns a page of the workspaces
+func (r WorkspaceRepository) ListPage(ctx context.Context, page db.Page) ([]Workspace, db.PageResult, error) {
+
+	items := make([]Workspace, 0)
+	res, err := db.FindPage(
+		ctx,
+		r.client.Database("cms").Collection(workspaceCollection),
+		bson.M{},
+		page,
+		nil,
+		func(cursor *mongo.Cursor) error {
+			ws := &Workspace{}
+			if err := cursor.Decode(ws); err != nil {
+				return err
+			}
+
+			items = append(items, *ws)
+			return nil
+		})
+
+	if err != nil {
+		return nil, db.PageResult{}, err
+	}
+
+	return items, res, nil
+}
+
 func (r WorkspaceRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	_, err := r.client.Database("cms").
 		Collection(workspaceCollection).